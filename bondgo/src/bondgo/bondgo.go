@@ -33,8 +33,9 @@ type BondgoCheck struct {
 	Outer *BondgoCheck // The previuos scope
 	Clean *BondgoCheck // The scope that need to be clean by its next brother aka when the scope ends
 
-	Vars    map[string]VarCell // The map of variable in the current scope
-	Returns []VarCell          // Returns for functions call
+	Vars    map[string]VarCell   // The map of variable in the current scope
+	Consts  map[string]ConstCell // The map of constants declared in the current scope
+	Returns []VarCell            // Returns for functions call
 
 	CurrentLoop   string
 	CurrentSwitch string
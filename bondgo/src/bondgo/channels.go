@@ -0,0 +1,223 @@
+package bondgo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"procbuilder"
+	"strconv"
+)
+
+// Chan_make recognizes make(chan T) and make(chan T, n), it returns the channel type and the buffer depth.
+// A non constant capacity sets the fault, the caller has to check it when ok is false.
+func (bg *BondgoCheck) Chan_make(n ast.Expr) (*VarType, int, bool) {
+	call, ok := n.(*ast.CallExpr)
+	if !ok {
+		return nil, 0, false
+	}
+	if fun, ok := call.Fun.(*ast.Ident); !ok || fun.Name != "make" || len(call.Args) < 1 || len(call.Args) > 2 {
+		return nil, 0, false
+	}
+	if _, ok := call.Args[0].(*ast.ChanType); !ok {
+		return nil, 0, false
+	}
+	newt, err := Type_from_ast(call.Args[0])
+	if err != nil {
+		bg.Set_faulty(err.Error())
+		return nil, 0, false
+	}
+	depth := 0
+	if len(call.Args) == 2 {
+		if depth, ok = bg.Const_int(call.Args[1]); !ok {
+			bg.Set_faulty("Channel capacity has to be a non negative constant")
+			return nil, 0, false
+		}
+	}
+	return newt, depth, true
+}
+
+// Chan_supported tells if the channel type can be mapped to a channel shared object
+func (bg *BondgoCheck) Chan_supported(newt *VarType) bool {
+	if gent, _ := Type_from_string(bg.Basic_chantype); Same_Type(newt, gent) || bg.Chan_words(newt) > 1 {
+		return true
+	}
+	if gent, _ := Type_from_string("chan bool"); Same_Type(newt, gent) {
+		return true
+	}
+	return false
+}
+
+// Chan_new allocates a new channel of the given depth and binds it to the variable
+func (bg *BondgoCheck) Chan_new(vari string, newt *VarType, depth int) bool {
+	if !bg.Chan_supported(newt) {
+		bg.Set_faulty(vari + ": unsupported channel type " + newt.String())
+		return false
+	}
+	bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{newt, CHANNEL, 0, 0, 0, 0, 0, 0}}
+	resp := <-bg.Answers
+	if resp.AnsType != ANS_OK {
+		bg.Set_faulty("Resource reservation failed")
+		return false
+	}
+	bg.Vars[vari] = resp.Cell
+	bg.Chan_depth(resp.Cell, depth)
+
+	if bg.In_debug() {
+		fmt.Println("\t\tAllocated to " + vari + " the cell " + bg.Vars[vari].String())
+	}
+	return true
+}
+
+// Chan_depth records the buffer depth of a channel, the shared object gets the largest requested
+func (bg *BondgoCheck) Chan_depth(cell VarCell, depth int) {
+	if depth > 0 {
+		bg.Used <- UsageNotify{TR_CHAN, cell.Global_id, C_DEPTH, S_NIL, depth}
+	}
+}
+
+// chan_operand resolves an expression naming a channel variable
+func (bg *BondgoCheck) chan_operand(n ast.Expr) (VarCell, bool) {
+	ident, ok := n.(*ast.Ident)
+	if !ok {
+		bg.Set_faulty("A channel variable is expected")
+		return VarCell{}, false
+	}
+	cell, ok := bg.lookup_var(ident.Name)
+	if !ok {
+		return VarCell{}, false
+	}
+	if cell.Procobjtype != CHANNEL {
+		bg.Set_faulty(ident.Name + " is not a channel")
+		return VarCell{}, false
+	}
+	return cell, true
+}
+
+// Chan_close compiles close(ch), the channel refuses further sends and the receives past the buffered values report the close
+func (bg *BondgoCheck) Chan_close(call *ast.CallExpr) bool {
+	if len(call.Args) != 1 {
+		bg.Set_faulty("close expects one argument")
+		return false
+	}
+	channel, ok := bg.chan_operand(call.Args[0])
+	if !ok {
+		return false
+	}
+	bg.emit("chclose", procbuilder.Get_channel_name(channel.Id))
+	return true
+}
+
+// Chan_receive gets a value from a channel together with the ok flag. The read of a closed and drained channel completes
+// past the queued operation, the chw register is not zero and the value is zero. For a multi-word value the last
+// word tells.
+func (bg *BondgoCheck) Chan_receive(channel VarCell) (VarCell, VarCell, bool) {
+	cell, ok := bg.new_cell(channel.Vtype.Values[0], REGISTER)
+	if !ok {
+		return VarCell{}, VarCell{}, false
+	}
+	gent, _ := Type_from_string(bg.Basic_type)
+	wait, ok := bg.new_cell(gent, REGISTER)
+	if !ok {
+		return VarCell{}, VarCell{}, false
+	}
+	gentb, _ := Type_from_string("bool")
+	okreg, ok := bg.new_cell(gentb, REGISTER)
+	if !ok {
+		return VarCell{}, VarCell{}, false
+	}
+	channame := procbuilder.Get_channel_name(channel.Id)
+	waitname := procbuilder.Get_register_name(wait.Id)
+	okname := procbuilder.Get_register_name(okreg.Id)
+	for k := 0; k < bg.Type_words(cell.Vtype); k++ {
+		bg.emit("wrd", word_name(cell, k)+" "+channame)
+		bg.emit("chw", waitname)
+	}
+	bg.emit("rset", okname+" 1")
+	bg.emit("jz", waitname+" "+bg.next_location(2))
+	bg.emit("clr", okname)
+	if !bg.free_cells(wait) {
+		return VarCell{}, VarCell{}, false
+	}
+	return cell, okreg, true
+}
+
+// Chan_receive_assign compiles v, ok := <-ch and v, ok = <-ch
+func (bg *BondgoCheck) Chan_receive_assign(x *ast.AssignStmt, chanexpr ast.Expr) bool {
+	channel, ok := bg.chan_operand(chanexpr)
+	if !ok {
+		return false
+	}
+	value, okreg, ok := bg.Chan_receive(channel)
+	if !ok {
+		return false
+	}
+	define := x.Tok == token.DEFINE
+	if !bg.select_assign(x.Lhs[0], value, define) || !bg.select_assign(x.Lhs[1], okreg, define) {
+		return false
+	}
+	return bg.free_cells(value, okreg)
+}
+
+// Range_loop compiles a range loop whose iteration starts with the given prologue, the loop is left only by a
+// jump to its end, either from the prologue or from a break
+func (bg *BondgoCheck) Range_loop(x *ast.RangeStmt, prologue func(bgfor *BondgoCheck) bool) bool {
+	// Create a new BondgoCheck for the loop, the iteration variable lives in its scope
+	results := new(BondgoResults) // Results go in here
+	results.Init_Results(bg.BondgoConfig)
+
+	vars := make(map[string]VarCell)
+	bgfor := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, vars, nil, bg.Returns, "", "", bg.CurrentDevice, bg.CurrentRoutine}
+
+	starting_point := bg.CountLines(bg.CurrentRoutine)
+
+	bgfor.CurrentLoop = fmt.Sprintf("%p", bgfor)
+
+	if !prologue(bgfor) {
+		return false
+	}
+
+	if x.Body != nil {
+		ast.Walk(bgfor, x.Body)
+		if bgfor.Is_faulty() {
+			return false
+		}
+	}
+
+	continue_point := bgfor.CountLines(bgfor.CurrentRoutine)
+
+	bgfor.Positions[bgfor.CurrentRoutine] = x.Pos()
+	bgfor.emit("j", "<<"+bgfor.CurrentLoop+"STARTFOR>>")
+
+	prod_lines_total := bgfor.CountLines(bgfor.CurrentRoutine)
+
+	bgfor.Replacer(bgfor.CurrentRoutine, "<<"+bgfor.CurrentLoop+"STARTFOR>>", "<<0>>")
+	bgfor.Replacer(bgfor.CurrentRoutine, "<<"+bgfor.CurrentLoop+"ENDFOR>>", "<<"+strconv.Itoa(prod_lines_total)+">>")
+	bgfor.Replacer(bgfor.CurrentRoutine, "<<"+bgfor.CurrentLoop+"CONTINUEFOR>>", "<<"+strconv.Itoa(continue_point)+">>")
+
+	// Shift eventually created reference to line number within the code
+	bgfor.Shift_program_location(bgfor.CurrentRoutine, starting_point)
+
+	bg.Append_program(bg.CurrentRoutine, bgfor.BondgoResults, bgfor.CurrentRoutine)
+
+	return true
+}
+
+// Chan_range compiles for v := range ch, the loop receives until the channel is closed and drained
+func (bg *BondgoCheck) Chan_range(x *ast.RangeStmt, channel VarCell) bool {
+	if x.Value != nil {
+		bg.Set_faulty("Range over a channel permits only one iteration variable")
+		return false
+	}
+
+	return bg.Range_loop(x, func(bgfor *BondgoCheck) bool {
+		value, okreg, ok := bgfor.Chan_receive(channel)
+		if !ok {
+			return false
+		}
+		bgfor.emit("jz", procbuilder.Get_register_name(okreg.Id)+" <<"+bgfor.CurrentLoop+"ENDFOR>>")
+		if x.Key != nil && !bgfor.select_assign(x.Key, value, x.Tok == token.DEFINE) {
+			return false
+		}
+		return bgfor.free_cells(value, okreg)
+	})
+}
@@ -9,6 +9,7 @@ type BondgoConfig struct {
 	Basic_type     string
 	Basic_chantype string
 	Cascading_io   bool
+	Clock_mhz      int
 }
 
 func (db *BondgoConfig) In_debug() bool {
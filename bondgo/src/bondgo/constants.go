@@ -0,0 +1,365 @@
+package bondgo
+
+import (
+	"errors"
+	"go/ast"
+	"go/constant"
+	"go/token"
+)
+
+// The type to hold constants, folded at compile time. Vtype is nil for untyped constants
+type ConstCell struct {
+	Vtype *VarType
+	Value constant.Value
+}
+
+// The function used to resolve constant names while folding
+type ConstLookup func(name string) (ConstCell, bool)
+
+var not_constant = errors.New("Not a constant expression")
+
+func (c ConstCell) String() string {
+	if c.Vtype == nil {
+		return c.Value.ExactString()
+	}
+	return c.Vtype.String() + "(" + c.Value.ExactString() + ")"
+}
+
+// Const_convert checks that a constant is representable by the type t and returns it typed
+func Const_convert(c ConstCell, t *VarType) (ConstCell, error) {
+	if gent, _ := Type_from_string("bool"); Same_Type(t, gent) {
+		if c.Value.Kind() != constant.Bool {
+			return ConstCell{}, errors.New("Cannot use " + c.String() + " as bool")
+		}
+		return ConstCell{gent, c.Value}, nil
+	}
+	width := Integer_width(t)
+	if width == 0 {
+		return ConstCell{}, errors.New("Unsupported constant type " + t.String())
+	}
+	if c.Value.Kind() != constant.Int {
+		return ConstCell{}, errors.New("Cannot use " + c.String() + " as " + t.String())
+	}
+	if constant.Sign(c.Value) < 0 || constant.BitLen(c.Value) > width {
+		return ConstCell{}, errors.New("Constant " + c.Value.ExactString() + " overflows " + t.String())
+	}
+	return ConstCell{t, c.Value}, nil
+}
+
+// Const_fold evaluates a constant expression, iota is negative outside const declarations.
+// The not_constant error is returned if the expression is not constant
+func Const_fold(n ast.Expr, lookup ConstLookup, iota int) (ConstCell, error) {
+	switch exptype := n.(type) {
+	case *ast.BasicLit:
+		if exptype.Kind == token.INT || exptype.Kind == token.CHAR {
+			return ConstCell{nil, constant.MakeFromLiteral(exptype.Value, exptype.Kind, 0)}, nil
+		}
+	case *ast.Ident:
+		switch exptype.Name {
+		case "true", "false":
+			return ConstCell{nil, constant.MakeBool(exptype.Name == "true")}, nil
+		case "iota":
+			if iota >= 0 {
+				return ConstCell{nil, constant.MakeInt64(int64(iota))}, nil
+			}
+		}
+		if c, ok := lookup(exptype.Name); ok {
+			return c, nil
+		}
+	case *ast.SelectorExpr:
+		// The time units, untyped in nanoseconds
+		if pkg, ok := exptype.X.(*ast.Ident); ok && pkg.Name == "time" {
+			if ns, ok := Time_unit(exptype.Sel.Name); ok {
+				return ConstCell{nil, constant.MakeInt64(ns)}, nil
+			}
+		}
+	case *ast.ParenExpr:
+		return Const_fold(exptype.X, lookup, iota)
+	case *ast.UnaryExpr:
+		x, err := Const_fold(exptype.X, lookup, iota)
+		if err != nil {
+			return ConstCell{}, err
+		}
+		switch exptype.Op {
+		case token.NOT:
+			if x.Value.Kind() == constant.Bool {
+				return ConstCell{x.Vtype, constant.UnaryOp(token.NOT, x.Value, 0)}, nil
+			}
+		case token.ADD, token.SUB, token.XOR:
+			if x.Value.Kind() == constant.Int {
+				if x.Vtype == nil {
+					return ConstCell{nil, constant.UnaryOp(exptype.Op, x.Value, 0)}, nil
+				}
+				return Const_convert(ConstCell{nil, constant.UnaryOp(exptype.Op, x.Value, uint(Integer_width(x.Vtype)))}, x.Vtype)
+			}
+		}
+		return ConstCell{}, errors.New("Invalid constant operation " + exptype.Op.String() + x.String())
+	case *ast.BinaryExpr:
+		x, err := Const_fold(exptype.X, lookup, iota)
+		if err != nil {
+			return ConstCell{}, err
+		}
+		y, err := Const_fold(exptype.Y, lookup, iota)
+		if err != nil {
+			return ConstCell{}, err
+		}
+		return const_binary(exptype.Op, x, y)
+	case *ast.CallExpr:
+		// Conversions
+		if fun, ok := exptype.Fun.(*ast.Ident); ok && len(exptype.Args) == 1 {
+			t, _ := Type_from_ast(fun)
+			gent, _ := Type_from_string("bool")
+			if Integer_width(t) > 0 || Same_Type(t, gent) {
+				x, err := Const_fold(exptype.Args[0], lookup, iota)
+				if err != nil {
+					return ConstCell{}, err
+				}
+				return Const_convert(x, t)
+			}
+		}
+	}
+	return ConstCell{}, not_constant
+}
+
+func const_binary(op token.Token, x ConstCell, y ConstCell) (ConstCell, error) {
+	invalid := errors.New("Invalid constant operation " + x.String() + " " + op.String() + " " + y.String())
+
+	if op == token.SHL || op == token.SHR {
+		count, exact := constant.Uint64Val(y.Value)
+		if x.Value.Kind() != constant.Int || y.Value.Kind() != constant.Int || !exact || count > 512 {
+			return ConstCell{}, invalid
+		}
+		result := ConstCell{nil, constant.Shift(x.Value, op, uint(count))}
+		if x.Vtype == nil {
+			return result, nil
+		}
+		return Const_convert(result, x.Vtype)
+	}
+
+	if x.Vtype != nil && y.Vtype != nil && !Same_Type(x.Vtype, y.Vtype) {
+		return ConstCell{}, errors.New("Mismatched types " + x.Vtype.String() + " and " + y.Vtype.String() + ", an explicit conversion is needed")
+	}
+	t := x.Vtype
+	if t == nil {
+		t = y.Vtype
+	}
+	if x.Value.Kind() != y.Value.Kind() {
+		return ConstCell{}, invalid
+	}
+
+	switch op {
+	case token.EQL, token.NEQ, token.LSS, token.GTR, token.LEQ, token.GEQ:
+		if x.Value.Kind() == constant.Bool && op != token.EQL && op != token.NEQ {
+			return ConstCell{}, invalid
+		}
+		return ConstCell{nil, constant.MakeBool(constant.Compare(x.Value, op, y.Value))}, nil
+	case token.LAND, token.LOR:
+		if x.Value.Kind() != constant.Bool {
+			return ConstCell{}, invalid
+		}
+		return ConstCell{t, constant.BinaryOp(x.Value, op, y.Value)}, nil
+	case token.ADD, token.SUB, token.MUL, token.QUO, token.REM, token.AND, token.OR, token.XOR, token.AND_NOT:
+		if x.Value.Kind() != constant.Int {
+			return ConstCell{}, invalid
+		}
+		if (op == token.QUO || op == token.REM) && constant.Sign(y.Value) == 0 {
+			return ConstCell{}, errors.New("Constant division by zero")
+		}
+		if op == token.QUO {
+			// Integer division
+			op = token.QUO_ASSIGN
+		}
+		result := ConstCell{nil, constant.BinaryOp(x.Value, op, y.Value)}
+		if t == nil {
+			return result, nil
+		}
+		return Const_convert(result, t)
+	}
+	return ConstCell{}, invalid
+}
+
+// Const_decl folds the specs of a const declaration and passes every constant to define.
+// A spec without values repeats the type and the values of the previous one, with the next iota
+func Const_decl(decl *ast.GenDecl, lookup ConstLookup, define func(string, ConstCell) error) error {
+	var lasttype ast.Expr
+	var lastvalues []ast.Expr
+	for iota, s := range decl.Specs {
+		spec := s.(*ast.ValueSpec)
+		if len(spec.Values) > 0 {
+			lasttype, lastvalues = spec.Type, spec.Values
+		} else if spec.Type != nil || lastvalues == nil {
+			return errors.New("Missing constant value for " + spec.Names[0].Name)
+		}
+		if len(lastvalues) != len(spec.Names) {
+			return errors.New("Wrong number of constant values for " + spec.Names[0].Name)
+		}
+		var t *VarType
+		if lasttype != nil {
+			t, _ = Type_from_ast(lasttype)
+			if t == nil {
+				return errors.New("Unsupported constant type")
+			}
+		}
+		for i, name := range spec.Names {
+			c, err := Const_fold(lastvalues[i], lookup, iota)
+			if err == not_constant {
+				return errors.New(name.Name + ": value is not constant")
+			} else if err != nil {
+				return err
+			}
+			if t != nil {
+				if c, err = Const_convert(c, t); err != nil {
+					return err
+				}
+			}
+			if name.Name == "_" {
+				continue
+			}
+			if err := define(name.Name, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Fold_constants resolves the package level const declarations, they may refer to each other in any order
+func (fn *BondgoFunctions) Fold_constants() {
+	lookup := func(name string) (ConstCell, bool) {
+		c, ok := fn.Constants[name]
+		return c, ok
+	}
+	pending := fn.Constdecls
+	for len(pending) > 0 {
+		var lasterr error
+		failed := make([]*ast.GenDecl, 0)
+		for _, decl := range pending {
+			folded := make(map[string]ConstCell)
+			err := Const_decl(decl, func(name string) (ConstCell, bool) {
+				if c, ok := folded[name]; ok {
+					return c, true
+				}
+				return lookup(name)
+			}, func(name string, c ConstCell) error {
+				if _, ok := fn.Constants[name]; ok {
+					return errors.New(name + ": name already used")
+				}
+				if _, ok := fn.Functions[name]; ok {
+					return errors.New(name + ": name already used")
+				}
+				folded[name] = c
+				return nil
+			})
+			if err != nil {
+				failed = append(failed, decl)
+				lasterr = err
+				continue
+			}
+			for name, c := range folded {
+				fn.Constants[name] = c
+			}
+		}
+		if len(failed) == len(pending) {
+			fn.Set_faulty(lasterr.Error())
+			return
+		}
+		pending = failed
+	}
+	fn.Constdecls = nil
+}
+
+// Const_declare folds a const declaration within the current scope
+func (bg *BondgoCheck) Const_declare(decl *ast.GenDecl) bool {
+	if bg.Consts == nil {
+		bg.Consts = make(map[string]ConstCell)
+	}
+	err := Const_decl(decl, bg.lookup_const, func(name string, c ConstCell) error {
+		if _, ok := bg.Vars[name]; ok {
+			return errors.New(name + ": name already used")
+		}
+		if _, ok := bg.Consts[name]; ok {
+			return errors.New(name + ": name already used")
+		}
+		bg.Consts[name] = c
+		return nil
+	})
+	if err != nil {
+		bg.Set_faulty(err.Error())
+		return false
+	}
+	return true
+}
+
+// Constants are looked up in the enclosing scopes, variables shadow them, and then at package level
+func (bg *BondgoCheck) lookup_const(name string) (ConstCell, bool) {
+	for scope := bg; scope != nil; scope = scope.Outer {
+		if _, ok := scope.Vars[name]; ok {
+			return ConstCell{}, false
+		}
+		if c, ok := scope.Consts[name]; ok {
+			return c, true
+		}
+	}
+	c, ok := bg.Constants[name]
+	return c, ok
+}
+
+// Const_eval folds an expression if it is constant, ok is false if it is not or if folding failed (the latter sets the fault)
+func (bg *BondgoCheck) Const_eval(n ast.Expr) (ConstCell, bool) {
+	c, err := Const_fold(n, bg.lookup_const, -1)
+	if err != nil {
+		if err != not_constant {
+			bg.Set_faulty(err.Error())
+		}
+		return ConstCell{}, false
+	}
+	return c, true
+}
+
+// Const_int returns the value of a constant integer expression
+func (bg *BondgoCheck) Const_int(n ast.Expr) (int, bool) {
+	if c, ok := bg.Const_eval(n); ok && c.Value.Kind() == constant.Int {
+		if value, exact := constant.Int64Val(c.Value); exact && value >= 0 {
+			return int(value), true
+		}
+	}
+	return 0, false
+}
+
+// Const_load loads a constant in a new register cell, an untyped constant gets the type t, or the basic type if t is not an integer type
+func (bg *BondgoCheck) Const_load(c ConstCell, t *VarType) ([]VarCell, bool) {
+	if c.Value.Kind() == constant.Bool {
+		gent, _ := Type_from_string("bool")
+		cell, ok := bg.new_cell(gent, REGISTER)
+		if !ok {
+			return []VarCell{}, false
+		}
+		if constant.BoolVal(c.Value) {
+			bg.emit("rset", word_name(cell, 0)+" 1")
+		} else {
+			bg.emit("clr", word_name(cell, 0))
+		}
+		return []VarCell{cell}, true
+	}
+	if c.Vtype != nil {
+		t = c.Vtype
+	}
+	if Integer_width(t) == 0 {
+		t, _ = Type_from_string(bg.Basic_type)
+	}
+	if bg.Type_words(t) == 0 {
+		bg.Set_faulty("Unsupported constant type " + t.String())
+		return []VarCell{}, false
+	}
+	if c.Value.Kind() != constant.Int {
+		bg.Set_faulty("Unsupported constant " + c.String())
+		return []VarCell{}, false
+	}
+	return bg.Word_const(t, c.Value.ExactString())
+}
+
+// An untyped constant expression, it takes the type of the context
+func (bg *BondgoCheck) untyped_const(n ast.Expr) bool {
+	c, err := Const_fold(n, bg.lookup_const, -1)
+	return err == nil && c.Vtype == nil
+}
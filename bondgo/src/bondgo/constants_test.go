@@ -0,0 +1,59 @@
+package bondgo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestConstants(t *testing.T) {
+	src := `package main
+const (
+	A = iota * 2
+	B
+	C uint8 = 1 << (B + 1)
+	D
+)
+const E = C + A + 3
+const F uint8 = 255 + 1
+`
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "consts.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := new(BondgoConfig)
+	config.Rsize = 8
+	messages := new(BondgoMessages)
+	messages.Init_Messages(config)
+	functs := new(BondgoFunctions)
+	functs.Init_Functions(config, messages)
+	ast.Walk(functs, f)
+	functs.Fold_constants()
+
+	fmt.Println(functs.Constants)
+
+	expected := map[string]int64{"A": 0, "B": 2, "C": 8, "D": 8, "E": 11}
+	for name, value := range expected {
+		c, ok := functs.Constants[name]
+		if !ok {
+			t.Error("Constant " + name + " not folded")
+			continue
+		}
+		if v, _ := constant.Int64Val(c.Value); v != value {
+			t.Error("Constant "+name+" wrong value", c)
+		}
+	}
+
+	if c := functs.Constants["E"]; c.Vtype == nil || c.Vtype.Name != "uint8" {
+		t.Error("E has to be typed")
+	}
+
+	if !functs.Is_faulty() {
+		t.Error("The overflow of F is not detected")
+	}
+}
@@ -191,8 +191,12 @@ func (bg *BondgoCheck) Create_Bondmachine(rsize int, filter string) (*bondmachin
 	// TODO Only channels here, include the others
 	creqs := bg.Chanr
 
-	for _, _ = range creqs {
-		bmach.Add_shared_objects([]string{"channel:"})
+	for chanid := 0; chanid < len(creqs); chanid++ {
+		if creq, ok := creqs[chanid]; ok && creq.Depth > 0 {
+			bmach.Add_shared_objects([]string{"channel:" + strconv.Itoa(creq.Depth)})
+		} else {
+			bmach.Add_shared_objects([]string{"channel:"})
+		}
 	}
 	for chanid, creq := range creqs {
 		for _, proc_id := range creq.Connected {
@@ -203,6 +207,37 @@ func (bg *BondgoCheck) Create_Bondmachine(rsize int, filter string) (*bondmachin
 		}
 	}
 
+	// The mutexes and the counters follow the channels, every processor links them in the order of its local ids
+	procids := make([]int, 0, len(bg.Procr))
+	for proc_id := range bg.Procr {
+		procids = append(procids, proc_id)
+	}
+	sort.Ints(procids)
+
+	sobase := len(creqs)
+	for _, sokind := range []string{"mutex:", "counter:"} {
+		sonum := len(bg.Mutexes)
+		if sokind == "counter:" {
+			sonum = len(bg.Counters)
+		}
+		for soid := 0; soid < sonum; soid++ {
+			bmach.Add_shared_objects([]string{sokind})
+		}
+		for _, proc_id := range procids {
+			soids := bg.Procr[proc_id].Mutexes_ids
+			if sokind == "counter:" {
+				soids = bg.Procr[proc_id].Counters_ids
+			}
+			for _, soid := range soids {
+				endpoints := make([]string, 2)
+				endpoints[0] = strconv.Itoa(proc_id)
+				endpoints[1] = strconv.Itoa(sobase + soid)
+				bmach.Connect_processor_shared_object(endpoints)
+			}
+		}
+		sobase += sonum
+	}
+
 	return bmach, residual, nil
 }
 
@@ -694,7 +729,8 @@ func (bg *BondgoCheck) Create_Udpbond_Cluster(rsize int, extc *udpbond.Cluster)
 	return resultcluster, resultpeerids, resultbond, resultio, resultresi, nil
 }
 
-func Assembly_2_Processor(rsize int, asmcode []string) (*procbuilder.Machine, error) {
+// Assembly_2_Processor creates the processor of an assembly program, the debug directives are returned by ROM address
+func Assembly_2_Processor(rsize int, asmcode []string) (*procbuilder.Machine, map[int]*procbuilder.Debug_line, error) {
 
 	config := new(BondgoConfig)
 	config.Debug = false
@@ -752,14 +788,16 @@ func Assembly_2_Processor(rsize int, asmcode []string) (*procbuilder.Machine, er
 	vars := make(map[string]VarCell)
 	returns := make([]VarCell, 0)
 
-	bgmain := &BondgoCheck{results, config, reqmnts, run, messages, functs, usagenotify, varreq, varans, nil, nil, vars, returns, "", "", "device_0", 0}
+	bgmain := &BondgoCheck{results, config, reqmnts, run, messages, functs, usagenotify, varreq, varans, nil, nil, vars, nil, returns, "", "", "device_0", 0}
 
 	bgmain.Used <- UsageNotify{TR_PROC, 0, C_DEVICE, bgmain.CurrentDevice, I_NIL}
 
 	bgmain.Abstract_assembler(rsize, asmcode, usagenotify)
 
 	for _, currline := range asmcode {
-		bgmain.WriteLine(0, currline)
+		if _, ok := procbuilder.Parse_debug_directive(currline); !ok {
+			bgmain.WriteLine(0, currline)
+		}
 	}
 
 	for procid, rout := range bgmain.Program {
@@ -772,28 +810,34 @@ func Assembly_2_Processor(rsize int, asmcode []string) (*procbuilder.Machine, er
 	<-usagedone
 
 	if mymachine, ok := bgmain.Create_Connecting_Processor(rsize, 0); ok {
-		return mymachine, nil
+		return mymachine, procbuilder.Assembly_debug_lines(asmcode), nil
 	} else {
-		return nil, errors.New("Creating processor failed")
+		return nil, nil, errors.New("Creating processor failed")
 	}
 
-	return nil, nil
+	return nil, nil, nil
 }
 
-func MultiAsm2BondMachine(rsize int, aafile *Abs_assembly) (*bondmachine.Bondmachine, error) {
+func MultiAsm2BondMachine(rsize int, aafile *Abs_assembly) (*bondmachine.Bondmachine, *procbuilder.Debug_info, error) {
 	bmach := new(bondmachine.Bondmachine)
 	bmach.Rsize = uint8(rsize)
 	bmach.Init()
 
+	debuginfo := new(procbuilder.Debug_info)
+	debuginfo.Init()
+
 	for i, proc_prog := range aafile.ProcProgs {
-		if mach, err := Assembly_2_Processor(rsize, strings.Split(proc_prog, "\n")); err == nil {
+		if mach, lines, err := Assembly_2_Processor(rsize, strings.Split(proc_prog, "\n")); err == nil {
 			bmach.Domains = append(bmach.Domains, mach)
 			if _, ok := bmach.Add_processor(i); ok != nil {
-				return nil, errors.New("Attach processor failed")
+				return nil, nil, errors.New("Attach processor failed")
+			}
+			for address, dl := range lines {
+				debuginfo.Set(i, address, dl)
 			}
 
 		} else {
-			return nil, errors.New("Creating processor failed")
+			return nil, nil, errors.New("Creating processor failed")
 		}
 	}
 
@@ -845,5 +889,5 @@ func MultiAsm2BondMachine(rsize int, aafile *Abs_assembly) (*bondmachine.Bondmac
 
 	}
 
-	return bmach, nil
+	return bmach, debuginfo, nil
 }
@@ -0,0 +1,112 @@
+package bondgo
+
+import (
+	"go/token"
+	"procbuilder"
+	"strconv"
+	"strings"
+)
+
+// Every emitted line records the position of the statement being compiled and the variables in scope, the lines
+// are then mapped to the ROM addresses (one per line) and the variables to the objects holding them.
+
+// WriteLine writes a line with the source of the current statement of the routine
+func (bg *BondgoCheck) WriteLine(proc_id int, line string) {
+	bg.WriteLine_debug(proc_id, line, Line_debug{bg.Positions[proc_id], bg.Scope_vars()})
+}
+
+// Scope_vars returns the variables visible from the current scope
+func (bg *BondgoCheck) Scope_vars() map[string]VarCell {
+	result := make(map[string]VarCell)
+	for scope := bg; scope != nil; scope = scope.Outer {
+		for name, cell := range scope.Vars {
+			if _, ok := result[name]; !ok {
+				result[name] = cell
+			}
+		}
+	}
+	return result
+}
+
+// The processor object of a variable word, the allocated one if the register allocator run
+func (p *BondgoRoutine) word_binding(cell VarCell, id int) (string, bool) {
+	switch cell.Procobjtype {
+	case REGISTER:
+		if p.Bindings != nil {
+			name, ok := p.Bindings[id]
+			return name, ok
+		}
+		return procbuilder.Get_register_name(id), true
+	case MEMORY:
+		return "m" + strconv.Itoa(id), true
+	case INPUT:
+		return "i" + strconv.Itoa(id), true
+	case OUTPUT:
+		return "o" + strconv.Itoa(id), true
+	}
+	return "", false
+}
+
+// The processor objects of a variable, the words of the multi-word ones are separated by colons
+func (p *BondgoRoutine) Binding(cell VarCell) (string, bool) {
+	words := make([]string, 0)
+	for id := cell.Id; id <= cell_last(cell); id++ {
+		name, ok := p.word_binding(cell, id)
+		if !ok {
+			return "", false
+		}
+		words = append(words, name)
+	}
+	return strings.Join(words, ":"), true
+}
+
+// Debug_lines returns the source of the lines of a processor by ROM address
+func (rs *BondgoResults) Debug_lines(proc_id int, fset *token.FileSet) map[int]*procbuilder.Debug_line {
+	result := make(map[int]*procbuilder.Debug_line)
+	routine, ok := rs.Program[proc_id]
+	if !ok {
+		return result
+	}
+	for address, ld := range routine.Debug {
+		if !ld.Pos.IsValid() {
+			continue
+		}
+		pos := fset.Position(ld.Pos)
+		dl := &procbuilder.Debug_line{File: pos.Filename, Line: pos.Line, Column: pos.Column, Vars: make(map[string]string)}
+		for name, cell := range ld.Vars {
+			if binding, ok := routine.Binding(cell); ok {
+				dl.Vars[name] = binding
+			}
+		}
+		result[address] = dl
+	}
+	return result
+}
+
+// Debug_info returns the debug info of all the processors
+func (rs *BondgoResults) Debug_info(fset *token.FileSet) *procbuilder.Debug_info {
+	di := new(procbuilder.Debug_info)
+	di.Init()
+	for proc_id := range rs.Program {
+		for address, dl := range rs.Debug_lines(proc_id, fset) {
+			di.Set(proc_id, address, dl)
+		}
+	}
+	return di
+}
+
+// Write_assembly_debug writes the assembly with a debug directive before every instruction whose source is known
+func (rs *BondgoResults) Write_assembly_debug(proc_id int, fset *token.FileSet) string {
+	lines := rs.Debug_lines(proc_id, fset)
+	result := ""
+	for address, line := range strings.SplitAfter(rs.Write_assembly(proc_id), "\n") {
+		if line == "" {
+			continue
+		}
+		if dl, ok := lines[address]; ok {
+			result += dl.Directive() + "\n"
+		}
+		result += line
+	}
+	return result
+}
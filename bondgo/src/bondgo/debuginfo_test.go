@@ -0,0 +1,51 @@
+package bondgo
+
+import (
+	"fmt"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestDebugInfo(t *testing.T) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("main.go", -1, 100)
+	file.SetLines([]int{0, 20, 40, 60})
+
+	gent, _ := Type_from_string("uint8")
+	a := VarCell{Vtype: gent, Procobjtype: REGISTER, Id: 5}
+	b := VarCell{Vtype: gent, Procobjtype: MEMORY, Id: 2}
+
+	results := new(BondgoResults)
+	results.Init_Results(new(BondgoConfig))
+	results.WriteLine_debug(0, "rset r5 3", Line_debug{file.Pos(21), map[string]VarCell{"a": a}})
+	results.WriteLine(0, "nop")
+
+	inner := new(BondgoResults)
+	inner.Init_Results(new(BondgoConfig))
+	inner.WriteLine_debug(0, "r2m r5 2", Line_debug{file.Pos(42), map[string]VarCell{"a": a, "b": b}})
+	results.Append_program(0, inner, 0)
+
+	lines := results.Debug_lines(0, fset)
+	if len(lines) != 2 || lines[0].Line != 2 || lines[2].Line != 3 || lines[2].Column != 3 {
+		t.Error("Wrong source lines", lines)
+	}
+	if lines[2].String() != "main.go:3:3 a=r5 b=m2" {
+		t.Error("Wrong debug line", lines[2].String())
+	}
+
+	results.Program[0].Bindings = map[int]string{5: "r0"}
+	asm := results.Write_assembly_debug(0, fset)
+	fmt.Print(asm)
+	if !strings.Contains(asm, "#@ main.go:3:3 a=r0 b=m2\nr2m r5 2\n") {
+		t.Error("Wrong assembly directives")
+	}
+
+	mach, source, err := Assembly_2_Processor(8, strings.Split(asm, "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mach.Program.Slocs) != 3 || source[2] == nil || source[2].Vars["a"] != "r0" || source[1] != nil {
+		t.Error("Debug directives not carried to the ROM addresses", source)
+	}
+}
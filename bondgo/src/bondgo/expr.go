@@ -11,85 +11,31 @@ import (
 
 func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 
-	switch exptype := n.(type) {
-	case *ast.BasicLit:
-		if exptype.Kind == token.INT {
-			value := exptype.Value
-
-			gent, _ := Type_from_string(bg.Basic_type)
-
-			bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
-
-			resp := <-bg.Answers
-			if resp.AnsType == ANS_OK {
-
-				newregcell := resp.Cell
-
-				regname := procbuilder.Get_register_name(newregcell.Id)
-
-				bg.WriteLine(bg.CurrentRoutine, "rset "+regname+" "+value)
-				bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "rset", I_NIL}
-
-				result := make([]VarCell, 1)
-				result[0] = newregcell
+	if c, ok := bg.Const_eval(n); ok {
+		return bg.Const_load(c, nil)
+	} else if bg.Is_faulty() {
+		return []VarCell{}, false
+	}
 
-				return result, true
-			} else {
-				bg.Set_faulty("Resource reservation failed")
-				return []VarCell{}, false
-			}
-		}
+	switch exptype := n.(type) {
 	case *ast.Ident:
 		identname := exptype.Name
 
-		switch identname {
-		case "true":
-			gent, _ := Type_from_string("bool")
-			bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
-			resp := <-bg.Answers
-			if resp.AnsType == ANS_OK {
-				newregcell := resp.Cell
-				regname := procbuilder.Get_register_name(newregcell.Id)
-				bg.WriteLine(bg.CurrentRoutine, "rset "+regname+" 1")
-				bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "rset", I_NIL}
-				result := make([]VarCell, 1)
-				result[0] = newregcell
-
-				return result, true
-			} else {
-				bg.Set_faulty("Resource reservation failed")
-				return []VarCell{}, false
-			}
-		case "false":
-			gent, _ := Type_from_string("bool")
-			bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
-			resp := <-bg.Answers
-			if resp.AnsType == ANS_OK {
-				newregcell := resp.Cell
-				regname := procbuilder.Get_register_name(newregcell.Id)
-				bg.WriteLine(bg.CurrentRoutine, "clr "+regname)
-				bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "clr", I_NIL}
-				result := make([]VarCell, 1)
-				result[0] = newregcell
-
-				return result, true
-			} else {
-				bg.Set_faulty("Resource reservation failed")
-				return []VarCell{}, false
-			}
-		}
-
 		varexist := false
 		for scope := bg; scope != nil; scope = scope.Outer {
 			if cell, ok := scope.Vars[identname]; ok {
 				varexist = true
 
+				if bg.Type_words(cell.Vtype) > 1 && (cell.Procobjtype == REGISTER || cell.Procobjtype == MEMORY) {
+					return bg.Word_load(cell)
+				}
+
 				var newregcell VarCell
 
 				switch cell.Procobjtype {
 				case REGISTER:
-					gent, _ := Type_from_string(bg.Basic_type)
-					bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
+					// The copy keeps the type of the variable, so that the bool variables can be used as conditions
+					bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{cell.Vtype, REGISTER, 0, 0, 0, 0, 0, 0}}
 					resp := <-bg.Answers
 					if resp.AnsType == ANS_OK {
 						newregcell = resp.Cell
@@ -102,8 +48,7 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 						return []VarCell{}, false
 					}
 				case MEMORY:
-					gent, _ := Type_from_string(bg.Basic_type)
-					bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
+					bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{cell.Vtype, REGISTER, 0, 0, 0, 0, 0, 0}}
 					resp := <-bg.Answers
 					if resp.AnsType == ANS_OK {
 						newregcell = resp.Cell
@@ -120,6 +65,12 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 					newregcell = cell
 				case CHANNEL:
 					newregcell = cell
+				case MUTEX, COUNTER:
+					bg.Set_faulty(identname + ": sync objects can only be used by address or by their methods")
+					return []VarCell{}, false
+				case TIMER:
+					bg.Set_faulty(identname + ": tickers can only be received from")
+					return []VarCell{}, false
 				}
 
 				result := make([]VarCell, 1)
@@ -134,6 +85,22 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 		}
 	case *ast.UnaryExpr:
 		x := exptype.X
+		if exptype.Op == token.AND {
+			// Only the mutexes and counters can be shared by address
+			if ident, ok := x.(*ast.Ident); ok {
+				if cell, ok := bg.Sync_lookup(ident.Name); ok {
+					return []VarCell{cell}, true
+				}
+			}
+			bg.Set_faulty("Unsupported address operation")
+			return []VarCell{}, false
+		}
+		if exptype.Op == token.ARROW && bg.Timer_lookup(x) {
+			if cell, ok := bg.Timer_receive(); ok {
+				return []VarCell{cell}, true
+			}
+			return []VarCell{}, false
+		}
 		if cell, ok := bg.Expr_eval(x); !ok {
 			bg.Set_faulty("Wrong expression")
 			return []VarCell{}, false
@@ -181,6 +148,8 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 							return []VarCell{}, false
 						}
 
+					} else if bg.Chan_words(cell[0].Vtype) > 1 {
+						return bg.Word_receive(cell[0])
 					} else {
 						bg.Set_faulty("Wrong type for the arrow operator")
 						return []VarCell{}, false
@@ -196,17 +165,23 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 		}
 
 	case *ast.BinaryExpr:
+		if exptype.Op == token.SHL || exptype.Op == token.SHR {
+			return bg.Shift_eval(exptype)
+		}
 		x := exptype.X
 		y := exptype.Y
-		if cell1, ok := bg.Expr_eval(x); !ok {
+		if cell1, ok := bg.Left_operand(x, y); !ok {
 			bg.Set_faulty("Wrong expression")
 			return []VarCell{}, false
 		} else {
-			if cell2, ok := bg.Expr_eval(y); !ok {
+			if cell1, cell2, ok := bg.Right_operand(x, y, cell1); !ok {
 				bg.Set_faulty("Wrong expression")
 				return []VarCell{}, false
 			} else {
 				if len(cell1) == 1 && len(cell2) == 1 {
+					if bg.Word_operation(exptype.Op, cell1[0], cell2[0]) {
+						return bg.Word_binary(exptype.Op, cell1[0], cell2[0])
+					}
 					switch exptype.Op {
 					case token.ADD:
 						gent, _ := Type_from_string(bg.Basic_type)
@@ -342,54 +317,46 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 							switch fullname {
 							case "bondgo.Input":
 
-								switch gidx := args[1].(type) {
-								case *ast.BasicLit:
-									if gidx.Kind == token.INT {
-										value, _ := strconv.Atoi(gidx.Value)
-										gent, _ := Type_from_string(bg.Basic_type)
-										bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, INPUT, 0, 0, 0, value, value, value}}
-										resp := <-bg.Answers
-										if resp.AnsType == ANS_OK {
-
-											cell := resp.Cell
+								if value, ok := bg.Const_int(args[1]); ok {
+									gent, _ := Type_from_string(bg.Basic_type)
+									bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, INPUT, 0, 0, 0, value, value, value}}
+									resp := <-bg.Answers
+									if resp.AnsType == ANS_OK {
 
-											result := make([]VarCell, 1)
-											result[0] = cell
+										cell := resp.Cell
 
-											return result, true
+										result := make([]VarCell, 1)
+										result[0] = cell
 
-										} else {
-											bg.Set_faulty("Resource reservation failed")
-											return []VarCell{}, false
-										}
+										return result, true
 
+									} else {
+										bg.Set_faulty("Resource reservation failed")
+										return []VarCell{}, false
 									}
+
 								}
 
 							case "bondgo.Output":
 
-								switch gidx := args[1].(type) {
-								case *ast.BasicLit:
-									if gidx.Kind == token.INT {
-										value, _ := strconv.Atoi(gidx.Value)
-										gent, _ := Type_from_string(bg.Basic_type)
-										bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, OUTPUT, 0, 0, 0, value, value, value}}
-										resp := <-bg.Answers
-										if resp.AnsType == ANS_OK {
-
-											cell := resp.Cell
+								if value, ok := bg.Const_int(args[1]); ok {
+									gent, _ := Type_from_string(bg.Basic_type)
+									bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, OUTPUT, 0, 0, 0, value, value, value}}
+									resp := <-bg.Answers
+									if resp.AnsType == ANS_OK {
 
-											result := make([]VarCell, 1)
-											result[0] = cell
+										cell := resp.Cell
 
-											return result, true
+										result := make([]VarCell, 1)
+										result[0] = cell
 
-										} else {
-											bg.Set_faulty("Resource reservation failed")
-											return []VarCell{}, false
-										}
+										return result, true
 
+									} else {
+										bg.Set_faulty("Resource reservation failed")
+										return []VarCell{}, false
 									}
+
 								}
 
 							default:
@@ -465,6 +432,20 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 					bg.Set_faulty("Unknown function " + sel.Name)
 					return []VarCell{}, false
 				}
+			} else if x.Name == "atomic" {
+				if results, ok := bg.Atomic_call(exptype, sel.Name); ok && len(results) == 0 {
+					bg.Set_faulty(x.Name + "." + sel.Name + " has no value")
+					return []VarCell{}, false
+				} else {
+					return results, ok
+				}
+			} else if cell, ok := bg.Sync_lookup(x.Name); ok {
+				if results, ok := bg.Sync_call(cell, sel.Name, exptype.Args); ok && len(results) == 0 {
+					bg.Set_faulty(x.Name + "." + sel.Name + " has no value")
+					return []VarCell{}, false
+				} else {
+					return results, ok
+				}
 			} else {
 				bg.Set_faulty("Unknown module " + x.Name)
 				return []VarCell{}, false
@@ -474,6 +455,11 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 			// This id the case of a function with no receiver
 			funname := fun.Name
 
+			// Explicit conversions between integer types
+			if convtype, err := Type_from_string(funname); err == nil && Integer_width(convtype) > 0 {
+				return bg.Word_convert(exptype, convtype)
+			}
+
 			if _, ok := bg.Functions[funname]; ok {
 				functcell = bg.Functions[funname]
 			} else {
@@ -511,10 +497,12 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 			results := new(BondgoResults) // Results go in here
 			results.Init_Results(bg.BondgoConfig)
 
-			bgfunct := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, nil, nil, vars, returns, "", "", bg.CurrentDevice, bg.CurrentRoutine}
+			bgfunct := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, nil, nil, vars, nil, returns, "", "", bg.CurrentDevice, bg.CurrentRoutine}
 
-			// Launch Walk on the function body using the new BondgoCheck
+			// Launch Walk on the function body using the new BondgoCheck, the caller statement continues afterwards
+			caller_pos := bg.Positions[bg.CurrentRoutine]
 			ast.Walk(bgfunct, functcell.Body)
+			bg.Positions[bg.CurrentRoutine] = caller_pos
 			//fmt.Print("---\n", bgfunct.Write_assembly(), "\n----\n")
 
 			// Get the generated code, count the lines and substitute the <<LASTN>> placeholders whit the actual line number
@@ -527,9 +515,7 @@ func (bg *BondgoCheck) Expr_eval(n ast.Expr) ([]VarCell, bool) {
 			bgfunct.Shift_program_location(bgfunct.CurrentRoutine, starting_point)
 
 			// Return the results
-			for _, line := range bgfunct.GetProgram(bgfunct.CurrentRoutine) {
-				bg.WriteLine(bg.CurrentRoutine, line)
-			}
+			bg.Append_program(bg.CurrentRoutine, bgfunct.BondgoResults, bgfunct.CurrentRoutine)
 
 			result := make([]VarCell, len(bgfunct.Returns))
 			for i, cell := range bgfunct.Returns {
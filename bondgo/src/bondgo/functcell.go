@@ -3,12 +3,15 @@ package bondgo
 import (
 	"fmt"
 	"go/ast"
+	"go/token"
 )
 
 type BondgoFunctions struct {
 	*BondgoConfig
 	*BondgoMessages
-	Functions map[string]FunctCell
+	Functions  map[string]FunctCell
+	Constants  map[string]ConstCell // Package level constants
+	Constdecls []*ast.GenDecl       // Package level const declarations still to fold
 }
 
 type FunctArg struct {
@@ -40,6 +43,8 @@ func (fn *BondgoFunctions) Init_Functions(cfg *BondgoConfig, ms *BondgoMessages)
 	fn.BondgoConfig = cfg
 	fn.BondgoMessages = ms
 	fn.Functions = make(map[string]FunctCell)
+	fn.Constants = make(map[string]ConstCell)
+	fn.Constdecls = make([]*ast.GenDecl, 0)
 }
 
 func (fn *BondgoFunctions) String() string {
@@ -54,17 +59,30 @@ func (fn *BondgoFunctions) String() string {
 func (fn *BondgoFunctions) Visit(n ast.Node) ast.Visitor {
 
 	switch n.(type) {
+	case *ast.GenDecl:
+		genDecl := n.(*ast.GenDecl)
+		if genDecl.Tok == token.CONST {
+			fn.Constdecls = append(fn.Constdecls, genDecl)
+		}
+		return nil
 	case *ast.FuncDecl:
 		funcDecl := n.(*ast.FuncDecl)
 		fname := funcDecl.Name
 		funcType := funcDecl.Type
 
 		supported := []string{fn.Basic_type, "bool", fn.Basic_chantype, "chan bool"}
+		supported = append(supported, fn.Wide_types()...)
+		supported = append(supported, Sync_pointer_types()...)
 
 		if fn.In_debug() {
 			fmt.Println("New function declaration:", fname)
 		}
 
+		if _, ok := fn.Functions[fname.Name]; ok {
+			fn.Set_faulty(fname.Name + ": function redeclared")
+			return nil
+		}
+
 		inputs := make([]FunctArg, 0)
 		outputs := make([]FunctArg, 0)
 
@@ -0,0 +1,732 @@
+package bondgo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"procbuilder"
+	"strconv"
+)
+
+// Integer types wider than the registers are lowered to sequences of words, the least significant first.
+// A multi-word cell uses the consecutive objects of its kind from Id to End_id.
+
+// Integer_width returns the bits of an unsigned integer type, 0 for any other type
+func Integer_width(t *VarType) int {
+	if t != nil && t.MainType == T_NAMED {
+		switch t.Name {
+		case "uint8":
+			return 8
+		case "uint16":
+			return 16
+		case "uint32":
+			return 32
+		case "uint64":
+			return 64
+		}
+	}
+	return 0
+}
+
+// Type_words returns how many registers hold a value of the given type, 0 if the type cannot be lowered on the current register size
+func (cfg *BondgoConfig) Type_words(t *VarType) int {
+	if gent, _ := Type_from_string("bool"); Same_Type(t, gent) {
+		return 1
+	}
+	width := Integer_width(t)
+	rsize := int(cfg.Rsize)
+	if width == 0 || rsize == 0 || width < rsize || width%rsize != 0 {
+		return 0
+	}
+	return width / rsize
+}
+
+// Chan_words returns the words of a value transferred through a channel type, 0 if the type is not a channel
+func (cfg *BondgoConfig) Chan_words(t *VarType) int {
+	if t != nil && t.MainType == T_CHAN && len(t.Values) == 1 {
+		return cfg.Type_words(t.Values[0])
+	}
+	return 0
+}
+
+// Wide_types lists the names of the multi-word types and of their channels
+func (cfg *BondgoConfig) Wide_types() []string {
+	result := make([]string, 0)
+	for _, name := range []string{"uint8", "uint16", "uint32", "uint64"} {
+		if t, _ := Type_from_string(name); cfg.Type_words(t) > 1 {
+			result = append(result, name, "chan "+name)
+		}
+	}
+	return result
+}
+
+func cell_last(c VarCell) int {
+	if c.End_id > c.Id {
+		return c.End_id
+	}
+	return c.Id
+}
+
+// Two cells of the same kind sharing at least an object
+func cells_overlap(a VarCell, b VarCell) bool {
+	return a.Procobjtype == b.Procobjtype && a.Id <= cell_last(b) && b.Id <= cell_last(a)
+}
+
+// The register holding the k-th word of a register cell
+func word_name(c VarCell, k int) string {
+	return procbuilder.Get_register_name(c.Id + k)
+}
+
+// Write an instruction and notify the usage of its opcode
+func (bg *BondgoCheck) emit(opcode string, args string) {
+	bg.WriteLine(bg.CurrentRoutine, opcode+" "+args)
+	bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, opcode, I_NIL}
+}
+
+// The program location delta lines after the instruction about to be written
+func (bg *BondgoCheck) next_location(delta int) string {
+	return "<<" + strconv.Itoa(bg.CountLines(bg.CurrentRoutine)+delta) + ">>"
+}
+
+func (bg *BondgoCheck) new_cell(t *VarType, objtype uint8) (VarCell, bool) {
+	bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{t, objtype, 0, 0, 0, 0, 0, 0}}
+	resp := <-bg.Answers
+	if resp.AnsType != ANS_OK {
+		bg.Set_faulty("Resource reservation failed")
+		return VarCell{}, false
+	}
+	return resp.Cell, true
+}
+
+func (bg *BondgoCheck) free_cells(cells ...VarCell) bool {
+	for _, cell := range cells {
+		bg.Reqs <- VarReq{REQ_REMOVE, bg.CurrentRoutine, cell}
+		if (<-bg.Answers).AnsType != ANS_OK {
+			bg.Set_faulty("Resource clean failed")
+			return false
+		}
+	}
+	return true
+}
+
+func (bg *BondgoCheck) lookup_var(name string) (VarCell, bool) {
+	for scope := bg; scope != nil; scope = scope.Outer {
+		if cell, ok := scope.Vars[name]; ok {
+			return cell, true
+		}
+	}
+	bg.Set_faulty("Variable " + name + " not defined")
+	return VarCell{}, false
+}
+
+func (bg *BondgoCheck) mismatched(t1 *VarType, t2 *VarType) {
+	bg.Set_faulty("Mismatched types " + t1.String() + " and " + t2.String() + ", an explicit conversion is needed")
+}
+
+// Expr_eval_typed evaluates an expression expected to be of type t, so that untyped constants and input reads get the width of t
+func (bg *BondgoCheck) Expr_eval_typed(n ast.Expr, t *VarType) ([]VarCell, bool) {
+	if c, ok := bg.Const_eval(n); ok {
+		return bg.Const_load(c, t)
+	} else if bg.Is_faulty() {
+		return []VarCell{}, false
+	}
+	if bg.Type_words(t) > 1 {
+		if call, ok := n.(*ast.CallExpr); ok {
+			if sel, ok := call.Fun.(*ast.SelectorExpr); ok {
+				if x, ok := sel.X.(*ast.Ident); ok && x.Name == "bondgo" && sel.Sel.Name == "IORead" {
+					return bg.Word_ioread(call, t)
+				}
+			}
+		}
+	}
+	return bg.Expr_eval(n)
+}
+
+// Word_const loads an integer literal of type t, one rset for every word
+func (bg *BondgoCheck) Word_const(t *VarType, literal string) ([]VarCell, bool) {
+	value, err := strconv.ParseUint(literal, 0, 64)
+	width := uint(Integer_width(t))
+	if err != nil || (width < 64 && value>>width != 0) {
+		bg.Set_faulty("Constant " + literal + " overflows " + t.String())
+		return []VarCell{}, false
+	}
+	cell, ok := bg.new_cell(t, REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	rsize := uint(bg.Rsize)
+	mask := uint64(1)<<rsize - 1
+	for k := 0; k < bg.Type_words(t); k++ {
+		word := (value >> (uint(k) * rsize)) & mask
+		bg.emit("rset", word_name(cell, k)+" "+strconv.FormatUint(word, 10))
+	}
+	return []VarCell{cell}, true
+}
+
+// Word_ioread reads a value of type t from an input as a sequence of words, the least significant first. Every word
+// waits its valid, so the writer has to send one word per handshake as Word_iowrite does.
+func (bg *BondgoCheck) Word_ioread(call *ast.CallExpr, t *VarType) ([]VarCell, bool) {
+	if len(call.Args) != 1 {
+		bg.Set_faulty("Only one input expected")
+		return []VarCell{}, false
+	}
+	arg, ok := call.Args[0].(*ast.Ident)
+	if !ok {
+		bg.Set_faulty("Read can only be used on input registers")
+		return []VarCell{}, false
+	}
+	input, ok := bg.lookup_var(arg.Name)
+	if !ok {
+		return []VarCell{}, false
+	}
+	if input.Procobjtype != INPUT {
+		bg.Set_faulty("Read can only be used on input registers")
+		return []VarCell{}, false
+	}
+	if input.Global_id == 0 {
+		bg.Set_faulty("Input not allocated")
+		return []VarCell{}, false
+	}
+	cell, ok := bg.new_cell(t, REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	inname := procbuilder.Get_input_name(input.Id)
+	for k := 0; k < bg.Type_words(t); k++ {
+		bg.emit("i2rw", word_name(cell, k)+" "+inname)
+	}
+	return []VarCell{cell}, true
+}
+
+// Word_iowrite writes a register cell to an output as a sequence of words, the least significant first. Every word
+// waits to be received before the next one is written.
+func (bg *BondgoCheck) Word_iowrite(output VarCell, cell VarCell) bool {
+	outname := procbuilder.Get_output_name(output.Id)
+	for k := 0; k < bg.Type_words(cell.Vtype); k++ {
+		bg.emit("r2owa", word_name(cell, k)+" "+outname)
+	}
+	return bg.free_cells(cell)
+}
+
+// Word_load copies a multi-word variable to new registers
+func (bg *BondgoCheck) Word_load(cell VarCell) ([]VarCell, bool) {
+	newcell, ok := bg.new_cell(cell.Vtype, REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	for k := 0; k < bg.Type_words(cell.Vtype); k++ {
+		switch cell.Procobjtype {
+		case REGISTER:
+			bg.emit("cpy", word_name(newcell, k)+" "+word_name(cell, k))
+		case MEMORY:
+			bg.emit("m2r", word_name(newcell, k)+" "+strconv.Itoa(cell.Id+k))
+		}
+	}
+	return []VarCell{newcell}, true
+}
+
+// Word_store copies a register cell to a variable of the same type
+func (bg *BondgoCheck) Word_store(src VarCell, dest VarCell) bool {
+	if !Same_Type(src.Vtype, dest.Vtype) {
+		bg.mismatched(dest.Vtype, src.Vtype)
+		return false
+	}
+	for k := 0; k < bg.Type_words(dest.Vtype); k++ {
+		switch dest.Procobjtype {
+		case REGISTER:
+			bg.emit("cpy", word_name(dest, k)+" "+word_name(src, k))
+		case MEMORY:
+			bg.emit("r2m", word_name(src, k)+" "+strconv.Itoa(dest.Id+k))
+		default:
+			bg.Set_faulty("Multi-word values can only be assigned to variables")
+			return false
+		}
+	}
+	return true
+}
+
+// Word_clear zeroes every word of a variable
+func (bg *BondgoCheck) Word_clear(cell VarCell) bool {
+	switch cell.Procobjtype {
+	case REGISTER:
+		for k := 0; k < bg.Type_words(cell.Vtype); k++ {
+			bg.emit("clr", word_name(cell, k))
+		}
+	case MEMORY:
+		gent, _ := Type_from_string(bg.Basic_type)
+		zero, ok := bg.new_cell(gent, REGISTER)
+		if !ok {
+			return false
+		}
+		zeroname := procbuilder.Get_register_name(zero.Id)
+		bg.emit("clr", zeroname)
+		for k := 0; k < bg.Type_words(cell.Vtype); k++ {
+			bg.emit("r2m", zeroname+" "+strconv.Itoa(cell.Id+k))
+		}
+		return bg.free_cells(zero)
+	}
+	return true
+}
+
+// Word_send transfers a register cell through a channel one word at the time
+func (bg *BondgoCheck) Word_send(channel VarCell, cell VarCell) bool {
+	if !Same_Type(channel.Vtype.Values[0], cell.Vtype) {
+		bg.mismatched(channel.Vtype.Values[0], cell.Vtype)
+		return false
+	}
+	gent, _ := Type_from_string(bg.Basic_type)
+	wait, ok := bg.new_cell(gent, REGISTER)
+	if !ok {
+		return false
+	}
+	channame := procbuilder.Get_channel_name(channel.Id)
+	waitname := procbuilder.Get_register_name(wait.Id)
+	for k := 0; k < bg.Type_words(cell.Vtype); k++ {
+		bg.emit("wwr", word_name(cell, k)+" "+channame)
+		bg.emit("chw", waitname)
+	}
+	return bg.free_cells(cell, wait)
+}
+
+// Word_receive gets a multi-word value from a channel one word at the time
+func (bg *BondgoCheck) Word_receive(channel VarCell) ([]VarCell, bool) {
+	cell, ok := bg.new_cell(channel.Vtype.Values[0], REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	gent, _ := Type_from_string(bg.Basic_type)
+	wait, ok := bg.new_cell(gent, REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	channame := procbuilder.Get_channel_name(channel.Id)
+	waitname := procbuilder.Get_register_name(wait.Id)
+	for k := 0; k < bg.Type_words(cell.Vtype); k++ {
+		bg.emit("wrd", word_name(cell, k)+" "+channame)
+		bg.emit("chw", waitname)
+	}
+	if !bg.free_cells(wait) {
+		return []VarCell{}, false
+	}
+	return []VarCell{cell}, true
+}
+
+// Word_incdec increments or decrements a multi-word register, the carry goes through the upper words
+func (bg *BondgoCheck) Word_incdec(tok token.Token, cell VarCell) {
+	n := bg.Type_words(cell.Vtype)
+	starting_point := bg.CountLines(bg.CurrentRoutine)
+	switch tok {
+	case token.INC:
+		end := "<<" + strconv.Itoa(starting_point+2*(n-1)+1) + ">>"
+		for k := 0; k < n-1; k++ {
+			bg.emit("incc", word_name(cell, k))
+			bg.emit("jc", end)
+		}
+		bg.emit("inc", word_name(cell, n-1))
+	case token.DEC:
+		end := "<<" + strconv.Itoa(starting_point+4*(n-1)+1) + ">>"
+		for k := 0; k < n-1; k++ {
+			bg.emit("jz", word_name(cell, k)+" "+bg.next_location(3))
+			bg.emit("dec", word_name(cell, k))
+			bg.emit("j", end)
+			bg.emit("dec", word_name(cell, k))
+		}
+		bg.emit("dec", word_name(cell, n-1))
+	}
+}
+
+// Word_convert evaluates the explicit conversion of its argument to the integer type t
+func (bg *BondgoCheck) Word_convert(call *ast.CallExpr, t *VarType) ([]VarCell, bool) {
+	if len(call.Args) != 1 {
+		bg.Set_faulty("Conversion to " + t.String() + " needs one argument")
+		return []VarCell{}, false
+	}
+	nt := bg.Type_words(t)
+	if nt == 0 {
+		bg.Set_faulty("Type " + t.String() + " not supported with " + strconv.Itoa(int(bg.Rsize)) + " bits registers")
+		return []VarCell{}, false
+	}
+	cells, ok := bg.Expr_eval_typed(call.Args[0], t)
+	if !ok || len(cells) != 1 {
+		bg.Set_faulty("Wrong evaluation")
+		return []VarCell{}, false
+	}
+	src := cells[0]
+	if Same_Type(src.Vtype, t) {
+		return cells, true
+	}
+	ns := bg.Type_words(src.Vtype)
+	if Integer_width(src.Vtype) == 0 || ns == 0 || src.Procobjtype != REGISTER {
+		bg.Set_faulty("Cannot convert " + src.Vtype.String() + " to " + t.String())
+		return []VarCell{}, false
+	}
+	dest, ok := bg.new_cell(t, REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	// Narrowing keeps the low words, widening clears the high ones
+	for k := 0; k < nt; k++ {
+		if k < ns {
+			bg.emit("cpy", word_name(dest, k)+" "+word_name(src, k))
+		} else {
+			bg.emit("clr", word_name(dest, k))
+		}
+	}
+	if !bg.free_cells(src) {
+		return []VarCell{}, false
+	}
+	return []VarCell{dest}, true
+}
+
+// Left_operand evaluates the left operand of a binary operation, an untyped constant facing a typed operand is left to Right_operand
+func (bg *BondgoCheck) Left_operand(x ast.Expr, y ast.Expr) ([]VarCell, bool) {
+	if bg.untyped_const(x) && !bg.untyped_const(y) {
+		return []VarCell{}, true
+	}
+	return bg.Expr_eval(x)
+}
+
+// Right_operand evaluates the right operand of a binary operation after Left_operand, an untyped constant takes the type of the other operand
+func (bg *BondgoCheck) Right_operand(x ast.Expr, y ast.Expr, cell1 []VarCell) ([]VarCell, []VarCell, bool) {
+	if bg.untyped_const(x) && !bg.untyped_const(y) {
+		if cell2, ok := bg.Expr_eval(y); ok && len(cell2) == 1 {
+			cell1, ok := bg.Expr_eval_typed(x, cell2[0].Vtype)
+			return cell1, cell2, ok
+		}
+		return []VarCell{}, []VarCell{}, false
+	}
+	if len(cell1) == 1 {
+		cell2, ok := bg.Expr_eval_typed(y, cell1[0].Vtype)
+		return cell1, cell2, ok
+	}
+	cell2, ok := bg.Expr_eval(y)
+	return cell1, cell2, ok
+}
+
+// Word_operation tells if a binary operation is lowered to a sequence of word operations rather than to a single opcode
+func (bg *BondgoCheck) Word_operation(op token.Token, a VarCell, b VarCell) bool {
+	if bg.Type_words(a.Vtype) > 1 || bg.Type_words(b.Vtype) > 1 {
+		return true
+	}
+	switch op {
+	case token.SUB, token.NEQ, token.LSS, token.GTR, token.LEQ, token.GEQ:
+		return true
+	}
+	return false
+}
+
+// Word_binary lowers a binary operation on two register cells of the same type, of any number of words
+func (bg *BondgoCheck) Word_binary(op token.Token, a VarCell, b VarCell) ([]VarCell, bool) {
+	if !Same_Type(a.Vtype, b.Vtype) {
+		bg.mismatched(a.Vtype, b.Vtype)
+		return []VarCell{}, false
+	}
+	n := bg.Type_words(a.Vtype)
+	if n == 0 || a.Procobjtype != REGISTER || b.Procobjtype != REGISTER {
+		bg.Set_faulty("Unsupported operands type " + a.Vtype.String())
+		return []VarCell{}, false
+	}
+	if Integer_width(a.Vtype) == 0 && op != token.EQL && op != token.NEQ {
+		bg.Set_faulty("Unsupported operation on type " + a.Vtype.String())
+		return []VarCell{}, false
+	}
+
+	switch op {
+	case token.ADD, token.SUB:
+		opcode, wordopcode := "adc", "add"
+		if op == token.SUB {
+			opcode, wordopcode = "sbc", "sub"
+		}
+		if n == 1 {
+			bg.emit(wordopcode, word_name(a, 0)+" "+word_name(b, 0))
+			if !bg.free_cells(b) {
+				return []VarCell{}, false
+			}
+			return []VarCell{a}, true
+		}
+		if carry, ok := bg.word_addsub(opcode, a, b, false); !ok || !bg.free_cells(b, carry) {
+			return []VarCell{}, false
+		}
+		return []VarCell{a}, true
+	case token.MUL:
+		if n == 1 {
+			bg.emit("mult", word_name(a, 0)+" "+word_name(b, 0))
+			if !bg.free_cells(b) {
+				return []VarCell{}, false
+			}
+			return []VarCell{a}, true
+		}
+		return bg.word_mul(a, b)
+	case token.EQL, token.NEQ:
+		return bg.word_equal(op, a, b)
+	case token.LSS, token.GTR, token.LEQ, token.GEQ:
+		// a < b is the borrow of a - b, a > b the one of b - a, the others are their negations
+		var result VarCell
+		var ok bool
+		if op == token.LSS || op == token.GEQ {
+			result, ok = bg.word_addsub("sbc", a, b, true)
+		} else {
+			result, ok = bg.word_addsub("sbc", b, a, true)
+		}
+		if !ok || !bg.free_cells(a, b) {
+			return []VarCell{}, false
+		}
+		if op == token.LEQ || op == token.GEQ {
+			bg.word_not(result)
+		}
+		return []VarCell{result}, true
+	}
+	bg.Set_faulty("Unsupported binary operation")
+	return []VarCell{}, false
+}
+
+// Shift_eval lowers the shift of an integer by a constant number of bits
+func (bg *BondgoCheck) Shift_eval(n *ast.BinaryExpr) ([]VarCell, bool) {
+	count, ok := bg.Const_int(n.Y)
+	if !ok {
+		bg.Set_faulty("The shift count has to be a constant")
+		return []VarCell{}, false
+	}
+	cells, ok := bg.Expr_eval(n.X)
+	if !ok || len(cells) != 1 {
+		bg.Set_faulty("Wrong expression")
+		return []VarCell{}, false
+	}
+	cell := cells[0]
+	if Integer_width(cell.Vtype) == 0 || bg.Type_words(cell.Vtype) == 0 || cell.Procobjtype != REGISTER {
+		bg.Set_faulty("Unsupported shift operand")
+		return []VarCell{}, false
+	}
+	if !bg.word_shift(n.Op, cell, count) {
+		return []VarCell{}, false
+	}
+	return cells, true
+}
+
+// word_addsub adds (adc) or subtracts (sbc) s to d word by word and returns a bool register with the final carry,
+// if keep is not set the carry of the last word is not computed
+func (bg *BondgoCheck) word_addsub(opcode string, d VarCell, s VarCell, keep bool) (VarCell, bool) {
+	gent, _ := Type_from_string("bool")
+	carry, ok := bg.new_cell(gent, REGISTER)
+	if !ok {
+		return VarCell{}, false
+	}
+	carryname := procbuilder.Get_register_name(carry.Id)
+	n := bg.Type_words(d.Vtype)
+	for k := 0; k < n; k++ {
+		destname := word_name(d, k)
+		sourcename := word_name(s, k)
+		if k == 0 {
+			bg.emit(opcode, destname+" "+sourcename)
+		} else {
+			// The carry in first, it cannot overflow together with the source word
+			bg.emit(opcode, destname+" "+carryname)
+			if k == n-1 && !keep {
+				bg.emit(opcode, destname+" "+sourcename)
+				continue
+			}
+			bg.emit("clr", carryname)
+			bg.emit("jc", bg.next_location(2))
+			bg.emit("inc", carryname)
+			bg.emit(opcode, destname+" "+sourcename)
+			bg.emit("jc", bg.next_location(2))
+			bg.emit("rset", carryname+" 1")
+			continue
+		}
+		if n > 1 || keep {
+			bg.emit("clr", carryname)
+			bg.emit("jc", bg.next_location(2))
+			bg.emit("inc", carryname)
+		}
+	}
+	return carry, true
+}
+
+// word_equal compares two cells word by word with je
+func (bg *BondgoCheck) word_equal(op token.Token, a VarCell, b VarCell) ([]VarCell, bool) {
+	gent, _ := Type_from_string("bool")
+	result, ok := bg.new_cell(gent, REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	resultname := procbuilder.Get_register_name(result.Id)
+	if op == token.EQL {
+		bg.emit("rset", resultname+" 1")
+	} else {
+		bg.emit("clr", resultname)
+	}
+	for k := 0; k < bg.Type_words(a.Vtype); k++ {
+		bg.emit("je", word_name(a, k)+" "+word_name(b, k)+" "+bg.next_location(2))
+		if op == token.EQL {
+			bg.emit("clr", resultname)
+		} else {
+			bg.emit("rset", resultname+" 1")
+		}
+	}
+	if !bg.free_cells(a, b) {
+		return []VarCell{}, false
+	}
+	return []VarCell{result}, true
+}
+
+// word_not negates a bool register
+func (bg *BondgoCheck) word_not(cell VarCell) {
+	regname := procbuilder.Get_register_name(cell.Id)
+	bg.emit("jz", regname+" "+bg.next_location(3))
+	bg.emit("clr", regname)
+	bg.emit("j", bg.next_location(2))
+	bg.emit("rset", regname+" 1")
+}
+
+// word_shl1 shifts a cell one bit left, if out is given it receives the bit shifted out
+func (bg *BondgoCheck) word_shl1(cell VarCell, out *VarCell) {
+	n := bg.Type_words(cell.Vtype)
+	if out != nil {
+		outname := procbuilder.Get_register_name(out.Id)
+		bg.emit("cilc", word_name(cell, n-1))
+		bg.emit("clr", outname)
+		bg.emit("jc", bg.next_location(2))
+		bg.emit("inc", outname)
+	} else {
+		bg.emit("cil", word_name(cell, n-1))
+	}
+	for k := n - 2; k >= 0; k-- {
+		bg.emit("cilc", word_name(cell, k))
+		bg.emit("jc", bg.next_location(2))
+		bg.emit("inc", word_name(cell, k+1))
+	}
+}
+
+// word_shr1 shifts a cell one bit right, the lsb of every upper word is moved to the msb of the word below using the
+// one, msb and temp registers
+func (bg *BondgoCheck) word_shr1(cell VarCell, one string, msb string, temp string) {
+	n := bg.Type_words(cell.Vtype)
+	for k := 0; k < n; k++ {
+		bg.emit("cir", word_name(cell, k))
+		if k < n-1 {
+			bg.emit("cpy", temp+" "+word_name(cell, k+1))
+			bg.emit("and", temp+" "+one)
+			bg.emit("jz", temp+" "+bg.next_location(2))
+			bg.emit("add", word_name(cell, k)+" "+msb)
+		}
+	}
+}
+
+// word_shift shifts a register cell by a constant, whole words are moved first then the remaining bits one at the time
+func (bg *BondgoCheck) word_shift(op token.Token, cell VarCell, count int) bool {
+	n := bg.Type_words(cell.Vtype)
+	rsize := int(bg.Rsize)
+	wordshift := count / rsize
+	bitshift := count % rsize
+	if wordshift >= n {
+		wordshift = n
+		bitshift = 0
+	}
+
+	if wordshift > 0 {
+		switch op {
+		case token.SHL:
+			for k := n - 1; k >= 0; k-- {
+				if k-wordshift >= 0 {
+					bg.emit("cpy", word_name(cell, k)+" "+word_name(cell, k-wordshift))
+				} else {
+					bg.emit("clr", word_name(cell, k))
+				}
+			}
+		case token.SHR:
+			for k := 0; k < n; k++ {
+				if k+wordshift < n {
+					bg.emit("cpy", word_name(cell, k)+" "+word_name(cell, k+wordshift))
+				} else {
+					bg.emit("clr", word_name(cell, k))
+				}
+			}
+		}
+	}
+
+	if bitshift == 0 {
+		return true
+	}
+
+	switch op {
+	case token.SHL:
+		for i := 0; i < bitshift; i++ {
+			bg.word_shl1(cell, nil)
+		}
+	case token.SHR:
+		if n == 1 {
+			for i := 0; i < bitshift; i++ {
+				bg.word_shr1(cell, "", "", "")
+			}
+			return true
+		}
+		gent, _ := Type_from_string(bg.Basic_type)
+		temps := make([]VarCell, 3)
+		for i := range temps {
+			if cell, ok := bg.new_cell(gent, REGISTER); ok {
+				temps[i] = cell
+			} else {
+				return false
+			}
+		}
+		one := procbuilder.Get_register_name(temps[0].Id)
+		msb := procbuilder.Get_register_name(temps[1].Id)
+		temp := procbuilder.Get_register_name(temps[2].Id)
+		bg.emit("rset", one+" 1")
+		bg.emit("rset", msb+" "+strconv.FormatUint(uint64(1)<<uint(rsize-1), 10))
+		for i := 0; i < bitshift; i++ {
+			bg.word_shr1(cell, one, msb, temp)
+		}
+		return bg.free_cells(temps...)
+	}
+	return true
+}
+
+// word_mul multiplies two multi-word cells with a shift and add loop, the result keeps the low words of the product.
+// The per-word mulc partial products are not used: mulc keeps only the low word of a product and flags the overflow,
+// but every partial product below the top word has to carry its high word into the next one. Building the high words
+// would need half-word splits and shifts for each pair, while the loop needs only adc and one bit shifts.
+func (bg *BondgoCheck) word_mul(a VarCell, b VarCell) ([]VarCell, bool) {
+	n := bg.Type_words(a.Vtype)
+	result, ok := bg.new_cell(a.Vtype, REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	gent, _ := Type_from_string(bg.Basic_type)
+	counter, ok := bg.new_cell(gent, REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	boolt, _ := Type_from_string("bool")
+	bit, ok := bg.new_cell(boolt, REGISTER)
+	if !ok {
+		return []VarCell{}, false
+	}
+	countername := procbuilder.Get_register_name(counter.Id)
+	bitname := procbuilder.Get_register_name(bit.Id)
+
+	for k := 0; k < n; k++ {
+		bg.emit("clr", word_name(result, k))
+	}
+	bg.emit("rset", countername+" "+strconv.Itoa(n*int(bg.Rsize)))
+
+	// The bits of b from the msb, result = result * 2 + a for every bit set
+	loop := bg.CountLines(bg.CurrentRoutine)
+	skip := "<<" + fmt.Sprintf("%p", bg) + "MULSKIP" + strconv.Itoa(loop) + ">>"
+	bg.word_shl1(result, nil)
+	bg.word_shl1(b, &bit)
+	bg.emit("jz", bitname+" "+skip)
+	carry, ok := bg.word_addsub("adc", result, a, false)
+	if !ok {
+		return []VarCell{}, false
+	}
+	bg.Replacer(bg.CurrentRoutine, skip, "<<"+strconv.Itoa(bg.CountLines(bg.CurrentRoutine))+">>")
+	bg.emit("dec", countername)
+	bg.emit("jz", countername+" "+bg.next_location(2))
+	bg.emit("j", "<<"+strconv.Itoa(loop)+">>")
+
+	if !bg.free_cells(a, b, counter, bit, carry) {
+		return []VarCell{}, false
+	}
+	return []VarCell{result}, true
+}
@@ -0,0 +1,32 @@
+package bondgo
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestTypeWords(t *testing.T) {
+	cfg := &BondgoConfig{Rsize: 8, Basic_type: "uint8", Basic_chantype: "chan uint8"}
+	for _, name := range []string{"uint8", "uint16", "uint32", "uint64", "bool", "chan uint32"} {
+		vt, _ := Type_from_string(name)
+		fmt.Println(name, cfg.Type_words(vt), cfg.Chan_words(vt))
+	}
+	fmt.Println(cfg.Wide_types())
+
+	t16, _ := Type_from_string("uint16")
+	t8, _ := Type_from_string("uint8")
+	c32, _ := Type_from_string("chan uint32")
+	if cfg.Type_words(t16) != 2 || cfg.Chan_words(c32) != 4 || len(cfg.Wide_types()) != 6 {
+		t.Error("Wrong words on 8 bits registers")
+	}
+
+	cfg.Rsize = 16
+	if cfg.Type_words(t8) != 0 || cfg.Type_words(t16) != 1 {
+		t.Error("Wrong words on 16 bits registers")
+	}
+
+	wide := VarCell{t16, REGISTER, 2, 2, 3, 0, 0, 0}
+	if !cells_overlap(wide, VarCell{t8, REGISTER, 3, 0, 0, 0, 0, 0}) || cells_overlap(wide, VarCell{t8, REGISTER, 4, 0, 0, 0, 0, 0}) || cells_overlap(wide, VarCell{t8, MEMORY, 2, 2, 2, 0, 0, 0}) {
+		t.Error("Wrong overlap")
+	}
+}
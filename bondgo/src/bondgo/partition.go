@@ -0,0 +1,432 @@
+package bondgo
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Automatic placement of the processors on the devices of an etherbond/udpbond cluster. Processors sharing a channel
+// stay on the same device since channels are not transported by the network, IO bonds between processors on
+// different devices are and they are the cost to minimise.
+
+// A device and its budgets, 0 means no limit
+type DeviceBudget struct {
+	Name           string
+	Max_processors int
+	Max_luts       int
+	Io_pins        int
+}
+
+// The network cost of the bonds between two devices
+type DeviceLink struct {
+	Devices []string
+	Cost    int
+}
+
+type DeviceCatalogue struct {
+	Devices      []DeviceBudget
+	Links        []DeviceLink
+	Default_cost int // Cost of the links not listed, 1 if not given
+}
+
+// A set of processors that have to be placed together
+type Partition_node struct {
+	Procs  []int
+	Luts   int
+	Pins   int
+	Pinned int // Index of the device the node has to be placed on, -1 if free
+}
+
+// An IO bond between two nodes, Weight is the number of IO ids they share
+type Partition_edge struct {
+	A      int
+	B      int
+	Weight int
+}
+
+func Load_catalogue(filename string) (*DeviceCatalogue, error) {
+	cat := new(DeviceCatalogue)
+	if jsonfile, err := ioutil.ReadFile(filename); err == nil {
+		if err := json.Unmarshal(jsonfile, cat); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, err
+	}
+	if len(cat.Devices) == 0 {
+		return nil, errors.New("The device catalogue has no devices")
+	}
+	names := make(map[string]bool)
+	for _, dev := range cat.Devices {
+		if dev.Name == "" || names[dev.Name] {
+			return nil, errors.New("Device names in the catalogue have to be unique and not empty")
+		}
+		names[dev.Name] = true
+	}
+	for _, link := range cat.Links {
+		if len(link.Devices) != 2 || !names[link.Devices[0]] || !names[link.Devices[1]] {
+			return nil, errors.New("Links have to connect two devices of the catalogue")
+		}
+	}
+	return cat, nil
+}
+
+func (cat *DeviceCatalogue) device_index(name string) int {
+	for i, dev := range cat.Devices {
+		if dev.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (cat *DeviceCatalogue) link_cost(a int, b int) int {
+	if a == b {
+		return 0
+	}
+	for _, link := range cat.Links {
+		la := cat.device_index(link.Devices[0])
+		lb := cat.device_index(link.Devices[1])
+		if (la == a && lb == b) || (la == b && lb == a) {
+			return link.Cost
+		}
+	}
+	if cat.Default_cost > 0 {
+		return cat.Default_cost
+	}
+	return 1
+}
+
+// Estimate_luts is a rough size of a processor: fetch and decode, the datapath of every opcode and the registers
+func (reqmnt *ProcRequirements) Estimate_luts(rsize int) int {
+	return 100 + 2*rsize*len(reqmnt.Opcodes) + rsize*reqmnt.Registersize
+}
+
+type partition_state struct {
+	cat    *DeviceCatalogue
+	nodes  []Partition_node
+	edges  []Partition_edge
+	assign []int
+	procs  []int
+	luts   []int
+	pins   []int
+}
+
+func (ps *partition_state) fits(node int, dev int) bool {
+	n := ps.nodes[node]
+	budget := ps.cat.Devices[dev]
+	if budget.Max_processors != 0 && ps.procs[dev]+len(n.Procs) > budget.Max_processors {
+		return false
+	}
+	if budget.Max_luts != 0 && ps.luts[dev]+n.Luts > budget.Max_luts {
+		return false
+	}
+	if budget.Io_pins != 0 && ps.pins[dev]+n.Pins > budget.Io_pins {
+		return false
+	}
+	return true
+}
+
+func (ps *partition_state) place(node int, dev int) {
+	n := ps.nodes[node]
+	if old := ps.assign[node]; old != -1 {
+		ps.procs[old] -= len(n.Procs)
+		ps.luts[old] -= n.Luts
+		ps.pins[old] -= n.Pins
+	}
+	ps.assign[node] = dev
+	if dev != -1 {
+		ps.procs[dev] += len(n.Procs)
+		ps.luts[dev] += n.Luts
+		ps.pins[dev] += n.Pins
+	}
+}
+
+// The network cost of the edges of a node toward the placed ones, as if it were on dev
+func (ps *partition_state) node_cost(node int, dev int) int {
+	cost := 0
+	for _, e := range ps.edges {
+		other := -1
+		if e.A == node {
+			other = e.B
+		} else if e.B == node {
+			other = e.A
+		}
+		if other != -1 && other != node && ps.assign[other] != -1 {
+			cost += e.Weight * ps.cat.link_cost(dev, ps.assign[other])
+		}
+	}
+	return cost
+}
+
+func (ps *partition_state) total_cost() int {
+	cost := 0
+	for _, e := range ps.edges {
+		cost += e.Weight * ps.cat.link_cost(ps.assign[e.A], ps.assign[e.B])
+	}
+	return cost
+}
+
+// Move the node to the device if it fits there, otherwise leave it where it is
+func (ps *partition_state) try_move(node int, dev int) bool {
+	old := ps.assign[node]
+	ps.place(node, -1)
+	if ps.fits(node, dev) {
+		ps.place(node, dev)
+		return true
+	}
+	ps.place(node, old)
+	return false
+}
+
+// Partition places the nodes on the devices of the catalogue: a greedy placement, started once for every device as
+// the one of the first free node, followed by moves and swaps of nodes as long as they lower the network cost within
+// the budgets. It returns the device index of every node and the cost of the best placement.
+func Partition(cat *DeviceCatalogue, nodes []Partition_node, edges []Partition_edge) ([]int, int, error) {
+	var best []int
+	bestcost := 0
+	var lasterr error
+	for seed := range cat.Devices {
+		if assign, cost, err := partition_run(cat, nodes, edges, seed); err == nil {
+			if best == nil || cost < bestcost {
+				best = assign
+				bestcost = cost
+			}
+		} else {
+			lasterr = err
+		}
+	}
+	if best == nil {
+		return nil, 0, lasterr
+	}
+	return best, bestcost, nil
+}
+
+func partition_run(cat *DeviceCatalogue, nodes []Partition_node, edges []Partition_edge, seed int) ([]int, int, error) {
+	ndev := len(cat.Devices)
+	ps := &partition_state{cat, nodes, edges, make([]int, len(nodes)), make([]int, ndev), make([]int, ndev), make([]int, ndev)}
+	for i := range ps.assign {
+		ps.assign[i] = -1
+	}
+
+	order := make([]int, len(nodes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		ni, nj := nodes[order[i]], nodes[order[j]]
+		if (ni.Pinned != -1) != (nj.Pinned != -1) {
+			return ni.Pinned != -1
+		}
+		return len(ni.Procs) > len(nj.Procs)
+	})
+
+	seeded := false
+	for _, node := range order {
+		if pinned := nodes[node].Pinned; pinned != -1 {
+			if !ps.fits(node, pinned) {
+				return nil, 0, errors.New("Processors " + procs_string(nodes[node].Procs) + " do not fit the device " + cat.Devices[pinned].Name)
+			}
+			ps.place(node, pinned)
+			continue
+		}
+		if !seeded {
+			seeded = true
+			if ps.fits(node, seed) {
+				ps.place(node, seed)
+				continue
+			}
+		}
+		best := -1
+		bestcost := 0
+		for dev := 0; dev < ndev; dev++ {
+			if !ps.fits(node, dev) {
+				continue
+			}
+			cost := ps.node_cost(node, dev)
+			// On equal costs the devices already in use are preferred
+			if best == -1 || cost < bestcost || (cost == bestcost && ps.procs[best] == 0 && ps.procs[dev] != 0) {
+				best = dev
+				bestcost = cost
+			}
+		}
+		if best == -1 {
+			return nil, 0, errors.New("Processors " + procs_string(nodes[node].Procs) + " do not fit any device")
+		}
+		ps.place(node, best)
+	}
+
+	for improved := true; improved; {
+		improved = false
+		for node := range nodes {
+			if nodes[node].Pinned != -1 {
+				continue
+			}
+			current := ps.assign[node]
+			for dev := 0; dev < ndev; dev++ {
+				if dev == current || ps.node_cost(node, dev) >= ps.node_cost(node, current) {
+					continue
+				}
+				if ps.try_move(node, dev) {
+					current = dev
+					improved = true
+				}
+			}
+		}
+		for a := range nodes {
+			for b := a + 1; b < len(nodes); b++ {
+				da, db := ps.assign[a], ps.assign[b]
+				if da == db || nodes[a].Pinned != -1 || nodes[b].Pinned != -1 {
+					continue
+				}
+				before := ps.total_cost()
+				ps.place(a, -1)
+				ps.place(b, -1)
+				if ps.fits(a, db) {
+					ps.place(a, db)
+					if ps.fits(b, da) {
+						ps.place(b, da)
+						if ps.total_cost() < before {
+							improved = true
+							continue
+						}
+						ps.place(b, -1)
+					}
+					ps.place(a, -1)
+				}
+				ps.place(a, da)
+				ps.place(b, db)
+			}
+		}
+	}
+
+	return ps.assign, ps.total_cost(), nil
+}
+
+func procs_string(procs []int) string {
+	result := make([]string, len(procs))
+	for i, p := range procs {
+		result[i] = strconv.Itoa(p)
+	}
+	return strings.Join(result, ",")
+}
+
+// Partition_devices assigns every processor to a device of the catalogue. Processors already assigned by a label to a
+// catalogue device stay there.
+func (bg *BondgoCheck) Partition_devices(cat *DeviceCatalogue) error {
+	nprocs := len(bg.Program)
+	rsize := int(bg.Rsize)
+
+	// Processors sharing a channel form a single node
+	group := make([]int, nprocs)
+	for i := range group {
+		group[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if group[i] != i {
+			group[i] = find(group[i])
+		}
+		return group[i]
+	}
+	for _, creq := range bg.Chanr {
+		for _, proc := range creq.Connected {
+			if proc < nprocs && creq.Connected[0] < nprocs {
+				group[find(proc)] = find(creq.Connected[0])
+			}
+		}
+	}
+
+	// IO ids produced and consumed, the unmatched ones use device pins
+	producers := make(map[int]int)
+	consumers := make(map[int][]int)
+	for proc, ioreq := range bg.IOr {
+		for _, id := range ioreq.Outputs_ids {
+			producers[id] = proc
+		}
+		for _, id := range ioreq.Inputs_ids {
+			consumers[id] = append(consumers[id], proc)
+		}
+	}
+
+	nodeof := make(map[int]int)
+	nodes := make([]Partition_node, 0)
+	for proc := 0; proc < nprocs; proc++ {
+		root := find(proc)
+		if _, ok := nodeof[root]; !ok {
+			nodeof[root] = len(nodes)
+			nodes = append(nodes, Partition_node{[]int{}, 0, 0, -1})
+		}
+		n := &nodes[nodeof[root]]
+		n.Procs = append(n.Procs, proc)
+		if preq, ok := bg.Procr[proc]; ok {
+			n.Luts += preq.Estimate_luts(rsize)
+			if dev := cat.device_index(preq.Device); dev != -1 {
+				if n.Pinned != -1 && n.Pinned != dev {
+					return errors.New("Processors sharing a channel are labeled with different devices")
+				}
+				n.Pinned = dev
+			}
+		}
+		if ioreq, ok := bg.IOr[proc]; ok {
+			for _, id := range ioreq.Inputs_ids {
+				if _, ok := producers[id]; !ok {
+					n.Pins += rsize
+				}
+			}
+			for _, id := range ioreq.Outputs_ids {
+				if _, ok := consumers[id]; !ok {
+					n.Pins += rsize
+				}
+			}
+		}
+	}
+
+	weights := make(map[[2]int]int)
+	for id, producer := range producers {
+		for _, consumer := range consumers[id] {
+			a, b := nodeof[find(producer)], nodeof[find(consumer)]
+			if a > b {
+				a, b = b, a
+			}
+			if a != b {
+				weights[[2]int{a, b}]++
+			}
+		}
+	}
+	keys := make([][2]int, 0, len(weights))
+	for k := range weights {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || (keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1])
+	})
+	edges := make([]Partition_edge, len(keys))
+	for i, k := range keys {
+		edges[i] = Partition_edge{k[0], k[1], weights[k]}
+	}
+
+	assign, cost, err := Partition(cat, nodes, edges)
+	if err != nil {
+		return err
+	}
+
+	for i, n := range nodes {
+		for _, proc := range n.Procs {
+			if preq, ok := bg.Procr[proc]; ok {
+				preq.Device = cat.Devices[assign[i]].Name
+			}
+			if bg.Verbose {
+				bg.Log("Processor " + strconv.Itoa(proc) + " placed on " + cat.Devices[assign[i]].Name)
+			}
+		}
+	}
+	if bg.Verbose {
+		bg.Log("Partitioning network cost: " + strconv.Itoa(cost))
+	}
+	return nil
+}
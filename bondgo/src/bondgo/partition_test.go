@@ -0,0 +1,41 @@
+package bondgo
+
+import (
+	"fmt"
+	"testing"
+)
+
+// Four processors in a ring of IO bonds, the big device has the cheapest placement unless it is too small
+func TestPartition(t *testing.T) {
+	cat := &DeviceCatalogue{[]DeviceBudget{{"a", 2, 0, 0}, {"b", 2, 0, 0}, {"c", 4, 0, 0}}, []DeviceLink{{[]string{"a", "b"}, 1}}, 10}
+	nodes := make([]Partition_node, 4)
+	for i := range nodes {
+		nodes[i] = Partition_node{[]int{i}, 100, 0, -1}
+	}
+	edges := []Partition_edge{{0, 1, 1}, {1, 2, 1}, {2, 3, 1}, {0, 3, 1}}
+
+	assign, cost, err := Partition(cat, nodes, edges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(assign, cost)
+	if cost != 0 || assign[0] != 2 {
+		t.Error("Single device placement not found")
+	}
+
+	cat.Devices[2].Max_processors = 3
+	assign, cost, err = Partition(cat, nodes, edges)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(assign, cost)
+	if cost != 2 || assign[0] == 2 {
+		t.Error("Wrong split", assign, cost)
+	}
+
+	nodes[3].Pinned = 2
+	cat.Devices[2].Max_luts = 50
+	if _, _, err := Partition(cat, nodes, edges); err == nil {
+		t.Error("Budget exceeded by a pinned processor")
+	}
+}
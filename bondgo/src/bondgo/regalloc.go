@@ -0,0 +1,519 @@
+package bondgo
+
+import (
+	"errors"
+	"procbuilder"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The code generator uses a fresh virtual register for every variable and temporary, the allocator maps them
+// to the physical registers once the program of a processor is complete.
+
+// Operand roles of the register arguments: u is used, d is defined, b is both. The opcodes without registers are
+// listed too, an unknown opcode keeps its registers pinned.
+var regalloc_roles = map[string]string{
+	"adc":     "bu",
+	"add":     "bu",
+	"and":     "bu",
+	"chclose": "",
+	"cil":     "b",
+	"cilc":    "b",
+	"cir":     "b",
+	"clr":     "d",
+	"cpy":     "du",
+	"dec":     "b",
+	"evd":     "",
+	"eve":     "",
+	"evr":     "",
+	"fadd":    "b",
+	"fswp":    "b",
+	"i2r":     "d",
+	"i2rw":    "d",
+	"inc":     "b",
+	"incc":    "b",
+	"je":      "uu",
+	"jz":      "u",
+	"lfsr82r": "d",
+	"lfsrn2r": "d",
+	"lock":    "",
+	"m2r":     "d",
+	"mulc":    "bu",
+	"mult":    "bu",
+	"r2m":     "u",
+	"r2o":     "u",
+	"r2owa":   "u",
+	"rset":    "d",
+	"sbc":     "bu",
+	"semacq":  "",
+	"semrel":  "",
+	"sub":     "bu",
+	"tma":     "u",
+	"tmp":     "u",
+	"tmr":     "d",
+	"tmw":     "",
+	"unlock":  "",
+}
+
+// Registers within these opcodes are read or written asynchronously, they are never spilled nor shared
+var regalloc_pinned = map[string]bool{
+	"chc": true,
+	"chw": true,
+	"wrd": true,
+	"wwr": true,
+}
+
+type regalloc_instr struct {
+	op     string
+	args   []string
+	regs   []int    // Virtual register of each argument, -1 if the argument is not a register
+	roles  []string // Role of each register argument
+	target int      // Jump location, -1 if none
+	pinned bool
+}
+
+type regalloc_interval struct {
+	vreg   int
+	start  int
+	end    int
+	weight int // Uses weighted by the loop depth, the lowest is the coldest
+	pinned bool
+}
+
+type Regalloc_result struct {
+	Lines     []string
+	Registers int            // Physical registers used
+	Spilled   int            // Number of variables moved to RAM
+	Ramsize   int            // RAM cells needed including the spill area
+	Location  []int          // New index of every original line, the inserted loads come first
+	Bindings  map[int]string // Virtual register -> physical register or RAM cell
+}
+
+func regalloc_register(arg string) (int, bool) {
+	if len(arg) > 1 && arg[0] == 'r' {
+		if n, err := strconv.Atoi(arg[1:]); err == nil && n >= 0 {
+			return n, true
+		}
+	}
+	return -1, false
+}
+
+func regalloc_location(arg string) (int, bool) {
+	if strings.HasPrefix(arg, "<<") && strings.HasSuffix(arg, ">>") {
+		if n, err := strconv.Atoi(arg[2 : len(arg)-2]); err == nil {
+			return n, true
+		}
+	}
+	return -1, false
+}
+
+func regalloc_parse(lines []string) ([]regalloc_instr, int, int) {
+	prog := make([]regalloc_instr, len(lines))
+	vregs := 0
+	ramsize := 0
+	for i, line := range lines {
+		words := strings.Fields(line)
+		ins := regalloc_instr{target: -1}
+		if len(words) > 0 {
+			ins.op = words[0]
+			ins.args = words[1:]
+		}
+		ins.regs = make([]int, len(ins.args))
+		ins.roles = make([]string, len(ins.args))
+
+		roles, known := regalloc_roles[ins.op]
+		ins.pinned = regalloc_pinned[ins.op] || !known
+
+		nreg := 0
+		for j, arg := range ins.args {
+			ins.regs[j] = -1
+			if reg, ok := regalloc_register(arg); ok {
+				ins.regs[j] = reg
+				if known && nreg < len(roles) {
+					ins.roles[j] = roles[nreg : nreg+1]
+				} else {
+					ins.roles[j] = "b"
+				}
+				nreg++
+				if reg+1 > vregs {
+					vregs = reg + 1
+				}
+			} else if loc, ok := regalloc_location(arg); ok {
+				ins.target = loc
+			}
+		}
+
+		if (ins.op == "r2m" || ins.op == "m2r") && len(ins.args) == 2 {
+			if addr, err := strconv.Atoi(ins.args[1]); err == nil && addr+1 > ramsize {
+				ramsize = addr + 1
+			}
+		}
+		prog[i] = ins
+	}
+	return prog, vregs, ramsize
+}
+
+// The instructions that can follow the one at position i, only j never falls through
+func (ins *regalloc_instr) successors(i int, n int) []int {
+	result := make([]int, 0, 2)
+	if ins.op != "j" && i+1 < n {
+		result = append(result, i+1)
+	}
+	if ins.target >= 0 && ins.target < n {
+		result = append(result, ins.target)
+	}
+	return result
+}
+
+// Backward liveness analysis, returns for every instruction the set of virtual registers live after it
+func regalloc_liveness(prog []regalloc_instr, vregs int) [][]bool {
+	n := len(prog)
+	livein := make([][]bool, n)
+	liveout := make([][]bool, n)
+	for i := range prog {
+		livein[i] = make([]bool, vregs)
+		liveout[i] = make([]bool, vregs)
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := n - 1; i >= 0; i-- {
+			ins := &prog[i]
+			for _, s := range ins.successors(i, n) {
+				for v, live := range livein[s] {
+					if live && !liveout[i][v] {
+						liveout[i][v] = true
+						changed = true
+					}
+				}
+			}
+
+			newin := make([]bool, vregs)
+			copy(newin, liveout[i])
+			for j, reg := range ins.regs {
+				if reg >= 0 && ins.roles[j] == "d" {
+					newin[reg] = false
+				}
+			}
+			for j, reg := range ins.regs {
+				if reg >= 0 && ins.roles[j] != "d" {
+					newin[reg] = true
+				}
+			}
+			for v := range newin {
+				if newin[v] != livein[i][v] {
+					livein[i] = newin
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	return liveout
+}
+
+func regalloc_intervals(prog []regalloc_instr, vregs int, liveout [][]bool) []*regalloc_interval {
+	intervals := make([]*regalloc_interval, vregs)
+	extend := func(v int, i int) {
+		if intervals[v] == nil {
+			intervals[v] = &regalloc_interval{v, i, i, 0, false}
+		}
+		if i < intervals[v].start {
+			intervals[v].start = i
+		}
+		if i > intervals[v].end {
+			intervals[v].end = i
+		}
+	}
+
+	// Every backward jump is a loop, the instructions in its body count more
+	depth := make([]int, len(prog))
+	for i, ins := range prog {
+		if ins.target >= 0 && ins.target <= i {
+			for j := ins.target; j <= i; j++ {
+				depth[j]++
+			}
+		}
+	}
+
+	for i, ins := range prog {
+		for _, reg := range ins.regs {
+			if reg >= 0 {
+				extend(reg, i)
+				weight := 1
+				for d := 0; d < depth[i] && d < 3; d++ {
+					weight *= 10
+				}
+				intervals[reg].weight += weight
+			}
+		}
+		for v, live := range liveout[i] {
+			if live {
+				extend(v, i)
+				if i+1 < len(prog) {
+					extend(v, i+1)
+				}
+			}
+		}
+	}
+
+	// Pinned registers stay reserved for the whole program
+	for _, ins := range prog {
+		if ins.pinned {
+			for _, reg := range ins.regs {
+				if reg >= 0 {
+					intervals[reg].pinned = true
+					intervals[reg].start = 0
+					intervals[reg].end = len(prog) - 1
+				}
+			}
+		}
+	}
+
+	result := make([]*regalloc_interval, 0)
+	for _, iv := range intervals {
+		if iv != nil {
+			result = append(result, iv)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].start != result[j].start {
+			return result[i].start < result[j].start
+		}
+		return result[i].vreg < result[j].vreg
+	})
+	return result
+}
+
+// Linear scan over the live intervals, when no register is free the coldest one is spilled
+func regalloc_scan(intervals []*regalloc_interval, k int) (map[int]int, map[int]bool, error) {
+	assigned := make(map[int]int)
+	spilled := make(map[int]bool)
+	active := make([]*regalloc_interval, 0)
+	free := make([]bool, k)
+	for i := range free {
+		free[i] = true
+	}
+
+	for _, cur := range intervals {
+		kept := active[:0]
+		for _, act := range active {
+			if act.end < cur.start {
+				free[assigned[act.vreg]] = true
+			} else {
+				kept = append(kept, act)
+			}
+		}
+		active = kept
+
+		phys := -1
+		for i, f := range free {
+			if f {
+				phys = i
+				break
+			}
+		}
+
+		if phys != -1 {
+			free[phys] = false
+			assigned[cur.vreg] = phys
+			active = append(active, cur)
+			continue
+		}
+
+		var victim *regalloc_interval
+		if !cur.pinned {
+			victim = cur
+		}
+		for _, act := range active {
+			if !act.pinned && (victim == nil || act.weight < victim.weight || (act.weight == victim.weight && act.end > victim.end)) {
+				victim = act
+			}
+		}
+
+		if victim == nil {
+			return nil, nil, errors.New("Too many registers in use by operations that cannot be spilled")
+		}
+
+		spilled[victim.vreg] = true
+		if victim != cur {
+			assigned[cur.vreg] = assigned[victim.vreg]
+			delete(assigned, victim.vreg)
+			for i, act := range active {
+				if act == victim {
+					active[i] = cur
+					break
+				}
+			}
+		}
+	}
+	return assigned, spilled, nil
+}
+
+// Maps the virtual registers of a program to at most maxregs physical registers (0 means no limit).
+// Variables that do not fit are spilled in RAM after the cells already used by the program.
+func Register_allocate(lines []string, maxregs int) (*Regalloc_result, error) {
+	prog, vregs, ramsize := regalloc_parse(lines)
+	liveout := regalloc_liveness(prog, vregs)
+	intervals := regalloc_intervals(prog, vregs, liveout)
+
+	k := maxregs
+	if k <= 0 || k > len(intervals) {
+		k = len(intervals)
+	}
+
+	assigned, spilled, err := regalloc_scan(intervals, k)
+	if err != nil {
+		return nil, err
+	}
+
+	scratch := 0
+	if len(spilled) > 0 {
+		// Two registers are kept aside to load and store the spilled variables
+		if maxregs < 3 {
+			return nil, errors.New("At least 3 registers are needed to spill variables to RAM, " + strconv.Itoa(maxregs) + " available")
+		}
+		scratch = 2
+		if assigned, spilled, err = regalloc_scan(intervals, maxregs-scratch); err != nil {
+			return nil, err
+		}
+	}
+
+	cells := make(map[int]int)
+	for _, iv := range intervals {
+		if spilled[iv.vreg] {
+			cells[iv.vreg] = ramsize + len(cells)
+		}
+	}
+
+	result := new(Regalloc_result)
+	result.Spilled = len(spilled)
+	result.Ramsize = ramsize + len(cells)
+	result.Bindings = make(map[int]string)
+	for vreg, phys := range assigned {
+		result.Bindings[vreg] = procbuilder.Get_register_name(phys)
+	}
+	for vreg, cell := range cells {
+		result.Bindings[vreg] = "m" + strconv.Itoa(cell)
+	}
+	for _, phys := range assigned {
+		if phys+1 > result.Registers {
+			result.Registers = phys + 1
+		}
+	}
+	if scratch > 0 {
+		result.Registers = maxregs
+	}
+
+	newlines := make([]string, 0, len(lines))
+	location := make([]int, len(lines)+1)
+	for i, ins := range prog {
+		location[i] = len(newlines)
+
+		words := make([]string, len(ins.args))
+		copy(words, ins.args)
+		loads := make([]string, 0)
+		stores := make([]string, 0)
+		temps := make(map[int]int)
+		for j, reg := range ins.regs {
+			if reg < 0 {
+				continue
+			}
+			if cell, ok := cells[reg]; ok {
+				temp, ok := temps[reg]
+				if !ok {
+					temp = maxregs - scratch + len(temps)
+					temps[reg] = temp
+					tname := procbuilder.Get_register_name(temp)
+					if regalloc_uses(ins, reg) {
+						loads = append(loads, "m2r "+tname+" "+strconv.Itoa(cell))
+					}
+					if regalloc_defines(ins, reg) {
+						stores = append(stores, "r2m "+tname+" "+strconv.Itoa(cell))
+					}
+				}
+				words[j] = procbuilder.Get_register_name(temp)
+			} else {
+				words[j] = procbuilder.Get_register_name(assigned[reg])
+			}
+		}
+
+		newlines = append(newlines, loads...)
+		if len(words) > 0 {
+			newlines = append(newlines, ins.op+" "+strings.Join(words, " "))
+		} else {
+			newlines = append(newlines, lines[i])
+		}
+		newlines = append(newlines, stores...)
+	}
+	location[len(lines)] = len(newlines)
+
+	// Jump locations follow the inserted loads and stores
+	for i, line := range newlines {
+		words := strings.Fields(line)
+		for j, word := range words {
+			if loc, ok := regalloc_location(word); ok && loc >= 0 && loc <= len(lines) {
+				words[j] = "<<" + strconv.Itoa(location[loc]) + ">>"
+				newlines[i] = strings.Join(words, " ")
+			}
+		}
+	}
+
+	result.Lines = newlines
+	result.Location = location
+	return result, nil
+}
+
+func regalloc_uses(ins regalloc_instr, reg int) bool {
+	for j, r := range ins.regs {
+		if r == reg && ins.roles[j] != "d" {
+			return true
+		}
+	}
+	return false
+}
+
+func regalloc_defines(ins regalloc_instr, reg int) bool {
+	for j, r := range ins.regs {
+		if r == reg && ins.roles[j] != "u" {
+			return true
+		}
+	}
+	return false
+}
+
+// Runs the allocator on the program of a processor and notifies the resulting requirements
+func (bg *BondgoCheck) Register_allocation(proc_id int) {
+	routine, ok := bg.Program[proc_id]
+	if !ok {
+		return
+	}
+
+	alloc, err := Register_allocate(routine.Lines, bg.MaxRegs)
+	if err != nil {
+		bg.Set_faulty("Register allocation failed for processor " + strconv.Itoa(proc_id) + ": " + err.Error())
+		return
+	}
+
+	// The inserted loads and stores take the source of their instruction
+	debug := make([]Line_debug, len(alloc.Lines))
+	for i, ld := range routine.Debug {
+		for j := alloc.Location[i]; j < alloc.Location[i+1]; j++ {
+			debug[j] = ld
+		}
+	}
+
+	routine.Lines = alloc.Lines
+	routine.Debug = debug
+	routine.Bindings = alloc.Bindings
+	bg.Used <- UsageNotify{TR_PROC, proc_id, C_REGSIZE, S_NIL, alloc.Registers}
+	if alloc.Spilled > 0 {
+		bg.Used <- UsageNotify{TR_PROC, proc_id, C_OPCODE, "m2r", I_NIL}
+		bg.Used <- UsageNotify{TR_PROC, proc_id, C_OPCODE, "r2m", I_NIL}
+		bg.Used <- UsageNotify{TR_PROC, proc_id, C_RAMSIZE, S_NIL, alloc.Ramsize}
+	}
+	if bg.Verbose {
+		bg.Log("Processor " + strconv.Itoa(proc_id) + ": " + strconv.Itoa(alloc.Registers) + " registers, " + strconv.Itoa(alloc.Spilled) + " variables spilled to RAM")
+	}
+}
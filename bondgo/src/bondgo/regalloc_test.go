@@ -0,0 +1,81 @@
+package bondgo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// r5 is used in the loop, r7, r8 and r9 only outside, r6 is a dead temporary
+func TestRegisterAllocation(t *testing.T) {
+	program := []string{
+		"rset r5 3",
+		"rset r6 1",
+		"rset r7 2",
+		"rset r8 4",
+		"rset r9 5",
+		"dec r5",
+		"jz r5 <<8>>",
+		"j <<5>>",
+		"add r7 r8",
+		"add r7 r9",
+		"r2m r7 1",
+	}
+
+	alloc, err := Register_allocate(program, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(strings.Join(alloc.Lines, "\n") + "\n")
+	if alloc.Registers != 4 || alloc.Spilled != 0 {
+		t.Error("Dead temporary not reused", alloc.Registers, alloc.Spilled)
+	}
+
+	alloc, err = Register_allocate(program, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(strings.Join(alloc.Lines, "\n") + "\n")
+	if alloc.Registers != 3 || alloc.Spilled != 4 || alloc.Ramsize != 6 {
+		t.Error("Wrong spilling", alloc.Registers, alloc.Spilled, alloc.Ramsize)
+	}
+	for _, line := range alloc.Lines {
+		if strings.HasPrefix(line, "dec") && line != "dec r0" {
+			t.Error("The loop variable has been spilled")
+		}
+		if strings.HasPrefix(line, "jz") && line != "jz r0 <<12>>" {
+			t.Error("Jump location not moved", line)
+		}
+	}
+
+	if _, err := Register_allocate(program, 2); err == nil {
+		t.Error("Spilling with 2 registers accepted")
+	}
+}
+
+// The registers of the timer, counter and lfsr opcodes are reused once dead
+func TestRegisterAllocationRoles(t *testing.T) {
+	program := []string{
+		"rset r5 10",
+		"tma r5 0",
+		"tmw",
+		"tmr r6 0",
+		"r2m r6 1",
+		"rset r7 3",
+		"fadd r7 0",
+		"r2m r7 2",
+		"lock 0",
+		"lfsrn2r r8 0",
+		"r2m r8 3",
+		"unlock 0",
+	}
+
+	alloc, err := Register_allocate(program, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(strings.Join(alloc.Lines, "\n") + "\n")
+	if alloc.Registers != 1 {
+		t.Error("Dead registers not reused", alloc.Registers)
+	}
+}
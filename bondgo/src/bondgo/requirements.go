@@ -21,6 +21,9 @@ const (
 	C_SHAREDOBJECT
 	C_CONNECTED
 	C_DEVICE
+	C_DEPTH
+	C_MUTEX
+	C_COUNTER
 )
 
 const (
@@ -39,6 +42,8 @@ type ProcRequirements struct {
 	Ramsize       int
 	SharedObjects []string
 	Device        string
+	Mutexes_ids   []int // Global ids of the mutexes, in the order of the processor local ids
+	Counters_ids  []int // Global ids of the counters, in the order of the processor local ids
 }
 
 type IORequirements struct {
@@ -49,6 +54,7 @@ type IORequirements struct {
 // Maybe a rewrite in term of SO (with interfaces) is need.
 type ChanRequirements struct {
 	Connected []int
+	Depth     int // Buffer depth, 0 for the unbuffered channels
 }
 
 type SharedMemRequirements struct {
@@ -94,10 +100,27 @@ func (reqmnt *ProcRequirements) Dump_Requirements() string {
 		}
 	}
 	result += "\n"
+	if len(reqmnt.Mutexes_ids) > 0 {
+		result += "Mutexes: " + ids_list(reqmnt.Mutexes_ids) + "\n"
+	}
+	if len(reqmnt.Counters_ids) > 0 {
+		result += "Counters: " + ids_list(reqmnt.Counters_ids) + "\n"
+	}
 
 	return result
 }
 
+func ids_list(ids []int) string {
+	result := ""
+	for i, id := range ids {
+		result += strconv.Itoa(id)
+		if i != len(ids)-1 {
+			result += ","
+		}
+	}
+	return result
+}
+
 func (reqmnt *IORequirements) Dump_Requirements() string {
 	result := "Inputs: "
 	for i, con := range reqmnt.Inputs_ids {
@@ -133,6 +156,9 @@ func (reqmnt *ChanRequirements) Dump_Requirements() string {
 			result += "\n"
 		}
 	}
+	if reqmnt.Depth > 0 {
+		result += "Depth: " + strconv.Itoa(reqmnt.Depth) + "\n"
+	}
 	result += "\n"
 
 	return result
@@ -268,6 +294,10 @@ UB:
 				proc.SharedObjects = append(proc.SharedObjects, components)
 			case C_DEVICE:
 				proc.Device = components
+			case C_MUTEX:
+				proc.Mutexes_ids = append(proc.Mutexes_ids, componenti)
+			case C_COUNTER:
+				proc.Counters_ids = append(proc.Counters_ids, componenti)
 			}
 
 			// TODO Other cases
@@ -291,6 +321,10 @@ UB:
 				if !present {
 					cchan.Connected = append(cchan.Connected, componenti)
 				}
+			case C_DEPTH:
+				if componenti > cchan.Depth {
+					cchan.Depth = componenti
+				}
 			}
 
 		case TR_EXIT:
@@ -23,16 +23,29 @@ func (rs *BondgoResults) Init_Results(cfg *BondgoConfig) {
 }
 
 func (rs *BondgoResults) WriteLine(proc_id int, line string) {
+	rs.WriteLine_debug(proc_id, line, Line_debug{})
+}
+
+func (rs *BondgoResults) WriteLine_debug(proc_id int, line string, ld Line_debug) {
 	if proccode, ok := rs.Program[proc_id]; ok {
-		proccode.Append(line)
+		proccode.Append_debug(line, ld)
 	} else {
 		proccode := new(BondgoRoutine)
 		proccode.Lines = make([]string, 0)
-		proccode.Append(line)
+		proccode.Append_debug(line, ld)
 		rs.Program[proc_id] = proccode
 	}
 }
 
+// Append_program appends the program of a routine of another results keeping the source of the lines
+func (rs *BondgoResults) Append_program(proc_id int, from *BondgoResults, from_id int) {
+	if proccode, ok := from.Program[from_id]; ok {
+		for i, line := range proccode.Lines {
+			rs.WriteLine_debug(proc_id, line, proccode.Debug[i])
+		}
+	}
+}
+
 func (rs *BondgoResults) CountLines(proc_id int) int {
 	if proccode, ok := rs.Program[proc_id]; ok {
 		return len(proccode.Lines)
@@ -1,12 +1,21 @@
 package bondgo
 
 import (
+	"go/token"
 	"strconv"
 	"strings"
 )
 
 type BondgoRoutine struct {
-	Lines []string
+	Lines    []string
+	Debug    []Line_debug   // The source of every line
+	Bindings map[int]string // Virtual register -> allocated object, filled by the register allocator
+}
+
+// Source position and variables in scope of an emitted line
+type Line_debug struct {
+	Pos  token.Pos
+	Vars map[string]VarCell
 }
 
 func (p *BondgoRoutine) Shift_program_location(n int) {
@@ -61,7 +70,12 @@ func (p *BondgoRoutine) Checker(ck string) bool {
 }
 
 func (p *BondgoRoutine) Append(line string) {
+	p.Append_debug(line, Line_debug{})
+}
+
+func (p *BondgoRoutine) Append_debug(line string, ld Line_debug) {
 	if p != nil {
 		p.Lines = append(p.Lines, line)
+		p.Debug = append(p.Debug, ld)
 	}
 }
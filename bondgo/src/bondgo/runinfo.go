@@ -2,6 +2,7 @@ package bondgo
 
 import (
 	"fmt"
+	"go/token"
 	"strconv"
 )
 
@@ -25,6 +26,9 @@ type BondgoRuninfo struct {
 	IO         []IOInfo
 	Channels   []ChanInfo
 	SharedRAM  []SharedRAMInfo
+	Mutexes    []SyncInfo
+	Counters   []SyncInfo
+	Positions  map[int]token.Pos // Source position of the statement being compiled, by routine
 }
 
 // IO topology
@@ -48,6 +52,12 @@ type SharedRAMInfo struct {
 	Connected []int
 }
 
+// bondmachine mutexes and counters
+type SyncInfo struct {
+	Global_id int
+	Connected []int
+}
+
 type VarReq struct {
 	ReqType      uint8
 	Processor_id int
@@ -79,6 +89,9 @@ func (ri *BondgoRuninfo) Init_Runinfo(cfg *BondgoConfig) {
 	ri.IO = make([]IOInfo, 0)
 	ri.Channels = make([]ChanInfo, 0)
 	ri.SharedRAM = make([]SharedRAMInfo, 0)
+	ri.Mutexes = make([]SyncInfo, 0)
+	ri.Counters = make([]SyncInfo, 0)
+	ri.Positions = make(map[int]token.Pos)
 }
 
 // This goroutine assign or frees used memory within a processor
@@ -104,7 +117,7 @@ VA:
 		case REQ_REMOVE:
 			switch rcell.Procobjtype {
 			case REGISTER, MEMORY:
-				if gent, _ := Type_from_string(ri.Config.Basic_type); Same_Type(rcell.Vtype, gent) {
+				if gent, _ := Type_from_string(ri.Config.Basic_type); Same_Type(rcell.Vtype, gent) || ri.Config.Type_words(rcell.Vtype) > 1 {
 					if _, ok := busylist[rproc]; ok {
 						blist := busylist[rproc]
 						if i, ok := memused(r.Cell, blist); ok {
@@ -141,7 +154,7 @@ VA:
 				} else {
 					panic("Allocator received a wrong type, this cannot happen. A bug is here")
 				}
-			case INPUT, OUTPUT, CHANNEL:
+			case INPUT, OUTPUT, CHANNEL, MUTEX, COUNTER, TIMER:
 				// TODO Check and make better
 				resp <- VarAns{ANS_OK, r.Cell}
 			}
@@ -158,14 +171,14 @@ VA:
 						guessed := VarCell{gent, REGISTER, i, 0, 0, 0, 0, 0}
 						present := false
 						for _, assigned := range busylist[rproc] {
-							if assigned.Procobjtype == guessed.Procobjtype && assigned.Id == guessed.Id {
+							if cells_overlap(assigned, guessed) {
 								present = true
 								break
 							}
 						}
 						if !present {
+							// The register is virtual, the requirement is notified by the register allocation
 							resp <- VarAns{ANS_OK, guessed}
-							useditem <- UsageNotify{TR_PROC, rproc, C_REGSIZE, S_NIL, i + 1}
 							busylist[rproc] = append(busylist[rproc], guessed)
 							created = true
 							break
@@ -186,14 +199,14 @@ VA:
 						guessed := VarCell{gent, REGISTER, i, 0, 0, 0, 0, 0}
 						present := false
 						for _, assigned := range busylist[rproc] {
-							if assigned.Procobjtype == guessed.Procobjtype && assigned.Id == guessed.Id {
+							if cells_overlap(assigned, guessed) {
 								present = true
 								break
 							}
 						}
 						if !present {
+							// The register is virtual, the requirement is notified by the register allocation
 							resp <- VarAns{ANS_OK, guessed}
-							useditem <- UsageNotify{TR_PROC, rproc, C_REGSIZE, S_NIL, i + 1}
 							busylist[rproc] = append(busylist[rproc], guessed)
 							created = true
 							break
@@ -204,6 +217,33 @@ VA:
 						panic("Recursion function not allowed")
 					}
 
+				} else if words := ri.Config.Type_words(rcell.Vtype); words > 1 {
+					// Multi-word values take consecutive registers
+					created := false
+					if _, ok := busylist[rproc]; !ok {
+						vcells := make([]VarCell, 0)
+						busylist[rproc] = vcells
+					}
+					for i := 0; i+words <= MAX_REGS; i++ {
+						guessed := VarCell{rcell.Vtype, REGISTER, i, i, i + words - 1, 0, 0, 0}
+						present := false
+						for _, assigned := range busylist[rproc] {
+							if cells_overlap(assigned, guessed) {
+								present = true
+								break
+							}
+						}
+						if !present {
+							resp <- VarAns{ANS_OK, guessed}
+							busylist[rproc] = append(busylist[rproc], guessed)
+							created = true
+							break
+						}
+					}
+
+					if !created {
+						panic("Recursion function not allowed")
+					}
 				} else {
 					panic("Allocator received a wrong type, this cannot happen. A bug is here")
 				}
@@ -219,7 +259,7 @@ VA:
 						guessed := VarCell{gent, MEMORY, i, i, i, 0, 0, 0}
 						present := false
 						for _, assigned := range busylist[rproc] {
-							if assigned.Procobjtype == guessed.Procobjtype && assigned.Id == guessed.Id {
+							if cells_overlap(assigned, guessed) {
 								present = true
 								break
 							}
@@ -248,7 +288,7 @@ VA:
 						guessed := VarCell{gent, MEMORY, i, i, i, 0, 0, 0}
 						present := false
 						for _, assigned := range busylist[rproc] {
-							if assigned.Procobjtype == guessed.Procobjtype && assigned.Id == guessed.Id {
+							if cells_overlap(assigned, guessed) {
 								present = true
 								break
 							}
@@ -266,6 +306,34 @@ VA:
 						panic("Recursion function not allowed")
 					}
 
+				} else if words := ri.Config.Type_words(rcell.Vtype); words > 1 {
+					// Multi-word values take consecutive memory cells
+					created := false
+					if _, ok := busylist[rproc]; !ok {
+						vcells := make([]VarCell, 0)
+						busylist[rproc] = vcells
+					}
+					for i := 0; i+words <= MAX_MEMORY; i++ {
+						guessed := VarCell{rcell.Vtype, MEMORY, i, i, i + words - 1, 0, 0, 0}
+						present := false
+						for _, assigned := range busylist[rproc] {
+							if cells_overlap(assigned, guessed) {
+								present = true
+								break
+							}
+						}
+						if !present {
+							resp <- VarAns{ANS_OK, guessed}
+							useditem <- UsageNotify{TR_PROC, rproc, C_RAMSIZE, S_NIL, i + words}
+							busylist[rproc] = append(busylist[rproc], guessed)
+							created = true
+							break
+						}
+					}
+
+					if !created {
+						panic("Recursion function not allowed")
+					}
 				} else {
 					panic("Allocator received a wrong type, this cannot happen. A bug is here")
 				}
@@ -393,7 +461,9 @@ VA:
 					panic("Allocator received a wrong type, this cannot happen. A bug is here")
 				}
 			case CHANNEL:
-				if gent, _ := Type_from_string(ri.Config.Basic_chantype); Same_Type(rcell.Vtype, gent) {
+				if gent, _ := Type_from_string(ri.Config.Basic_chantype); Same_Type(rcell.Vtype, gent) || ri.Config.Chan_words(rcell.Vtype) > 1 {
+					// Channels of multi-word values transfer one word at the time
+					gent = rcell.Vtype
 					if _, ok := busylist[rproc]; !ok {
 						vcells := make([]VarCell, 0)
 						busylist[rproc] = vcells
@@ -502,11 +572,23 @@ VA:
 						panic("global channel id failed")
 					}
 				}
+			case MUTEX, COUNTER:
+				busysync := &ri.Mutexes
+				if rcell.Procobjtype == COUNTER {
+					busysync = &ri.Counters
+				}
+				guessed_global_id := len(*busysync)
+				*busysync = append(*busysync, SyncInfo{guessed_global_id, make([]int, 0)})
+				resp <- VarAns{ANS_OK, ri.sync_attach(rproc, rcell, guessed_global_id, useditem)}
 			}
 		case REQ_ATTACH:
 			switch rcell.Procobjtype {
+			case MUTEX, COUNTER:
+				resp <- VarAns{ANS_OK, ri.sync_attach(rproc, rcell, rcell.Global_id, useditem)}
 			case CHANNEL:
-				if gent, _ := Type_from_string(ri.Config.Basic_chantype); Same_Type(rcell.Vtype, gent) {
+				if gent, _ := Type_from_string(ri.Config.Basic_chantype); Same_Type(rcell.Vtype, gent) || ri.Config.Chan_words(rcell.Vtype) > 1 {
+					// Channels of multi-word values transfer one word at the time
+					gent = rcell.Vtype
 					if _, ok := busylist[rproc]; !ok {
 						vcells := make([]VarCell, 0)
 						busylist[rproc] = vcells
@@ -575,3 +657,34 @@ VA:
 	}
 	assignerdone <- true
 }
+
+// sync_attach gives the processor the first free local id of a mutex or counter and links it to the global one
+func (ri *BondgoRuninfo) sync_attach(rproc int, rcell VarCell, global_id int, useditem chan UsageNotify) VarCell {
+	busylist := ri.Used_cells
+	busysync := ri.Mutexes
+	soname := "mutex:"
+	component := C_MUTEX
+	if rcell.Procobjtype == COUNTER {
+		busysync = ri.Counters
+		soname = "counter:"
+		component = C_COUNTER
+	}
+
+	for i := 0; ; i++ {
+		guessed := VarCell{rcell.Vtype, rcell.Procobjtype, i, i, i, global_id, global_id, global_id}
+		present := false
+		for _, assigned := range busylist[rproc] {
+			if assigned.Procobjtype == guessed.Procobjtype && assigned.Id == guessed.Id {
+				present = true
+				break
+			}
+		}
+		if !present {
+			busylist[rproc] = append(busylist[rproc], guessed)
+			busysync[global_id].Connected = append(busysync[global_id].Connected, rproc)
+			useditem <- UsageNotify{TR_PROC, rproc, C_SHAREDOBJECT, soname, I_NIL}
+			useditem <- UsageNotify{TR_PROC, rproc, component, S_NIL, global_id}
+			return guessed
+		}
+	}
+}
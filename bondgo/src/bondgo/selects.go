@@ -0,0 +1,335 @@
+package bondgo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"procbuilder"
+	"strconv"
+)
+
+// A select is lowered to a wwr or wrd for every case, followed by a chw (or a chc when there is a default case) and a
+// jump table on the index of the completed operation. The channels and the values to send are evaluated once, in
+// source order, when entering the select. Among the ready cases the processor picks the channel its scan reaches
+// first, the RTL scans them following a free running LFSR. A receive completed by a closed channel is reported past
+// the queued operations, the table continues with these entries.
+
+// A send or receive case of a select
+type select_comm struct {
+	clause  *ast.CommClause
+	channel VarCell
+	send    bool
+	value   VarCell    // The register sent or received
+	lhs     []ast.Expr // The receive targets, the value and the optional ok
+	define  bool
+}
+
+// select_channel resolves the channel operand of a select case
+func (bg *BondgoCheck) select_channel(n ast.Expr) (VarCell, bool) {
+	ident, ok := n.(*ast.Ident)
+	if !ok {
+		bg.Set_faulty("Only channel variables can be used in select")
+		return VarCell{}, false
+	}
+	cell, ok := bg.lookup_var(ident.Name)
+	if !ok {
+		return VarCell{}, false
+	}
+	if cell.Procobjtype != CHANNEL {
+		bg.Set_faulty(ident.Name + " is not a channel")
+		return VarCell{}, false
+	}
+	if bg.Chan_words(cell.Vtype) > 1 {
+		bg.Set_faulty(ident.Name + ": multi-word channels cannot be used in select")
+		return VarCell{}, false
+	}
+	return cell, true
+}
+
+// select_receive recognizes <-ch, returning the channel operand
+func select_receive(n ast.Expr) (ast.Expr, bool) {
+	if unary, ok := n.(*ast.UnaryExpr); ok && unary.Op == token.ARROW {
+		return unary.X, true
+	}
+	return nil, false
+}
+
+// select_comm_eval evaluates the channel and the value of a case
+func (bg *BondgoCheck) select_comm_eval(cc *ast.CommClause) (select_comm, bool) {
+	comm := select_comm{clause: cc}
+	var chanexpr ast.Expr
+
+	switch commst := cc.Comm.(type) {
+	case *ast.SendStmt:
+		comm.send = true
+		chanexpr = commst.Chan
+	case *ast.ExprStmt:
+		if x, ok := select_receive(commst.X); ok {
+			chanexpr = x
+		}
+	case *ast.AssignStmt:
+		if len(commst.Rhs) == 1 && (len(commst.Lhs) == 1 || len(commst.Lhs) == 2) {
+			if x, ok := select_receive(commst.Rhs[0]); ok {
+				chanexpr = x
+				comm.lhs = commst.Lhs
+				comm.define = commst.Tok == token.DEFINE
+			}
+		}
+	}
+	if chanexpr == nil {
+		bg.Set_faulty("Operation not valid in select")
+		return comm, false
+	}
+
+	var ok bool
+	if comm.channel, ok = bg.select_channel(chanexpr); !ok {
+		return comm, false
+	}
+	elemt := comm.channel.Vtype.Values[0]
+
+	if comm.send {
+		value, ok := bg.Expr_eval_typed(cc.Comm.(*ast.SendStmt).Value, elemt)
+		if !ok {
+			bg.Set_faulty("Select send evaluation failed")
+			return comm, false
+		}
+		if value[0].Procobjtype != REGISTER {
+			bg.Set_faulty("Select send value not in a register")
+			return comm, false
+		}
+		if !Same_Type(value[0].Vtype, elemt) {
+			bg.mismatched(elemt, value[0].Vtype)
+			return comm, false
+		}
+		comm.value = value[0]
+	} else {
+		if comm.value, ok = bg.new_cell(elemt, REGISTER); !ok {
+			return comm, false
+		}
+	}
+	return comm, true
+}
+
+// select_assign stores a register to the receive target of a case, defining it in the case scope if requested
+func (bg *BondgoCheck) select_assign(target ast.Expr, value VarCell, define bool) bool {
+	ident, ok := target.(*ast.Ident)
+	if !ok {
+		bg.Set_faulty("Wrong assignment")
+		return false
+	}
+	if ident.Name == "_" {
+		return true
+	}
+	if define {
+		cell, ok := bg.new_cell(value.Vtype, REGISTER)
+		if !ok {
+			return false
+		}
+		bg.Vars[ident.Name] = cell
+		return bg.Word_store(value, cell)
+	}
+	cell, ok := bg.lookup_var(ident.Name)
+	if !ok {
+		return false
+	}
+	switch cell.Procobjtype {
+	case REGISTER, MEMORY:
+		return bg.Word_store(value, cell)
+	case OUTPUT:
+		bg.emit("r2o", procbuilder.Get_register_name(value.Id)+" "+procbuilder.Get_output_name(cell.Id))
+		return true
+	}
+	bg.Set_faulty(ident.Name + " cannot be written")
+	return false
+}
+
+// select_received assigns the received value and the ok flag of a receive case. It returns the location where the
+// closed entry of the jump table lands, clearing the flag, or -1 when the case does not tell a close.
+func (bg *BondgoCheck) select_received(comm select_comm) (int, bool) {
+	if len(comm.lhs) == 2 {
+		gent, _ := Type_from_string("bool")
+		okreg, ok := bg.new_cell(gent, REGISTER)
+		if !ok {
+			return -1, false
+		}
+		okname := procbuilder.Get_register_name(okreg.Id)
+		bg.emit("rset", okname+" 1")
+		bg.emit("j", bg.next_location(2))
+		closed_point := bg.CountLines(bg.CurrentRoutine)
+		bg.emit("clr", okname)
+		if !bg.select_assign(comm.lhs[0], comm.value, comm.define) || !bg.select_assign(comm.lhs[1], okreg, comm.define) {
+			return -1, false
+		}
+		return closed_point, bg.free_cells(okreg)
+	}
+	if len(comm.lhs) == 1 {
+		return -1, bg.select_assign(comm.lhs[0], comm.value, comm.define)
+	}
+	return -1, true
+}
+
+// Select_stmt compiles a select statement
+func (bg *BondgoCheck) Select_stmt(x *ast.SelectStmt) bool {
+	// Create a new BondgoCheck for the select statement with an empty program
+	results := new(BondgoResults) // Results go in here
+	results.Init_Results(bg.BondgoConfig)
+
+	bgsel := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, bg.Vars, bg.Consts, bg.Returns, bg.CurrentLoop, "", bg.CurrentDevice, bg.CurrentRoutine}
+
+	starting_point := bg.CountLines(bg.CurrentRoutine)
+
+	bgsel.CurrentSwitch = fmt.Sprintf("SEL%p", bgsel)
+
+	// The first loop evaluates the cases
+	comms := make([]select_comm, 0)
+	var defaultclause *ast.CommClause
+	for _, clause := range x.Body.List {
+		cc, ok := clause.(*ast.CommClause)
+		if !ok {
+			bgsel.Set_faulty("Wrong case")
+			return false
+		}
+		if cc.Comm == nil {
+			if defaultclause != nil {
+				bgsel.Set_faulty("Duplicate default case")
+				return false
+			}
+			defaultclause = cc
+			continue
+		}
+		if comm, ok := bgsel.select_comm_eval(cc); ok {
+			comms = append(comms, comm)
+		} else {
+			return false
+		}
+	}
+
+	if len(comms) == 0 && defaultclause == nil {
+		// An empty select blocks forever
+		bgsel.emit("j", bgsel.next_location(0))
+	}
+
+	if len(comms) > 0 {
+		for _, comm := range comms {
+			regname := procbuilder.Get_register_name(comm.value.Id)
+			channame := procbuilder.Get_channel_name(comm.channel.Id)
+			if comm.send {
+				bgsel.emit("wwr", regname+" "+channame)
+			} else {
+				bgsel.emit("wrd", regname+" "+channame)
+			}
+		}
+
+		gent, _ := Type_from_string(bg.Basic_type)
+		eventreg, ok := bgsel.new_cell(gent, REGISTER)
+		if !ok {
+			return false
+		}
+		eventname := procbuilder.Get_register_name(eventreg.Id)
+
+		if defaultclause != nil {
+			occurreg, ok := bgsel.new_cell(gent, REGISTER)
+			if !ok {
+				return false
+			}
+			occurname := procbuilder.Get_register_name(occurreg.Id)
+			bgsel.emit("chc", occurname+" "+eventname)
+			bgsel.emit("jz", occurname+" <<"+bgsel.CurrentSwitch+"CASEDEFAULT>>")
+			if !bgsel.free_cells(occurreg) {
+				return false
+			}
+		} else {
+			bgsel.emit("chw", eventname)
+		}
+
+		// The jump table on the index of the completed operation, the closed entries follow when a case receives
+		entries := len(comms)
+		for _, comm := range comms {
+			if !comm.send {
+				entries = 2 * len(comms)
+				break
+			}
+		}
+		for i := 0; i < entries; i++ {
+			label := "<<" + bgsel.CurrentSwitch + "CASE" + strconv.Itoa(i) + ">>"
+			if i >= len(comms) {
+				label = "<<" + bgsel.CurrentSwitch + "CLOSED" + strconv.Itoa(i-len(comms)) + ">>"
+			}
+			if i < entries-1 {
+				bgsel.emit("jz", eventname+" "+label)
+				bgsel.emit("dec", eventname)
+			} else {
+				bgsel.emit("j", label)
+			}
+		}
+
+		if !bgsel.free_cells(eventreg) {
+			return false
+		}
+	}
+
+	// The second loop creates the cases, each one with its own scope
+	default_point := 0
+	starting_points := make([]int, len(comms))
+	closed_points := make([]int, len(comms))
+	for i := 0; i <= len(comms); i++ {
+		var clause *ast.CommClause
+		if i < len(comms) {
+			starting_points[i] = bgsel.CountLines(bgsel.CurrentRoutine)
+			clause = comms[i].clause
+		} else if defaultclause != nil {
+			default_point = bgsel.CountLines(bgsel.CurrentRoutine)
+			clause = defaultclause
+		} else {
+			break
+		}
+
+		vars := make(map[string]VarCell)
+		bgcase := &BondgoCheck{bgsel.BondgoResults, bgsel.BondgoConfig, bgsel.BondgoRequirements, bgsel.BondgoRuninfo, bgsel.BondgoMessages, bgsel.BondgoFunctions, bgsel.Used, bgsel.Reqs, bgsel.Answers, bgsel, nil, vars, nil, bgsel.Returns, bgsel.CurrentLoop, bgsel.CurrentSwitch, bgsel.CurrentDevice, bgsel.CurrentRoutine}
+
+		if i < len(comms) {
+			closed_points[i] = -1
+			if !comms[i].send {
+				var ok bool
+				if closed_points[i], ok = bgcase.select_received(comms[i]); !ok {
+					return false
+				}
+			}
+		}
+
+		for _, stmt := range clause.Body {
+			ast.Walk(bgcase, stmt)
+			if bgcase.Is_faulty() {
+				return false
+			}
+		}
+
+		bgsel.emit("j", "<<"+bgsel.CurrentSwitch+"SELEND>>")
+	}
+
+	for _, comm := range comms {
+		if !bgsel.free_cells(comm.value) {
+			return false
+		}
+	}
+
+	select_end := bgsel.CountLines(bgsel.CurrentRoutine)
+
+	for i := range starting_points {
+		bgsel.Replacer(bgsel.CurrentRoutine, "<<"+bgsel.CurrentSwitch+"CASE"+strconv.Itoa(i)+">>", "<<"+strconv.Itoa(starting_points[i])+">>")
+		// A closed channel delivers the zero value, without an ok flag the case runs as usual
+		if closed_points[i] < 0 {
+			closed_points[i] = starting_points[i]
+		}
+		bgsel.Replacer(bgsel.CurrentRoutine, "<<"+bgsel.CurrentSwitch+"CLOSED"+strconv.Itoa(i)+">>", "<<"+strconv.Itoa(closed_points[i])+">>")
+	}
+	bgsel.Replacer(bgsel.CurrentRoutine, "<<"+bgsel.CurrentSwitch+"CASEDEFAULT>>", "<<"+strconv.Itoa(default_point)+">>")
+	bgsel.Replacer(bgsel.CurrentRoutine, "<<"+bgsel.CurrentSwitch+"SELEND>>", "<<"+strconv.Itoa(select_end)+">>")
+
+	// Shift eventually created reference to line number within the code
+	bgsel.Shift_program_location(bgsel.CurrentRoutine, starting_point)
+
+	bg.Append_program(bg.CurrentRoutine, bgsel.BondgoResults, bgsel.CurrentRoutine)
+
+	return true
+}
@@ -0,0 +1,199 @@
+package bondgo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"procbuilder"
+	"strings"
+)
+
+// sync.Mutex variables are mapped on mutex shared objects and the sync/atomic integers on counter shared objects.
+// The counters are as wide as the registers whatever the atomic type, they are shared with the goroutines passing
+// their address as argument. The atomic functions are accepted on the address of an atomic variable, CompareAndSwap
+// is rejected as the counter shared objects have only the fetch and add and the swap.
+
+// Sync_type returns the processor object a sync or atomic type is mapped to
+func Sync_type(fullname string) (uint8, bool) {
+	switch fullname {
+	case "sync.Mutex":
+		return MUTEX, true
+	case "atomic.Int32", "atomic.Int64", "atomic.Uint32", "atomic.Uint64", "atomic.Uintptr":
+		return COUNTER, true
+	}
+	return 0, false
+}
+
+// Sync_pointer_types lists the pointers to the sync and atomic types, the way they are passed to functions
+func Sync_pointer_types() []string {
+	return []string{"* sync.Mutex", "* atomic.Int32", "* atomic.Int64", "* atomic.Uint32", "* atomic.Uint64", "* atomic.Uintptr"}
+}
+
+// Sync_declare allocates the shared objects for a var declaration of a sync or atomic type
+func (bg *BondgoCheck) Sync_declare(names []*ast.Ident, fullname string) bool {
+	objtype, ok := Sync_type(fullname)
+	if !ok {
+		bg.Set_faulty("Unsupported type " + fullname)
+		return false
+	}
+	synct, _ := Type_from_string(fullname)
+	for _, vari := range names {
+		if _, ok := bg.Vars[vari.Name]; ok {
+			bg.Set_faulty(vari.Name + ": name already used")
+			return false
+		}
+		bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{synct, objtype, 0, 0, 0, 0, 0, 0}}
+		resp := <-bg.Answers
+		if resp.AnsType != ANS_OK {
+			bg.Set_faulty("Resource reservation failed")
+			return false
+		}
+		bg.Vars[vari.Name] = resp.Cell
+
+		if bg.In_debug() {
+			fmt.Println("\t\tAllocated to " + vari.Name + " the cell " + bg.Vars[vari.Name].String())
+		}
+	}
+	return true
+}
+
+// Sync_lookup finds a mutex or counter variable in the visible scopes
+func (bg *BondgoCheck) Sync_lookup(name string) (VarCell, bool) {
+	for scope := bg; scope != nil; scope = scope.Outer {
+		if cell, ok := scope.Vars[name]; ok {
+			if cell.Procobjtype == MUTEX || cell.Procobjtype == COUNTER {
+				return cell, true
+			}
+			return VarCell{}, false
+		}
+	}
+	return VarCell{}, false
+}
+
+// sync_operand evaluates the value argument of a counter operation to a register, the caller frees it
+func (bg *BondgoCheck) sync_operand(args []ast.Expr) (VarCell, bool) {
+	if len(args) != 1 {
+		bg.Set_faulty("Wrong argument number")
+		return VarCell{}, false
+	}
+	value, ok := bg.Expr_eval(args[0])
+	if !ok || len(value) != 1 || value[0].Procobjtype != REGISTER {
+		bg.Set_faulty("Wrong evaluation")
+		return VarCell{}, false
+	}
+	return value[0], true
+}
+
+// Sync_call lowers a method call on a mutex or counter. Add, Load and Swap return a register with the new value,
+// or the old one for Swap, the register has to be freed by the caller.
+func (bg *BondgoCheck) Sync_call(cell VarCell, method string, args []ast.Expr) ([]VarCell, bool) {
+	switch cell.Procobjtype {
+	case MUTEX:
+		if len(args) != 0 {
+			bg.Set_faulty("Wrong argument number")
+			return []VarCell{}, false
+		}
+		mxname := procbuilder.Get_mutex_name(cell.Id)
+		switch method {
+		case "Lock":
+			bg.emit("lock", mxname)
+		case "Unlock":
+			bg.emit("unlock", mxname)
+		default:
+			bg.Set_faulty("Unsupported mutex operation " + method)
+			return []VarCell{}, false
+		}
+		return []VarCell{}, true
+	case COUNTER:
+		ctname := procbuilder.Get_counter_name(cell.Id)
+		gent, _ := Type_from_string(bg.Basic_type)
+		switch method {
+		case "Add":
+			delta, ok := bg.sync_operand(args)
+			if !ok {
+				return []VarCell{}, false
+			}
+			result, ok := bg.new_cell(gent, REGISTER)
+			if !ok {
+				return []VarCell{}, false
+			}
+			// fadd returns the old value, the delta is added back to have the new one as atomic.Add does
+			regname := procbuilder.Get_register_name(result.Id)
+			deltaname := procbuilder.Get_register_name(delta.Id)
+			bg.emit("cpy", regname+" "+deltaname)
+			bg.emit("fadd", regname+" "+ctname)
+			bg.emit("add", regname+" "+deltaname)
+			return []VarCell{result}, bg.free_cells(delta)
+		case "Load":
+			if len(args) != 0 {
+				bg.Set_faulty("Wrong argument number")
+				return []VarCell{}, false
+			}
+			result, ok := bg.new_cell(gent, REGISTER)
+			if !ok {
+				return []VarCell{}, false
+			}
+			// Adding zero reads the counter
+			regname := procbuilder.Get_register_name(result.Id)
+			bg.emit("clr", regname)
+			bg.emit("fadd", regname+" "+ctname)
+			return []VarCell{result}, true
+		case "Store", "Swap":
+			// fswp stores the register and loads it with the old value
+			value, ok := bg.sync_operand(args)
+			if !ok {
+				return []VarCell{}, false
+			}
+			bg.emit("fswp", procbuilder.Get_register_name(value.Id)+" "+ctname)
+			if method == "Store" {
+				return []VarCell{}, bg.free_cells(value)
+			}
+			return []VarCell{value}, true
+		case "CompareAndSwap":
+			bg.Set_faulty("CompareAndSwap is not supported, the counters only add and swap atomically")
+			return []VarCell{}, false
+		default:
+			bg.Set_faulty("Unsupported atomic operation " + method)
+			return []VarCell{}, false
+		}
+	}
+	bg.Set_faulty("Not a sync object")
+	return []VarCell{}, false
+}
+
+// Atomic_call lowers the sync/atomic functions, as atomic.AddInt32(&x, 1), to the methods of the counter x. The
+// address has to be the one of an atomic variable of the same type, or a pointer parameter, the plain integers are
+// not shared between the goroutines.
+func (bg *BondgoCheck) Atomic_call(call *ast.CallExpr, name string) ([]VarCell, bool) {
+	for _, method := range []string{"Add", "CompareAndSwap", "Load", "Store", "Swap"} {
+		if !strings.HasPrefix(name, method) {
+			continue
+		}
+		fullname := "atomic." + strings.TrimPrefix(name, method)
+		if objtype, ok := Sync_type(fullname); !ok || objtype != COUNTER {
+			break
+		}
+		if len(call.Args) == 0 {
+			bg.Set_faulty("Wrong argument number")
+			return []VarCell{}, false
+		}
+		// The address of a counter or a pointer parameter, which is bound to the counter itself
+		addr := call.Args[0]
+		if unary, ok := addr.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+			addr = unary.X
+		}
+		if ident, ok := addr.(*ast.Ident); ok {
+			if cell, ok := bg.Sync_lookup(ident.Name); ok && cell.Procobjtype == COUNTER {
+				if atomt, _ := Type_from_string(fullname); !Same_Type(cell.Vtype, atomt) {
+					bg.Set_faulty("atomic." + name + " needs a " + fullname + " variable, " + ident.Name + " is " + cell.Vtype.String())
+					return []VarCell{}, false
+				}
+				return bg.Sync_call(cell, method, call.Args[1:])
+			}
+		}
+		bg.Set_faulty("atomic." + name + " needs the address of a " + fullname + " variable, the plain integers are not shared between the goroutines")
+		return []VarCell{}, false
+	}
+	bg.Set_faulty("Unsupported function atomic." + name)
+	return []VarCell{}, false
+}
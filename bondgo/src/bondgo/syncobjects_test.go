@@ -0,0 +1,146 @@
+package bondgo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"strings"
+	"testing"
+)
+
+func TestSyncTypes(t *testing.T) {
+	expr, _ := parser.ParseExpr("*sync.Mutex")
+	fromast, err := Type_from_ast(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromstring, _ := Type_from_string("* sync.Mutex")
+	if !Same_Type(fromast, fromstring) {
+		t.Error("Wrong pointer type", fromast, fromstring)
+	}
+	if objtype, ok := Sync_type("atomic.Uint32"); !ok || objtype != COUNTER {
+		t.Error("atomic.Uint32 not mapped to a counter")
+	}
+	if _, ok := Sync_type("sync.WaitGroup"); ok {
+		t.Error("sync.WaitGroup is not supported")
+	}
+}
+
+// A mutex created by processor 0 and shared with processor 1, which creates its own counter too
+func TestSyncAttach(t *testing.T) {
+	cfg := new(BondgoConfig)
+	cfg.Basic_type = "uint8"
+	ri := new(BondgoRuninfo)
+	ri.Init_Runinfo(cfg)
+	reqmnt := new(BondgoRequirements)
+	reqmnt.Init_Requirements(cfg)
+
+	reqs := make(chan VarReq)
+	answers := make(chan VarAns)
+	used := make(chan UsageNotify)
+	done := make(chan bool)
+	go ri.Var_assigner(reqs, answers, used, done)
+	go reqmnt.Usage_Monitor(used, done)
+
+	mutext, _ := Type_from_string("sync.Mutex")
+	countert, _ := Type_from_string("atomic.Uint32")
+
+	reqs <- VarReq{REQ_NEW, 0, VarCell{mutext, MUTEX, 0, 0, 0, 0, 0, 0}}
+	mx := (<-answers).Cell
+	reqs <- VarReq{REQ_NEW, 1, VarCell{countert, COUNTER, 0, 0, 0, 0, 0, 0}}
+	ct := (<-answers).Cell
+	reqs <- VarReq{REQ_ATTACH, 1, mx}
+	mx1 := (<-answers).Cell
+
+	reqs <- VarReq{REQ_EXIT, 0, VarCell{}}
+	used <- UsageNotify{TR_EXIT, 0, 0, S_NIL, I_NIL}
+	<-done
+	<-done
+
+	fmt.Print(reqmnt.Dump_Requirements())
+	if mx.Id != 0 || ct.Id != 0 || mx1.Id != 0 || mx1.Global_id != mx.Global_id {
+		t.Error("Wrong cells", mx, ct, mx1)
+	}
+	if len(ri.Mutexes) != 1 || len(ri.Counters) != 1 || len(ri.Mutexes[0].Connected) != 2 {
+		t.Error("Wrong sync objects", ri.Mutexes, ri.Counters)
+	}
+	p1 := reqmnt.Procr[1]
+	if len(p1.SharedObjects) != 2 || len(p1.Mutexes_ids) != 1 || len(p1.Counters_ids) != 1 {
+		t.Error("Wrong processor requirements", p1)
+	}
+}
+
+// The atomic functions are lowered on the counter methods, the plain integers and CompareAndSwap are rejected
+func TestAtomicCalls(t *testing.T) {
+	cfg := new(BondgoConfig)
+	cfg.Rsize = 8
+	cfg.Basic_type = "uint8"
+	ri := new(BondgoRuninfo)
+	ri.Init_Runinfo(cfg)
+	reqmnt := new(BondgoRequirements)
+	reqmnt.Init_Requirements(cfg)
+	results := new(BondgoResults)
+	results.Init_Results(cfg)
+	messages := new(BondgoMessages)
+	messages.Init_Messages(cfg)
+	functs := new(BondgoFunctions)
+	functs.Init_Functions(cfg, messages)
+
+	reqs := make(chan VarReq)
+	answers := make(chan VarAns)
+	used := make(chan UsageNotify)
+	done := make(chan bool)
+	go ri.Var_assigner(reqs, answers, used, done)
+	go reqmnt.Usage_Monitor(used, done)
+
+	bg := &BondgoCheck{BondgoResults: results, BondgoConfig: cfg, BondgoRequirements: reqmnt, BondgoRuninfo: ri, BondgoMessages: messages, BondgoFunctions: functs, Used: used, Reqs: reqs, Answers: answers, Vars: make(map[string]VarCell)}
+	bg.Program[0] = new(BondgoRoutine)
+	bg.Program[0].Lines = make([]string, 0)
+	gent, _ := Type_from_string("uint8")
+	bg.Vars["n"] = VarCell{Vtype: gent, Procobjtype: REGISTER}
+	if !bg.Sync_declare([]*ast.Ident{ast.NewIdent("c")}, "atomic.Uint32") {
+		t.Fatal(bg.Dump_log())
+	}
+
+	tests := []struct {
+		call    string
+		results int
+	}{
+		{"atomic.AddUint32(&c, 3)", 1},
+		{"atomic.StoreUint32(&c, 4)", 0},
+		{"atomic.SwapUint32(&c, 5)", 1},
+		{"atomic.LoadUint32(c)", 1},
+		{"atomic.AddInt32(&c, 1)", -1},
+		{"atomic.CompareAndSwapUint32(&c, 1, 2)", -1},
+		{"atomic.AddUint32(&n, 1)", -1},
+	}
+	for _, test := range tests {
+		expr, err := parser.ParseExpr(test.call)
+		if err != nil {
+			t.Fatal(err)
+		}
+		call := expr.(*ast.CallExpr)
+		cells, ok := bg.Atomic_call(call, call.Fun.(*ast.SelectorExpr).Sel.Name)
+		if test.results < 0 {
+			if ok || !bg.Is_faulty() {
+				t.Error(test.call + " has to fail")
+			}
+			bg.Init_Messages(cfg)
+			continue
+		}
+		if !ok || len(cells) != test.results || !bg.free_cells(cells...) {
+			t.Error("Wrong lowering of "+test.call, bg.Dump_log())
+		}
+	}
+
+	reqs <- VarReq{REQ_EXIT, 0, VarCell{}}
+	used <- UsageNotify{TR_EXIT, 0, 0, S_NIL, I_NIL}
+	<-done
+	<-done
+
+	asm := strings.Join(bg.Program[0].Lines, "\n")
+	fmt.Println(asm)
+	if strings.Count(asm, "fadd") != 2 || strings.Count(asm, "fswp") != 2 {
+		t.Error("Wrong counter opcodes")
+	}
+}
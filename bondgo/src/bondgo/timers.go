@@ -0,0 +1,197 @@
+package bondgo
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"procbuilder"
+	"strconv"
+)
+
+// time.Sleep and time.Tick are mapped on the processor timer, their constant durations are converted to cycles with
+// the configured clock and loaded as a register shifted by the smallest immediate that fits. There is one timer per
+// processor: a Sleep re-arms it, so a ticker does not survive a Sleep of the same goroutine. The values received
+// from a ticker are the cycles counter.
+
+// Time_unit returns the nanoseconds of a time unit constant
+func Time_unit(name string) (int64, bool) {
+	switch name {
+	case "Nanosecond":
+		return 1, true
+	case "Microsecond":
+		return 1000, true
+	case "Millisecond":
+		return 1000000, true
+	case "Second":
+		return 1000000000, true
+	case "Minute":
+		return 60000000000, true
+	case "Hour":
+		return 3600000000000, true
+	}
+	return 0, false
+}
+
+// Timer_duration converts a constant duration to the register value and the shift of the timer opcodes, the
+// duration is rounded up to the resolution of the shift
+func (bg *BondgoCheck) Timer_duration(n ast.Expr) (uint64, int, bool) {
+	c, ok := bg.Const_eval(n)
+	if !ok || c.Value.Kind() != constant.Int {
+		bg.Set_faulty("The duration has to be a constant")
+		return 0, 0, false
+	}
+	ns, exact := constant.Int64Val(c.Value)
+	if !exact || ns < 0 {
+		bg.Set_faulty("The duration has to be a non negative constant")
+		return 0, 0, false
+	}
+	if bg.Clock_mhz <= 0 {
+		bg.Set_faulty("The clock frequency is needed to convert durations to cycles")
+		return 0, 0, false
+	}
+	mhz := uint64(bg.Clock_mhz)
+	if uint64(ns) > ^uint64(0)/mhz {
+		bg.Set_faulty("Duration too long " + strconv.FormatInt(ns, 10) + "ns")
+		return 0, 0, false
+	}
+	cycles := (uint64(ns)*mhz + 999) / 1000
+
+	limit := ^uint64(0)
+	if bg.Rsize < 64 {
+		limit = uint64(1)<<bg.Rsize - 1
+	}
+	for shift := 0; shift < 1<<procbuilder.Timer_shift_bits; shift++ {
+		value := cycles >> uint(shift)
+		if cycles&(uint64(1)<<uint(shift)-1) != 0 {
+			value++
+		}
+		if value <= limit {
+			return value, shift, true
+		}
+	}
+	bg.Set_faulty("Duration too long " + strconv.FormatInt(ns, 10) + "ns")
+	return 0, 0, false
+}
+
+// timer_load emits the arming of the timer, the register holding the duration is freed
+func (bg *BondgoCheck) timer_load(opcode string, n ast.Expr) bool {
+	value, shift, ok := bg.Timer_duration(n)
+	if !ok {
+		return false
+	}
+	gent, _ := Type_from_string(bg.Basic_type)
+	cell, ok := bg.new_cell(gent, REGISTER)
+	if !ok {
+		return false
+	}
+	regname := procbuilder.Get_register_name(cell.Id)
+	bg.emit("rset", regname+" "+strconv.FormatUint(value, 10))
+	bg.emit(opcode, regname+" "+strconv.Itoa(shift))
+	return bg.free_cells(cell)
+}
+
+// Time_call compiles the statement calls to the time package
+func (bg *BondgoCheck) Time_call(call *ast.CallExpr, name string) bool {
+	switch name {
+	case "Sleep":
+		if len(call.Args) != 1 {
+			bg.Set_faulty("time.Sleep expects one argument")
+			return false
+		}
+		if !bg.timer_load("tma", call.Args[0]) {
+			return false
+		}
+		bg.emit("tmw", "")
+		return true
+	case "Tick":
+		bg.Set_faulty("The ticker of time.Tick has to be assigned")
+		return false
+	}
+	bg.Set_faulty("Unknown function time." + name)
+	return false
+}
+
+// Time_tick recognizes time.Tick(d)
+func Time_tick(n ast.Expr) (*ast.CallExpr, bool) {
+	call, ok := n.(*ast.CallExpr)
+	if !ok {
+		return nil, false
+	}
+	fun, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok || fun.Sel.Name != "Tick" {
+		return nil, false
+	}
+	if pkg, ok := fun.X.(*ast.Ident); !ok || pkg.Name != "time" {
+		return nil, false
+	}
+	return call, true
+}
+
+// Tick_new arms the timer periodic and binds the ticker to the variable
+func (bg *BondgoCheck) Tick_new(vari string, call *ast.CallExpr) bool {
+	if len(call.Args) != 1 {
+		bg.Set_faulty("time.Tick expects one argument")
+		return false
+	}
+	if c, ok := bg.Const_eval(call.Args[0]); ok && c.Value.Kind() == constant.Int && constant.Sign(c.Value) == 0 {
+		bg.Set_faulty(vari + ": time.Tick needs a positive duration")
+		return false
+	}
+	if !bg.timer_load("tmp", call.Args[0]) {
+		return false
+	}
+	tickt, _ := Type_from_string(bg.Basic_chantype)
+	bg.Vars[vari] = VarCell{tickt, TIMER, 0, 0, 0, 0, 0, 0}
+
+	if bg.In_debug() {
+		fmt.Println("\t\tAllocated to " + vari + " the cell " + bg.Vars[vari].String())
+	}
+	return true
+}
+
+// Timer_lookup finds a ticker variable in the visible scopes, it does not fault if the name is something else
+func (bg *BondgoCheck) Timer_lookup(n ast.Expr) bool {
+	ident, ok := n.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	for scope := bg; scope != nil; scope = scope.Outer {
+		if cell, ok := scope.Vars[ident.Name]; ok {
+			return cell.Procobjtype == TIMER
+		}
+	}
+	return false
+}
+
+// Timer_receive compiles <-t, it waits the next tick and reads the cycles counter
+func (bg *BondgoCheck) Timer_receive() (VarCell, bool) {
+	bg.emit("tmw", "")
+	gent, _ := Type_from_string(bg.Basic_type)
+	cell, ok := bg.new_cell(gent, REGISTER)
+	if !ok {
+		return VarCell{}, false
+	}
+	bg.emit("tmr", procbuilder.Get_register_name(cell.Id)+" 0")
+	return cell, true
+}
+
+// Timer_range compiles for range t and for now := range t, the loop runs at every tick until a break
+func (bg *BondgoCheck) Timer_range(x *ast.RangeStmt) bool {
+	if x.Value != nil {
+		bg.Set_faulty("Range over a ticker permits only one iteration variable")
+		return false
+	}
+
+	return bg.Range_loop(x, func(bgfor *BondgoCheck) bool {
+		if x.Key == nil {
+			bgfor.emit("tmw", "")
+			return true
+		}
+		value, ok := bgfor.Timer_receive()
+		if !ok {
+			return false
+		}
+		return bgfor.select_assign(x.Key, value, x.Tok == token.DEFINE) && bgfor.free_cells(value)
+	})
+}
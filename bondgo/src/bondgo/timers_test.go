@@ -0,0 +1,45 @@
+package bondgo
+
+import (
+	"go/parser"
+	"testing"
+)
+
+func TestTimerDuration(t *testing.T) {
+	config := new(BondgoConfig)
+	config.Rsize = 8
+	config.Clock_mhz = 100
+	messages := new(BondgoMessages)
+	messages.Init_Messages(config)
+	functs := new(BondgoFunctions)
+	functs.Init_Functions(config, messages)
+	bg := &BondgoCheck{BondgoConfig: config, BondgoMessages: messages, BondgoFunctions: functs}
+
+	tests := []struct {
+		expr  string
+		value uint64
+		shift int
+	}{
+		{"0", 0, 0},
+		{"50 * time.Nanosecond", 5, 0},
+		{"time.Microsecond", 100, 0},
+		{"(2 + 1) * time.Microsecond", 150, 1}, // 300 cycles
+		{"3 * time.Second", 144, 21},           // 3e8 cycles rounded up
+		{"15", 2, 0},                           // 1.5 cycles rounded up
+	}
+	for _, test := range tests {
+		expr, err := parser.ParseExpr(test.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, shift, ok := bg.Timer_duration(expr)
+		if !ok || value != test.value || shift != test.shift {
+			t.Error("Wrong conversion of "+test.expr, value, shift, ok)
+		}
+	}
+
+	expr, _ := parser.ParseExpr("time.Hour * 1000000")
+	if _, _, ok := bg.Timer_duration(expr); ok || !bg.Is_faulty() {
+		t.Error("A duration overflowing the timer has to fail")
+	}
+}
@@ -14,6 +14,9 @@ const (
 	OUTPUT // Used only in assignment
 	CHANNEL
 	SHAREDMEMORY
+	MUTEX   // sync.Mutex, mapped on a mutex shared object
+	COUNTER // sync/atomic integers, mapped on a counter shared object
+	TIMER   // time.Tick, mapped on the processor timer
 )
 
 const (
@@ -94,6 +97,13 @@ func Type_from_string(types string) (*VarType, error) {
 				newtype.Values = make([]*VarType, 0)
 				return newtype, nil
 			default:
+				if _, ok := Sync_type(t); ok {
+					newtype := new(VarType)
+					newtype.MainType = T_NAMED
+					newtype.Name = t
+					newtype.Values = make([]*VarType, 0)
+					return newtype, nil
+				}
 				return nil, errors.New("Type " + t + " unsupported")
 			}
 		}
@@ -152,6 +162,14 @@ func Type_from_ast(spec ast.Node) (*VarType, error) {
 			newtype.Values[0] = inner_type
 			return newtype, nil
 		}
+	case *ast.SelectorExpr:
+		if pkg, ok := vtype.X.(*ast.Ident); ok {
+			newtype := new(VarType)
+			newtype.MainType = T_NAMED
+			newtype.Name = pkg.Name + "." + vtype.Sel.Name
+			newtype.Values = make([]*VarType, 0)
+			return newtype, nil
+		}
 	case *ast.StructType:
 	}
 	return nil, errors.New("Import failed")
@@ -171,6 +189,12 @@ func (m VarCell) String() string {
 		result += "mem "
 	case CHANNEL:
 		result += "chan "
+	case MUTEX:
+		result += "mutex "
+	case COUNTER:
+		result += "counter "
+	case TIMER:
+		result += "timer "
 	}
 	result += strconv.Itoa(m.Id) + ">"
 	return result
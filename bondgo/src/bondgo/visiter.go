@@ -10,7 +10,7 @@ import (
 )
 
 func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
-	//bgclone := &BondgoCheck{bg.BondgoResults, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg.Outer, bg.Clean, bg.Vars, bg.Returns, bg.CurrentLoop, bg.CurrentRoutine}
+	//bgclone := &BondgoCheck{bg.BondgoResults, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg.Outer, bg.Clean, bg.Vars, bg.Consts, bg.Returns, bg.CurrentLoop, bg.CurrentRoutine}
 
 	if bg.Clean != nil {
 		for vari, cell := range bg.Clean.Vars {
@@ -26,6 +26,13 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		bg.Clean = nil
 	}
 
+	// The statements lines are emitted within this position, blocks have the one of their enclosing statement
+	if _, ok := n.(ast.Stmt); ok {
+		if _, block := n.(*ast.BlockStmt); !block {
+			bg.Positions[bg.CurrentRoutine] = n.Pos()
+		}
+	}
+
 	switch x := n.(type) {
 	case *ast.GenDecl:
 		if bg.In_debug() {
@@ -93,6 +100,10 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 							bg.Set_faulty("Unknown selector " + vtype.Sel.Name)
 							return nil
 						}
+					} else if x.Name == "sync" || x.Name == "atomic" {
+						if !bg.Sync_declare(spec.Names, x.Name+"."+vtype.Sel.Name) {
+							return nil
+						}
 					} else {
 						bg.Set_faulty("Unknown package " + x.Name)
 						return nil
@@ -211,55 +222,61 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 								}
 							}
 						}
-					} else if gent, _ := Type_from_string(bg.Basic_chantype); Same_Type(newt, gent) {
+					} else if bg.Type_words(newt) > 1 {
+
 						for _, vari := range spec.Names {
 							if _, ok := bg.Vars[vari.Name]; ok {
 								bg.Set_faulty(vari.Name + ": name already used")
 								return nil
 							} else {
-								bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, CHANNEL, 0, 0, 0, 0, 0, 0}}
-								resp := <-bg.Answers
-								if resp.AnsType == ANS_OK {
-
-									cell := resp.Cell
+								objtype := MEMORY
+								if len(vari.Name) > 4 && vari.Name[:4] == "reg_" {
+									objtype = REGISTER
+								}
+								if cell, ok := bg.new_cell(newt, objtype); ok {
 									bg.Vars[vari.Name] = cell
-
+									if !bg.Word_clear(cell) {
+										return nil
+									}
 									if bg.In_debug() {
 										fmt.Println("\t\tAllocated to " + vari.Name + " the cell " + bg.Vars[vari.Name].String())
 									}
 								} else {
-									bg.Set_faulty("Resource reservation failed")
 									return nil
 								}
 							}
 						}
-					} else if gent, _ := Type_from_string("chan bool"); Same_Type(newt, gent) {
-						for _, vari := range spec.Names {
+					} else if newt != nil && newt.MainType == T_CHAN {
+						for i, vari := range spec.Names {
 							if _, ok := bg.Vars[vari.Name]; ok {
 								bg.Set_faulty(vari.Name + ": name already used")
 								return nil
-							} else {
-								bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, CHANNEL, 0, 0, 0, 0, 0, 0}}
-								resp := <-bg.Answers
-								if resp.AnsType == ANS_OK {
-
-									cell := resp.Cell
-									bg.Vars[vari.Name] = cell
-
-									if bg.In_debug() {
-										fmt.Println("\t\tAllocated to " + vari.Name + " the cell " + bg.Vars[vari.Name].String())
-									}
+							}
+							depth := 0
+							if i < len(spec.Values) {
+								// Only make is accepted as initial value, its capacity sets the buffer depth
+								if _, d, ok := bg.Chan_make(spec.Values[i]); ok {
+									depth = d
 								} else {
-									bg.Set_faulty("Resource reservation failed")
+									if !bg.Is_faulty() {
+										bg.Set_faulty(vari.Name + ": channels can be initialized only with make")
+									}
 									return nil
 								}
 							}
+							if !bg.Chan_new(vari.Name, newt, depth) {
+								return nil
+							}
 						}
 					} else {
 
 					}
 				}
 			}
+		case token.CONST:
+			if !bg.Const_declare(genDecl) {
+				return nil
+			}
 		}
 
 	case *ast.AssignStmt:
@@ -272,11 +289,19 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		}
 
 		assignStmt := n.(*ast.AssignStmt)
+		if len(assignStmt.Lhs) == 2 && len(assignStmt.Rhs) == 1 {
+			// The receive with the ok flag
+			if chanexpr, ok := select_receive(assignStmt.Rhs[0]); ok {
+				bg.Chan_receive_assign(assignStmt, chanexpr)
+				return nil
+			}
+		}
 		switch assignStmt.Tok {
 		case token.ASSIGN:
 			if len(assignStmt.Lhs) == len(assignStmt.Rhs) {
 				destinations := make([]VarCell, len(assignStmt.Lhs))
 				sources := make([]VarCell, len(assignStmt.Lhs))
+				made := make([]bool, len(assignStmt.Lhs))
 
 				for assindex, _ := range assignStmt.Lhs {
 					lhs := assignStmt.Lhs[assindex].(*ast.Ident)
@@ -298,7 +323,18 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 
 					rhs := assignStmt.Rhs[assindex]
 
-					if newcell, ok := bg.Expr_eval(rhs); ok {
+					if destinations[assindex].Procobjtype == CHANNEL {
+						// A channel variable keeps its shared object, make only sets the buffer depth
+						if _, depth, ok := bg.Chan_make(rhs); ok {
+							bg.Chan_depth(destinations[assindex], depth)
+							made[assindex] = true
+							continue
+						} else if bg.Is_faulty() {
+							return nil
+						}
+					}
+
+					if newcell, ok := bg.Expr_eval_typed(rhs, destinations[assindex].Vtype); ok {
 						sources[assindex] = newcell[0]
 					} else {
 						bg.Set_faulty("Assignment RHS evaluation failed")
@@ -307,7 +343,16 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 				}
 
 				for assindex, cell := range destinations {
+					if made[assindex] {
+						continue
+					}
 					newcell := sources[assindex]
+					if bg.Type_words(cell.Vtype) > 1 || bg.Type_words(newcell.Vtype) > 1 {
+						if !bg.Word_store(newcell, cell) || !bg.free_cells(newcell) {
+							return nil
+						}
+						continue
+					}
 					switch cell.Procobjtype {
 					case REGISTER:
 						regname := procbuilder.Get_register_name(cell.Id)
@@ -366,6 +411,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 			// TODO Finish
 			if len(assignStmt.Lhs) == len(assignStmt.Rhs) {
 				sources := make([]VarCell, len(assignStmt.Lhs))
+				made := make([]bool, len(assignStmt.Lhs))
 
 				for assindex, _ := range assignStmt.Lhs {
 					lhs := assignStmt.Lhs[assindex].(*ast.Ident)
@@ -384,6 +430,24 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 
 					rhs := assignStmt.Rhs[assindex]
 
+					if newt, depth, ok := bg.Chan_make(rhs); ok {
+						if !bg.Chan_new(vari, newt, depth) {
+							return nil
+						}
+						made[assindex] = true
+						continue
+					} else if bg.Is_faulty() {
+						return nil
+					}
+
+					if call, ok := Time_tick(rhs); ok {
+						if !bg.Tick_new(vari, call) {
+							return nil
+						}
+						made[assindex] = true
+						continue
+					}
+
 					if newcell, ok := bg.Expr_eval(rhs); ok {
 						sources[assindex] = newcell[0]
 					} else {
@@ -393,6 +457,9 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 				}
 
 				for assindex, cell := range sources {
+					if made[assindex] {
+						continue
+					}
 					lhs := assignStmt.Lhs[assindex].(*ast.Ident)
 					vari := lhs.Name
 
@@ -405,9 +472,10 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 							bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, MEMORY, 0, 0, 0, 0, 0, 0}}
 							resp := <-bg.Answers
 							if resp.AnsType == ANS_OK {
-								regname := procbuilder.Get_register_name(cell.Id)
-								bg.WriteLine(bg.CurrentRoutine, "r2m "+regname+" "+strconv.Itoa(resp.Cell.Id))
-								bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "r2m", I_NIL}
+								for k := 0; k < bg.Type_words(gent); k++ {
+									bg.emit("r2m", word_name(cell, k)+" "+strconv.Itoa(resp.Cell.Id+k))
+								}
+								bg.Vars[vari] = resp.Cell
 								bg.Reqs <- VarReq{REQ_REMOVE, bg.CurrentRoutine, cell}
 								if (<-bg.Answers).AnsType != ANS_OK {
 									bg.Set_faulty("Resource clean failed")
@@ -449,20 +517,37 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 			if _, ok := scope.Vars[vari]; ok {
 				varexist = true
 				cell := scope.Vars[vari]
+				if bg.Type_words(cell.Vtype) > 1 {
+					// Multi-word variables are updated in registers
+					switch cell.Procobjtype {
+					case REGISTER:
+						bg.Word_incdec(incDecStmt.Tok, cell)
+					case MEMORY:
+						if regcell, ok := bg.Word_load(cell); ok {
+							bg.Word_incdec(incDecStmt.Tok, regcell[0])
+							if !bg.Word_store(regcell[0], cell) || !bg.free_cells(regcell[0]) {
+								return nil
+							}
+						} else {
+							return nil
+						}
+					}
+					break
+				}
 				switch cell.Procobjtype {
 				case REGISTER:
 					regname := procbuilder.Get_register_name(cell.Id)
 					switch incDecStmt.Tok {
 					case token.INC:
-						scope.WriteLine(scope.CurrentRoutine, "inc "+regname)
-						scope.Used <- UsageNotify{TR_PROC, scope.CurrentRoutine, C_OPCODE, "inc", I_NIL}
+						bg.WriteLine(bg.CurrentRoutine, "inc "+regname)
+						bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "inc", I_NIL}
 					case token.DEC:
-						scope.WriteLine(scope.CurrentRoutine, "dec "+regname)
-						scope.Used <- UsageNotify{TR_PROC, scope.CurrentRoutine, C_OPCODE, "dec", I_NIL}
+						bg.WriteLine(bg.CurrentRoutine, "dec "+regname)
+						bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "dec", I_NIL}
 					}
 				case MEMORY:
 					gent, _ := Type_from_string(bg.Basic_type)
-					scope.Reqs <- VarReq{REQ_NEW, scope.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
+					bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
 					resp := <-bg.Answers
 					if resp.AnsType == ANS_OK {
 
@@ -470,22 +555,22 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 
 						regname := procbuilder.Get_register_name(newregcell.Id)
 
-						scope.WriteLine(scope.CurrentRoutine, "m2r "+regname+" "+strconv.Itoa(cell.Id))
-						scope.Used <- UsageNotify{TR_PROC, scope.CurrentRoutine, C_OPCODE, "m2r", I_NIL}
+						bg.WriteLine(bg.CurrentRoutine, "m2r "+regname+" "+strconv.Itoa(cell.Id))
+						bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "m2r", I_NIL}
 
 						switch incDecStmt.Tok {
 						case token.INC:
-							scope.WriteLine(scope.CurrentRoutine, "inc "+regname)
-							scope.Used <- UsageNotify{TR_PROC, scope.CurrentRoutine, C_OPCODE, "inc", I_NIL}
+							bg.WriteLine(bg.CurrentRoutine, "inc "+regname)
+							bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "inc", I_NIL}
 						case token.DEC:
-							scope.WriteLine(scope.CurrentRoutine, "dec "+regname)
-							scope.Used <- UsageNotify{TR_PROC, scope.CurrentRoutine, C_OPCODE, "dec", I_NIL}
+							bg.WriteLine(bg.CurrentRoutine, "dec "+regname)
+							bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "dec", I_NIL}
 						}
 
-						scope.WriteLine(scope.CurrentRoutine, "r2m "+regname+" "+strconv.Itoa(cell.Id))
-						scope.Used <- UsageNotify{TR_PROC, scope.CurrentRoutine, C_OPCODE, "r2m", I_NIL}
-						scope.Reqs <- VarReq{REQ_REMOVE, scope.CurrentRoutine, newregcell}
-						if (<-scope.Answers).AnsType != ANS_OK {
+						bg.WriteLine(bg.CurrentRoutine, "r2m "+regname+" "+strconv.Itoa(cell.Id))
+						bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "r2m", I_NIL}
+						bg.Reqs <- VarReq{REQ_REMOVE, bg.CurrentRoutine, newregcell}
+						if (<-bg.Answers).AnsType != ANS_OK {
 							bg.Set_faulty("Resource clean failed")
 							return nil
 						}
@@ -510,7 +595,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 
 			vars := make(map[string]VarCell)
 
-			bgleaf := &BondgoCheck{bg.BondgoResults, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, vars, bg.Returns, bg.CurrentLoop, bg.CurrentSwitch, bg.CurrentDevice, bg.CurrentRoutine}
+			bgleaf := &BondgoCheck{bg.BondgoResults, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, vars, nil, bg.Returns, bg.CurrentLoop, bg.CurrentSwitch, bg.CurrentDevice, bg.CurrentRoutine}
 			bg.Clean = bgleaf
 
 			if bg.In_debug() {
@@ -528,7 +613,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		results := new(BondgoResults) // Results go in here
 		results.Init_Results(bg.BondgoConfig)
 
-		bgif := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, bg.Vars, bg.Returns, bg.CurrentLoop, bg.CurrentSwitch, bg.CurrentDevice, bg.CurrentRoutine}
+		bgif := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, bg.Vars, bg.Consts, bg.Returns, bg.CurrentLoop, bg.CurrentSwitch, bg.CurrentDevice, bg.CurrentRoutine}
 
 		starting_point := bg.CountLines(bg.CurrentRoutine)
 
@@ -569,6 +654,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		}
 
 		if x.Else != nil {
+			bgif.Positions[bgif.CurrentRoutine] = x.Pos()
 			bgif.WriteLine(bgif.CurrentRoutine, "j <<"+fmt.Sprintf("%p", bgif)+"IFEND>>")
 			bgif.Used <- UsageNotify{TR_PROC, bgif.CurrentRoutine, C_OPCODE, "j", I_NIL}
 		}
@@ -588,9 +674,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		// Shift eventually created reference to line number within the code
 		bgif.Shift_program_location(bgif.CurrentRoutine, starting_point)
 
-		for _, line := range bgif.GetProgram(bgif.CurrentRoutine) {
-			bg.WriteLine(bg.CurrentRoutine, line)
-		}
+		bg.Append_program(bg.CurrentRoutine, bgif.BondgoResults, bgif.CurrentRoutine)
 
 		// The node has already visited.
 		return nil
@@ -604,7 +688,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		results := new(BondgoResults) // Results go in here
 		results.Init_Results(bg.BondgoConfig)
 
-		bgfor := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, bg.Vars, bg.Returns, "", bg.CurrentSwitch, bg.CurrentDevice, bg.CurrentRoutine}
+		bgfor := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, bg.Vars, bg.Consts, bg.Returns, "", "", bg.CurrentDevice, bg.CurrentRoutine}
 
 		if bg.In_debug() {
 			fmt.Printf("%p\n", bgfor)
@@ -659,6 +743,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 			ast.Walk(bgfor, x.Post)
 		}
 
+		bgfor.Positions[bgfor.CurrentRoutine] = x.Pos()
 		bgfor.WriteLine(bgfor.CurrentRoutine, "j <<"+bgfor.CurrentLoop+"STARTFOR>>")
 		bgfor.Used <- UsageNotify{TR_PROC, bgfor.CurrentRoutine, C_OPCODE, "j", I_NIL}
 
@@ -671,328 +756,30 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		// Shift eventually created reference to line number within the code
 		bgfor.Shift_program_location(bgfor.CurrentRoutine, starting_point)
 
-		for _, line := range bgfor.GetProgram(bgfor.CurrentRoutine) {
-			bg.WriteLine(bg.CurrentRoutine, line)
-		}
+		bg.Append_program(bg.CurrentRoutine, bgfor.BondgoResults, bgfor.CurrentRoutine)
 
 		// The node has already been visited.
 		return nil
 
-	case *ast.SelectStmt:
+	case *ast.RangeStmt:
 		if bg.In_debug() {
-			fmt.Printf("%p - Select statement", bg)
+			fmt.Printf("%p - Range statement", bg)
 		}
 
-		// Create a new BondgoCheck for the select statement with an empty program
-		results := new(BondgoResults) // Results go in here
-		results.Init_Results(bg.BondgoConfig)
-
-		bgsel := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, bg.Vars, bg.Returns, bg.CurrentLoop, "", bg.CurrentDevice, bg.CurrentRoutine}
-
-		starting_point := bg.CountLines(bg.CurrentRoutine)
-
-		bgsel.CurrentSwitch = fmt.Sprintf("SEL%p", bgsel)
-
-		selectbody := x.Body
-
-		if selectbody.List != nil {
-			// There is a default case or not
-			defaulted := false
-			var default_point int
-
-			cases := len(selectbody.List)
-			starting_points := make([]int, cases)
-
-			comm_registers := make([]VarCell, cases)
-			comm_direction := make([]bool, cases) // true = send
-			comm_channel := make([]VarCell, cases)
-
-			// The first loop set all the jumps toward cases
-			ii := 0
-			for _, clause := range selectbody.List {
-				switch cc := clause.(type) {
-				case *ast.CommClause:
-					if cc.Comm != nil {
-						switch commst := cc.Comm.(type) {
-						case *ast.SendStmt:
-							// This is a send operation
-							if newcell, ok := bgsel.Expr_eval(commst.Value); ok {
-								// TODO Optimization, this eval here may be a problem, everytime the expression is evaluted even if the channel is not used.
-								comm_registers[ii] = newcell[0]
-								comm_direction[ii] = true
-							} else {
-								bgsel.Set_faulty("Select condition evaluation failed")
-								return nil
-							}
-
-							chn := commst.Chan.(*ast.Ident)
-
-							for scope := bgsel; scope != nil; scope = scope.Outer {
-								if _, ok := scope.Vars[chn.Name]; ok {
-									comm_channel[ii] = scope.Vars[chn.Name]
-									break
-								}
-							}
-						case *ast.AssignStmt:
-							// This is a receive operation, no matter if it's a assign or define space will be reserved.
-							if len(commst.Lhs) == len(commst.Rhs) && len(commst.Lhs) == 1 {
-								switch commst.Rhs[0].(type) {
-								case *ast.UnaryExpr:
-									rhs := commst.Rhs[0].(*ast.UnaryExpr)
-									if rhs.Op == token.ARROW {
-										switch rhs.X.(type) {
-										case *ast.Ident:
-											chn := rhs.X.(*ast.Ident)
-											for scope := bgsel; scope != nil; scope = scope.Outer {
-												if _, ok := scope.Vars[chn.Name]; ok {
-													comm_channel[ii] = scope.Vars[chn.Name]
-													if gent, _ := Type_from_string(bg.Basic_type); Same_Type(comm_channel[ii].Vtype.Values[0], gent) {
-														bgsel.Reqs <- VarReq{REQ_NEW, bgsel.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
-														resp := <-bgsel.Answers
-														if resp.AnsType == ANS_OK {
-															comm_registers[ii] = resp.Cell
-														} else {
-															bgsel.Set_faulty("Resource reservation failed")
-															return nil
-														}
-													} else {
-														bgsel.Set_faulty("Only allowed basic type")
-														return nil
-													}
-													break
-												}
-											}
-										default:
-											bgsel.Set_faulty("Operation not valid in select assigment")
-											return nil
-										}
-									} else {
-										bgsel.Set_faulty("Operation not valid in select assigment")
-										return nil
-									}
-								default:
-									bgsel.Set_faulty("Operation not valid in select assigment")
-									return nil
-								}
-							} else {
-								bgsel.Set_faulty("Multivalue not allowed in receive operations")
-								return nil
-							}
-						}
-						ii++
-					} else {
-						// This is the default case
-						if defaulted {
-							bg.Set_faulty("Duplicate default case")
-							return nil
-						}
-						defaulted = true
-					}
-
-				default:
-					bgsel.Set_faulty("Wrong case")
-					return nil
-				}
-			}
-
-			if defaulted {
-				comm_direction = comm_direction[:cases-1]
-				comm_channel = comm_channel[:cases-1]
-				comm_registers = comm_registers[:cases-1]
-				starting_points = starting_points[:cases-1]
-			}
-
-			for i, dir := range comm_direction {
-				if dir {
-					bgsel.WriteLine(bgsel.CurrentRoutine, "wwr "+procbuilder.Get_register_name(comm_registers[i].Id)+" "+procbuilder.Get_channel_name(comm_channel[i].Id))
-					bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "wwr", I_NIL}
-				} else {
-					bgsel.WriteLine(bgsel.CurrentRoutine, "wrd "+procbuilder.Get_register_name(comm_registers[i].Id)+" "+procbuilder.Get_channel_name(comm_channel[i].Id))
-					bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "wrd", I_NIL}
-				}
-			}
-
-			var eventreg VarCell
-
-			gent, _ := Type_from_string(bg.Basic_type)
-			bgsel.Reqs <- VarReq{REQ_NEW, bgsel.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
-			resp := <-bgsel.Answers
-			if resp.AnsType == ANS_OK {
-				eventreg = resp.Cell
-			} else {
-				bgsel.Set_faulty("Resource reservation failed")
-				return nil
-			}
-
-			if defaulted { // Jump to the end of the switch
-
-				var occurreg VarCell
-
-				bgsel.Reqs <- VarReq{REQ_NEW, bgsel.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
-				resp := <-bgsel.Answers
-				if resp.AnsType == ANS_OK {
-					occurreg = resp.Cell
-				} else {
-					bgsel.Set_faulty("Resource reservation failed")
-					return nil
-				}
-
-				bgsel.WriteLine(bgsel.CurrentRoutine, "chc "+procbuilder.Get_register_name(occurreg.Id)+" "+procbuilder.Get_register_name(eventreg.Id))
-				bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "chc", I_NIL}
-
-				bgsel.WriteLine(bgsel.CurrentRoutine, "jz "+procbuilder.Get_register_name(occurreg.Id)+" <<"+bgsel.CurrentSwitch+"CASEDEFAULT>>")
-				bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "jz", I_NIL}
-
-				bgsel.Reqs <- VarReq{REQ_REMOVE, bgsel.CurrentRoutine, occurreg}
-				if (<-bgsel.Answers).AnsType != ANS_OK {
-					bgsel.Set_faulty("Resource clean failed")
-					return nil
-				}
-			} else {
-				bgsel.WriteLine(bgsel.CurrentRoutine, "chw "+procbuilder.Get_register_name(eventreg.Id))
-				bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "chw", I_NIL}
-			}
-
-			for i, _ := range comm_registers {
-				bgsel.WriteLine(bgsel.CurrentRoutine, "jz "+procbuilder.Get_register_name(eventreg.Id)+" <<"+bgsel.CurrentSwitch+"CASE"+strconv.Itoa(i)+">>")
-				bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "jz", I_NIL}
-				if i < len(comm_registers)-1 {
-					bgsel.WriteLine(bgsel.CurrentRoutine, "dec "+procbuilder.Get_register_name(eventreg.Id))
-					bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "dec", I_NIL}
-				}
-			}
-
-			bgsel.Reqs <- VarReq{REQ_REMOVE, bgsel.CurrentRoutine, eventreg}
-			if (<-bgsel.Answers).AnsType != ANS_OK {
-				bgsel.Set_faulty("Resource clean failed")
-				return nil
-			}
-
-			// The second loop create the cases
-			ii = 0
-			for _, clause := range selectbody.List {
-				switch cc := clause.(type) {
-				case *ast.CommClause:
-
-					if cc.Comm != nil {
-						// This is a case
-						starting_points[ii] = bgsel.CountLines(bgsel.CurrentRoutine)
-
-						// It's a receive
-						if !comm_direction[ii] {
-							vari := cc.Comm.(*ast.AssignStmt).Lhs[0].(*ast.Ident).Name
-							switch cc.Comm.(*ast.AssignStmt).Tok {
-							case token.ASSIGN:
-
-								varexist := false
-								for scope := bgsel; scope != nil; scope = scope.Outer {
-									if _, ok := scope.Vars[vari]; ok {
-										varexist = true
-
-										cell := scope.Vars[vari]
-										newcell := comm_registers[ii]
-
-										switch cell.Procobjtype {
-										case REGISTER:
-											regname := procbuilder.Get_register_name(cell.Id)
-											newregname := procbuilder.Get_register_name(newcell.Id)
-											bgsel.WriteLine(bgsel.CurrentRoutine, "cpy "+regname+" "+newregname)
-											bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "cpy", I_NIL}
-										case MEMORY:
-											newregname := procbuilder.Get_register_name(newcell.Id)
-											bgsel.WriteLine(bgsel.CurrentRoutine, "r2m "+newregname+" "+strconv.Itoa(cell.Id))
-											bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "r2m", I_NIL}
-										case INPUT:
-											bgsel.Set_faulty("An input cannot be written")
-											return nil
-										case OUTPUT:
-											newregname := procbuilder.Get_register_name(newcell.Id)
-											bgsel.WriteLine(bgsel.CurrentRoutine, "r2o "+newregname+" "+strconv.Itoa(cell.Id))
-											bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "r2o", I_NIL}
-										case CHANNEL:
-											bgsel.Set_faulty("A channel cannot be written")
-											return nil
-										}
-										break
-									}
-								}
-
-								if !varexist {
-									bgsel.Set_faulty("Unitialized variable " + vari)
-									return nil
-								}
-
-							case token.DEFINE:
-
-								gent, _ := Type_from_string(bgsel.Basic_type)
-								bgsel.Reqs <- VarReq{REQ_NEW, bgsel.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
-								resp := <-bgsel.Answers
-								if resp.AnsType == ANS_OK {
-
-									newcell := resp.Cell
-									cell := comm_registers[ii]
-
-									regname := procbuilder.Get_register_name(cell.Id)
-									newregname := procbuilder.Get_register_name(newcell.Id)
-									bgsel.WriteLine(bgsel.CurrentRoutine, "cpy "+newregname+" "+regname)
-									bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "cpy", I_NIL}
-
-								} else {
-									bgsel.Set_faulty("Resource reservation failed")
-									return nil
-								}
-							default:
-								bgsel.Set_faulty("Wrong assignment")
-								return nil
-							}
-						}
-
-					} else {
-						// This is the default case
-						default_point = bgsel.CountLines(bgsel.CurrentRoutine)
-					}
-
-					body := clause.(*ast.CommClause).Body
-
-					for _, cl := range body {
-						ast.Walk(bgsel, cl)
-					}
-
-				default:
-					bgsel.Set_faulty("Wrong case")
-					return nil
-				}
-
-				bgsel.WriteLine(bg.CurrentRoutine, "j <<"+bgsel.CurrentSwitch+"SELEND>>")
-				bgsel.Used <- UsageNotify{TR_PROC, bgsel.CurrentRoutine, C_OPCODE, "j", I_NIL}
-
-				ii++
-			}
-
-			for _, jreg := range comm_registers {
-				bgsel.Reqs <- VarReq{REQ_REMOVE, bgsel.CurrentRoutine, jreg}
-				if (<-bgsel.Answers).AnsType != ANS_OK {
-					bgsel.Set_faulty("Resource clean failed")
-					return nil
-				}
-			}
-
-			select_end := bgsel.CountLines(bgsel.CurrentRoutine)
-
-			for i, _ := range starting_points {
-				bgsel.Replacer(bgsel.CurrentRoutine, "<<"+bgsel.CurrentSwitch+"CASE"+strconv.Itoa(i)+">>", "<<"+strconv.Itoa(starting_points[i])+">>")
-			}
-			bgsel.Replacer(bgsel.CurrentRoutine, "<<"+bgsel.CurrentSwitch+"CASEDEFAULT>>", "<<"+strconv.Itoa(default_point)+">>")
-			bgsel.Replacer(bgsel.CurrentRoutine, "<<"+bgsel.CurrentSwitch+"SELEND>>", "<<"+strconv.Itoa(select_end)+">>")
-
-			// Shift eventually created reference to line number within the code
-			bgsel.Shift_program_location(bgsel.CurrentRoutine, starting_point)
-
-			for _, line := range bgsel.GetProgram(bgsel.CurrentRoutine) {
-				bg.WriteLine(bg.CurrentRoutine, line)
-			}
+		// Only the range over a channel or a ticker is supported
+		if bg.Timer_lookup(x.X) {
+			bg.Timer_range(x)
+		} else if channel, ok := bg.chan_operand(x.X); ok {
+			bg.Chan_range(x, channel)
+		}
+		return nil
 
+	case *ast.SelectStmt:
+		if bg.In_debug() {
+			fmt.Printf("%p - Select statement", bg)
 		}
+
+		bg.Select_stmt(x)
 		return nil
 
 	case *ast.SwitchStmt:
@@ -1006,7 +793,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		results := new(BondgoResults) // Results go in here
 		results.Init_Results(bg.BondgoConfig)
 
-		bgsw := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, bg.Vars, bg.Returns, bg.CurrentLoop, "", bg.CurrentDevice, bg.CurrentRoutine}
+		bgsw := &BondgoCheck{results, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, bg.Vars, bg.Consts, bg.Returns, bg.CurrentLoop, "", bg.CurrentDevice, bg.CurrentRoutine}
 
 		starting_point := bg.CountLines(bg.CurrentRoutine)
 
@@ -1165,9 +952,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 			// Shift eventually created reference to line number within the code
 			bgsw.Shift_program_location(bgsw.CurrentRoutine, starting_point)
 
-			for _, line := range bgsw.GetProgram(bgsw.CurrentRoutine) {
-				bg.WriteLine(bg.CurrentRoutine, line)
-			}
+			bg.Append_program(bg.CurrentRoutine, bgsw.BondgoResults, bgsw.CurrentRoutine)
 
 		}
 		return nil
@@ -1183,7 +968,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 			obj := x.Label.Obj
 			label := obj.Name
 
-			bglabel := &BondgoCheck{bg.BondgoResults, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, vars, bg.Returns, bg.CurrentLoop, bg.CurrentSwitch, label, bg.CurrentRoutine}
+			bglabel := &BondgoCheck{bg.BondgoResults, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, bg, nil, vars, nil, bg.Returns, bg.CurrentLoop, bg.CurrentSwitch, label, bg.CurrentRoutine}
 
 			if bg.In_debug() {
 				fmt.Printf("%p\n", bglabel)
@@ -1258,7 +1043,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 			bg.Program[next_currentroutine] = proccode
 
 			// Prepare the new bondgocheck for the other processor
-			bggoroutine := &BondgoCheck{bg.BondgoResults, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, nil, nil, newvars, newreturns, "", "", bg.CurrentDevice, next_currentroutine}
+			bggoroutine := &BondgoCheck{bg.BondgoResults, bg.BondgoConfig, bg.BondgoRequirements, bg.BondgoRuninfo, bg.BondgoMessages, bg.BondgoFunctions, bg.Used, bg.Reqs, bg.Answers, nil, nil, newvars, nil, newreturns, "", "", bg.CurrentDevice, next_currentroutine}
 
 			// Establish the Device parameter for the next goroutine
 			bggoroutine.Used <- UsageNotify{TR_PROC, next_currentroutine, C_DEVICE, bg.CurrentDevice, I_NIL}
@@ -1267,7 +1052,16 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 
 			needchan := false
 			for varname, cell := range vars {
-				if gent, _ := Type_from_string(bg.Basic_type); Same_Type(cell.Vtype, gent) {
+				if cell.Procobjtype == MUTEX || cell.Procobjtype == COUNTER {
+					bggoroutine.Reqs <- VarReq{REQ_ATTACH, bggoroutine.CurrentRoutine, cell}
+					resp := <-bg.Answers
+					if resp.AnsType == ANS_OK {
+						newvars[varname] = resp.Cell
+					} else {
+						bggoroutine.Set_faulty("Sync object attach failed")
+						return nil
+					}
+				} else if gent, _ := Type_from_string(bg.Basic_type); Same_Type(cell.Vtype, gent) {
 					needchan = true
 					bggoroutine.Reqs <- VarReq{REQ_NEW, bggoroutine.CurrentRoutine, cell}
 					resp := <-bg.Answers
@@ -1349,7 +1143,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 						bg.Set_faulty("Allocation failed")
 						return nil
 					}
-				} else if gent, _ := Type_from_string(bg.Basic_chantype); Same_Type(cell.Vtype, gent) {
+				} else if gent, _ := Type_from_string(bg.Basic_chantype); Same_Type(cell.Vtype, gent) || bg.Chan_words(cell.Vtype) > 1 {
 					bggoroutine.Reqs <- VarReq{REQ_ATTACH, bggoroutine.CurrentRoutine, cell}
 					resp := <-bg.Answers
 					if resp.AnsType == ANS_OK {
@@ -1396,7 +1190,9 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 						// Send the passed by value data to the channel
 						channame := procbuilder.Get_channel_name(cell.Id)
 
-						for _, cell := range vars {
+						// The values go in the order of the function inputs
+						for _, arg := range functcell.Inputs {
+							cell := vars[arg.Argname]
 							gent1, _ := Type_from_string(bg.Basic_type)
 							gent2, _ := Type_from_string("bool")
 							if Same_Type(cell.Vtype, gent1) || Same_Type(cell.Vtype, gent2) {
@@ -1406,7 +1202,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 									regname := procbuilder.Get_register_name(cell.Id)
 									bg.WriteLine(bg.CurrentRoutine, "wwr "+regname+" "+channame)
 									bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "wwr", I_NIL}
-									bg.WriteLine(bg.CurrentRoutine, "chw")
+									bg.WriteLine(bg.CurrentRoutine, "chw "+regname)
 									bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "chw", I_NIL}
 								case MEMORY:
 									bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
@@ -1422,7 +1218,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 										bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "m2r", I_NIL}
 										bg.WriteLine(bg.CurrentRoutine, "wwr "+regname+" "+channame)
 										bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "wwr", I_NIL}
-										bg.WriteLine(bg.CurrentRoutine, "chw")
+										bg.WriteLine(bg.CurrentRoutine, "chw "+regname)
 										bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "chw", I_NIL}
 
 										bg.Reqs <- VarReq{REQ_REMOVE, bg.CurrentRoutine, newregcell}
@@ -1441,7 +1237,8 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 						// Get the data passed by value from the channel on the other side
 						ochanname := procbuilder.Get_channel_name(ocell.Id)
 
-						for _, cell := range newvars {
+						for _, arg := range functcell.Inputs {
+							cell := newvars[arg.Argname]
 							gent1, _ := Type_from_string(bg.Basic_type)
 							gent2, _ := Type_from_string("bool")
 							if Same_Type(cell.Vtype, gent1) || Same_Type(cell.Vtype, gent2) {
@@ -1449,10 +1246,17 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 								switch cell.Procobjtype {
 								case REGISTER:
 									regname := procbuilder.Get_register_name(cell.Id)
+									waitcell, ok := bggoroutine.new_cell(gent1, REGISTER)
+									if !ok {
+										return nil
+									}
 									bggoroutine.WriteLine(bggoroutine.CurrentRoutine, "wrd "+regname+" "+ochanname)
 									bggoroutine.Used <- UsageNotify{TR_PROC, bggoroutine.CurrentRoutine, C_OPCODE, "wrd", I_NIL}
-									bggoroutine.WriteLine(bggoroutine.CurrentRoutine, "chw")
+									bggoroutine.WriteLine(bggoroutine.CurrentRoutine, "chw "+procbuilder.Get_register_name(waitcell.Id))
 									bggoroutine.Used <- UsageNotify{TR_PROC, bggoroutine.CurrentRoutine, C_OPCODE, "chw", I_NIL}
+									if !bggoroutine.free_cells(waitcell) {
+										return nil
+									}
 								case MEMORY:
 									bggoroutine.Reqs <- VarReq{REQ_NEW, bggoroutine.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
 									newresp := <-bggoroutine.Answers
@@ -1465,7 +1269,7 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 										bggoroutine.Used <- UsageNotify{TR_PROC, bggoroutine.CurrentRoutine, C_OPCODE, "clr", I_NIL}
 										bggoroutine.WriteLine(bg.CurrentRoutine, "wrd "+regname+" "+ochanname)
 										bggoroutine.Used <- UsageNotify{TR_PROC, bggoroutine.CurrentRoutine, C_OPCODE, "wrd", I_NIL}
-										bggoroutine.WriteLine(bg.CurrentRoutine, "chw")
+										bggoroutine.WriteLine(bg.CurrentRoutine, "chw "+regname)
 										bggoroutine.Used <- UsageNotify{TR_PROC, bggoroutine.CurrentRoutine, C_OPCODE, "chw", I_NIL}
 										bggoroutine.WriteLine(bg.CurrentRoutine, "r2m "+regname+" "+strconv.Itoa(cell.Id))
 										bggoroutine.Used <- UsageNotify{TR_PROC, bggoroutine.CurrentRoutine, C_OPCODE, "m2r", I_NIL}
@@ -1510,7 +1314,11 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		}
 		switch x.Tok {
 		case token.BREAK:
-			if bg.CurrentLoop != "" {
+			if strings.HasPrefix(bg.CurrentSwitch, "SEL") {
+				// A break within a select ends the select
+				bg.WriteLine(bg.CurrentRoutine, "j <<"+bg.CurrentSwitch+"SELEND>>")
+				bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "j", I_NIL}
+			} else if bg.CurrentLoop != "" {
 				bg.WriteLine(bg.CurrentRoutine, "j <<"+bg.CurrentLoop+"ENDFOR>>")
 				bg.Used <- UsageNotify{TR_PROC, bg.CurrentRoutine, C_OPCODE, "j", I_NIL}
 			} else {
@@ -1576,7 +1384,12 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 
 		value := x.Value
 
-		if newcell, ok := bg.Expr_eval(value); ok {
+		if bg.Chan_words(destchan.Vtype) > 1 {
+			if newcell, ok := bg.Expr_eval_typed(value, destchan.Vtype.Values[0]); !ok || !bg.Word_send(destchan, newcell[0]) {
+				bg.Set_faulty("Send evaluation failed")
+				return nil
+			}
+		} else if newcell, ok := bg.Expr_eval(value); ok {
 			// TODO Missing types checks
 			gent, _ := Type_from_string(bg.Basic_type)
 			bg.Reqs <- VarReq{REQ_NEW, bg.CurrentRoutine, VarCell{gent, REGISTER, 0, 0, 0, 0, 0, 0}}
@@ -1614,6 +1427,13 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 			return nil
 		}
 
+	case *ast.UnaryExpr:
+		// A receive statement from a ticker waits the next tick
+		if x.Op == token.ARROW && bg.Timer_lookup(x.X) {
+			bg.emit("tmw", "")
+			return nil
+		}
+
 	case *ast.CallExpr:
 
 		if bg.In_debug() {
@@ -1654,7 +1474,11 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 											if bg.In_debug() {
 												fmt.Printf("%p - IO Write call\n", bg)
 											}
-											if ncell, ok := bg.Expr_eval(args[1]); ok {
+											if ncell, ok := bg.Expr_eval(args[1]); ok && bg.Type_words(ncell[0].Vtype) > 1 {
+												if !bg.Word_iowrite(cell, ncell[0]) {
+													return nil
+												}
+											} else if ok {
 												newcell := ncell[0]
 												newregname := procbuilder.Get_register_name(newcell.Id)
 												newoutname := procbuilder.Get_output_name(cell.Id)
@@ -1692,6 +1516,18 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 					bg.Set_faulty("Unknown function " + sel.Name)
 					return nil
 				}
+			} else if xf.Name == "time" {
+				if !bg.Time_call(x, sel.Name) {
+					return nil
+				}
+			} else if xf.Name == "atomic" {
+				if results, ok := bg.Atomic_call(x, sel.Name); !ok || !bg.free_cells(results...) {
+					return nil
+				}
+			} else if cell, ok := bg.Sync_lookup(xf.Name); ok {
+				if results, ok := bg.Sync_call(cell, sel.Name, x.Args); !ok || !bg.free_cells(results...) {
+					return nil
+				}
 			} else {
 				bg.Set_faulty("Unknown module " + xf.Name)
 				return nil
@@ -1700,6 +1536,9 @@ func (bg *BondgoCheck) Visit(n ast.Node) ast.Visitor {
 		case (*ast.Ident):
 			// This id the case of a function with no receiver
 			// TODO Finish
+			if fun.Name == "close" {
+				bg.Chan_close(x)
+			}
 		}
 		return nil
 	}
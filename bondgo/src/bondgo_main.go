@@ -4,6 +4,7 @@ import (
 	"bondgo"
 	"bufio"
 	"encoding/json"
+	"errors"
 	"etherbond"
 	"flag"
 	"fmt"
@@ -13,7 +14,9 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 	"udpbond"
 )
 
@@ -27,9 +30,13 @@ var cascading_io = flag.Bool("cascading-io", false, "Connect the processors in c
 
 var o = flag.Int("O", 0, "Optimization level")
 
+var max_registers = flag.Int("max-registers", 0, "Maximum number of registers per processor, variables that do not fit are spilled to RAM (0 means no limit)")
+
+var clock_mhz = flag.Int("clock-mhz", 100, "Clock frequency in MHz, time.Sleep and time.Tick durations are converted to cycles with it")
+
 var show_requirements = flag.Bool("show-requirements", false, "Show bondmachine requirements")
 
-var input_file = flag.String("input-file", "", "Go input file")
+var input_file = flag.String("input-file", "", "Go input file, or a directory with a Go package")
 
 var multi_abstract_assembly_input = flag.Bool("multi-abstract-assembly-input", false, "Input from a multi abstract assembly JSON file")
 var abstract_assembly_input = flag.Bool("abstract-assembly-input", false, "Input from abstract assembly")
@@ -54,6 +61,10 @@ var save_bondmachine = flag.String("save-bondmachine", "", "Create a bondmachine
 
 // For standard, checking, enforcing
 var save_assembly = flag.String("save-assembly", "", "Machine or bondmachine (numbered per domain) assembly output file")
+var assembly_debug = flag.Bool("assembly-debug", false, "Precede the saved assembly instructions with their source as #@ directives")
+
+// Source level debug info keyed by processor and ROM address
+var save_debug_info = flag.String("save-debug-info", "", "Create a debug info JSON file mapping the ROM addresses to the source lines and variables")
 
 var use_etherbond = flag.Bool("use-etherbond", false, "Build including etherbond support")
 var etherbond_external = flag.String("etherbond-external", "", "Etherbond external peers description file")
@@ -63,6 +74,8 @@ var use_udpbond = flag.Bool("use-udpbond", false, "Build including udpbond suppo
 var udpbond_external = flag.String("udpbond-external", "", "Udobond external peers description file")
 var save_udpbond_cluster = flag.String("save-udpbond-cluster", "ebcluster", "Create several BM files and the cluster file with the given prefix")
 
+var device_catalogue = flag.String("device-catalogue", "", "Device catalogue JSON file, the processors are automatically partitioned among its devices (etherbond/udpbond)")
+
 var save_redeployer_file = flag.String("save-redeployer-file", "", "Create a redeployer file out of the cluster")
 
 func check(e error) {
@@ -90,6 +103,50 @@ func init() {
 	}
 }
 
+// The Go input is a single file or all the files of the package within a directory
+func parse_go_input(fset *token.FileSet, input string) ([]*ast.File, error) {
+	if info, err := os.Stat(input); err != nil || !info.IsDir() {
+		f, err := parser.ParseFile(fset, input, nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []*ast.File{f}, nil
+	}
+
+	nottest := func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}
+
+	pkgs, err := parser.ParseDir(fset, input, nottest, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) != 1 {
+		return nil, errors.New("Exactly one package expected in " + input)
+	}
+
+	files := make([]*ast.File, 0)
+	for _, pkg := range pkgs {
+		names := make([]string, 0, len(pkg.Files))
+		for name, _ := range pkg.Files {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			files = append(files, pkg.Files[name])
+		}
+	}
+	return files, nil
+}
+
+func write_assembly(bgmain *bondgo.BondgoCheck, proc_id int, fset *token.FileSet) string {
+	if *assembly_debug {
+		return bgmain.Write_assembly_debug(proc_id, fset)
+	}
+	return bgmain.Write_assembly(proc_id)
+}
+
 func main() {
 
 	fset := token.NewFileSet()
@@ -104,6 +161,9 @@ func main() {
 		config.Mpm = false
 	}
 
+	config.MaxRegs = *max_registers
+	config.Clock_mhz = *clock_mhz
+
 	if *cascading_io {
 		config.Cascading_io = true
 	} else {
@@ -136,7 +196,7 @@ func main() {
 
 	if *input_file != "" {
 		if *go_input {
-			f, err := parser.ParseFile(fset, *input_file, nil, 0)
+			files, err := parse_go_input(fset, *input_file)
 			if err != nil {
 				fmt.Println(err)
 				return
@@ -172,16 +232,18 @@ func main() {
 			vars := make(map[string]bondgo.VarCell)
 			returns := make([]bondgo.VarCell, 0)
 
-			bgmain := &bondgo.BondgoCheck{results, config, reqmnts, run, messages, functs, usagenotify, varreq, varans, nil, nil, vars, returns, "", "", "device_0", 0}
+			bgmain := &bondgo.BondgoCheck{results, config, reqmnts, run, messages, functs, usagenotify, varreq, varans, nil, nil, vars, nil, returns, "", "", "device_0", 0}
 
 			bgmain.Used <- bondgo.UsageNotify{bondgo.TR_PROC, 0, bondgo.C_DEVICE, bgmain.CurrentDevice, bondgo.I_NIL}
 
-			if config.Debug {
-				ast.Print(fset, f)
+			// Load all the functions and the package constants
+			for _, f := range files {
+				if config.Debug {
+					ast.Print(fset, f)
+				}
+				ast.Walk(functs, f)
 			}
-
-			// Load all the functions
-			ast.Walk(functs, f)
+			functs.Fold_constants()
 
 			if !bgmain.Is_faulty() {
 
@@ -212,6 +274,10 @@ func main() {
 					}
 				}
 
+				for procid, _ := range bgmain.Program {
+					bgmain.Register_allocation(procid)
+				}
+
 				for procid, rout := range bgmain.Program {
 					// TODO Recheck
 					linesn := len(rout.Lines)
@@ -224,6 +290,14 @@ func main() {
 				gent, _ := bondgo.Type_from_string(bgmain.Basic_type)
 				bgmain.Reqs <- bondgo.VarReq{bondgo.REQ_EXIT, 0, bondgo.VarCell{gent, 0, 0, 0, 0, 0, 0, 0}}
 				<-assignerdone
+
+				if *device_catalogue != "" && (*use_etherbond || *use_udpbond) {
+					if cat, err := bondgo.Load_catalogue(*device_catalogue); err != nil {
+						bgmain.Set_faulty(err.Error())
+					} else if err := bgmain.Partition_devices(cat); err != nil {
+						bgmain.Set_faulty(err.Error())
+					}
+				}
 			}
 
 			fmt.Print(bgmain.Dump_log())
@@ -234,6 +308,12 @@ func main() {
 					fmt.Print(bgmain.Dump_Requirements())
 				}
 
+				if *save_debug_info != "" {
+					b, errj := json.Marshal(bgmain.Debug_info(fset))
+					check(errj)
+					check(ioutil.WriteFile(*save_debug_info, b, 0644))
+				}
+
 				switch *compiler_mode {
 				case "standard":
 
@@ -254,7 +334,7 @@ func main() {
 									f, err := os.Create(*save_assembly + "_" + strconv.Itoa(i))
 									check(err)
 									defer f.Close()
-									f.WriteString(bgmain.Write_assembly(i))
+									f.WriteString(write_assembly(bgmain, i, fset))
 								}
 							}
 						} else {
@@ -262,7 +342,7 @@ func main() {
 								f, err := os.Create(*save_assembly)
 								check(err)
 								defer f.Close()
-								f.WriteString(bgmain.Write_assembly(0))
+								f.WriteString(write_assembly(bgmain, 0, fset))
 							}
 						}
 					}
@@ -488,7 +568,7 @@ func main() {
 			vars := make(map[string]bondgo.VarCell)
 			returns := make([]bondgo.VarCell, 0)
 
-			bgmain := &bondgo.BondgoCheck{results, config, reqmnts, run, messages, functs, usagenotify, varreq, varans, nil, nil, vars, returns, "", "", "device_0", 0}
+			bgmain := &bondgo.BondgoCheck{results, config, reqmnts, run, messages, functs, usagenotify, varreq, varans, nil, nil, vars, nil, returns, "", "", "device_0", 0}
 
 			// Establish the Device parameter for the next goroutine
 			bgmain.Used <- bondgo.UsageNotify{bondgo.TR_PROC, 0, bondgo.C_DEVICE, bgmain.CurrentDevice, bondgo.I_NIL}
@@ -572,7 +652,12 @@ func main() {
 			}
 
 			if *save_bondmachine != "" {
-				if mymachine, err := bondgo.MultiAsm2BondMachine(*register_size, aafile); err == nil {
+				if mymachine, debuginfo, err := bondgo.MultiAsm2BondMachine(*register_size, aafile); err == nil {
+					if *save_debug_info != "" {
+						b, errj := json.Marshal(debuginfo)
+						check(errj)
+						check(ioutil.WriteFile(*save_debug_info, b, 0644))
+					}
 					if _, err := os.Stat(*save_bondmachine); os.IsNotExist(err) {
 						f, err := os.Create(*save_bondmachine)
 						check(err)
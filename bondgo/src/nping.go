@@ -785,7 +785,7 @@ func main() {
 	ball254 = 1
 	ball255 = 1
 
-	var sel uint8
+	var sel uint16
 
 	sel = 0
 
@@ -0,0 +1,321 @@
+package bondmachine
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"procbuilder"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// A batch is a script of bondmachine editing operations, one per line, using the same names of the command line
+// options (without the leading dash), for example:
+//
+//	# comment
+//	add-domains proc.json
+//	add-processor 0
+//	add-inputs 2
+//	add-bond i0,p0i0
+//	add-shared-objects channel:
+//	connect-processor-shared-object 0,0
+//
+// The operations are applied in order on a copy of the bondmachine, the original one is left untouched if any of them fails.
+
+type Batch_op struct {
+	Line   int
+	Action string
+	Args   []string
+}
+
+func (op Batch_op) String() string {
+	return op.Action + " " + strings.Join(op.Args, ",")
+}
+
+func Parse_batch(script []byte) ([]Batch_op, error) {
+	result := make([]Batch_op, 0)
+	for i, line := range strings.Split(string(script), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		words := strings.Fields(line)
+		newop := Batch_op{Line: i + 1, Action: words[0], Args: make([]string, 0)}
+		for _, word := range words[1:] {
+			for _, arg := range strings.Split(word, ",") {
+				if arg != "" {
+					newop.Args = append(newop.Args, arg)
+				}
+			}
+		}
+		result = append(result, newop)
+	}
+	return result, nil
+}
+
+// Copy returns a deep copy of the bondmachine passing through its JSON representation
+func (bmach *Bondmachine) Copy() (*Bondmachine, error) {
+	b, err := json.Marshal(bmach.Jsoner())
+	if err != nil {
+		return nil, err
+	}
+	var bmachj Bondmachine_json
+	if err := json.Unmarshal(b, &bmachj); err != nil {
+		return nil, err
+	}
+	result := (&bmachj).Dejsoner()
+	result.Init()
+	return result, nil
+}
+
+// Apply_batch applies the operations on a copy of the bondmachine and returns it, the first failing operation stops the batch
+func (bmach *Bondmachine) Apply_batch(ops []Batch_op) (*Bondmachine, error) {
+	result, err := bmach.Copy()
+	if err != nil {
+		return nil, err
+	}
+	for _, op := range ops {
+		if err := result.apply_batch_op(op); err != nil {
+			return nil, Prerror{"Batch line " + strconv.Itoa(op.Line) + " (" + op.String() + "): " + err.Error()}
+		}
+	}
+	return result, nil
+}
+
+func batch_ints(args []string) ([]int, error) {
+	result := make([]int, len(args))
+	for i, arg := range args {
+		if value, err := strconv.Atoi(arg); err == nil {
+			result[i] = value
+		} else {
+			return nil, Prerror{arg + " is not a valid id"}
+		}
+	}
+	return result, nil
+}
+
+// batch_ids_descending sorts the ids higher first and drops the repeated ones, the removals shift the higher ids down
+func batch_ids_descending(ids []int) []int {
+	sort.Sort(sort.Reverse(sort.IntSlice(ids)))
+	result := make([]int, 0, len(ids))
+	for i, id := range ids {
+		if i == 0 || ids[i-1] != id {
+			result = append(result, id)
+		}
+	}
+	return result
+}
+
+func (bmach *Bondmachine) apply_batch_op(op Batch_op) error {
+	switch op.Action {
+	case "add-domains":
+		if len(op.Args) == 0 {
+			return Prerror{"No domain file given"}
+		}
+		for _, load_machine := range op.Args {
+			jsonfile, err := ioutil.ReadFile(load_machine)
+			if err != nil {
+				return err
+			}
+			var machj procbuilder.Machine_json
+			if err := json.Unmarshal(jsonfile, &machj); err != nil {
+				return err
+			}
+			bmach.Domains = append(bmach.Domains, (&machj).Dejsoner())
+		}
+	case "del-domains":
+		ids, err := batch_ints(op.Args)
+		if err != nil {
+			return err
+		}
+		for _, id := range batch_ids_descending(ids) {
+			if id >= len(bmach.Domains) || id < 0 {
+				return Prerror{strconv.Itoa(id) + " not a valid domain id"}
+			}
+			for _, dom_id := range bmach.Processors {
+				if dom_id == id {
+					return Prerror{"Domain " + strconv.Itoa(id) + " is used by a processor"}
+				}
+			}
+			bmach.Domains = append(bmach.Domains[:id], bmach.Domains[id+1:]...)
+			for i, dom_id := range bmach.Processors {
+				if dom_id > id {
+					bmach.Processors[i] = dom_id - 1
+				}
+			}
+		}
+	case "add-processor":
+		ids, err := batch_ints(op.Args)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if _, err := bmach.Add_processor(id); err != nil {
+				return err
+			}
+		}
+	case "add-inputs", "add-outputs":
+		num, err := batch_ints(op.Args)
+		if err != nil {
+			return err
+		}
+		if len(num) != 1 {
+			return Prerror{"Wrong arguments number"}
+		}
+		for i := 0; i < num[0]; i++ {
+			if op.Action == "add-inputs" {
+				_, err = bmach.Add_input()
+			} else {
+				_, err = bmach.Add_output()
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case "del-inputs", "del-outputs":
+		ids, err := batch_ints(op.Args)
+		if err != nil {
+			return err
+		}
+		for _, id := range batch_ids_descending(ids) {
+			if op.Action == "del-inputs" {
+				err = bmach.Del_input(id)
+			} else {
+				err = bmach.Del_output(id)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case "add-bond":
+		if len(op.Args) != 2 {
+			return Prerror{"A bond needs two endpoints"}
+		}
+		if err := bmach.Check_bond(op.Args); err != nil {
+			return err
+		}
+		bmach.Add_bond(op.Args)
+	case "del-bonds":
+		ids, err := batch_ints(op.Args)
+		if err != nil {
+			return err
+		}
+		for _, id := range ids {
+			if err := bmach.Del_bond(id); err != nil {
+				return err
+			}
+		}
+	case "add-shared-objects":
+		for _, so := range op.Args {
+			loaded := false
+			for _, shr := range Allshared {
+				if _, ok := shr.Instantiate(so); ok {
+					loaded = true
+					break
+				}
+			}
+			if !loaded {
+				return Prerror{"How to make a shared object from \"" + so + "\" is unknown"}
+			}
+		}
+		bmach.Add_shared_objects(op.Args)
+	case "connect-processor-shared-object":
+		ids, err := batch_ints(op.Args)
+		if err != nil {
+			return err
+		}
+		if len(ids) != 2 {
+			return Prerror{"Wrong arguments number"}
+		}
+		if ids[0] < 0 || ids[0] >= len(bmach.Processors) {
+			return Prerror{"Non existent processor"}
+		}
+		if ids[1] < 0 || ids[1] >= len(bmach.Shared_objects) {
+			return Prerror{"Non existent shared object"}
+		}
+		bmach.Connect_processor_shared_object(op.Args)
+	default:
+		return Prerror{"Unknown batch operation " + op.Action}
+	}
+	return nil
+}
+
+// Check_bond verifies that the endpoints are an existing internal input and an existing internal output
+func (bmach *Bondmachine) Check_bond(endpoints []string) error {
+	if len(endpoints) != 2 {
+		return Prerror{"A bond needs two endpoints"}
+	}
+	for k := 0; k < 2; k++ {
+		inpfound := false
+		for _, inp := range bmach.Internal_inputs {
+			if inp.String() == endpoints[k] {
+				inpfound = true
+				break
+			}
+		}
+		if !inpfound {
+			continue
+		}
+		for _, outp := range bmach.Internal_outputs {
+			if outp.String() == endpoints[1-k] {
+				return nil
+			}
+		}
+		return Prerror{"Unknown internal output " + endpoints[1-k]}
+	}
+	return Prerror{"No internal input among " + endpoints[0] + " and " + endpoints[1]}
+}
+
+// Diff returns a line based diff between the JSON representations of two bondmachines
+func (bmach *Bondmachine) Diff(other *Bondmachine) (string, error) {
+	a, err := json.MarshalIndent(bmach.Jsoner(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	b, err := json.MarshalIndent(other.Jsoner(), "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return lines_diff(strings.Split(string(a), "\n"), strings.Split(string(b), "\n")), nil
+}
+
+func lines_diff(a []string, b []string) string {
+	// Longest common subsequence table
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	result := ""
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			i++
+			j++
+		} else if lcs[i+1][j] >= lcs[i][j+1] {
+			result += "- " + a[i] + "\n"
+			i++
+		} else {
+			result += "+ " + b[j] + "\n"
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		result += "- " + a[i] + "\n"
+	}
+	for ; j < len(b); j++ {
+		result += "+ " + b[j] + "\n"
+	}
+	return result
+}
@@ -0,0 +1,57 @@
+package bondmachine
+
+import (
+	"fmt"
+	"procbuilder"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	script := "# test\nadd-inputs 2\nadd-outputs 1\nadd-bond o0,i1\n"
+	ops, err := Parse_batch([]byte(script))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(ops)
+
+	bmach := new(Bondmachine)
+	bmach.Rsize = 8
+	bmach.Init()
+
+	newbmach, err := bmach.Apply_batch(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bmach.Inputs != 0 || newbmach.Inputs != 2 {
+		t.Error("Batch not applied on a copy")
+	}
+	fmt.Println(newbmach.List_bonds())
+
+	if _, err := newbmach.Apply_batch([]Batch_op{{1, "add-bond", []string{"o5", "i0"}}}); err == nil {
+		t.Error("Wrong bond accepted")
+	}
+
+	diff, _ := bmach.Diff(newbmach)
+	fmt.Print(diff)
+}
+
+// The domains are removed higher first, the listed ids refer to the bondmachine before the operation
+func TestBatchDelDomains(t *testing.T) {
+	bmach := new(Bondmachine)
+	bmach.Rsize = 8
+	bmach.Init()
+	for i := 0; i < 4; i++ {
+		dom := new(procbuilder.Machine)
+		dom.Rsize = 8
+		dom.R = uint8(i + 1)
+		bmach.Domains = append(bmach.Domains, dom)
+	}
+
+	newbmach, err := bmach.Apply_batch([]Batch_op{{1, "del-domains", []string{"0", "2"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(newbmach.Domains) != 2 || newbmach.Domains[0].R != 2 || newbmach.Domains[1].R != 4 {
+		t.Error("Wrong domains removed")
+	}
+}
@@ -0,0 +1,386 @@
+package bondmachine
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"procbuilder"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Board database used to generate the pin constraints of the top level design written by Write_verilog_board.
+// The signals names are the same used within the IOmap files (for example "clk", "btnC" or "[7:0] sw") so
+// the constraints always match the ports of the generated module.
+
+type Board_signal struct {
+	Pins       []string // Package pins, one per bit
+	Iostandard string   // Overrides the board default if not empty
+}
+
+type Board struct {
+	Name               string
+	Toolchain          string  // vivado, quartus, icestorm or trellis
+	Part               string  // The FPGA part, used by the toolchain project files
+	Family             string  // The FPGA family, used by the quartus project
+	Clock              string  // The board signal carrying the main clock
+	Clock_period       float64 // The period of the main clock in ns
+	Default_iostandard string
+	Signals            map[string]Board_signal
+	Modules            map[string]map[string]string // Extra modules ports to board signals (single bits, for example "ja[0]")
+}
+
+type Boards struct {
+	Boards map[string]*Board
+}
+
+// A single pin constraint
+type Pin_constraint struct {
+	Port       string
+	Pin        string
+	Iostandard string
+	Clock      bool
+}
+
+var Allboards map[string]*Board
+
+func init() {
+	Allboards = make(map[string]*Board)
+
+	Allboards["basys3"] = &Board{
+		Name:               "basys3",
+		Toolchain:          "vivado",
+		Part:               "xc7a35tcpg236-1",
+		Clock:              "clk",
+		Clock_period:       10.0,
+		Default_iostandard: "LVCMOS33",
+		Signals: map[string]Board_signal{
+			"clk":   {Pins: []string{"W5"}},
+			"btnC":  {Pins: []string{"U18"}},
+			"btnU":  {Pins: []string{"T18"}},
+			"btnL":  {Pins: []string{"W19"}},
+			"btnR":  {Pins: []string{"T17"}},
+			"btnD":  {Pins: []string{"U17"}},
+			"sw":    {Pins: []string{"V17", "V16", "W16", "W17", "W15", "V15", "W14", "W13", "V2", "T3", "T2", "R3", "W2", "U1", "T1", "R2"}},
+			"led":   {Pins: []string{"U16", "E19", "U19", "V19", "W18", "U15", "U14", "V14", "V13", "V3", "W3", "U3", "P3", "N3", "P1", "L1"}},
+			"seg":   {Pins: []string{"W7", "W6", "U8", "V8", "U5", "V5", "U7"}},
+			"dp":    {Pins: []string{"V7"}},
+			"an":    {Pins: []string{"U2", "U4", "V4", "W4"}},
+			"ja":    {Pins: []string{"J1", "L2", "J2", "G2", "H1", "K2", "H2", "G3"}},
+			"jb":    {Pins: []string{"A14", "A16", "B15", "B16", "A15", "A17", "C15", "C16"}},
+			"jc":    {Pins: []string{"K17", "M18", "N17", "P18", "L17", "M19", "P17", "R18"}},
+			"RsRx":  {Pins: []string{"B18"}},
+			"RsTx":  {Pins: []string{"A18"}},
+			"reset": {Pins: []string{"U18"}},
+		},
+		Modules: map[string]map[string]string{
+			"basys3_7segment": {
+				"segment[0]": "seg[0]", "segment[1]": "seg[1]", "segment[2]": "seg[2]", "segment[3]": "seg[3]",
+				"segment[4]": "seg[4]", "segment[5]": "seg[5]", "segment[6]": "seg[6]",
+				"enable_D1": "an[0]", "enable_D2": "an[1]", "enable_D3": "an[2]", "enable_D4": "an[3]",
+				"dp": "dp",
+			},
+			"etherbond": {"cs_n": "ja[0]", "mosi": "ja[1]", "miso": "ja[2]", "sck": "ja[3]", "int_n": "ja[4]"},
+			"udpbond":   {"wifi_enable": "jb[0]", "wifi_tx": "jb[1]", "wifi_rx": "jb[2]"},
+			"uart":      {"uart_rx": "RsRx", "uart_tx": "RsTx"},
+		},
+	}
+
+	Allboards["kintex7"] = &Board{
+		Name:               "kintex7",
+		Toolchain:          "vivado",
+		Part:               "xc7k325tffg900-2",
+		Clock:              "clk",
+		Clock_period:       5.0,
+		Default_iostandard: "LVCMOS25",
+		Signals: map[string]Board_signal{
+			"clk":   {Pins: []string{"AD12"}, Iostandard: "LVDS"},
+			"reset": {Pins: []string{"AB7"}, Iostandard: "LVCMOS15"},
+			"sw":    {Pins: []string{"Y29", "W29", "AA28", "Y28"}},
+			"led":   {Pins: []string{"AB8", "AA8", "AC9", "AB9", "AE26", "G19", "E18", "F16"}},
+		},
+		Modules: map[string]map[string]string{},
+	}
+
+	Allboards["de10nano"] = &Board{
+		Name:               "de10nano",
+		Toolchain:          "quartus",
+		Part:               "5CSEBA6U23I7",
+		Family:             "Cyclone V",
+		Clock:              "clk",
+		Clock_period:       20.0,
+		Default_iostandard: "3.3-V LVTTL",
+		Signals: map[string]Board_signal{
+			"clk":   {Pins: []string{"PIN_V11"}},
+			"reset": {Pins: []string{"PIN_AH17"}},
+			"key":   {Pins: []string{"PIN_AH17", "PIN_AH16"}},
+			"sw":    {Pins: []string{"PIN_Y24", "PIN_W24", "PIN_W21", "PIN_W20"}},
+			"led":   {Pins: []string{"PIN_W15", "PIN_AA24", "PIN_V16", "PIN_V15", "PIN_AF26", "PIN_AE26", "PIN_Y16", "PIN_AA23"}},
+			"gpio0": {Pins: []string{"PIN_V12", "PIN_E8", "PIN_W12", "PIN_D11", "PIN_D8", "PIN_AH13", "PIN_AF7", "PIN_AH14"}},
+		},
+		Modules: map[string]map[string]string{
+			"etherbond": {"cs_n": "gpio0[0]", "mosi": "gpio0[1]", "miso": "gpio0[2]", "sck": "gpio0[3]", "int_n": "gpio0[4]"},
+			"udpbond":   {"wifi_enable": "gpio0[5]", "wifi_tx": "gpio0[6]", "wifi_rx": "gpio0[7]"},
+		},
+	}
+
+	Allboards["icestick"] = &Board{
+		Name:         "icestick",
+		Toolchain:    "icestorm",
+		Part:         "hx1k-tq144",
+		Clock:        "clk",
+		Clock_period: 83.33,
+		Signals: map[string]Board_signal{
+			"clk":  {Pins: []string{"21"}},
+			"led":  {Pins: []string{"99", "98", "97", "96", "95"}},
+			"pmod": {Pins: []string{"78", "79", "80", "81", "87", "88", "90", "91"}},
+			"rx":   {Pins: []string{"9"}},
+			"tx":   {Pins: []string{"8"}},
+		},
+		Modules: map[string]map[string]string{
+			"etherbond": {"cs_n": "pmod[0]", "mosi": "pmod[1]", "miso": "pmod[2]", "sck": "pmod[3]", "int_n": "pmod[4]"},
+			"udpbond":   {"wifi_enable": "pmod[5]", "wifi_tx": "pmod[6]", "wifi_rx": "pmod[7]"},
+		},
+	}
+
+	Allboards["ulx3s"] = &Board{
+		Name:               "ulx3s",
+		Toolchain:          "trellis",
+		Part:               "LFE5U-85F-6BG381C",
+		Clock:              "clk",
+		Clock_period:       40.0,
+		Default_iostandard: "LVCMOS33",
+		Signals: map[string]Board_signal{
+			"clk":   {Pins: []string{"G2"}},
+			"reset": {Pins: []string{"R1"}},
+			"btn":   {Pins: []string{"D6", "R1", "T1", "R18", "V1", "U1", "H16"}},
+			"sw":    {Pins: []string{"E8", "D8", "D7", "E7"}},
+			"led":   {Pins: []string{"B2", "C2", "C1", "D2", "D1", "E2", "E1", "H3"}},
+		},
+		Modules: map[string]map[string]string{},
+	}
+}
+
+// Load_boards adds (or replaces) the boards defined in a JSON file to the database
+func Load_boards(filename string) error {
+	boards_json, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	boards := new(Boards)
+	if err := json.Unmarshal(boards_json, boards); err != nil {
+		return err
+	}
+	for name, board := range boards.Boards {
+		if board.Name == "" {
+			board.Name = name
+		}
+		Allboards[name] = board
+	}
+	return nil
+}
+
+// Resolve a board signal bit (for example "sw[3]" or "clk") to its package pin and IO standard
+func (board *Board) Resolve(signal string) (string, string, error) {
+	name := signal
+	index := 0
+	re := regexp.MustCompile("^(?P<name>[a-zA-Z0-9_]+)\\[(?P<index>[0-9]+)\\]$")
+	if re.MatchString(signal) {
+		name = re.ReplaceAllString(signal, "${name}")
+		index, _ = strconv.Atoi(re.ReplaceAllString(signal, "${index}"))
+	}
+	if bsig, ok := board.Signals[name]; ok {
+		if index < len(bsig.Pins) {
+			iostd := board.Default_iostandard
+			if bsig.Iostandard != "" {
+				iostd = bsig.Iostandard
+			}
+			return bsig.Pins[index], iostd, nil
+		}
+		return "", "", Prerror{"Board " + board.Name + " signal " + name + " has no bit " + strconv.Itoa(index)}
+	}
+	return "", "", Prerror{"Board " + board.Name + " has no signal " + name}
+}
+
+// Board_constraints collects the pin constraints of the ports generated by Write_verilog_board
+func (bmach *Bondmachine) Board_constraints(board *Board, iomaps *IOmap, extramods []ExtraModule) ([]Pin_constraint, error) {
+	result := make([]Pin_constraint, 0)
+
+	add := func(port string, signal string, clock bool) error {
+		pin, iostd, err := board.Resolve(signal)
+		if err != nil {
+			return err
+		}
+		for _, cons := range result {
+			if cons.Pin == pin && cons.Port != port {
+				return Prerror{"Pin " + pin + " assigned to both " + cons.Port + " and " + port}
+			}
+		}
+		result = append(result, Pin_constraint{Port: port, Pin: pin, Iostandard: iostd, Clock: clock})
+		return nil
+	}
+
+	clk_name := "clk"
+	clk_signal := board.Clock
+	if cname, ok := iomaps.Assoc["clk"]; ok {
+		clk_name = cname
+		clk_signal = cname
+	}
+	if err := add(clk_name, clk_signal, true); err != nil {
+		return nil, err
+	}
+
+	rst_name := "reset"
+	if rname, ok := iomaps.Assoc["reset"]; ok {
+		rst_name = rname
+	}
+	if err := add(rst_name, rst_name, false); err != nil {
+		return nil, err
+	}
+
+	ionames := make([]string, 0)
+	for i := 0; i < bmach.Inputs; i++ {
+		ionames = append(ionames, Get_input_name(i))
+	}
+	for i := 0; i < bmach.Outputs; i++ {
+		ionames = append(ionames, Get_output_name(i))
+	}
+
+	for _, ioname := range ionames {
+		if rname, ok := iomaps.Assoc[ioname]; ok {
+			done := make(map[string]bool)
+			for j := 0; j < int(bmach.Rsize); j++ {
+				bit := nth_assoc(rname, j)
+				if _, ok := done[bit]; ok {
+					continue
+				}
+				done[bit] = true
+				if err := add(bit, bit, false); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	for _, mod := range extramods {
+		bits := make([]string, 0)
+		for _, port := range mod.Get_Ports() {
+			bits = append(bits, port.Bits()...)
+		}
+		sort.Strings(bits)
+		for _, bit := range bits {
+			signal, ok := board.Modules[mod.Get_Name()][bit]
+			if !ok {
+				return nil, Prerror{"Board " + board.Name + " has no pin for the " + bit + " port of the " + mod.Get_Name() + " module"}
+			}
+			if err := add(bit, signal, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// Write_constraints returns the constraints file name and content in the format of the board toolchain
+func (board *Board) Write_constraints(module_name string, cons []Pin_constraint) (string, string, error) {
+	result := ""
+	switch board.Toolchain {
+	case "vivado":
+		for _, c := range cons {
+			result += "set_property PACKAGE_PIN " + c.Pin + " [get_ports {" + c.Port + "}]\n"
+			if c.Iostandard != "" {
+				result += "set_property IOSTANDARD " + c.Iostandard + " [get_ports {" + c.Port + "}]\n"
+			}
+			if c.Clock {
+				result += "create_clock -add -name sys_clk_pin -period " + strconv.FormatFloat(board.Clock_period, 'f', 2, 64) + " [get_ports {" + c.Port + "}]\n"
+			}
+		}
+		return module_name + ".xdc", result, nil
+	case "quartus":
+		for _, c := range cons {
+			result += "set_location_assignment " + c.Pin + " -to " + c.Port + "\n"
+			if c.Iostandard != "" {
+				result += "set_instance_assignment -name IO_STANDARD \"" + c.Iostandard + "\" -to " + c.Port + "\n"
+			}
+		}
+		// Sourced by the project settings written with the manifest, which reference the timing constraints too
+		return module_name + "_pins.tcl", result, nil
+	case "icestorm":
+		for _, c := range cons {
+			result += "set_io " + c.Port + " " + c.Pin + "\n"
+		}
+		return module_name + ".pcf", result, nil
+	case "trellis":
+		for _, c := range cons {
+			result += "LOCATE COMP \"" + c.Port + "\" SITE \"" + c.Pin + "\";\n"
+			if c.Iostandard != "" {
+				result += "IOBUF PORT \"" + c.Port + "\" IO_TYPE=" + c.Iostandard + ";\n"
+			}
+			if c.Clock {
+				freq := 1000.0 / board.Clock_period
+				result += "FREQUENCY PORT \"" + c.Port + "\" " + strconv.FormatFloat(freq, 'f', 2, 64) + " MHZ;\n"
+			}
+		}
+		return module_name + ".lpf", result, nil
+	}
+	return "", "", Prerror{"Unknown toolchain " + board.Toolchain + " for board " + board.Name}
+}
+
+// Write_sdc returns the timing constraints for the toolchains that keep them apart from the pins (quartus)
+func (board *Board) Write_sdc(cons []Pin_constraint) string {
+	result := ""
+	for _, c := range cons {
+		if c.Clock {
+			result += "create_clock -name sys_clk_pin -period " + strconv.FormatFloat(board.Clock_period, 'f', 2, 64) + " [get_ports {" + c.Port + "}]\n"
+		}
+	}
+	if result != "" {
+		result += "derive_clock_uncertainty\n"
+	}
+	return result
+}
+
+func (board *Board) String() string {
+	result := board.Name + " (" + board.Toolchain + ", " + strconv.FormatFloat(board.Clock_period, 'f', 2, 64) + "ns)\n"
+	names := make([]string, 0, len(board.Signals))
+	for name, _ := range board.Signals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result += "\t" + name + ": " + strings.Join(board.Signals[name].Pins, " ") + "\n"
+	}
+	return result
+}
+
+// Write_board_constraints writes the constraints files for the top level module of the board
+func (bmach *Bondmachine) Write_board_constraints(out *procbuilder.Output, module_name string, boardname string, iomaps *IOmap, extramods []ExtraModule) error {
+	board, ok := Allboards[boardname]
+	if !ok {
+		return Prerror{"Unknown board " + boardname}
+	}
+
+	cons, err := bmach.Board_constraints(board, iomaps, extramods)
+	if err != nil {
+		return err
+	}
+
+	filename, content, err := board.Write_constraints(module_name, cons)
+	if err != nil {
+		return err
+	}
+
+	if err := out.Write(filename, content); err != nil {
+		return err
+	}
+
+	if board.Toolchain == "quartus" {
+		if err := out.Write(module_name+".sdc", board.Write_sdc(cons)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
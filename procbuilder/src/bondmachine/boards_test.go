@@ -0,0 +1,66 @@
+package bondmachine
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// The constraints of the icestorm (.pcf) and trellis (.lpf) boards, and of a board loaded from a file
+func TestBoardConstraints(t *testing.T) {
+	bmach := new(Bondmachine)
+	bmach.Rsize = 4
+	bmach.Init()
+	bmach.Add_input()
+	bmach.Add_output()
+
+	tests := []struct {
+		board    string
+		assoc    map[string]string
+		filename string
+		expected string
+		count    int
+	}{
+		{"icestick", map[string]string{"reset": "rx", "o0": "[3:0] led"}, "bondmachine_main.pcf", "set_io led[2] 97\n", 6},
+		{"ulx3s", map[string]string{"i0": "[3:0] sw", "o0": "[3:0] led"}, "bondmachine_main.lpf", "LOCATE COMP \"sw[1]\" SITE \"D8\";\n", 10},
+	}
+	for _, test := range tests {
+		iomaps := &IOmap{Assoc: test.assoc}
+		cons, err := bmach.Board_constraints(Allboards[test.board], iomaps, []ExtraModule{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		filename, content, err := Allboards[test.board].Write_constraints("bondmachine_main", cons)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Print(content)
+		if len(cons) != test.count || filename != test.filename || !strings.Contains(content, test.expected) {
+			t.Error("Wrong constraints for "+test.board, filename, cons)
+		}
+	}
+
+	boardfile, err := ioutil.TempFile("", "boards")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(boardfile.Name())
+	boardfile.WriteString(`{"Boards": {"tinyboard": {"Toolchain": "icestorm", "Clock": "clk", "Clock_period": 10,
+		"Signals": {"clk": {"Pins": ["35"]}, "reset": {"Pins": ["10"]}, "led": {"Pins": ["11", "12", "13", "14"]}}}}}`)
+	boardfile.Close()
+	if err := Load_boards(boardfile.Name()); err != nil {
+		t.Fatal(err)
+	}
+	defer delete(Allboards, "tinyboard")
+
+	iomaps := &IOmap{Assoc: map[string]string{"o0": "[3:0] led"}}
+	cons, err := bmach.Board_constraints(Allboards["tinyboard"], iomaps, []ExtraModule{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filename, content, err := Allboards["tinyboard"].Write_constraints("bondmachine_main", cons); err != nil || filename != "bondmachine_main.pcf" || !strings.Contains(content, "set_io reset 10\n") {
+		t.Error("Wrong constraints for a loaded board", filename, content, err)
+	}
+}
@@ -17,11 +17,17 @@ type Prerror struct {
 var Allshared []Shared_element
 
 func init() {
+	// The built in shared objects and extra modules, see registry.go
 	Allshared = make([]Shared_element, 0)
-	Allshared = append(Allshared, Sharedmem{})
-	Allshared = append(Allshared, Channel{})
-	Allshared = append(Allshared, Barrier{})
-	Allshared = append(Allshared, Lfsr8{})
+	for _, so := range []Shared_element{Sharedmem{}, Channel{}, Barrier{}, Lfsr8{}, Mutex{}, Semaphore{}, Counter{}, Lfsrn{}} {
+		check(Register_shared_object(so, nil))
+	}
+
+	check(Register_extra_module("slow", func() ExtraModule { return new(Slow_extra) }, nil))
+	check(Register_extra_module("etherbond", func() ExtraModule { return new(Etherbond_extra) }, (*Bondmachine).Write_verilog_etherbond))
+	check(Register_extra_module("udpbond", func() ExtraModule { return new(Udpbond_extra) }, (*Bondmachine).Write_verilog_udpbond))
+	check(Register_extra_module("basys3_7segment", func() ExtraModule { return new(B37s) }, (*Bondmachine).Write_verilog_basys3_7segment))
+	check(Register_extra_module("uart", func() ExtraModule { return new(Uart_extra) }, (*Bondmachine).Write_verilog_uart))
 }
 
 func (e Prerror) Error() string {
@@ -46,6 +52,8 @@ type Config struct {
 	Debug             bool
 	Dotdetail         uint8
 	Commented_verilog bool
+	Output            *procbuilder.Output // Where the generated files go, the current directory if nil
+	Bus_wrapper       string              // The bus of the slave wrapper to generate, none if empty
 }
 
 //reorg {"name": "BondMachine typedefs", "descr": "Definition of BondMachine and BondMachine JSON data structures"}
@@ -120,6 +128,13 @@ type ExtraModule interface {
 	Verilog_headers() string
 	Static_verilog() string
 	ExtraFiles() ([]string, []string)
+	Estimate_resources(*Bondmachine, string) procbuilder.Resources
+	// The board level composition, see extramods.go
+	Get_Ports() []Extra_port
+	Get_IOs(*Bondmachine) ([]string, []string)
+	Get_Clocking() Extra_clocking
+	Get_Conflicts() []string
+	Board_verilog(*Bondmachine, string, string, map[string]string) (string, string)
 }
 
 //reorg {"name": "Configuration converter", "descr": "Funcion to extract a Config structure for procbuilder from a Config of the bondmachine"}
@@ -927,3 +942,28 @@ func (bmach *Bondmachine) Dot(conf *Config, prefix string, vm *VM, oldvmstate *V
 
 	return result
 }
+
+// Estimate_resources sums the estimates of the processors, the shared objects and the extra modules
+func (bmach *Bondmachine) Estimate_resources(flavor string, extramods []ExtraModule) *procbuilder.Resource_report {
+	report := &procbuilder.Resource_report{Flavor: procbuilder.Get_flavor_model(flavor).Name, Parts: make([]procbuilder.Resource_part, 0)}
+
+	for i, dom_id := range bmach.Processors {
+		proc := bmach.Domains[dom_id].Estimate_resources(flavor)
+		report.Add_part("p"+strconv.Itoa(i), proc.Total)
+	}
+
+	for i, so := range bmach.Shared_objects {
+		soname, _ := bmach.Get_so_name(i)
+		report.Add_part(soname, so.Estimate_resources(bmach, i, flavor))
+	}
+
+	if bmach.Inputs > 0 || bmach.Outputs > 0 {
+		report.Add_part("io", procbuilder.Resources{Ffs: (bmach.Inputs + bmach.Outputs) * int(bmach.Rsize)})
+	}
+
+	for _, em := range extramods {
+		report.Add_part(em.Get_Name(), em.Estimate_resources(bmach, flavor))
+	}
+
+	return report
+}
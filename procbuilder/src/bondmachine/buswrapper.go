@@ -0,0 +1,635 @@
+package bondmachine
+
+import (
+	"fmt"
+	"go/format"
+	"procbuilder"
+	"strconv"
+	"strings"
+)
+
+// The bus wrapper embeds the bondmachine module as a slave of an AXI4-Lite or a Wishbone bus. The registers are 32
+// bits wide, an IO larger than a word takes more words with the least significant first:
+//
+//   - writing the first word of an input sets it, the other words are staged and set together with the first one
+//   - reading the first word of an output clears its valid bit and latches the other words for the next reads
+//   - an output change sets its valid bit and its interrupt status bit, the interrupt line is high while an enabled
+//     status bit is set and the control interrupt enable is on. The status bits are cleared writing them to one.
+
+const (
+	BUS_AXI4LITE = "axi4lite"
+	BUS_WISHBONE = "wishbone"
+)
+
+// "BM" followed by the layout version
+const BUS_ID = 0x424d0001
+
+const (
+	BUS_CONTROL_RESET      = 1 << 0 // Hold the bondmachine in reset
+	BUS_CONTROL_IRQ_ENABLE = 1 << 1 // Enable the interrupt line
+)
+
+type Bus_register struct {
+	Name   string
+	Offset int // Bytes from the base address
+	Words  int
+	Access string // ro, rw or w1c
+	Descr  string
+}
+
+type Bus_regmap struct {
+	Rsize     uint8
+	Inputs    int
+	Outputs   int
+	Io_words  int // Words of an IO register
+	Size      int // Bytes of the address space
+	Registers []Bus_register
+}
+
+func Bus_names() []string {
+	return []string{BUS_AXI4LITE, BUS_WISHBONE}
+}
+
+func words_for(bits int) int {
+	return (bits + 31) / 32
+}
+
+// Bus_regmap computes the registers of the bus wrapper
+func (bmach *Bondmachine) Bus_regmap() (*Bus_regmap, error) {
+	if bmach.Rsize == 0 || bmach.Rsize > 64 {
+		return nil, Prerror{"The bus wrapper supports register sizes from 1 to 64 bits"}
+	}
+	if bmach.Inputs > 255 || bmach.Outputs > 255 {
+		return nil, Prerror{"The bus wrapper supports up to 255 inputs and outputs"}
+	}
+
+	rm := new(Bus_regmap)
+	rm.Rsize = bmach.Rsize
+	rm.Inputs = bmach.Inputs
+	rm.Outputs = bmach.Outputs
+	rm.Io_words = words_for(int(bmach.Rsize))
+	rm.Registers = make([]Bus_register, 0)
+
+	offset := 0
+	add := func(name string, words int, access string, descr string) {
+		rm.Registers = append(rm.Registers, Bus_register{name, offset, words, access, descr})
+		offset += words * 4
+	}
+
+	add("ID", 1, "ro", "Layout identifier "+fmt.Sprintf("0x%08x", BUS_ID))
+	add("INFO", 1, "ro", "Inputs [7:0], outputs [15:8], register size [23:16]")
+	add("CONTROL", 1, "rw", "Bondmachine reset [0], interrupt enable [1]")
+
+	if bmach.Inputs > 0 {
+		add("IN_WRITTEN", words_for(bmach.Inputs), "ro", "An input has been written since the bus reset")
+	}
+	if bmach.Outputs > 0 {
+		add("OUT_VALID", words_for(bmach.Outputs), "ro", "An output has changed since its last read")
+		add("IRQ_STATUS", words_for(bmach.Outputs), "w1c", "An output has changed since the bit was cleared")
+		add("IRQ_ENABLE", words_for(bmach.Outputs), "rw", "The output changes raising the interrupt")
+	}
+
+	// The IO registers start on a 16 bytes boundary
+	offset = (offset + 15) &^ 15
+	for i := 0; i < bmach.Inputs; i++ {
+		add("I"+strconv.Itoa(i), rm.Io_words, "rw", "Input i"+strconv.Itoa(i))
+	}
+	for i := 0; i < bmach.Outputs; i++ {
+		add("O"+strconv.Itoa(i), rm.Io_words, "ro", "Output o"+strconv.Itoa(i))
+	}
+	rm.Size = offset
+
+	return rm, nil
+}
+
+func (rm *Bus_regmap) Find(name string) (Bus_register, bool) {
+	for _, reg := range rm.Registers {
+		if reg.Name == name {
+			return reg, true
+		}
+	}
+	return Bus_register{}, false
+}
+
+// Addr_bits is the width of the byte address
+func (rm *Bus_regmap) Addr_bits() int {
+	return Needed_bits(rm.Size)
+}
+
+// The slice of a vector in a register word, with the width of the slice
+func bus_slice(width int, word int) (string, int) {
+	lo := word * 32
+	hi := lo + 31
+	if hi > width-1 {
+		hi = width - 1
+	}
+	return "[" + strconv.Itoa(hi) + ":" + strconv.Itoa(lo) + "]", hi - lo + 1
+}
+
+// The read value of a slice, padded to the word
+func bus_word(vector string, width int, word int) string {
+	slice, w := bus_slice(width, word)
+	if w == 32 {
+		return vector + slice
+	}
+	return "{" + strconv.Itoa(32-w) + "'b0, " + vector + slice + "}"
+}
+
+// The masked write of a slice
+func bus_masked(vector string, width int, word int) string {
+	slice, w := bus_slice(width, word)
+	low := "[" + strconv.Itoa(w-1) + ":0]"
+	return "(" + vector + slice + " & ~bus_wmask" + low + ") | (bus_wdata" + low + " & bus_wmask" + low + ")"
+}
+
+func bus_case(aw int, offset int) string {
+	return strconv.Itoa(aw-2) + "'d" + strconv.Itoa(offset/4)
+}
+
+// write_verilog_bus_core writes the registers, the bondmachine instance and the interrupt logic. The bus front end
+// drives bus_wr and bus_rd for a cycle with the addresses, the data and the byte mask and samples bus_rdata.
+func (bmach *Bondmachine) write_verilog_bus_core(rm *Bus_regmap, bm_module string) string {
+	aw := rm.Addr_bits()
+	rs := strconv.Itoa(int(rm.Rsize) - 1)
+	rsize := int(rm.Rsize)
+	ni := strconv.Itoa(rm.Inputs)
+	no := strconv.Itoa(rm.Outputs)
+	waddr := "bus_waddr[" + strconv.Itoa(aw-1) + ":2]"
+	raddr := "bus_raddr[" + strconv.Itoa(aw-1) + ":2]"
+
+	result := ""
+	result += "\t//--------------Registers-----------------------\n"
+	result += "\treg [1:0] control;\n"
+	for i := 0; i < rm.Inputs; i++ {
+		in := "i" + strconv.Itoa(i)
+		result += "\treg [" + rs + ":0] " + in + ";\n"
+		if rm.Io_words > 1 {
+			result += "\treg [" + rs + ":32] " + in + "_staged;\n"
+		}
+	}
+	for i := 0; i < rm.Outputs; i++ {
+		out := "o" + strconv.Itoa(i)
+		result += "\twire [" + rs + ":0] " + out + ";\n"
+		result += "\treg [" + rs + ":0] " + out + "_prev;\n"
+		if rm.Io_words > 1 {
+			result += "\treg [" + rs + ":32] " + out + "_latched;\n"
+		}
+	}
+	if rm.Inputs > 0 {
+		result += "\treg [" + ni + "-1:0] in_written;\n"
+	}
+	if rm.Outputs > 0 {
+		result += "\treg [" + no + "-1:0] out_valid;\n"
+		result += "\treg [" + no + "-1:0] irq_status;\n"
+		result += "\treg [" + no + "-1:0] irq_enable;\n"
+		result += "\twire [" + no + "-1:0] out_changed;\n"
+	}
+	result += "\treg [31:0] bus_rdata;\n"
+	result += "\twire bm_reset;\n"
+	result += "\n"
+
+	result += "\tassign bm_reset = reset | control[0];\n"
+	if rm.Outputs > 0 {
+		for i := 0; i < rm.Outputs; i++ {
+			out := "o" + strconv.Itoa(i)
+			result += "\tassign out_changed[" + strconv.Itoa(i) + "] = " + out + " != " + out + "_prev;\n"
+		}
+		result += "\tassign irq = control[1] && |(irq_status & irq_enable);\n"
+	} else {
+		result += "\tassign irq = 1'b0;\n"
+	}
+	result += "\n"
+
+	result += "\t" + bm_module + " bm_inst(clk, bm_reset"
+	for i := 0; i < rm.Inputs; i++ {
+		result += ", i" + strconv.Itoa(i)
+	}
+	for i := 0; i < rm.Outputs; i++ {
+		result += ", o" + strconv.Itoa(i)
+	}
+	result += ");\n"
+	result += "\n"
+
+	result += "\t// Register read\n"
+	result += "\talways @(*) begin\n"
+	result += "\t\tcase (" + raddr + ")\n"
+	for _, reg := range rm.Registers {
+		for w := 0; w < reg.Words; w++ {
+			value := ""
+			switch {
+			case reg.Name == "ID":
+				value = fmt.Sprintf("32'h%08x", BUS_ID)
+			case reg.Name == "INFO":
+				value = fmt.Sprintf("32'h%02x%02x%02x", rm.Rsize, rm.Outputs, rm.Inputs)
+			case reg.Name == "CONTROL":
+				value = "{30'b0, control}"
+			case reg.Name == "IN_WRITTEN":
+				value = bus_word("in_written", rm.Inputs, w)
+			case reg.Name == "OUT_VALID":
+				value = bus_word("out_valid", rm.Outputs, w)
+			case reg.Name == "IRQ_STATUS":
+				value = bus_word("irq_status", rm.Outputs, w)
+			case reg.Name == "IRQ_ENABLE":
+				value = bus_word("irq_enable", rm.Outputs, w)
+			case reg.Name[0] == 'I':
+				value = bus_word(strings.ToLower(reg.Name), rsize, w)
+			case w == 0:
+				value = bus_word(strings.ToLower(reg.Name), rsize, w)
+			default:
+				value = bus_word(strings.ToLower(reg.Name)+"_latched", rsize, w)
+			}
+			result += "\t\t" + bus_case(aw, reg.Offset+4*w) + ": bus_rdata = " + value + ";\n"
+		}
+	}
+	result += "\t\tdefault: bus_rdata = 32'b0;\n"
+	result += "\t\tendcase\n"
+	result += "\tend\n"
+	result += "\n"
+
+	result += "\t// Register write and the status bits, the output changes win over the clear of the interrupt status\n"
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif(reset) begin\n"
+	result += "\t\t\tcontrol <= #1 2'b0;\n"
+	for i := 0; i < rm.Inputs; i++ {
+		in := "i" + strconv.Itoa(i)
+		result += "\t\t\t" + in + " <= #1 'b0;\n"
+		if rm.Io_words > 1 {
+			result += "\t\t\t" + in + "_staged <= #1 'b0;\n"
+		}
+	}
+	for i := 0; i < rm.Outputs; i++ {
+		out := "o" + strconv.Itoa(i)
+		result += "\t\t\t" + out + "_prev <= #1 'b0;\n"
+		if rm.Io_words > 1 {
+			result += "\t\t\t" + out + "_latched <= #1 'b0;\n"
+		}
+	}
+	if rm.Inputs > 0 {
+		result += "\t\t\tin_written <= #1 'b0;\n"
+	}
+	if rm.Outputs > 0 {
+		result += "\t\t\tout_valid <= #1 'b0;\n"
+		result += "\t\t\tirq_status <= #1 'b0;\n"
+		result += "\t\t\tirq_enable <= #1 'b0;\n"
+	}
+	result += "\t\tend\n"
+	result += "\t\telse begin\n"
+
+	if rm.Outputs > 0 {
+		for i := 0; i < rm.Outputs; i++ {
+			out := "o" + strconv.Itoa(i)
+			result += "\t\t\t" + out + "_prev <= #1 " + out + ";\n"
+		}
+		result += "\t\t\tout_valid <= #1 out_valid | out_changed;\n"
+		result += "\t\t\tirq_status <= #1 irq_status | out_changed;\n"
+	}
+
+	result += "\t\t\tif (bus_wr) begin\n"
+	result += "\t\t\t\tcase (" + waddr + ")\n"
+	for _, reg := range rm.Registers {
+		if reg.Access == "ro" {
+			continue
+		}
+		for w := 0; w < reg.Words; w++ {
+			result += "\t\t\t\t" + bus_case(aw, reg.Offset+4*w) + ": "
+			switch reg.Name {
+			case "CONTROL":
+				result += "control <= #1 " + bus_masked("control", 2, 0) + ";\n"
+			case "IRQ_STATUS":
+				slice, width := bus_slice(rm.Outputs, w)
+				low := "[" + strconv.Itoa(width-1) + ":0]"
+				result += "irq_status" + slice + " <= #1 (irq_status" + slice + " & ~(bus_wdata" + low + " & bus_wmask" + low + ")) | out_changed" + slice + ";\n"
+			case "IRQ_ENABLE":
+				slice, _ := bus_slice(rm.Outputs, w)
+				result += "irq_enable" + slice + " <= #1 " + bus_masked("irq_enable", rm.Outputs, w) + ";\n"
+			default:
+				in := strings.ToLower(reg.Name)
+				index := in[1:]
+				if w == 0 {
+					result += "begin\n"
+					slice, _ := bus_slice(rsize, 0)
+					result += "\t\t\t\t\t" + in + slice + " <= #1 " + bus_masked(in, rsize, 0) + ";\n"
+					if rm.Io_words > 1 {
+						result += "\t\t\t\t\t" + in + "[" + rs + ":32] <= #1 " + in + "_staged;\n"
+					}
+					result += "\t\t\t\t\tin_written[" + index + "] <= #1 1'b1;\n"
+					result += "\t\t\t\tend\n"
+				} else {
+					slice, _ := bus_slice(rsize, w)
+					result += in + "_staged" + slice + " <= #1 " + bus_masked(in+"_staged", rsize, w) + ";\n"
+				}
+			}
+		}
+	}
+	result += "\t\t\t\tdefault: ;\n"
+	result += "\t\t\t\tendcase\n"
+	result += "\t\t\tend\n"
+
+	if rm.Outputs > 0 {
+		result += "\t\t\tif (bus_rd) begin\n"
+		result += "\t\t\t\tcase (" + raddr + ")\n"
+		for i := 0; i < rm.Outputs; i++ {
+			reg, _ := rm.Find("O" + strconv.Itoa(i))
+			out := "o" + strconv.Itoa(i)
+			result += "\t\t\t\t" + bus_case(aw, reg.Offset) + ": begin\n"
+			result += "\t\t\t\t\tout_valid[" + strconv.Itoa(i) + "] <= #1 1'b0;\n"
+			if rm.Io_words > 1 {
+				result += "\t\t\t\t\t" + out + "_latched <= #1 " + out + "[" + rs + ":32];\n"
+			}
+			result += "\t\t\t\tend\n"
+		}
+		result += "\t\t\t\tdefault: ;\n"
+		result += "\t\t\t\tendcase\n"
+		result += "\t\t\tend\n"
+	}
+
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+
+	return result
+}
+
+// Write_verilog_axi4lite writes an AXI4-Lite slave embedding the bondmachine module, it answers OKAY to every access
+func (bmach *Bondmachine) Write_verilog_axi4lite(module_name string, bm_module string) (string, error) {
+	rm, err := bmach.Bus_regmap()
+	if err != nil {
+		return "", err
+	}
+	aw := strconv.Itoa(rm.Addr_bits() - 1)
+
+	result := "\n"
+	result += "`timescale 1ns/1ps\n"
+	result += "module " + module_name + "(s_axi_aclk, s_axi_aresetn, s_axi_awaddr, s_axi_awprot, s_axi_awvalid, s_axi_awready, s_axi_wdata, s_axi_wstrb, s_axi_wvalid, s_axi_wready, s_axi_bresp, s_axi_bvalid, s_axi_bready, s_axi_araddr, s_axi_arprot, s_axi_arvalid, s_axi_arready, s_axi_rdata, s_axi_rresp, s_axi_rvalid, s_axi_rready, irq);\n"
+	result += "\n"
+	result += "\t//--------------Input Ports-----------------------\n"
+	result += "\tinput s_axi_aclk;\n"
+	result += "\tinput s_axi_aresetn;\n"
+	result += "\tinput [" + aw + ":0] s_axi_awaddr;\n"
+	result += "\tinput [2:0] s_axi_awprot;\n"
+	result += "\tinput s_axi_awvalid;\n"
+	result += "\tinput [31:0] s_axi_wdata;\n"
+	result += "\tinput [3:0] s_axi_wstrb;\n"
+	result += "\tinput s_axi_wvalid;\n"
+	result += "\tinput s_axi_bready;\n"
+	result += "\tinput [" + aw + ":0] s_axi_araddr;\n"
+	result += "\tinput [2:0] s_axi_arprot;\n"
+	result += "\tinput s_axi_arvalid;\n"
+	result += "\tinput s_axi_rready;\n"
+	result += "\n"
+	result += "\t//--------------Output Ports-----------------------\n"
+	result += "\toutput s_axi_awready;\n"
+	result += "\toutput s_axi_wready;\n"
+	result += "\toutput [1:0] s_axi_bresp;\n"
+	result += "\toutput reg s_axi_bvalid;\n"
+	result += "\toutput s_axi_arready;\n"
+	result += "\toutput reg [31:0] s_axi_rdata;\n"
+	result += "\toutput [1:0] s_axi_rresp;\n"
+	result += "\toutput reg s_axi_rvalid;\n"
+	result += "\toutput irq;\n"
+	result += "\n"
+	result += "\twire clk;\n"
+	result += "\twire reset;\n"
+	result += "\twire bus_wr;\n"
+	result += "\twire bus_rd;\n"
+	result += "\twire [" + aw + ":0] bus_waddr;\n"
+	result += "\twire [" + aw + ":0] bus_raddr;\n"
+	result += "\twire [31:0] bus_wdata;\n"
+	result += "\twire [31:0] bus_wmask;\n"
+	result += "\n"
+	result += "\tassign clk = s_axi_aclk;\n"
+	result += "\tassign reset = !s_axi_aresetn;\n"
+	result += "\n"
+	result += "\t// A write is taken when both the address and the data are valid and the previous response is gone\n"
+	result += "\tassign bus_wr = s_axi_awvalid && s_axi_wvalid && !s_axi_bvalid;\n"
+	result += "\tassign s_axi_awready = bus_wr;\n"
+	result += "\tassign s_axi_wready = bus_wr;\n"
+	result += "\tassign bus_waddr = s_axi_awaddr;\n"
+	result += "\tassign bus_wdata = s_axi_wdata;\n"
+	result += "\tassign bus_wmask = {{8{s_axi_wstrb[3]}}, {8{s_axi_wstrb[2]}}, {8{s_axi_wstrb[1]}}, {8{s_axi_wstrb[0]}}};\n"
+	result += "\tassign s_axi_bresp = 2'b00;\n"
+	result += "\n"
+	result += "\tassign bus_rd = s_axi_arvalid && !s_axi_rvalid;\n"
+	result += "\tassign s_axi_arready = bus_rd;\n"
+	result += "\tassign bus_raddr = s_axi_araddr;\n"
+	result += "\tassign s_axi_rresp = 2'b00;\n"
+	result += "\n"
+
+	result += bmach.write_verilog_bus_core(rm, bm_module)
+
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif(reset) begin\n"
+	result += "\t\t\ts_axi_bvalid <= #1 1'b0;\n"
+	result += "\t\t\ts_axi_rvalid <= #1 1'b0;\n"
+	result += "\t\t\ts_axi_rdata <= #1 32'b0;\n"
+	result += "\t\tend\n"
+	result += "\t\telse begin\n"
+	result += "\t\t\tif (bus_wr)\n"
+	result += "\t\t\t\ts_axi_bvalid <= #1 1'b1;\n"
+	result += "\t\t\telse if (s_axi_bready)\n"
+	result += "\t\t\t\ts_axi_bvalid <= #1 1'b0;\n"
+	result += "\t\t\tif (bus_rd) begin\n"
+	result += "\t\t\t\ts_axi_rvalid <= #1 1'b1;\n"
+	result += "\t\t\t\ts_axi_rdata <= #1 bus_rdata;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\telse if (s_axi_rready)\n"
+	result += "\t\t\t\ts_axi_rvalid <= #1 1'b0;\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+	result += "endmodule\n"
+
+	return result, nil
+}
+
+// Write_verilog_wishbone writes a Wishbone classic slave embedding the bondmachine module, every cycle is
+// acknowledged the clock after the strobe
+func (bmach *Bondmachine) Write_verilog_wishbone(module_name string, bm_module string) (string, error) {
+	rm, err := bmach.Bus_regmap()
+	if err != nil {
+		return "", err
+	}
+	aw := strconv.Itoa(rm.Addr_bits() - 1)
+
+	result := "\n"
+	result += "`timescale 1ns/1ps\n"
+	result += "module " + module_name + "(wb_clk_i, wb_rst_i, wb_adr_i, wb_dat_i, wb_dat_o, wb_sel_i, wb_we_i, wb_stb_i, wb_cyc_i, wb_ack_o, irq);\n"
+	result += "\n"
+	result += "\t//--------------Input Ports-----------------------\n"
+	result += "\tinput wb_clk_i;\n"
+	result += "\tinput wb_rst_i;\n"
+	result += "\tinput [" + aw + ":0] wb_adr_i;\n"
+	result += "\tinput [31:0] wb_dat_i;\n"
+	result += "\tinput [3:0] wb_sel_i;\n"
+	result += "\tinput wb_we_i;\n"
+	result += "\tinput wb_stb_i;\n"
+	result += "\tinput wb_cyc_i;\n"
+	result += "\n"
+	result += "\t//--------------Output Ports-----------------------\n"
+	result += "\toutput reg [31:0] wb_dat_o;\n"
+	result += "\toutput reg wb_ack_o;\n"
+	result += "\toutput irq;\n"
+	result += "\n"
+	result += "\twire clk;\n"
+	result += "\twire reset;\n"
+	result += "\twire bus_req;\n"
+	result += "\twire bus_wr;\n"
+	result += "\twire bus_rd;\n"
+	result += "\twire [" + aw + ":0] bus_waddr;\n"
+	result += "\twire [" + aw + ":0] bus_raddr;\n"
+	result += "\twire [31:0] bus_wdata;\n"
+	result += "\twire [31:0] bus_wmask;\n"
+	result += "\n"
+	result += "\tassign clk = wb_clk_i;\n"
+	result += "\tassign reset = wb_rst_i;\n"
+	result += "\n"
+	result += "\tassign bus_req = wb_cyc_i && wb_stb_i && !wb_ack_o;\n"
+	result += "\tassign bus_wr = bus_req && wb_we_i;\n"
+	result += "\tassign bus_rd = bus_req && !wb_we_i;\n"
+	result += "\tassign bus_waddr = wb_adr_i;\n"
+	result += "\tassign bus_raddr = wb_adr_i;\n"
+	result += "\tassign bus_wdata = wb_dat_i;\n"
+	result += "\tassign bus_wmask = {{8{wb_sel_i[3]}}, {8{wb_sel_i[2]}}, {8{wb_sel_i[1]}}, {8{wb_sel_i[0]}}};\n"
+	result += "\n"
+
+	result += bmach.write_verilog_bus_core(rm, bm_module)
+
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif(reset) begin\n"
+	result += "\t\t\twb_ack_o <= #1 1'b0;\n"
+	result += "\t\t\twb_dat_o <= #1 32'b0;\n"
+	result += "\t\tend\n"
+	result += "\t\telse begin\n"
+	result += "\t\t\twb_ack_o <= #1 bus_req;\n"
+	result += "\t\t\tif (bus_rd)\n"
+	result += "\t\t\t\twb_dat_o <= #1 bus_rdata;\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+	result += "endmodule\n"
+
+	return result, nil
+}
+
+// Write_bus_c_header writes the register map as C defines, the names start with the prefix
+func (bmach *Bondmachine) Write_bus_c_header(prefix string) (string, error) {
+	rm, err := bmach.Bus_regmap()
+	if err != nil {
+		return "", err
+	}
+	prefix = strings.ToUpper(prefix)
+
+	result := "/* Register map of the " + strings.ToLower(prefix) + " bus wrapper, generated by bondmachine */\n"
+	result += "\n"
+	result += "#ifndef " + prefix + "_REGS_H\n"
+	result += "#define " + prefix + "_REGS_H\n"
+	result += "\n"
+	result += "#include <stdint.h>\n"
+	result += "\n"
+	result += fmt.Sprintf("#define %s_ID_VALUE 0x%08xu\n", prefix, BUS_ID)
+	result += fmt.Sprintf("#define %s_INPUTS %d\n", prefix, rm.Inputs)
+	result += fmt.Sprintf("#define %s_OUTPUTS %d\n", prefix, rm.Outputs)
+	result += fmt.Sprintf("#define %s_RSIZE %d\n", prefix, rm.Rsize)
+	result += fmt.Sprintf("#define %s_IO_WORDS %d\n", prefix, rm.Io_words)
+	result += fmt.Sprintf("#define %s_SIZE 0x%x\n", prefix, rm.Size)
+	result += "\n"
+	for _, reg := range rm.Registers {
+		result += fmt.Sprintf("#define %s_%s 0x%02x /* %s %s */\n", prefix, reg.Name, reg.Offset, reg.Access, reg.Descr)
+	}
+	result += "\n"
+	result += fmt.Sprintf("#define %s_CONTROL_RESET (1u << 0)\n", prefix)
+	result += fmt.Sprintf("#define %s_CONTROL_IRQ_ENABLE (1u << 1)\n", prefix)
+	result += "\n"
+	result += "#define " + prefix + "_REG(base, offset) (*(volatile uint32_t *)((uintptr_t)(base) + (offset)))\n"
+	result += "\n"
+	result += "#endif\n"
+
+	return result, nil
+}
+
+// Write_bus_go_regmap writes the register map as a Go package
+func (bmach *Bondmachine) Write_bus_go_regmap(pkg string) (string, error) {
+	rm, err := bmach.Bus_regmap()
+	if err != nil {
+		return "", err
+	}
+
+	result := "// Code generated by bondmachine. DO NOT EDIT.\n"
+	result += "\n"
+	result += "// Package " + pkg + " has the register map of the bondmachine bus wrapper\n"
+	result += "package " + pkg + "\n"
+	result += "\n"
+	result += "const (\n"
+	result += fmt.Sprintf("ID_VALUE = 0x%08x\n", BUS_ID)
+	result += fmt.Sprintf("INPUTS = %d\n", rm.Inputs)
+	result += fmt.Sprintf("OUTPUTS = %d\n", rm.Outputs)
+	result += fmt.Sprintf("RSIZE = %d\n", rm.Rsize)
+	result += fmt.Sprintf("IO_WORDS = %d\n", rm.Io_words)
+	result += fmt.Sprintf("SIZE = 0x%x\n", rm.Size)
+	result += ")\n"
+	result += "\n"
+	result += "const (\n"
+	for _, reg := range rm.Registers {
+		result += fmt.Sprintf("%s = 0x%02x // %s %s\n", reg.Name, reg.Offset, reg.Access, reg.Descr)
+	}
+	result += ")\n"
+	result += "\n"
+	result += "const (\n"
+	result += "CONTROL_RESET = 1 << 0\n"
+	result += "CONTROL_IRQ_ENABLE = 1 << 1\n"
+	result += ")\n"
+	result += "\n"
+	result += "type Register struct {\n"
+	result += "Name string\n"
+	result += "Offset uint32\n"
+	result += "Words int\n"
+	result += "Access string\n"
+	result += "}\n"
+	result += "\n"
+	result += "var Registers = []Register{\n"
+	for _, reg := range rm.Registers {
+		result += fmt.Sprintf("{%q, %s, %d, %q},\n", reg.Name, reg.Name, reg.Words, reg.Access)
+	}
+	result += "}\n"
+
+	source, err := format.Source([]byte(result))
+	if err != nil {
+		return "", err
+	}
+	return string(source), nil
+}
+
+// Write_bus_wrapper writes the wrapper of the bondmachine module for the bus with the C and the Go register maps
+func (bmach *Bondmachine) Write_bus_wrapper(out *procbuilder.Output, bus string, bm_module string) error {
+	module_name := bm_module + "_" + bus
+	var wrapper string
+	var err error
+	switch bus {
+	case BUS_AXI4LITE:
+		wrapper, err = bmach.Write_verilog_axi4lite(module_name, bm_module)
+	case BUS_WISHBONE:
+		wrapper, err = bmach.Write_verilog_wishbone(module_name, bm_module)
+	default:
+		return Prerror{"Unknown bus " + bus + ", the supported are " + strings.Join(Bus_names(), ",")}
+	}
+	if err != nil {
+		return err
+	}
+	if err := out.Write(module_name+".v", wrapper); err != nil {
+		return err
+	}
+
+	header, err := bmach.Write_bus_c_header(bm_module)
+	if err != nil {
+		return err
+	}
+	if err := out.Write(bm_module+"_regs.h", header); err != nil {
+		return err
+	}
+
+	regmap, err := bmach.Write_bus_go_regmap(bm_module + "_regs")
+	if err != nil {
+		return err
+	}
+	return out.Write(bm_module+"_regs.go", regmap)
+}
@@ -0,0 +1,81 @@
+package bondmachine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBusWrapper(t *testing.T) {
+	bmach := new(Bondmachine)
+	bmach.Rsize = 8
+	bmach.Inputs = 2
+	bmach.Outputs = 1
+
+	rm, err := bmach.Bus_regmap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, reg := range rm.Registers {
+		fmt.Println(reg.Name, reg.Offset, reg.Words, reg.Access)
+	}
+	expected := map[string]int{"ID": 0x00, "CONTROL": 0x08, "IN_WRITTEN": 0x0c, "IRQ_STATUS": 0x14, "I0": 0x20, "I1": 0x24, "O0": 0x28}
+	for name, offset := range expected {
+		if reg, ok := rm.Find(name); !ok || reg.Offset != offset {
+			t.Error("Wrong offset of "+name, reg.Offset)
+		}
+	}
+	if rm.Addr_bits() != 6 {
+		t.Error("Wrong address width", rm.Addr_bits())
+	}
+
+	for _, bus := range Bus_names() {
+		var wrapper string
+		if bus == BUS_AXI4LITE {
+			wrapper, err = bmach.Write_verilog_axi4lite("bondmachine_"+bus, "bondmachine")
+		} else {
+			wrapper, err = bmach.Write_verilog_wishbone("bondmachine_"+bus, "bondmachine")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, line := range []string{"bondmachine bm_inst(clk, bm_reset, i0, i1, o0);", "4'd10: bus_rdata = {24'b0, o0[7:0]};", "4'd8: begin", "out_valid[0] <= #1 1'b0;"} {
+			if !strings.Contains(wrapper, line) {
+				t.Error(bus + " wrapper without: " + line)
+			}
+		}
+	}
+
+	header, err := bmach.Write_bus_c_header("bondmachine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(header, "#define BONDMACHINE_O0 0x28") {
+		t.Error("Missing output define in the C header")
+	}
+
+	regmap, err := bmach.Write_bus_go_regmap("bondmachine_regs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(regmap, "{\"O0\", O0, 1, \"ro\"}") {
+		t.Error("Missing output in the Go register map")
+	}
+
+	// A register larger than a word is staged and latched
+	bmach.Rsize = 40
+	wrapper, err := bmach.Write_verilog_wishbone("bondmachine_wishbone", "bondmachine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"reg [39:32] i0_staged;", "i0[39:32] <= #1 i0_staged;", "o0_latched <= #1 o0[39:32];", "bus_rdata = {24'b0, o0_latched[39:32]};"} {
+		if !strings.Contains(wrapper, line) {
+			t.Error("40 bits wrapper without: " + line)
+		}
+	}
+
+	bmach.Rsize = 65
+	if _, err := bmach.Bus_regmap(); err == nil {
+		t.Error("A 65 bits register accepted")
+	}
+}
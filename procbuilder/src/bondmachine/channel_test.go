@@ -0,0 +1,84 @@
+package bondmachine
+
+import (
+	"fmt"
+	"procbuilder"
+	"sort"
+	"testing"
+)
+
+func channel_machine(program string) (*procbuilder.Machine, error) {
+	mach := new(procbuilder.Machine)
+	arch := &mach.Arch
+	arch.Modes = []string{"ha"}
+	arch.Rsize = 8
+	arch.R = 2
+	arch.L = 4
+	arch.O = 4
+	arch.Shared_constraints = "channel:"
+	arch.Op = make([]procbuilder.Opcode, 0)
+	for _, op := range procbuilder.Allopcodes {
+		switch op.Op_get_name() {
+		case "chw", "inc", "j", "rset", "wrd", "wwr":
+			arch.Op = append(arch.Op, op)
+		}
+	}
+	sort.Sort(procbuilder.ByName(arch.Op))
+	var err error
+	mach.Program, err = mach.Assembler([]byte(program))
+	return mach, err
+}
+
+// A producer sends 1, 2, 3... to a slower consumer. On the rendezvous channel the producer waits for every read, on the
+// buffered one it runs ahead by the channel depth.
+func TestChannels(t *testing.T) {
+	producer, err := channel_machine("rset r0 1\nwwr r0 ch0\nchw r1\ninc r0\nj 1\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumer, err := channel_machine("wrd r2 ch0\nchw r1\ninc r3\ninc r3\ninc r3\ninc r3\nj 0\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, so := range []string{"channel:", "channel:3"} {
+		bmach := new(Bondmachine)
+		bmach.Rsize = 8
+		bmach.Init()
+		bmach.Domains = append(bmach.Domains, producer, consumer)
+		bmach.Add_processor(0)
+		bmach.Add_processor(1)
+		bmach.Add_shared_objects([]string{so})
+		bmach.Connect_processor_shared_object([]string{"0", "0"})
+		bmach.Connect_processor_shared_object([]string{"1", "0"})
+
+		vm := new(VM)
+		vm.Bmach = bmach
+		if err := vm.Init(); err != nil {
+			t.Fatal(err)
+		}
+		vm.Launch_processors(nil)
+
+		received := make([]int, 0)
+		for i := 0; i < 60; i++ {
+			if _, err := vm.Step(nil); err != nil {
+				t.Fatal(err)
+			}
+			value := int(vm.Processors[1].Registers[2].(uint8))
+			if len(received) == 0 && value != 0 || len(received) > 0 && value != received[len(received)-1] {
+				received = append(received, value)
+			}
+		}
+		fmt.Println(so, received, vm.Processors[0].Registers[0], "in the buffer:", len(vm.Channels.Channels[0].Fifo))
+
+		for i, value := range received {
+			if value != i+1 {
+				t.Fatal(so+": wrong sequence", received)
+			}
+		}
+		ahead := int(vm.Processors[0].Registers[0].(uint8)) - received[len(received)-1]
+		if so == "channel:" && ahead > 1 || so == "channel:3" && ahead < 3 {
+			t.Error(so+": wrong producer advance", ahead)
+		}
+	}
+}
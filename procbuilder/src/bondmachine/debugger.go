@@ -0,0 +1,49 @@
+package bondmachine
+
+import (
+	"procbuilder"
+)
+
+// The debug target of a bondmachine, the processors have to be launched. A tick moves the bonds as a simulation step,
+// the single processor steps do not.
+type Vm_target struct {
+	Vm    *VM
+	Drive *Sim_drive
+}
+
+func (t *Vm_target) Processors() []*procbuilder.VM {
+	return t.Vm.Processors
+}
+
+func (t *Vm_target) Tick() error {
+	if t.Drive != nil {
+		if act, exist_actions := t.Drive.AbsSet[t.Vm.abs_tick]; exist_actions {
+			for k, val := range act {
+				*t.Drive.Injectables[k] = val
+			}
+		}
+	}
+	_, err := t.Vm.Step(nil)
+	return err
+}
+
+func (t *Vm_target) Ticks() uint64 {
+	return t.Vm.abs_tick
+}
+
+func (t *Vm_target) Snapshot() interface{} {
+	state := new(VM)
+	state.Bmach = t.Vm.Bmach
+	state.Timing = t.Vm.Timing
+	state.Init()
+	state.CopyState(t.Vm)
+	return state
+}
+
+func (t *Vm_target) Restore(snapshot interface{}) {
+	t.Vm.CopyState(snapshot.(*VM))
+}
+
+func (t *Vm_target) Element(name string) (*interface{}, error) {
+	return t.Vm.Get_element_location(name)
+}
@@ -3,6 +3,7 @@ package bondmachine
 import (
 	"encoding/json"
 	"errors"
+	"procbuilder"
 )
 
 type B37s struct {
@@ -45,3 +46,35 @@ func (sl *B37s) Static_verilog() string {
 func (sl *B37s) ExtraFiles() ([]string, []string) {
 	return []string{}, []string{}
 }
+
+func (sl *B37s) Estimate_resources(bmach *Bondmachine, flavor string) procbuilder.Resources {
+	// The digits multiplexing counter and the decoders
+	return procbuilder.Resources{Luts: 60, Ffs: 20, Levels: 3}
+}
+
+func (sl *B37s) Get_Ports() []Extra_port {
+	return []Extra_port{
+		{Name: "segment", Width: 7},
+		{Name: "enable_D1", Width: 1},
+		{Name: "enable_D2", Width: 1},
+		{Name: "enable_D3", Width: 1},
+		{Name: "enable_D4", Width: 1},
+		{Name: "dp", Width: 1},
+	}
+}
+
+func (sl *B37s) Get_IOs(bmach *Bondmachine) ([]string, []string) {
+	return []string{}, []string{sl.Mapped_output}
+}
+
+func (sl *B37s) Get_Clocking() Extra_clocking {
+	return Extra_clocking{Clock: "board", Reset: true}
+}
+
+func (sl *B37s) Get_Conflicts() []string {
+	return []string{}
+}
+
+func (sl *B37s) Board_verilog(bmach *Bondmachine, clock string, reset string, ios map[string]string) (string, string) {
+	return "", "\tbond2seg bond2seg_inst(" + clock + ", " + reset + ", " + ios[sl.Mapped_output] + ", segment ,enable_D1, enable_D2, enable_D3, enable_D4, dp);\n"
+}
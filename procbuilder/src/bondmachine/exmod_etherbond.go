@@ -2,6 +2,7 @@ package bondmachine
 
 import (
 	"etherbond"
+	"procbuilder"
 	//"fmt"
 	"strconv"
 )
@@ -964,3 +965,52 @@ func (sl *Etherbond_extra) Static_verilog() string {
 func (sl *Etherbond_extra) ExtraFiles() ([]string, []string) {
 	return []string{}, []string{}
 }
+
+func (sl *Etherbond_extra) Estimate_resources(bmach *Bondmachine, flavor string) procbuilder.Resources {
+	// The ENC28J60 SPI driver, the frame buffer and a register for every transported IO
+	ios := (bmach.Inputs + bmach.Outputs) * int(bmach.Rsize)
+	return procbuilder.Resources{Luts: 1500 + ios, Ffs: 1200 + ios, Levels: 6}.Merge(procbuilder.Memory_cost(1536, 8, flavor))
+}
+
+func (sl *Etherbond_extra) Get_Ports() []Extra_port {
+	return []Extra_port{
+		{Name: "sck", Width: 1},
+		{Name: "mosi", Width: 1},
+		{Name: "cs_n", Width: 1},
+		{Name: "miso", Input: true, Width: 1},
+		{Name: "int_n", Input: true, Width: 1},
+	}
+}
+
+// The inputs received from the cluster and the outputs sent to it
+func (sl *Etherbond_extra) Get_IOs(bmach *Bondmachine) ([]string, []string) {
+	params := sl.Get_Params().Params
+	return split_names(params["inputs"]), split_names(params["outputs"])
+}
+
+func (sl *Etherbond_extra) Get_Clocking() Extra_clocking {
+	return Extra_clocking{Clock: "board", Reset: true}
+}
+
+// Only one network module drives the cluster IOs
+func (sl *Etherbond_extra) Get_Conflicts() []string {
+	return []string{"udpbond"}
+}
+
+func (sl *Etherbond_extra) Board_verilog(bmach *Bondmachine, clock string, reset string, ios map[string]string) (string, string) {
+	inputs, outputs := sl.Get_IOs(bmach)
+	result := "\tetherbond_main etherbond_main_inst " + "(" + clock + ", " + reset
+	result += ", sck"
+	result += ", mosi"
+	result += ", cs_n"
+	result += ", miso"
+	result += ", int_n"
+	for _, iname := range inputs {
+		result += ", " + ios[iname]
+	}
+	for _, oname := range outputs {
+		result += ", " + ios[oname]
+	}
+	result += ");\n"
+	return "", result
+}
@@ -3,6 +3,7 @@ package bondmachine
 import (
 	"encoding/json"
 	"errors"
+	"procbuilder"
 	"strconv"
 )
 
@@ -53,3 +54,32 @@ func (sl *Slow_extra) Static_verilog() string {
 func (sl *Slow_extra) ExtraFiles() ([]string, []string) {
 	return []string{}, []string{}
 }
+
+func (sl *Slow_extra) Estimate_resources(bmach *Bondmachine, flavor string) procbuilder.Resources {
+	// The clock divider counter
+	return procbuilder.Resources{Luts: 32, Ffs: 32, Levels: 2}
+}
+
+func (sl *Slow_extra) Get_Ports() []Extra_port {
+	return []Extra_port{}
+}
+
+func (sl *Slow_extra) Get_IOs(bmach *Bondmachine) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+// The bondmachine runs on a bit of the divider counter
+func (sl *Slow_extra) Get_Clocking() Extra_clocking {
+	return Extra_clocking{Clock: "board", Drive_clock: "divider[" + sl.Slow_factor + "]"}
+}
+
+func (sl *Slow_extra) Get_Conflicts() []string {
+	return []string{}
+}
+
+func (sl *Slow_extra) Board_verilog(bmach *Bondmachine, clock string, reset string, ios map[string]string) (string, string) {
+	result := "\talways @ (posedge " + clock + ") begin\n"
+	result += "\t\tdivider <= divider + 1;\n"
+	result += "\tend\n"
+	return "\treg [31:0] divider;\n\n", result
+}
@@ -0,0 +1,390 @@
+package bondmachine
+
+import (
+	"encoding/json"
+	"errors"
+	"procbuilder"
+	"strconv"
+	"strings"
+)
+
+// The uart module exposes the chosen bondmachine IOs on a serial line, the protocol is in uart.go
+type Uart_extra struct {
+	Inputs    []string // The inputs the host sets
+	Outputs   []string // The outputs sent to the host
+	Baud_rate int      // 115200 when 0
+}
+
+func (sl *Uart_extra) Get_Name() string {
+	return "uart"
+}
+
+func (sl *Uart_extra) Get_Params() *ExtraParams {
+	result := new(ExtraParams)
+	result.Params = map[string]string{
+		"inputs":    strings.Join(sl.Inputs, ","),
+		"outputs":   strings.Join(sl.Outputs, ","),
+		"baud_rate": strconv.Itoa(sl.baud()),
+	}
+	return result
+}
+
+func (sl *Uart_extra) Import(inp string) error {
+	if err := json.Unmarshal([]byte(inp), sl); err != nil {
+		return errors.New("Unmarshalling failed")
+	}
+	return nil
+}
+
+func (sl *Uart_extra) Export() string {
+	b, _ := json.Marshal(sl)
+	return string(b)
+}
+
+func (sl *Uart_extra) baud() int {
+	if sl.Baud_rate == 0 {
+		return 115200
+	}
+	return sl.Baud_rate
+}
+
+// The protocol number of an IO name
+func uart_io_index(ioname string) (int, error) {
+	if len(ioname) > 1 {
+		if index, err := strconv.Atoi(ioname[1:]); err == nil && index >= 0 {
+			if index >= UART_MAX_IO {
+				return 0, Prerror{"IO " + ioname + " out of the uart protocol range"}
+			}
+			return index, nil
+		}
+	}
+	return 0, Prerror{"Wrong IO name " + ioname}
+}
+
+func (sl *Uart_extra) Check(bmach *Bondmachine) error {
+	if sl.Baud_rate < 0 {
+		return errors.New("Negative baud rate")
+	}
+	for _, ioname := range append(append([]string{}, sl.Inputs...), sl.Outputs...) {
+		if _, err := uart_io_index(ioname); err != nil {
+			return err
+		}
+		if !bmach.has_io(ioname) {
+			return Prerror{"Unknown IO " + ioname}
+		}
+	}
+	return nil
+}
+
+func (sl *Uart_extra) Verilog_headers() string {
+	return ""
+}
+
+func (sl *Uart_extra) Static_verilog() string {
+	return ""
+}
+
+func (sl *Uart_extra) ExtraFiles() ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (sl *Uart_extra) Estimate_resources(bmach *Bondmachine, flavor string) procbuilder.Resources {
+	// The receiver, the transmitter and a register for every IO
+	ios := (len(sl.Inputs) + 2*len(sl.Outputs)) * int(bmach.Rsize)
+	return procbuilder.Resources{Luts: 150 + ios, Ffs: 120 + ios, Levels: 4}
+}
+
+func (sl *Uart_extra) Get_Ports() []Extra_port {
+	return []Extra_port{
+		{Name: "uart_rx", Input: true, Width: 1},
+		{Name: "uart_tx", Width: 1},
+	}
+}
+
+func (sl *Uart_extra) Get_IOs(bmach *Bondmachine) ([]string, []string) {
+	return sl.Inputs, sl.Outputs
+}
+
+func (sl *Uart_extra) Get_Clocking() Extra_clocking {
+	return Extra_clocking{Clock: "board", Reset: true}
+}
+
+func (sl *Uart_extra) Get_Conflicts() []string {
+	return []string{}
+}
+
+func (sl *Uart_extra) Board_verilog(bmach *Bondmachine, clock string, reset string, ios map[string]string) (string, string) {
+	result := "\tuart_bridge uart_bridge_inst(" + clock + ", " + reset + ", uart_rx, uart_tx"
+	for _, ioname := range append(append([]string{}, sl.Inputs...), sl.Outputs...) {
+		result += ", " + ios[ioname]
+	}
+	result += ");\n"
+	return "", result
+}
+
+// Write_verilog_uart writes the bridge between the serial line and the IOs, it runs on the board clock
+func (bmach *Bondmachine) Write_verilog_uart(module_name string, flavor string, iomaps *IOmap, extramods []ExtraModule) (string, error) {
+	var sl *Uart_extra
+	for _, mod := range extramods {
+		if mod.Get_Name() == "uart" {
+			sl = mod.(*Uart_extra)
+		}
+	}
+	if sl == nil {
+		return "", errors.New("No uart module found")
+	}
+
+	board, ok := Allboards[flavor]
+	if !ok || board.Clock_period <= 0 {
+		return "", Prerror{"The uart module needs the clock period of the board " + flavor}
+	}
+	clks_per_bit := int(1e9/(board.Clock_period*float64(sl.baud())) + 0.5)
+	if clks_per_bit < 4 {
+		return "", Prerror{"Baud rate " + strconv.Itoa(sl.baud()) + " too high for the board clock"}
+	}
+
+	rsize := int(bmach.Rsize)
+	nbytes := Uart_value_bytes(bmach.Rsize)
+	vw := strconv.Itoa(nbytes*8 - 1)
+	rs := strconv.Itoa(rsize - 1)
+	pad := ""
+	if nbytes*8 > rsize {
+		pad = "{" + strconv.Itoa(nbytes*8-rsize) + "'b0}, "
+	}
+
+	ports := ""
+	decls := ""
+	for _, iname := range sl.Inputs {
+		ports += ", in_" + iname
+		decls += "\toutput reg [" + rs + ":0] in_" + iname + ";\n"
+	}
+	for _, oname := range sl.Outputs {
+		ports += ", out_" + oname
+		decls += "\tinput [" + rs + ":0] out_" + oname + ";\n"
+	}
+
+	result := "\n"
+	result += "module uart_bridge(clk, reset, rx, tx" + ports + ");\n"
+	result += "\n"
+	result += "\tinput clk;\n"
+	result += "\tinput reset;\n"
+	result += "\tinput rx;\n"
+	result += "\toutput tx;\n"
+	result += decls
+	result += "\n"
+	result += "\tlocalparam CLKS_PER_BIT = " + strconv.Itoa(clks_per_bit) + ";\n"
+	result += "\tlocalparam [7:0] SYNC = 8'h" + strconv.FormatInt(UART_SYNC, 16) + ";\n"
+	result += "\tlocalparam [1:0] VALUE = 2'd" + strconv.Itoa(int(UART_VALUE)) + ", ACK = 2'd" + strconv.Itoa(int(UART_ACK)) + ", READ = 2'd" + strconv.Itoa(int(UART_READ)) + ";\n"
+	result += "\tlocalparam NBYTES = " + strconv.Itoa(nbytes) + ";\n"
+	result += "\n"
+
+	// The bytes receiver, 8N1 sampled in the middle of the bits
+	result += "\treg rx_d1, rx_d2;\n"
+	result += "\treg [1:0] rx_state;\n"
+	result += "\treg [31:0] rx_count;\n"
+	result += "\treg [2:0] rx_bit;\n"
+	result += "\treg [7:0] rx_shift;\n"
+	result += "\treg rx_valid;\n"
+	result += "\treg [7:0] rx_data;\n"
+	result += "\n"
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif (reset) begin\n"
+	result += "\t\t\trx_d1 <= 1'b1;\n"
+	result += "\t\t\trx_d2 <= 1'b1;\n"
+	result += "\t\t\trx_state <= 2'd0;\n"
+	result += "\t\t\trx_count <= 0;\n"
+	result += "\t\t\trx_bit <= 3'd0;\n"
+	result += "\t\t\trx_valid <= 1'b0;\n"
+	result += "\t\tend\n"
+	result += "\t\telse begin\n"
+	result += "\t\t\trx_d1 <= rx;\n"
+	result += "\t\t\trx_d2 <= rx_d1;\n"
+	result += "\t\t\trx_valid <= 1'b0;\n"
+	result += "\t\t\tcase (rx_state)\n"
+	result += "\t\t\t2'd0: if (!rx_d2) begin\n"
+	result += "\t\t\t\trx_state <= 2'd1;\n"
+	result += "\t\t\t\trx_count <= CLKS_PER_BIT / 2;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\t2'd1: if (rx_count == 0) begin\n"
+	result += "\t\t\t\trx_state <= rx_d2 ? 2'd0 : 2'd2;\n"
+	result += "\t\t\t\trx_count <= CLKS_PER_BIT - 1;\n"
+	result += "\t\t\t\trx_bit <= 3'd0;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\telse rx_count <= rx_count - 1;\n"
+	result += "\t\t\t2'd2: if (rx_count == 0) begin\n"
+	result += "\t\t\t\trx_shift <= {rx_d2, rx_shift[7:1]};\n"
+	result += "\t\t\t\trx_count <= CLKS_PER_BIT - 1;\n"
+	result += "\t\t\t\trx_bit <= rx_bit + 1;\n"
+	result += "\t\t\t\tif (rx_bit == 3'd7)\n"
+	result += "\t\t\t\t\trx_state <= 2'd3;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\telse rx_count <= rx_count - 1;\n"
+	result += "\t\t\t2'd3: if (rx_count == 0) begin\n"
+	result += "\t\t\t\trx_state <= 2'd0;\n"
+	result += "\t\t\t\tif (rx_d2) begin\n"
+	result += "\t\t\t\t\trx_valid <= 1'b1;\n"
+	result += "\t\t\t\t\trx_data <= rx_shift;\n"
+	result += "\t\t\t\tend\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\telse rx_count <= rx_count - 1;\n"
+	result += "\t\t\tendcase\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+
+	// The frames, the transmitter sends a whole frame before choosing the next one: the pending acks first, then
+	// the outputs asked by the host and the changed ones whose previous value was acknowledged
+	nouts := strconv.Itoa(len(sl.Outputs))
+	result += "\treg [1:0] dec_state;\n"
+	result += "\treg [7:0] dec_header;\n"
+	result += "\treg [3:0] dec_count;\n"
+	result += "\treg [" + vw + ":0] dec_value;\n"
+	result += "\treg dec_done;\n"
+	result += "\treg ack_req;\n"
+	result += "\treg [5:0] ack_index;\n"
+	if len(sl.Outputs) > 0 {
+		result += "\treg [" + nouts + "-1:0] pending;\n"
+		result += "\treg [" + nouts + "-1:0] read_req;\n"
+	}
+	for _, oname := range sl.Outputs {
+		result += "\treg [" + rs + ":0] sent_" + oname + ";\n"
+		result += "\twire [" + vw + ":0] value_" + oname + " = {" + pad + "out_" + oname + "};\n"
+	}
+	result += "\treg [7:0] tx_frame [0:NBYTES+1];\n"
+	result += "\treg [3:0] tx_len;\n"
+	result += "\treg [3:0] tx_pos;\n"
+	result += "\treg tx_busy;\n"
+	result += "\treg [31:0] tx_count;\n"
+	result += "\treg [3:0] tx_bit;\n"
+	result += "\treg [8:0] tx_shift;\n"
+	result += "\treg tx_reg;\n"
+	result += "\n"
+	result += "\tassign tx = tx_reg;\n"
+	result += "\n"
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif (reset) begin\n"
+	result += "\t\t\tdec_state <= 2'd0;\n"
+	result += "\t\t\tdec_done <= 1'b0;\n"
+	result += "\t\t\tack_req <= 1'b0;\n"
+	if len(sl.Outputs) > 0 {
+		result += "\t\t\tpending <= 0;\n"
+		result += "\t\t\tread_req <= 0;\n"
+	}
+	for _, iname := range sl.Inputs {
+		result += "\t\t\tin_" + iname + " <= 0;\n"
+	}
+	for _, oname := range sl.Outputs {
+		result += "\t\t\tsent_" + oname + " <= 0;\n"
+	}
+	result += "\t\t\ttx_len <= 4'd0;\n"
+	result += "\t\t\ttx_pos <= 4'd0;\n"
+	result += "\t\t\ttx_busy <= 1'b0;\n"
+	result += "\t\t\ttx_reg <= 1'b1;\n"
+	result += "\t\tend\n"
+	result += "\t\telse begin\n"
+
+	// Transmitter
+	result += "\t\t\tif (tx_busy) begin\n"
+	result += "\t\t\t\tif (tx_count == 0) begin\n"
+	result += "\t\t\t\t\tif (tx_bit == 4'd9) begin\n"
+	result += "\t\t\t\t\t\ttx_busy <= 1'b0;\n"
+	result += "\t\t\t\t\t\ttx_pos <= tx_pos + 1;\n"
+	result += "\t\t\t\t\tend\n"
+	result += "\t\t\t\t\telse begin\n"
+	result += "\t\t\t\t\t\ttx_reg <= tx_shift[0];\n"
+	result += "\t\t\t\t\t\ttx_shift <= {1'b1, tx_shift[8:1]};\n"
+	result += "\t\t\t\t\t\ttx_bit <= tx_bit + 1;\n"
+	result += "\t\t\t\t\t\ttx_count <= CLKS_PER_BIT - 1;\n"
+	result += "\t\t\t\t\tend\n"
+	result += "\t\t\t\tend\n"
+	result += "\t\t\t\telse tx_count <= tx_count - 1;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\telse if (tx_pos < tx_len) begin\n"
+	result += "\t\t\t\ttx_shift <= {1'b1, tx_frame[tx_pos]};\n"
+	result += "\t\t\t\ttx_reg <= 1'b0;\n"
+	result += "\t\t\t\ttx_bit <= 4'd0;\n"
+	result += "\t\t\t\ttx_count <= CLKS_PER_BIT - 1;\n"
+	result += "\t\t\t\ttx_busy <= 1'b1;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\telse if (ack_req) begin\n"
+	result += "\t\t\t\ttx_frame[0] <= SYNC;\n"
+	result += "\t\t\t\ttx_frame[1] <= {ACK, ack_index};\n"
+	result += "\t\t\t\ttx_len <= 4'd2;\n"
+	result += "\t\t\t\ttx_pos <= 4'd0;\n"
+	result += "\t\t\t\tack_req <= 1'b0;\n"
+	result += "\t\t\tend\n"
+	for k, oname := range sl.Outputs {
+		ks := strconv.Itoa(k)
+		index, _ := uart_io_index(oname)
+		result += "\t\t\telse if (read_req[" + ks + "] || (!pending[" + ks + "] && out_" + oname + " != sent_" + oname + ")) begin\n"
+		result += "\t\t\t\ttx_frame[0] <= SYNC;\n"
+		result += "\t\t\t\ttx_frame[1] <= {VALUE, 6'd" + strconv.Itoa(index) + "};\n"
+		for b := 0; b < nbytes; b++ {
+			hi := strconv.Itoa((nbytes-b)*8 - 1)
+			lo := strconv.Itoa((nbytes - b - 1) * 8)
+			result += "\t\t\t\ttx_frame[" + strconv.Itoa(2+b) + "] <= value_" + oname + "[" + hi + ":" + lo + "];\n"
+		}
+		result += "\t\t\t\ttx_len <= NBYTES + 2;\n"
+		result += "\t\t\t\ttx_pos <= 4'd0;\n"
+		result += "\t\t\t\tsent_" + oname + " <= out_" + oname + ";\n"
+		result += "\t\t\t\tpending[" + ks + "] <= 1'b1;\n"
+		result += "\t\t\t\tread_req[" + ks + "] <= 1'b0;\n"
+		result += "\t\t\tend\n"
+	}
+	result += "\n"
+
+	// Decoder, after the transmitter so its requests are not lost
+	result += "\t\t\tdec_done <= 1'b0;\n"
+	result += "\t\t\tif (rx_valid) begin\n"
+	result += "\t\t\t\tcase (dec_state)\n"
+	result += "\t\t\t\t2'd0: if (rx_data == SYNC) dec_state <= 2'd1;\n"
+	result += "\t\t\t\t2'd1: begin\n"
+	result += "\t\t\t\t\tdec_header <= rx_data;\n"
+	result += "\t\t\t\t\tdec_count <= 4'd0;\n"
+	result += "\t\t\t\t\tdec_state <= (rx_data[7:6] == VALUE) ? 2'd2 : 2'd0;\n"
+	for k, oname := range sl.Outputs {
+		ks := strconv.Itoa(k)
+		index, _ := uart_io_index(oname)
+		result += "\t\t\t\t\tif (rx_data[5:0] == 6'd" + strconv.Itoa(index) + ") begin\n"
+		result += "\t\t\t\t\t\tif (rx_data[7:6] == ACK) pending[" + ks + "] <= 1'b0;\n"
+		result += "\t\t\t\t\t\tif (rx_data[7:6] == READ) read_req[" + ks + "] <= 1'b1;\n"
+		result += "\t\t\t\t\tend\n"
+	}
+	result += "\t\t\t\tend\n"
+	result += "\t\t\t\t2'd2: begin\n"
+	if nbytes > 1 {
+		result += "\t\t\t\t\tdec_value <= {dec_value[" + strconv.Itoa(nbytes*8-9) + ":0], rx_data};\n"
+	} else {
+		result += "\t\t\t\t\tdec_value <= rx_data;\n"
+	}
+	result += "\t\t\t\t\tdec_count <= dec_count + 1;\n"
+	result += "\t\t\t\t\tif (dec_count == NBYTES - 1) begin\n"
+	result += "\t\t\t\t\t\tdec_state <= 2'd0;\n"
+	result += "\t\t\t\t\t\tdec_done <= 1'b1;\n"
+	result += "\t\t\t\t\tend\n"
+	result += "\t\t\t\tend\n"
+	result += "\t\t\t\tdefault: dec_state <= 2'd0;\n"
+	result += "\t\t\t\tendcase\n"
+	result += "\t\t\tend\n"
+	result += "\n"
+	result += "\t\t\t// A complete value frame sets the input and is acknowledged\n"
+	result += "\t\t\tif (dec_done) begin\n"
+	result += "\t\t\t\tcase (dec_header[5:0])\n"
+	for _, iname := range sl.Inputs {
+		index, _ := uart_io_index(iname)
+		result += "\t\t\t\t6'd" + strconv.Itoa(index) + ": begin\n"
+		result += "\t\t\t\t\tin_" + iname + " <= dec_value[" + rs + ":0];\n"
+		result += "\t\t\t\t\tack_req <= 1'b1;\n"
+		result += "\t\t\t\t\tack_index <= dec_header[5:0];\n"
+		result += "\t\t\t\tend\n"
+	}
+	result += "\t\t\t\tdefault: ;\n"
+	result += "\t\t\t\tendcase\n"
+	result += "\t\t\tend\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+	result += "endmodule\n"
+
+	return result, nil
+}
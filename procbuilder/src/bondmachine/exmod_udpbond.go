@@ -2,6 +2,7 @@ package bondmachine
 
 import (
 	"encoding/hex"
+	"procbuilder"
 	"strconv"
 	"strings"
 	"udpbond"
@@ -316,3 +317,45 @@ func (sl *Udpbond_extra) Static_verilog() string {
 func (sl *Udpbond_extra) ExtraFiles() ([]string, []string) {
 	return []string{}, []string{}
 }
+
+func (sl *Udpbond_extra) Estimate_resources(bmach *Bondmachine, flavor string) procbuilder.Resources {
+	// The ESP8266 UART driver, the firmware commands ROM and a register for every transported IO
+	ios := (bmach.Inputs + bmach.Outputs) * int(bmach.Rsize)
+	return procbuilder.Resources{Luts: 900 + ios, Ffs: 700 + ios, Levels: 5}.Merge(procbuilder.Memory_cost(2048, 8, flavor))
+}
+
+func (sl *Udpbond_extra) Get_Ports() []Extra_port {
+	return []Extra_port{
+		{Name: "wifi_enable", Width: 1},
+		{Name: "wifi_rx", Input: true, Width: 1},
+		{Name: "wifi_tx", Width: 1},
+	}
+}
+
+// The inputs received from the cluster and the outputs sent to it
+func (sl *Udpbond_extra) Get_IOs(bmach *Bondmachine) ([]string, []string) {
+	params := sl.Get_Params().Params
+	return split_names(params["inputs"]), split_names(params["outputs"])
+}
+
+func (sl *Udpbond_extra) Get_Clocking() Extra_clocking {
+	return Extra_clocking{Clock: "board", Reset: true}
+}
+
+// Only one network module drives the cluster IOs
+func (sl *Udpbond_extra) Get_Conflicts() []string {
+	return []string{"etherbond"}
+}
+
+func (sl *Udpbond_extra) Board_verilog(bmach *Bondmachine, clock string, reset string, ios map[string]string) (string, string) {
+	inputs, outputs := sl.Get_IOs(bmach)
+	result := "\tudpbond_main udpbond_main (.clk100(" + clock + "), .reset(" + reset + "), .wifi_enable(wifi_enable), .wifi_rx(wifi_rx), .wifi_tx(wifi_tx)"
+	for _, iname := range inputs {
+		result += ", .input_" + iname + "(" + ios[iname] + ")"
+	}
+	for _, oname := range outputs {
+		result += ", .output_" + oname + "(" + ios[oname] + ")"
+	}
+	result += ");\n"
+	return "", result
+}
@@ -0,0 +1,154 @@
+package bondmachine
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The extra modules are composed within the board top level module by Write_verilog_board. Every module declares:
+//
+//   - the ports it adds to the top level module, that the boards map to pins
+//   - the bondmachine inputs it drives and the outputs it reads, by name (i0, o1, ...)
+//   - the clock it runs on, if it uses the reset and if it replaces the bondmachine clock
+//   - the modules it cannot be used together with
+//
+// Board_verilog gets the clock and the reset to use and the wires of the bondmachine IOs, it returns the
+// declarations and the instances to place in the top level module.
+
+type Extra_port struct {
+	Name  string
+	Input bool
+	Width int // 1 for a single bit port
+}
+
+type Extra_clocking struct {
+	Clock       string // "board" for the board clock, "bondmachine" for the one of the bondmachine
+	Reset       bool   // When false the module gets a reset that is never asserted
+	Drive_clock string // A verilog expression replacing the bondmachine clock, empty if the module does not drive it
+}
+
+func (port Extra_port) String() string {
+	result := "output"
+	if port.Input {
+		result = "input"
+	}
+	if port.Width > 1 {
+		result += " [" + strconv.Itoa(port.Width-1) + ":0]"
+	}
+	return result + " " + port.Name
+}
+
+// The bits of a port, as the boards map them
+func (port Extra_port) Bits() []string {
+	if port.Width <= 1 {
+		return []string{port.Name}
+	}
+	result := make([]string, port.Width)
+	for i := range result {
+		result[i] = port.Name + "[" + strconv.Itoa(i) + "]"
+	}
+	return result
+}
+
+// The signal name of a board mapping like "led" or "[7:0] led"
+func assoc_name(assoc string) string {
+	words := strings.Fields(assoc)
+	if len(words) == 0 {
+		return ""
+	}
+	return words[len(words)-1]
+}
+
+// Check_extra_modules verifies that a set of extra modules can be composed on the board described by iomaps
+func (bmach *Bondmachine) Check_extra_modules(iomaps *IOmap, extramods []ExtraModule) error {
+	ports := make(map[string]string)
+	ports["clk"] = "the board"
+	ports["reset"] = "the board"
+	for _, assoc := range iomaps.Assoc {
+		ports[assoc_name(assoc)] = "the board"
+	}
+
+	drivers := make(map[string]string)
+	for ioname, _ := range iomaps.Assoc {
+		drivers[ioname] = "the board"
+	}
+
+	names := make(map[string]bool)
+	clock_driver := ""
+
+	for _, mod := range extramods {
+		name := mod.Get_Name()
+		if names[name] {
+			return Prerror{"Extra module " + name + " used twice"}
+		}
+		names[name] = true
+
+		for _, other := range extramods {
+			for _, conflict := range other.Get_Conflicts() {
+				if conflict == name {
+					return Prerror{"Extra modules " + other.Get_Name() + " and " + name + " cannot be used together"}
+				}
+			}
+		}
+
+		for _, port := range mod.Get_Ports() {
+			if owner, ok := ports[port.Name]; ok {
+				return Prerror{"Port " + port.Name + " of the " + name + " module already used by " + owner}
+			}
+			ports[port.Name] = "the " + name + " module"
+		}
+
+		inputs, outputs := mod.Get_IOs(bmach)
+		for _, iname := range inputs {
+			if !bmach.has_io(iname) || iname[0] != 'i' {
+				return Prerror{"The " + name + " module drives the unknown input " + iname}
+			}
+			if driver, ok := drivers[iname]; ok {
+				return Prerror{"Input " + iname + " driven by both " + driver + " and the " + name + " module"}
+			}
+			drivers[iname] = "the " + name + " module"
+		}
+		for _, oname := range outputs {
+			if !bmach.has_io(oname) || oname[0] != 'o' {
+				return Prerror{"The " + name + " module reads the unknown output " + oname}
+			}
+		}
+
+		clocking := mod.Get_Clocking()
+		if clocking.Clock != "" && clocking.Clock != "board" && clocking.Clock != "bondmachine" {
+			return Prerror{"The " + name + " module needs the unknown clock " + clocking.Clock}
+		}
+		if clocking.Drive_clock != "" {
+			if clock_driver != "" {
+				return Prerror{"The bondmachine clock is driven by both the " + clock_driver + " and the " + name + " modules"}
+			}
+			clock_driver = name
+		}
+	}
+	return nil
+}
+
+func (bmach *Bondmachine) has_io(ioname string) bool {
+	for i := 0; i < bmach.Inputs; i++ {
+		if Get_input_name(i) == ioname {
+			return true
+		}
+	}
+	for i := 0; i < bmach.Outputs; i++ {
+		if Get_output_name(i) == ioname {
+			return true
+		}
+	}
+	return false
+}
+
+// The names of a comma separated list, the empty ones are skipped
+func split_names(list string) []string {
+	result := make([]string, 0)
+	for _, name := range strings.Split(list, ",") {
+		if name != "" {
+			result = append(result, name)
+		}
+	}
+	return result
+}
@@ -0,0 +1,88 @@
+package bondmachine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// A module reading o1 and driving i1, it cannot be used together with the 7 segments display
+type probe_extra struct {
+	Slow_extra
+	Drive string
+}
+
+func (sl *probe_extra) Get_Name() string {
+	return "probe"
+}
+
+func (sl *probe_extra) Get_Ports() []Extra_port {
+	return []Extra_port{{Name: "probe_out", Width: 8}}
+}
+
+func (sl *probe_extra) Get_IOs(bmach *Bondmachine) ([]string, []string) {
+	return []string{"i1"}, []string{"o1"}
+}
+
+func (sl *probe_extra) Get_Clocking() Extra_clocking {
+	return Extra_clocking{Clock: "bondmachine", Drive_clock: sl.Drive}
+}
+
+func (sl *probe_extra) Get_Conflicts() []string {
+	return []string{"basys3_7segment"}
+}
+
+func (sl *probe_extra) Board_verilog(bmach *Bondmachine, clock string, reset string, ios map[string]string) (string, string) {
+	return "", "\tprobe probe_inst(" + clock + ", " + reset + ", " + ios["o1"] + ", " + ios["i1"] + ", probe_out);\n"
+}
+
+func TestExtraModules(t *testing.T) {
+	bmach := new(Bondmachine)
+	bmach.Rsize = 8
+	bmach.Init()
+	for i := 0; i < 2; i++ {
+		bmach.Add_input()
+		bmach.Add_output()
+	}
+	iomaps := &IOmap{Assoc: map[string]string{"i0": "[7:0] sw", "o0": "[7:0] led"}}
+
+	slow := &Slow_extra{Slow_factor: "3"}
+	probe := new(probe_extra)
+	top, err := bmach.Write_verilog_board("bondmachine", "basys3", iomaps, []ExtraModule{slow, probe})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(top)
+	for _, expected := range []string{"output [7:0] probe_out", "wire [7:0] Input1;", "wire [7:0] Output1;", "bondmachine_inst (divider[3]", "probe_inst(divider[3], 1'b0, Output1, Input1, probe_out)", "divider <= divider + 1;"} {
+		if !strings.Contains(top, expected) {
+			t.Error("Missing " + expected)
+		}
+	}
+
+	for _, mods := range [][]ExtraModule{
+		{slow, slow},
+		{probe, &B37s{Mapped_output: "o0"}},
+		{&B37s{Mapped_output: "o5"}},
+		{&probe_extra{Drive: "divider2[4]"}, slow},
+	} {
+		if _, err := bmach.Write_verilog_board("bondmachine", "basys3", iomaps, mods); err == nil {
+			t.Error("Conflict not reported")
+		} else {
+			fmt.Println(err)
+		}
+	}
+
+	iomaps.Assoc["i1"] = "[7:0] btn"
+	if _, err := bmach.Write_verilog_board("bondmachine", "basys3", iomaps, []ExtraModule{probe}); err == nil {
+		t.Error("Input driven twice")
+	} else {
+		fmt.Println(err)
+	}
+	delete(iomaps.Assoc, "i1")
+
+	if _, err := bmach.Board_constraints(Allboards["basys3"], iomaps, []ExtraModule{probe}); err == nil {
+		t.Error("Port without board pins accepted")
+	} else {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,105 @@
+package bondmachine
+
+import (
+	"procbuilder"
+	"sort"
+)
+
+// The registry of the shared objects and of the extra modules. A package providing its own shared object registers
+// both its sides from its init: the bondmachine one and the processor one, that goes in the procbuilder registry.
+// An extra module is registered with a factory, so it can be created by name and configured with Import, and with
+// the function writing its verilog module when it has one.
+
+type Extra_verilog_writer func(bmach *Bondmachine, module_name string, flavor string, iomaps *IOmap, extramods []ExtraModule) (string, error)
+
+type extra_module_entry struct {
+	factory func() ExtraModule
+	verilog Extra_verilog_writer
+}
+
+var extra_modules = make(map[string]extra_module_entry)
+
+// Register_shared_object adds a shared object, proc is its processor side and it may be nil only if the procbuilder
+// package has it already. The names have to be unique and the same on both sides.
+func Register_shared_object(so Shared_element, proc procbuilder.Sharedel) error {
+	name := so.Shr_get_name()
+	if _, ok := Get_shared_object(name); ok {
+		return Prerror{"Shared object " + name + " already registered"}
+	}
+	if proc == nil {
+		if _, ok := procbuilder.Get_shared(name); !ok {
+			return Prerror{"Shared object " + name + " has no processor side"}
+		}
+	} else {
+		if proc.Shr_get_name() != name {
+			return Prerror{"Shared object " + name + " has a processor side named " + proc.Shr_get_name()}
+		}
+		if err := procbuilder.Register_shared(proc); err != nil {
+			return err
+		}
+	}
+	Allshared = append(Allshared, so)
+	return nil
+}
+
+func Get_shared_object(name string) (Shared_element, bool) {
+	for _, so := range Allshared {
+		if so.Shr_get_name() == name {
+			return so, true
+		}
+	}
+	return nil, false
+}
+
+func Shared_object_names() []string {
+	result := make([]string, len(Allshared))
+	for i, so := range Allshared {
+		result[i] = so.Shr_get_name()
+	}
+	return result
+}
+
+// Register_extra_module adds an extra module, the name has to be unique and the one returned by the Get_Name of the
+// modules the factory creates. verilog is nil for the modules without a verilog module of their own.
+func Register_extra_module(name string, factory func() ExtraModule, verilog Extra_verilog_writer) error {
+	if _, ok := extra_modules[name]; ok {
+		return Prerror{"Extra module " + name + " already registered"}
+	}
+	if factory().Get_Name() != name {
+		return Prerror{"Extra module " + name + " creates modules with a different name"}
+	}
+	extra_modules[name] = extra_module_entry{factory, verilog}
+	return nil
+}
+
+// New_extra_module creates a registered extra module and configures it from its JSON export
+func New_extra_module(name string, config string) (ExtraModule, error) {
+	entry, ok := extra_modules[name]
+	if !ok {
+		return nil, Prerror{"Unknown extra module " + name}
+	}
+	mod := entry.factory()
+	if config != "" {
+		if err := mod.Import(config); err != nil {
+			return nil, err
+		}
+	}
+	return mod, nil
+}
+
+func Extra_module_names() []string {
+	result := make([]string, 0, len(extra_modules))
+	for name, _ := range extra_modules {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// The verilog writer of an extra module, nil if it has none or it is not registered
+func extra_module_verilog(name string) Extra_verilog_writer {
+	if entry, ok := extra_modules[name]; ok {
+		return entry.verilog
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package bondmachine
+
+import (
+	"fmt"
+	"procbuilder"
+	"testing"
+)
+
+// A shared object provided by another package, here a barrier under a different name
+type custom_barrier struct {
+	Barrier
+}
+
+func (sh custom_barrier) Shr_get_name() string {
+	return "cbarrier"
+}
+
+type custom_barrier_proc struct {
+	procbuilder.Barrier
+}
+
+func (sh custom_barrier_proc) Shr_get_name() string {
+	return "cbarrier"
+}
+
+func TestRegistry(t *testing.T) {
+	saved := append([]Shared_element{}, Allshared...)
+	savedproc := append([]procbuilder.Sharedel{}, procbuilder.Allshared...)
+	defer func() {
+		Allshared = saved
+		procbuilder.Allshared = savedproc
+		delete(extra_modules, "slow2")
+	}()
+
+	if err := Register_shared_object(custom_barrier{}, nil); err == nil {
+		t.Error("Shared object without processor side accepted")
+	}
+	if err := Register_shared_object(custom_barrier{}, procbuilder.Barrier{}); err == nil {
+		t.Error("Shared object with a different processor side accepted")
+	}
+	if err := Register_shared_object(custom_barrier{}, custom_barrier_proc{}); err != nil {
+		t.Error(err)
+	}
+	if err := Register_shared_object(custom_barrier{}, custom_barrier_proc{}); err == nil {
+		t.Error("Duplicate shared object accepted")
+	}
+	if _, ok := procbuilder.Get_shared("cbarrier"); !ok {
+		t.Error("Processor side not registered")
+	}
+	fmt.Println(Shared_object_names())
+
+	if err := Register_extra_module("slow", func() ExtraModule { return new(Slow_extra) }, nil); err == nil {
+		t.Error("Duplicate extra module accepted")
+	}
+	if err := Register_extra_module("slow2", func() ExtraModule { return new(Slow_extra) }, nil); err == nil {
+		t.Error("Extra module with a different name accepted")
+	}
+
+	em, err := New_extra_module("slow", "{\"Slow_factor\":\"4\"}")
+	if err != nil {
+		t.Error(err)
+	} else if em.Get_Params().Params["slow_factor"] != "4" {
+		t.Error("Extra module configuration not imported")
+	}
+	if _, err := New_extra_module("none", ""); err == nil {
+		t.Error("Unknown extra module created")
+	}
+	fmt.Println(Extra_module_names())
+}
@@ -0,0 +1,91 @@
+package bondmachine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"procbuilder"
+	"sort"
+	"testing"
+)
+
+// A bondmachine with two processors sharing each kind of shared object, checked with the external tools if present
+func TestRtlSharedObjects(t *testing.T) {
+	tools := procbuilder.Rtl_available_tools()
+
+	cases := map[string][]string{
+		"sharedmem:8": {"nop", "r2s", "s2r"},
+		"channel:":    {"chc", "chw", "nop", "wrd", "wwr"},
+		"channel:4":   {"chc", "chclose", "chw", "nop", "wrd", "wwr"},
+		"barrier:10":  {"hit", "nop"},
+		"lfsr8:1":     {"lfsr82r", "nop"},
+		"mutex:":      {"lock", "nop", "unlock"},
+		"semaphore:1": {"nop", "semacq", "semrel"},
+		"counter:":    {"fadd", "fswp", "nop"},
+		"lfsrn:12:5":  {"lfsrn2r", "nop"},
+	}
+
+	for so, opnames := range cases {
+		mach := new(procbuilder.Machine)
+		arch := &mach.Arch
+		arch.Modes = []string{"ha"}
+		arch.Rsize = 8
+		arch.R = 2
+		arch.L = 4
+		arch.O = 4
+		arch.Op = make([]procbuilder.Opcode, 0)
+		for _, opname := range opnames {
+			for _, op := range procbuilder.Allopcodes {
+				if op.Op_get_name() == opname {
+					arch.Op = append(arch.Op, op)
+				}
+			}
+		}
+		sort.Sort(procbuilder.ByName(arch.Op))
+
+		bmach := new(Bondmachine)
+		bmach.Rsize = 8
+		bmach.Init()
+		bmach.Domains = append(bmach.Domains, mach)
+		for i := 0; i < 2; i++ {
+			if _, err := bmach.Add_processor(0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		bmach.Add_shared_objects([]string{so})
+		bmach.Connect_processor_shared_object([]string{"0", "0"})
+		bmach.Connect_processor_shared_object([]string{"1", "0"})
+
+		dir, err := ioutil.TempDir("", "rtlcheck")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		conf := new(Config)
+		conf.Output = &procbuilder.Output{Dir: dir, Policy: procbuilder.OUT_FORCE}
+
+		if err := bmach.Write_verilog(conf, "iverilog", nil, []ExtraModule{}, nil); err != nil {
+			t.Errorf("%s: %v", so, err)
+			continue
+		}
+
+		names := make([]string, 0)
+		for _, file := range conf.Output.Files {
+			if filepath.Ext(file.Name) == ".v" {
+				names = append(names, file.Name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, tool := range tools {
+			if output, err := tool.Rtl_check(dir, names, "bondmachine_tb"); err != nil {
+				t.Errorf("%s: %v\n%s", so, err, output)
+			}
+		}
+	}
+
+	if len(tools) == 0 {
+		t.Skip("No RTL tools found, only the generation has been tested")
+	}
+}
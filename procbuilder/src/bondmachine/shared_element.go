@@ -1,5 +1,9 @@
 package bondmachine
 
+import (
+	"procbuilder"
+)
+
 type Shared_element interface {
 	Shr_get_name() string // The name
 	Shr_get_desc() string // A description
@@ -18,4 +22,18 @@ type Shared_instance interface {
 	Write_verilog(*Bondmachine, int, string, string) string
 	Get_wires_perproc(*Bondmachine, int, int, string) string
 	Get_header_perproc(*Bondmachine, int, int, string) string
+	Estimate_resources(*Bondmachine, int, string) procbuilder.Resources
+}
+
+// The number of processors connected to a shared object
+func (bmach *Bondmachine) Shared_users(so_id int) int {
+	users := 0
+	for _, solist := range bmach.Shared_links {
+		for _, so := range solist {
+			if so == so_id {
+				users++
+			}
+		}
+	}
+	return users
 }
@@ -1,6 +1,7 @@
 package bondmachine
 
 import (
+	"procbuilder"
 	"strconv"
 	"strings"
 )
@@ -219,3 +220,9 @@ func (sm Barrier_instance) Get_header_perproc(bmach *Bondmachine, proc_id int, s
 	}
 	return result
 }
+
+func (sm Barrier_instance) Estimate_resources(bmach *Bondmachine, so_id int, flavor string) procbuilder.Resources {
+	// The hit flags, their reduction and the timeout counter
+	users := bmach.Shared_users(so_id)
+	return procbuilder.Resources{Luts: 2*users + 32, Ffs: users + 32}.Chain(procbuilder.Mux_cost(users, 1))
+}
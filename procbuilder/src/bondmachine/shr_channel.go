@@ -1,6 +1,7 @@
 package bondmachine
 
 import (
+	"procbuilder"
 	"strconv"
 	"strings"
 )
@@ -39,9 +40,17 @@ func (op Channel) GV_config(element uint8) string {
 }
 
 func (op Channel) Instantiate(s string) (Shared_instance, bool) {
+	// channel: is the rendezvous channel, channel:<depth> the buffered one
 	if strings.HasPrefix(s, "channel:") {
 		result := new(Channel_instance)
 		result.Shared_element = op
+		if len(s) > 8 {
+			if depth, ok := strconv.Atoi(s[8:]); ok == nil && depth >= 0 {
+				result.Depth = depth
+			} else {
+				return nil, false
+			}
+		}
 		return *result, true
 	}
 	return nil, false
@@ -51,14 +60,22 @@ func (op Channel) Instantiate(s string) (Shared_instance, bool) {
 
 type Channel_instance struct {
 	Shared_element
+	Depth int // Buffer depth, 0 for the rendezvous channel
 }
 
 func (sm Channel_instance) String() string {
+	if sm.Depth > 0 {
+		return "channel:" + strconv.Itoa(sm.Depth)
+	}
 	return "channel:"
 }
 
 func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, channel_name string, flavor string) string {
 
+	if sm.Depth > 0 {
+		return sm.write_verilog_fifo(bmach, so_index, channel_name)
+	}
+
 	result := ""
 
 	subresult := ""
@@ -79,6 +96,8 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 				subresult += ", p" + strconv.Itoa(num_processors) + "ack_w2r"
 				subresult += ", p" + strconv.Itoa(num_processors) + "ch_ready"
 				subresult += ", p" + strconv.Itoa(num_processors) + "ch_w_r_ready"
+				subresult += ", p" + strconv.Itoa(num_processors) + "close"
+				subresult += ", p" + strconv.Itoa(num_processors) + "closed"
 				num_processors++
 			}
 		}
@@ -110,6 +129,8 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 				subresult_out += "	output p" + strconv.Itoa(num_processors) + "ack_w2r;\n"
 				subresult_out += "	output p" + strconv.Itoa(num_processors) + "ch_ready;\n"
 				subresult_out += "	output [1:0] p" + strconv.Itoa(num_processors) + "ch_w_r_ready;\n"
+				subresult_in += "	input p" + strconv.Itoa(num_processors) + "close;\n"
+				subresult_out += "	output p" + strconv.Itoa(num_processors) + "closed;\n"
 				num_processors++
 			}
 		}
@@ -175,6 +196,8 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 	result += "	wire [" + strconv.Itoa((num_processors)-1) + ":0] ack_ch_ready_i;\n"
 	result += "	wire [" + strconv.Itoa((num_processors)-1) + ":0] op_check_ready_i;\n"
 	result += "	wire [" + strconv.Itoa((num_processors)-1) + ":0] finish_channel_i;\n"
+	result += "	wire [" + strconv.Itoa((num_processors)-1) + ":0] close_i;\n"
+	result += "	reg closed;\n"
 	result += "\n"
 	result += "	wire [" + strconv.Itoa((num_processors)-1) + ":0] reset_w2w_storbe;\n"
 	result += "	wire [" + strconv.Itoa((num_processors)-1) + ":0] reset_w2r_storbe;\n"
@@ -205,6 +228,7 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 		result += "	assign p_w2r_i[" + strconv.Itoa(proc_id) + "] = p" + strconv.Itoa(proc_id) + "w2r;\n" //rd_strobe
 		result += "	assign ack_ch_ready_i[" + strconv.Itoa(proc_id) + "] = p" + strconv.Itoa(proc_id) + "ack_ch_ready;\n"
 		result += "	assign op_check_ready_i[" + strconv.Itoa(proc_id) + "] = p" + strconv.Itoa(proc_id) + "op_check_ready;\n"
+		result += "	assign close_i[" + strconv.Itoa(proc_id) + "] = p" + strconv.Itoa(proc_id) + "close;\n"
 
 	}
 	result += "\n"
@@ -217,6 +241,7 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 		result += "	assign p" + strconv.Itoa(proc_id) + "ack_w2w = ack_w2w_i[" + strconv.Itoa(proc_id) + "];\n"
 		result += "	assign p" + strconv.Itoa(proc_id) + "ack_w2r = ack_w2r_i[" + strconv.Itoa(proc_id) + "];\n"
 		result += "	assign p" + strconv.Itoa(proc_id) + "chout = ch2proc_i[" + strconv.Itoa(proc_id) + "];\n"
+		result += "	assign p" + strconv.Itoa(proc_id) + "closed = closed;\n"
 	}
 
 	result += "\n"
@@ -233,6 +258,16 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 	result += "     end\n"
 	result += "	end\n"
 	result += "\n"
+	result += "	//---------------------Close------------------------------------------------------\n"
+	result += "	//once closed the writes are never paired and the reads complete alone, with zero\n"
+	result += "	always@(posedge clk or posedge reset)\n"
+	result += "	begin\n"
+	result += "		if(reset)\n"
+	result += "			closed <= #1 1'b0;\n"
+	result += "		else if(|close_i)\n"
+	result += "			closed <= #1 1'b1;\n"
+	result += "	end\n"
+	result += "\n"
 
 	result += "	//-------------- Logic design ---------------------------------------------\n"
 	result += "	//Define the TAG ID for each processor attached to the channel\n"
@@ -547,7 +582,7 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 	result += "	assign dv_w2r = w2r_reg_check & w2r_pointer_check; 																	\n"
 
 	result += "\t//logic to assert the ACK operation\n"
-	result += "\tassign ch_ready = (wwr_ready_ch | wrd_ready_ch) & {" + strconv.Itoa(num_processors) + "{|wwr_ready_ch}} & {" + strconv.Itoa(num_processors) + "{|wrd_ready_ch}};\n"
+	result += "\tassign ch_ready = (wwr_ready_ch | wrd_ready_ch) & ({" + strconv.Itoa(num_processors) + "{|wwr_ready_ch}} | {" + strconv.Itoa(num_processors) + "{closed}}) & {" + strconv.Itoa(num_processors) + "{|wrd_ready_ch}};\n"
 	for proc_id := 0; proc_id < num_processors; proc_id++ {
 		result += "\tassign ch_w_r_ready[" + strconv.Itoa(proc_id) + "] = {wrd_ready_ch[" + strconv.Itoa(proc_id) + "], wwr_ready_ch[" + strconv.Itoa(proc_id) + "]};\n"
 	}
@@ -559,11 +594,11 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 	result += "\t		wrd_ready_ch <= #1 'b0;\n"
 	result += "\t	end\n"
 	result += "\t	else begin\n"
-	result += "\t		if(dv_w2w & dv_w2r & op_check_ready_i[tag_w2w]  & tag_w2w!=tag_w2r)\n"
+	result += "\t		if(dv_w2w & dv_w2r & op_check_ready_i[tag_w2w]  & tag_w2w!=tag_w2r & ~closed)\n"
 	result += "\t			wwr_ready_ch[tag_w2w] <= #1 1'b1;\n"
 	result += "\t		else\n"
 	result += "\t			wwr_ready_ch[tag_w2w] <= #1 1'b0;\n"
-	result += "\t		if(dv_w2r & dv_w2w & op_check_ready_i[tag_w2r]  & tag_w2w!=tag_w2r)\n"
+	result += "\t		if(dv_w2r & op_check_ready_i[tag_w2r] & ((dv_w2w & tag_w2w!=tag_w2r) | closed))\n"
 	result += "\t			wrd_ready_ch[tag_w2r] <= #1 1'b1;\n"
 	result += "\t		else\n"
 	result += "\t			wrd_ready_ch[tag_w2r] <= #1 1'b0;\n"
@@ -605,7 +640,7 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 	//result += "\tend\n"
 	
 	result += "\t\n"
-	result += "\tassign finish_channel_i = (finish_channel_wrd | finish_channel_wwr) & {" + strconv.Itoa(num_processors) + "{wrd_finish}}  & {" + strconv.Itoa(num_processors) + "{wwr_finish}};\n"
+	result += "\tassign finish_channel_i = (finish_channel_wrd | finish_channel_wwr) & {" + strconv.Itoa(num_processors) + "{wrd_finish}}  & ({" + strconv.Itoa(num_processors) + "{wwr_finish}} | {" + strconv.Itoa(num_processors) + "{closed}});\n"
 	result += "\n"
 	result += "\t//process finish\n"
 	result += "\talways @ (posedge clk)\n"
@@ -628,7 +663,7 @@ func (sm Channel_instance) Write_verilog(bmach *Bondmachine, so_index int, chann
 	result += "\talways @ (*) begin//(posedge clk or posedge reset) begin\n"
 	result += "\t	for (i_ch2proc=0; i_ch2proc < 2; i_ch2proc=i_ch2proc+1) begin\n"
 	result += "\t		ch2proc_i[i_ch2proc] <= 'b0;\n"
-	result += "\t	if(tag_w2r==i_ch2proc)\n"
+	result += "\t	if(tag_w2r==i_ch2proc & ~closed)\n"
 	result += "\t		ch2proc_i[i_ch2proc] <= proc2ch_i[tag_w2w];\n"
 	result += "\t	end\n"
 	result += "\tend\n"
@@ -692,6 +727,8 @@ func (sm Channel_instance) Get_wires_perproc(bmach *Bondmachine, proc_id int, so
 		result += "\twire p" + strconv.Itoa(proc_id) + soname + "ack_w2r;\n"
 		result += "\twire p" + strconv.Itoa(proc_id) + soname + "ch_ready;\n"
 		result += "\twire [1:0] p" + strconv.Itoa(proc_id) + soname + "ch_w_r_ready;\n"
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "close;\n"
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "closed;\n"
 		result += "\n"
 	}
 	return result
@@ -711,6 +748,23 @@ func (sm Channel_instance) Get_header_perproc(bmach *Bondmachine, proc_id int, s
 		result += ", p" + strconv.Itoa(proc_id) + soname + "ack_w2r"
 		result += ", p" + strconv.Itoa(proc_id) + soname + "ch_ready"
 		result += ", p" + strconv.Itoa(proc_id) + soname + "ch_w_r_ready"
+		result += ", p" + strconv.Itoa(proc_id) + soname + "close"
+		result += ", p" + strconv.Itoa(proc_id) + soname + "closed"
 	}
 	return result
 }
+
+func (sm Channel_instance) Estimate_resources(bmach *Bondmachine, so_id int, flavor string) procbuilder.Resources {
+	// The tags memories of the pending operations, and for every processor the request state and the data port
+	users := bmach.Shared_users(so_id)
+	rsize := int(bmach.Rsize)
+	if sm.Depth > 0 {
+		// The data FIFO, its pointers and the arbiter
+		fifo := procbuilder.Memory_cost(sm.Depth, rsize, flavor)
+		control := procbuilder.Resources{Luts: users*4 + 3*Needed_bits(sm.Depth+1) + 16, Ffs: users*4 + rsize + 3*Needed_bits(sm.Depth+1) + 8, Levels: 3}
+		return fifo.Merge(control.Chain(procbuilder.Mux_cost(users, rsize)))
+	}
+	tags := procbuilder.Memory_cost(2*(1<<bmach.Rsize), users, flavor)
+	control := procbuilder.Resources{Luts: users*(rsize+12) + 2*rsize, Ffs: users*(rsize+16) + 4*rsize, Levels: 3}
+	return tags.Merge(control.Chain(procbuilder.Mux_cost(users, rsize)))
+}
@@ -0,0 +1,225 @@
+package bondmachine
+
+import (
+	"strconv"
+)
+
+// The buffered channel. It has the same ports of the rendezvous channel, the processors are served one at the time
+// by a round robin arbiter: a write is granted while the FIFO has room and a read while it holds data, so the
+// writers do not wait for a reader until the buffer is full. Once closed the writes are refused and, when the FIFO
+// is empty, the reads are granted with zero and closed raised.
+
+func (sm Channel_instance) write_verilog_fifo(bmach *Bondmachine, so_index int, channel_name string) string {
+
+	rsize := strconv.Itoa(int(bmach.Rsize) - 1)
+
+	procs := make([]string, 0)
+	for _, solist := range bmach.Shared_links {
+		for _, so_id := range solist {
+			if so_id == so_index {
+				procs = append(procs, "p"+strconv.Itoa(len(procs)))
+			}
+		}
+	}
+	num_processors := len(procs)
+	nprocs := strconv.Itoa(num_processors)
+	gw := strconv.Itoa(Needed_bits(num_processors) - 1)
+	aw := strconv.Itoa(Needed_bits(sm.Depth) - 1)
+	cw := strconv.Itoa(Needed_bits(sm.Depth+1) - 1)
+
+	subresult := ""
+	subresult_in := ""
+	subresult_out := ""
+	for _, p := range procs {
+		subresult += ", " + p + "chin, " + p + "w2w, " + p + "w2r, " + p + "ack_ch_ready, " + p + "op_check_ready"
+		subresult += ", " + p + "finish_channel, " + p + "chout, " + p + "ack_w2w, " + p + "ack_w2r, " + p + "ch_ready, " + p + "ch_w_r_ready"
+		subresult += ", " + p + "close, " + p + "closed"
+		subresult_in += "\tinput [" + rsize + ":0] " + p + "chin;\n"
+		subresult_in += "\tinput " + p + "w2w;\n"
+		subresult_in += "\tinput " + p + "w2r;\n"
+		subresult_in += "\tinput " + p + "ack_ch_ready;\n"
+		subresult_in += "\tinput " + p + "op_check_ready;\n"
+		subresult_out += "\toutput " + p + "finish_channel;\n"
+		subresult_out += "\toutput [" + rsize + ":0] " + p + "chout;\n"
+		subresult_out += "\toutput " + p + "ack_w2w;\n"
+		subresult_out += "\toutput " + p + "ack_w2r;\n"
+		subresult_out += "\toutput " + p + "ch_ready;\n"
+		subresult_out += "\toutput [1:0] " + p + "ch_w_r_ready;\n"
+		subresult_in += "\tinput " + p + "close;\n"
+		subresult_out += "\toutput " + p + "closed;\n"
+	}
+
+	result := "\n"
+	result += "`timescale 1ns/1ps\n"
+	result += "module " + channel_name + "(clk, reset" + subresult + ");\n"
+	result += "\n"
+	result += "\t//--------------Input Ports-----------------------\n"
+	result += "\tinput clk;\n"
+	result += "\tinput reset;\n"
+	result += subresult_in
+	result += "\n"
+	result += "\t//--------------Output Ports-----------------------\n"
+	result += subresult_out
+	result += "\n"
+
+	result += "\t//--------------Generic Parameter-------------------\n"
+	result += "\tlocalparam DEPTH = " + strconv.Itoa(sm.Depth) + ";\n"
+	result += "\tlocalparam IDLE = 3'd0, GRANT = 3'd1, DATA1 = 3'd2, DATA2 = 3'd3, FINISH = 3'd4;\n"
+	result += "\n"
+
+	result += "\t//--------------Reg declaration---------------------------------------------\n"
+	result += "\treg [" + rsize + ":0] fifo [0:DEPTH-1];\n"
+	result += "\treg [" + aw + ":0] rd_pointer;\n"
+	result += "\treg [" + aw + ":0] wr_pointer;\n"
+	result += "\treg [" + cw + ":0] count;\n"
+	result += "\treg [2:0] state;\n"
+	result += "\treg [" + gw + ":0] grant;\n"
+	result += "\treg [" + gw + ":0] last;\n"
+	result += "\treg grant_write;\n"
+	result += "\treg grant_closed;\n"
+	result += "\treg closed;\n"
+	result += "\treg [" + rsize + ":0] data_out;\n"
+	result += "\treg [" + nprocs + "-1:0] p_w2w_i_d1;\n"
+	result += "\treg [" + nprocs + "-1:0] p_w2r_i_d1;\n"
+	result += "\treg [" + nprocs + "-1:0] ack_w2w_i;\n"
+	result += "\treg [" + nprocs + "-1:0] ack_w2r_i;\n"
+	result += "\treg found;\n"
+	result += "\treg sel_write;\n"
+	result += "\treg [" + gw + ":0] sel;\n"
+	result += "\n"
+
+	result += "\t//--------------Wire declaration--------------------------------------------\n"
+	result += "\twire [" + rsize + ":0] proc2ch_i [0:" + nprocs + "-1];\n"
+	result += "\twire [" + nprocs + "-1:0] p_w2w_i;\n"
+	result += "\twire [" + nprocs + "-1:0] p_w2r_i;\n"
+	result += "\twire [" + nprocs + "-1:0] ack_ch_ready_i;\n"
+	result += "\twire [" + nprocs + "-1:0] op_check_ready_i;\n"
+	result += "\twire [" + nprocs + "-1:0] close_i;\n"
+	result += "\n"
+
+	result += "\t//--------------Signal assignment----------------------------\n"
+	for i, p := range procs {
+		id := strconv.Itoa(i)
+		result += "\tassign proc2ch_i[" + id + "] = " + p + "chin;\n"
+		result += "\tassign p_w2w_i[" + id + "] = " + p + "w2w;\n"
+		result += "\tassign p_w2r_i[" + id + "] = " + p + "w2r;\n"
+		result += "\tassign ack_ch_ready_i[" + id + "] = " + p + "ack_ch_ready;\n"
+		result += "\tassign op_check_ready_i[" + id + "] = " + p + "op_check_ready;\n"
+		result += "\tassign close_i[" + id + "] = " + p + "close;\n"
+		result += "\tassign " + p + "ch_ready = (state != IDLE) && (grant == " + id + ");\n"
+		result += "\tassign " + p + "ch_w_r_ready = ((state != IDLE) && (grant == " + id + ")) ? (grant_write ? 2'b01 : 2'b10) : 2'b00;\n"
+		result += "\tassign " + p + "finish_channel = (state == FINISH) && (grant == " + id + ");\n"
+		result += "\tassign " + p + "chout = data_out;\n"
+		result += "\tassign " + p + "ack_w2w = ack_w2w_i[" + id + "];\n"
+		result += "\tassign " + p + "ack_w2r = ack_w2r_i[" + id + "];\n"
+		result += "\tassign " + p + "closed = (state != IDLE) && (grant == " + id + ") && grant_closed;\n"
+	}
+	result += "\n"
+
+	result += "\t// The wwr and wrd requests are acknowledged on the rising strobe\n"
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif(reset) begin\n"
+	result += "\t\t\tp_w2w_i_d1 <= #1 'b0;\n"
+	result += "\t\t\tp_w2r_i_d1 <= #1 'b0;\n"
+	result += "\t\t\tack_w2w_i <= #1 'b0;\n"
+	result += "\t\t\tack_w2r_i <= #1 'b0;\n"
+	result += "\t\tend\n"
+	result += "\t\telse begin\n"
+	result += "\t\t\tp_w2w_i_d1 <= #1 p_w2w_i;\n"
+	result += "\t\t\tp_w2r_i_d1 <= #1 p_w2r_i;\n"
+	result += "\t\t\tack_w2w_i <= #1 p_w2w_i & ~p_w2w_i_d1;\n"
+	result += "\t\t\tack_w2r_i <= #1 p_w2r_i & ~p_w2r_i_d1;\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+
+	result += "\t// Round robin arbiter, starting from the processor after the last served one\n"
+	result += "\tinteger i, cand;\n"
+	result += "\talways @(*) begin\n"
+	result += "\t\tfound = 1'b0;\n"
+	result += "\t\tsel = 'b0;\n"
+	result += "\t\tsel_write = 1'b0;\n"
+	result += "\t\tfor (i = 0; i < " + nprocs + "; i = i + 1) begin\n"
+	result += "\t\t\tcand = last + 1 + i;\n"
+	result += "\t\t\tif (cand >= " + nprocs + ")\n"
+	result += "\t\t\t\tcand = cand - " + nprocs + ";\n"
+	result += "\t\t\tif (!found && op_check_ready_i[cand]) begin\n"
+	result += "\t\t\t\tif (p_w2w_i[cand] && count < DEPTH && !closed) begin\n"
+	result += "\t\t\t\t\tfound = 1'b1;\n"
+	result += "\t\t\t\t\tsel = cand;\n"
+	result += "\t\t\t\t\tsel_write = 1'b1;\n"
+	result += "\t\t\t\tend\n"
+	result += "\t\t\t\telse if (p_w2r_i[cand] && (count > 0 || closed)) begin\n"
+	result += "\t\t\t\t\tfound = 1'b1;\n"
+	result += "\t\t\t\t\tsel = cand;\n"
+	result += "\t\t\t\tend\n"
+	result += "\t\t\tend\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+
+	result += "\t// The granted processor acknowledges with ack_ch_ready, a read pops the FIFO head at once while a write\n"
+	result += "\t// samples chin two cycles later. finish_channel is held until the processor drops the request strobe.\n"
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif(reset) begin\n"
+	result += "\t\t\tstate <= #1 IDLE;\n"
+	result += "\t\t\trd_pointer <= #1 'b0;\n"
+	result += "\t\t\twr_pointer <= #1 'b0;\n"
+	result += "\t\t\tcount <= #1 'b0;\n"
+	result += "\t\t\tgrant <= #1 'b0;\n"
+	result += "\t\t\tlast <= #1 " + strconv.Itoa(num_processors-1) + ";\n"
+	result += "\t\t\tgrant_write <= #1 1'b0;\n"
+	result += "\t\t\tgrant_closed <= #1 1'b0;\n"
+	result += "\t\t\tdata_out <= #1 'b0;\n"
+	result += "\t\t\tclosed <= #1 1'b0;\n"
+	result += "\t\tend\n"
+	result += "\t\telse begin\n"
+	result += "\t\t\tif (|close_i)\n"
+	result += "\t\t\t\tclosed <= #1 1'b1;\n"
+	result += "\t\t\tcase (state)\n"
+	result += "\t\t\tIDLE: begin\n"
+	result += "\t\t\t\tif (found) begin\n"
+	result += "\t\t\t\t\tgrant <= #1 sel;\n"
+	result += "\t\t\t\t\tlast <= #1 sel;\n"
+	result += "\t\t\t\t\tgrant_write <= #1 sel_write;\n"
+	result += "\t\t\t\t\tgrant_closed <= #1 !sel_write && count == 0;\n"
+	result += "\t\t\t\t\tdata_out <= #1 (count == 0) ? 'b0 : fifo[rd_pointer];\n"
+	result += "\t\t\t\t\tstate <= #1 GRANT;\n"
+	result += "\t\t\t\tend\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\tGRANT: begin\n"
+	result += "\t\t\t\tif (ack_ch_ready_i[grant]) begin\n"
+	result += "\t\t\t\t\tif (grant_write)\n"
+	result += "\t\t\t\t\t\tstate <= #1 DATA1;\n"
+	result += "\t\t\t\t\telse if (grant_closed)\n"
+	result += "\t\t\t\t\t\tstate <= #1 FINISH;\n"
+	result += "\t\t\t\t\telse begin\n"
+	result += "\t\t\t\t\t\trd_pointer <= #1 (rd_pointer == DEPTH-1) ? 'b0 : rd_pointer + 1;\n"
+	result += "\t\t\t\t\t\tcount <= #1 count - 1;\n"
+	result += "\t\t\t\t\t\tstate <= #1 FINISH;\n"
+	result += "\t\t\t\t\tend\n"
+	result += "\t\t\t\tend\n"
+	result += "\t\t\t\telse if (!op_check_ready_i[grant])\n"
+	result += "\t\t\t\t\t// A chc that did not wait for the grant\n"
+	result += "\t\t\t\t\tstate <= #1 IDLE;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\tDATA1: state <= #1 DATA2;\n"
+	result += "\t\t\tDATA2: begin\n"
+	result += "\t\t\t\tfifo[wr_pointer] <= #1 proc2ch_i[grant];\n"
+	result += "\t\t\t\twr_pointer <= #1 (wr_pointer == DEPTH-1) ? 'b0 : wr_pointer + 1;\n"
+	result += "\t\t\t\tcount <= #1 count + 1;\n"
+	result += "\t\t\t\tstate <= #1 FINISH;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\tFINISH: begin\n"
+	result += "\t\t\t\tif ((grant_write && !p_w2w_i[grant]) || (!grant_write && !p_w2r_i[grant]))\n"
+	result += "\t\t\t\t\tstate <= #1 IDLE;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\tdefault: state <= #1 IDLE;\n"
+	result += "\t\t\tendcase\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+	result += "endmodule\n"
+
+	return result
+}
@@ -0,0 +1,217 @@
+package bondmachine
+
+import (
+	"procbuilder"
+	"strconv"
+	"strings"
+)
+
+// The atomic fetch and add counter. The requests are served one per cycle by a round robin arbiter, the processor
+// gets the count before its addend is added, or stored with the swap flag, and the acknowledge is held until it
+// drops the request.
+
+type Counter struct{}
+
+func (op Counter) Shr_get_name() string {
+	return "counter"
+}
+
+func (op Counter) Shr_get_desc() string {
+	return "Counter"
+}
+
+func (op Counter) Shortname() string {
+	return "ct"
+}
+
+func (op Counter) GV_config(element uint8) string {
+	result := ""
+	switch element {
+	case GVNODEINPROC:
+		result += "style=filled fillcolor=plum color=black"
+	case GVNODE:
+		result += "style=filled fillcolor=plum color=black"
+	case GVEDGE:
+		result += "arrowhead=none"
+	case GVCLUS:
+		result += "style=filled;\n\t\tcolor=black;\n\t\tfillcolor=grey75"
+	case GVCLUSINPROC:
+		result += "style=filled;\n\t\tcolor=black;\n\t\tfillcolor=grey75"
+	}
+	return result
+}
+
+func (op Counter) Instantiate(s string) (Shared_instance, bool) {
+	// counter: starts from 0, counter:<value> from the value
+	if strings.HasPrefix(s, "counter:") {
+		result := new(Counter_instance)
+		result.Shared_element = op
+		if len(s) > 8 {
+			if value, ok := strconv.Atoi(s[8:]); ok == nil && value >= 0 {
+				result.Value = value
+			} else {
+				return nil, false
+			}
+		}
+		return *result, true
+	}
+	return nil, false
+}
+
+// The instance struct
+
+type Counter_instance struct {
+	Shared_element
+	Value int // The initial value
+}
+
+func (sm Counter_instance) String() string {
+	if sm.Value > 0 {
+		return "counter:" + strconv.Itoa(sm.Value)
+	}
+	return "counter:"
+}
+
+func (sm Counter_instance) Write_verilog(bmach *Bondmachine, so_index int, counter_name string, flavor string) string {
+
+	rsize := strconv.Itoa(int(bmach.Rsize))
+
+	procs := make([]string, 0)
+	for _, solist := range bmach.Shared_links {
+		for _, so_id := range solist {
+			if so_id == so_index {
+				procs = append(procs, "p"+strconv.Itoa(len(procs)))
+			}
+		}
+	}
+	num_processors := len(procs)
+	nprocs := strconv.Itoa(num_processors)
+	gw := strconv.Itoa(Needed_bits(num_processors) - 1)
+
+	subresult := ""
+	subresult_in := ""
+	subresult_out := ""
+	for _, p := range procs {
+		subresult += ", " + p + "req, " + p + "swp, " + p + "in, " + p + "out, " + p + "ack"
+		subresult_in += "\tinput " + p + "req;\n"
+		subresult_in += "\tinput " + p + "swp;\n"
+		subresult_in += "\tinput [" + rsize + "-1:0] " + p + "in;\n"
+		subresult_out += "\toutput [" + rsize + "-1:0] " + p + "out;\n"
+		subresult_out += "\toutput " + p + "ack;\n"
+	}
+
+	result := ""
+	result += "`timescale 1ns/1ps\n"
+	result += "module " + counter_name + "(clk, reset" + subresult + ");\n"
+	result += "\n"
+	result += "\t//--------------Input Ports-----------------------\n"
+	result += "\tinput clk;\n"
+	result += "\tinput reset;\n"
+	result += subresult_in
+	result += "\n"
+	result += "\t//--------------Output Ports-----------------------\n"
+	result += subresult_out
+	result += "\n"
+
+	result += "\t//--------------Reg declaration---------------------------------------------\n"
+	result += "\treg [" + rsize + "-1:0] count;\n"
+	result += "\treg [" + rsize + "-1:0] out_i [0:" + nprocs + "-1];\n"
+	result += "\treg [" + nprocs + "-1:0] ack_i;\n"
+	result += "\treg [" + gw + ":0] last;\n"
+	result += "\treg found;\n"
+	result += "\treg [" + gw + ":0] sel;\n"
+	result += "\n"
+
+	result += "\t//--------------Wire declaration--------------------------------------------\n"
+	result += "\twire [" + rsize + "-1:0] in_i [0:" + nprocs + "-1];\n"
+	result += "\twire [" + nprocs + "-1:0] req_i;\n"
+	result += "\twire [" + nprocs + "-1:0] swp_i;\n"
+	result += "\n"
+
+	result += "\t//--------------Signal assignment----------------------------\n"
+	for i, p := range procs {
+		id := strconv.Itoa(i)
+		result += "\tassign req_i[" + id + "] = " + p + "req;\n"
+		result += "\tassign swp_i[" + id + "] = " + p + "swp;\n"
+		result += "\tassign in_i[" + id + "] = " + p + "in;\n"
+		result += "\tassign " + p + "out = out_i[" + id + "];\n"
+		result += "\tassign " + p + "ack = ack_i[" + id + "];\n"
+	}
+	result += "\n"
+
+	result += "\t// Round robin arbiter among the requests not yet acknowledged\n"
+	result += "\tinteger i, cand;\n"
+	result += "\talways @(*) begin\n"
+	result += "\t\tfound = 1'b0;\n"
+	result += "\t\tsel = 'b0;\n"
+	result += "\t\tfor (i = 0; i < " + nprocs + "; i = i + 1) begin\n"
+	result += "\t\t\tcand = last + 1 + i;\n"
+	result += "\t\t\tif (cand >= " + nprocs + ")\n"
+	result += "\t\t\t\tcand = cand - " + nprocs + ";\n"
+	result += "\t\t\tif (!found && req_i[cand] && !ack_i[cand]) begin\n"
+	result += "\t\t\t\tfound = 1'b1;\n"
+	result += "\t\t\t\tsel = cand;\n"
+	result += "\t\t\tend\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+
+	result += "\t// The selected processor gets the count and its addend is added, or its value stored\n"
+	result += "\tinteger k;\n"
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif(reset) begin\n"
+	result += "\t\t\tcount <= #1 " + rsize + "'d" + strconv.Itoa(sm.Value) + ";\n"
+	result += "\t\t\tack_i <= #1 'b0;\n"
+	result += "\t\t\tlast <= #1 " + strconv.Itoa(num_processors-1) + ";\n"
+	result += "\t\t\tfor (k = 0; k < " + nprocs + "; k = k + 1)\n"
+	result += "\t\t\t\tout_i[k] <= #1 'b0;\n"
+	result += "\t\tend\n"
+	result += "\t\telse begin\n"
+	result += "\t\t\tack_i <= #1 ack_i & req_i;\n"
+	result += "\t\t\tif (found) begin\n"
+	result += "\t\t\t\tack_i[sel] <= #1 1'b1;\n"
+	result += "\t\t\t\tlast <= #1 sel;\n"
+	result += "\t\t\t\tout_i[sel] <= #1 count;\n"
+	result += "\t\t\t\tcount <= #1 swp_i[sel] ? in_i[sel] : count + in_i[sel];\n"
+	result += "\t\t\tend\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+	result += "endmodule\n"
+	result += "\n"
+
+	return result
+}
+
+func (sm Counter_instance) Get_wires_perproc(bmach *Bondmachine, proc_id int, so_id int, flavor string) string {
+	result := ""
+	if soname, ok := bmach.Get_so_name(so_id); ok {
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "req;\n"
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "swp;\n"
+		result += "\twire [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] p" + strconv.Itoa(proc_id) + soname + "in;\n"
+		result += "\twire [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] p" + strconv.Itoa(proc_id) + soname + "out;\n"
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "ack;\n"
+		result += "\n"
+	}
+	return result
+}
+
+func (sm Counter_instance) Get_header_perproc(bmach *Bondmachine, proc_id int, so_id int, flavor string) string {
+	result := ""
+	if soname, ok := bmach.Get_so_name(so_id); ok {
+		result += ", p" + strconv.Itoa(proc_id) + soname + "req"
+		result += ", p" + strconv.Itoa(proc_id) + soname + "swp"
+		result += ", p" + strconv.Itoa(proc_id) + soname + "in"
+		result += ", p" + strconv.Itoa(proc_id) + soname + "out"
+		result += ", p" + strconv.Itoa(proc_id) + soname + "ack"
+	}
+	return result
+}
+
+func (sm Counter_instance) Estimate_resources(bmach *Bondmachine, so_id int, flavor string) procbuilder.Resources {
+	// The count and its adder or the stored value after the addends multiplexer, the output registers and the arbiter
+	users := bmach.Shared_users(so_id)
+	rsize := int(bmach.Rsize)
+	control := procbuilder.Resources{Luts: 2*rsize + 4*users, Ffs: rsize*(users+1) + users + Needed_bits(users), Levels: 2}
+	return procbuilder.Mux_cost(users, rsize).Chain(control)
+}
@@ -1,6 +1,7 @@
 package bondmachine
 
 import (
+	"procbuilder"
 	"strconv"
 	"strings"
 )
@@ -125,3 +126,7 @@ func (sm Lfsr8_instance) Get_header_perproc(bmach *Bondmachine, proc_id int, so_
 	}
 	return result
 }
+
+func (sm Lfsr8_instance) Estimate_resources(bmach *Bondmachine, so_id int, flavor string) procbuilder.Resources {
+	return procbuilder.Resources{Luts: 1, Ffs: 8, Levels: 1}
+}
@@ -0,0 +1,149 @@
+package bondmachine
+
+import (
+	"procbuilder"
+	"strconv"
+	"strings"
+)
+
+// The free running LFSR of parametric width, every connected processor reads the same state.
+
+type Lfsrn struct{}
+
+func (op Lfsrn) Shr_get_name() string {
+	return "lfsrn"
+}
+
+func (op Lfsrn) Shr_get_desc() string {
+	return "Lfsrn"
+}
+
+func (op Lfsrn) Shortname() string {
+	return "lfsrn"
+}
+
+func (op Lfsrn) GV_config(element uint8) string {
+	result := ""
+	switch element {
+	case GVNODEINPROC:
+		result += "style=filled fillcolor=gold color=black"
+	case GVNODE:
+		result += "style=filled fillcolor=gold color=black"
+	case GVEDGE:
+		result += "arrowhead=none"
+	case GVCLUS:
+		result += "style=filled;\n\t\tcolor=black;\n\t\tfillcolor=grey65"
+	case GVCLUSINPROC:
+		result += "style=filled;\n\t\tcolor=black;\n\t\tfillcolor=grey65"
+	}
+	return result
+}
+
+func (op Lfsrn) Instantiate(s string) (Shared_instance, bool) {
+	// lfsrn:<width>:<seed>
+	if strings.HasPrefix(s, "lfsrn:") {
+		if width, seed, err := procbuilder.Lfsrn_params(s); err == nil {
+			result := new(Lfsrn_instance)
+			result.Shared_element = op
+			result.Width = width
+			result.Seed = seed
+			return *result, true
+		}
+	}
+	return nil, false
+}
+
+// The instance struct
+
+type Lfsrn_instance struct {
+	Shared_element
+	Width int
+	Seed  uint64
+}
+
+func (sm Lfsrn_instance) String() string {
+	return "lfsrn:" + strconv.Itoa(sm.Width) + ":" + strconv.FormatUint(sm.Seed, 10)
+}
+
+func (sm Lfsrn_instance) Write_verilog(bmach *Bondmachine, so_index int, lfsrn_name string, flavor string) string {
+
+	width := strconv.Itoa(sm.Width)
+	seed := width + "'d" + strconv.FormatUint(sm.Seed, 10)
+
+	procs := make([]string, 0)
+	for _, solist := range bmach.Shared_links {
+		for _, so_id := range solist {
+			if so_id == so_index {
+				procs = append(procs, "p"+strconv.Itoa(len(procs)))
+			}
+		}
+	}
+
+	subresult := ""
+	subresult_out := ""
+	for _, p := range procs {
+		subresult += ", " + p + "out"
+		subresult_out += "\toutput [" + width + "-1:0] " + p + "out;\n"
+	}
+
+	feedback := make([]string, 0)
+	for _, tap := range procbuilder.Lfsrn_taps(sm.Width) {
+		feedback = append(feedback, "state["+strconv.Itoa(tap-1)+"]")
+	}
+
+	result := ""
+	result += "`timescale 1ns/1ps\n"
+	result += "module " + lfsrn_name + "(clk, reset" + subresult + ");\n"
+	result += "\n"
+	result += "\t//--------------Input Ports-----------------------\n"
+	result += "\tinput clk;\n"
+	result += "\tinput reset;\n"
+	result += "\n"
+	result += "\t//--------------Output Ports-----------------------\n"
+	result += subresult_out
+	result += "\n"
+
+	result += "\treg [" + width + "-1:0] state;\n"
+	result += "\twire feedback;\n"
+	result += "\n"
+
+	result += "\tassign feedback = " + strings.Join(feedback, " ^ ") + ";\n"
+	for _, p := range procs {
+		result += "\tassign " + p + "out = state;\n"
+	}
+	result += "\n"
+
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif(reset)\n"
+	result += "\t\t\tstate <= #1 " + seed + ";\n"
+	result += "\t\telse\n"
+	result += "\t\t\tstate <= #1 {state[" + strconv.Itoa(sm.Width-2) + ":0], feedback};\n"
+	result += "\tend\n"
+	result += "\n"
+	result += "endmodule\n"
+	result += "\n"
+
+	return result
+}
+
+func (sm Lfsrn_instance) Get_wires_perproc(bmach *Bondmachine, proc_id int, so_id int, flavor string) string {
+	result := ""
+	if soname, ok := bmach.Get_so_name(so_id); ok {
+		result += "\twire [" + strconv.Itoa(sm.Width-1) + ":0] p" + strconv.Itoa(proc_id) + soname + "out;\n"
+		result += "\n"
+	}
+	return result
+}
+
+func (sm Lfsrn_instance) Get_header_perproc(bmach *Bondmachine, proc_id int, so_id int, flavor string) string {
+	result := ""
+	if soname, ok := bmach.Get_so_name(so_id); ok {
+		result += ", p" + strconv.Itoa(proc_id) + soname + "out"
+	}
+	return result
+}
+
+func (sm Lfsrn_instance) Estimate_resources(bmach *Bondmachine, so_id int, flavor string) procbuilder.Resources {
+	// The state and the xor of at most six taps
+	return procbuilder.Resources{Luts: 1, Ffs: sm.Width, Levels: 1}
+}
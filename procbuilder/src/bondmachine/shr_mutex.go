@@ -0,0 +1,182 @@
+package bondmachine
+
+import (
+	"procbuilder"
+	"strconv"
+)
+
+// The hardware mutex. A round robin arbiter grants it to one of the requesting processors, the owner keeps it until
+// it drops the request with the unlock.
+
+type Mutex struct{}
+
+func (op Mutex) Shr_get_name() string {
+	return "mutex"
+}
+
+func (op Mutex) Shr_get_desc() string {
+	return "Mutex"
+}
+
+func (op Mutex) Shortname() string {
+	return "mx"
+}
+
+func (op Mutex) GV_config(element uint8) string {
+	result := ""
+	switch element {
+	case GVNODEINPROC:
+		result += "style=filled fillcolor=tomato color=black"
+	case GVNODE:
+		result += "style=filled fillcolor=tomato color=black"
+	case GVEDGE:
+		result += "arrowhead=none"
+	case GVCLUS:
+		result += "style=filled;\n\t\tcolor=black;\n\t\tfillcolor=grey75"
+	case GVCLUSINPROC:
+		result += "style=filled;\n\t\tcolor=black;\n\t\tfillcolor=grey75"
+	}
+	return result
+}
+
+func (op Mutex) Instantiate(s string) (Shared_instance, bool) {
+	if s == "mutex:" {
+		result := new(Mutex_instance)
+		result.Shared_element = op
+		return *result, true
+	}
+	return nil, false
+}
+
+// The instance struct
+
+type Mutex_instance struct {
+	Shared_element
+}
+
+func (sm Mutex_instance) String() string {
+	return "mutex:"
+}
+
+func (sm Mutex_instance) Write_verilog(bmach *Bondmachine, so_index int, mutex_name string, flavor string) string {
+
+	procs := make([]string, 0)
+	for _, solist := range bmach.Shared_links {
+		for _, so_id := range solist {
+			if so_id == so_index {
+				procs = append(procs, "p"+strconv.Itoa(len(procs)))
+			}
+		}
+	}
+	num_processors := len(procs)
+	nprocs := strconv.Itoa(num_processors)
+	gw := strconv.Itoa(Needed_bits(num_processors) - 1)
+
+	subresult := ""
+	subresult_in := ""
+	subresult_out := ""
+	for _, p := range procs {
+		subresult += ", " + p + "req, " + p + "grant"
+		subresult_in += "\tinput " + p + "req;\n"
+		subresult_out += "\toutput " + p + "grant;\n"
+	}
+
+	result := ""
+	result += "`timescale 1ns/1ps\n"
+	result += "module " + mutex_name + "(clk, reset" + subresult + ");\n"
+	result += "\n"
+	result += "\t//--------------Input Ports-----------------------\n"
+	result += "\tinput clk;\n"
+	result += "\tinput reset;\n"
+	result += subresult_in
+	result += "\n"
+	result += "\t//--------------Output Ports-----------------------\n"
+	result += subresult_out
+	result += "\n"
+
+	result += "\t//--------------Reg declaration---------------------------------------------\n"
+	result += "\treg busy;\n"
+	result += "\treg [" + gw + ":0] owner;\n"
+	result += "\treg [" + gw + ":0] last;\n"
+	result += "\treg found;\n"
+	result += "\treg [" + gw + ":0] sel;\n"
+	result += "\n"
+
+	result += "\t//--------------Wire declaration--------------------------------------------\n"
+	result += "\twire [" + nprocs + "-1:0] req_i;\n"
+	result += "\n"
+
+	result += "\t//--------------Signal assignment----------------------------\n"
+	for i, p := range procs {
+		id := strconv.Itoa(i)
+		result += "\tassign req_i[" + id + "] = " + p + "req;\n"
+		result += "\tassign " + p + "grant = busy && (owner == " + id + ");\n"
+	}
+	result += "\n"
+
+	result += "\t// Round robin arbiter, starting from the processor after the last owner\n"
+	result += "\tinteger i, cand;\n"
+	result += "\talways @(*) begin\n"
+	result += "\t\tfound = 1'b0;\n"
+	result += "\t\tsel = 'b0;\n"
+	result += "\t\tfor (i = 0; i < " + nprocs + "; i = i + 1) begin\n"
+	result += "\t\t\tcand = last + 1 + i;\n"
+	result += "\t\t\tif (cand >= " + nprocs + ")\n"
+	result += "\t\t\t\tcand = cand - " + nprocs + ";\n"
+	result += "\t\t\tif (!found && req_i[cand]) begin\n"
+	result += "\t\t\t\tfound = 1'b1;\n"
+	result += "\t\t\t\tsel = cand;\n"
+	result += "\t\t\tend\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+
+	result += "\t// The mutex is free again when the owner drops its request\n"
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif(reset) begin\n"
+	result += "\t\t\tbusy <= #1 1'b0;\n"
+	result += "\t\t\towner <= #1 'b0;\n"
+	result += "\t\t\tlast <= #1 " + strconv.Itoa(num_processors-1) + ";\n"
+	result += "\t\tend\n"
+	result += "\t\telse if (busy) begin\n"
+	result += "\t\t\tif (!req_i[owner])\n"
+	result += "\t\t\t\tbusy <= #1 1'b0;\n"
+	result += "\t\tend\n"
+	result += "\t\telse if (found) begin\n"
+	result += "\t\t\tbusy <= #1 1'b1;\n"
+	result += "\t\t\towner <= #1 sel;\n"
+	result += "\t\t\tlast <= #1 sel;\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+	result += "endmodule\n"
+	result += "\n"
+
+	return result
+}
+
+func (sm Mutex_instance) Get_wires_perproc(bmach *Bondmachine, proc_id int, so_id int, flavor string) string {
+	result := ""
+	if soname, ok := bmach.Get_so_name(so_id); ok {
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "req;\n"
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "grant;\n"
+		result += "\n"
+	}
+	return result
+}
+
+func (sm Mutex_instance) Get_header_perproc(bmach *Bondmachine, proc_id int, so_id int, flavor string) string {
+	result := ""
+	if soname, ok := bmach.Get_so_name(so_id); ok {
+		result += ", p" + strconv.Itoa(proc_id) + soname + "req"
+		result += ", p" + strconv.Itoa(proc_id) + soname + "grant"
+	}
+	return result
+}
+
+func (sm Mutex_instance) Estimate_resources(bmach *Bondmachine, so_id int, flavor string) procbuilder.Resources {
+	// The arbiter, the owner and the grant decoders
+	users := bmach.Shared_users(so_id)
+	bits := Needed_bits(users)
+	return procbuilder.Resources{Luts: 2*users + bits, Ffs: 2*bits + 1, Levels: 2}.Chain(procbuilder.Mux_cost(users, 1))
+}
@@ -0,0 +1,210 @@
+package bondmachine
+
+import (
+	"procbuilder"
+	"strconv"
+	"strings"
+)
+
+// The counting semaphore. The requests are served one per cycle by a round robin arbiter: a release always, an
+// acquire while the count is positive. The acknowledge is held until the processor drops the request.
+
+type Semaphore struct{}
+
+func (op Semaphore) Shr_get_name() string {
+	return "semaphore"
+}
+
+func (op Semaphore) Shr_get_desc() string {
+	return "Semaphore"
+}
+
+func (op Semaphore) Shortname() string {
+	return "se"
+}
+
+func (op Semaphore) GV_config(element uint8) string {
+	result := ""
+	switch element {
+	case GVNODEINPROC:
+		result += "style=filled fillcolor=palegreen color=black"
+	case GVNODE:
+		result += "style=filled fillcolor=palegreen color=black"
+	case GVEDGE:
+		result += "arrowhead=none"
+	case GVCLUS:
+		result += "style=filled;\n\t\tcolor=black;\n\t\tfillcolor=grey75"
+	case GVCLUSINPROC:
+		result += "style=filled;\n\t\tcolor=black;\n\t\tfillcolor=grey75"
+	}
+	return result
+}
+
+func (op Semaphore) Instantiate(s string) (Shared_instance, bool) {
+	// semaphore:<count> with the initial count, 0 if missing
+	if strings.HasPrefix(s, "semaphore:") {
+		result := new(Semaphore_instance)
+		result.Shared_element = op
+		if len(s) > 10 {
+			if count, ok := strconv.Atoi(s[10:]); ok == nil && count >= 0 {
+				result.Count = count
+			} else {
+				return nil, false
+			}
+		}
+		return *result, true
+	}
+	return nil, false
+}
+
+// The instance struct
+
+type Semaphore_instance struct {
+	Shared_element
+	Count int // The initial count
+}
+
+func (sm Semaphore_instance) String() string {
+	return "semaphore:" + strconv.Itoa(sm.Count)
+}
+
+func (sm Semaphore_instance) Write_verilog(bmach *Bondmachine, so_index int, semaphore_name string, flavor string) string {
+
+	rsize := strconv.Itoa(int(bmach.Rsize))
+
+	procs := make([]string, 0)
+	for _, solist := range bmach.Shared_links {
+		for _, so_id := range solist {
+			if so_id == so_index {
+				procs = append(procs, "p"+strconv.Itoa(len(procs)))
+			}
+		}
+	}
+	num_processors := len(procs)
+	nprocs := strconv.Itoa(num_processors)
+	gw := strconv.Itoa(Needed_bits(num_processors) - 1)
+
+	subresult := ""
+	subresult_in := ""
+	subresult_out := ""
+	for _, p := range procs {
+		subresult += ", " + p + "acq, " + p + "rel, " + p + "ack"
+		subresult_in += "\tinput " + p + "acq;\n"
+		subresult_in += "\tinput " + p + "rel;\n"
+		subresult_out += "\toutput " + p + "ack;\n"
+	}
+
+	result := ""
+	result += "`timescale 1ns/1ps\n"
+	result += "module " + semaphore_name + "(clk, reset" + subresult + ");\n"
+	result += "\n"
+	result += "\t//--------------Input Ports-----------------------\n"
+	result += "\tinput clk;\n"
+	result += "\tinput reset;\n"
+	result += subresult_in
+	result += "\n"
+	result += "\t//--------------Output Ports-----------------------\n"
+	result += subresult_out
+	result += "\n"
+
+	result += "\t//--------------Reg declaration---------------------------------------------\n"
+	result += "\treg [" + rsize + "-1:0] count;\n"
+	result += "\treg [" + nprocs + "-1:0] ack_i;\n"
+	result += "\treg [" + gw + ":0] last;\n"
+	result += "\treg found;\n"
+	result += "\treg sel_rel;\n"
+	result += "\treg [" + gw + ":0] sel;\n"
+	result += "\n"
+
+	result += "\t//--------------Wire declaration--------------------------------------------\n"
+	result += "\twire [" + nprocs + "-1:0] acq_i;\n"
+	result += "\twire [" + nprocs + "-1:0] rel_i;\n"
+	result += "\n"
+
+	result += "\t//--------------Signal assignment----------------------------\n"
+	for i, p := range procs {
+		id := strconv.Itoa(i)
+		result += "\tassign acq_i[" + id + "] = " + p + "acq;\n"
+		result += "\tassign rel_i[" + id + "] = " + p + "rel;\n"
+		result += "\tassign " + p + "ack = ack_i[" + id + "];\n"
+	}
+	result += "\n"
+
+	result += "\t// Round robin arbiter among the requests not yet acknowledged, an acquire waits for a positive count\n"
+	result += "\tinteger i, cand;\n"
+	result += "\talways @(*) begin\n"
+	result += "\t\tfound = 1'b0;\n"
+	result += "\t\tsel = 'b0;\n"
+	result += "\t\tsel_rel = 1'b0;\n"
+	result += "\t\tfor (i = 0; i < " + nprocs + "; i = i + 1) begin\n"
+	result += "\t\t\tcand = last + 1 + i;\n"
+	result += "\t\t\tif (cand >= " + nprocs + ")\n"
+	result += "\t\t\t\tcand = cand - " + nprocs + ";\n"
+	result += "\t\t\tif (!found && !ack_i[cand]) begin\n"
+	result += "\t\t\t\tif (rel_i[cand]) begin\n"
+	result += "\t\t\t\t\tfound = 1'b1;\n"
+	result += "\t\t\t\t\tsel = cand;\n"
+	result += "\t\t\t\t\tsel_rel = 1'b1;\n"
+	result += "\t\t\t\tend\n"
+	result += "\t\t\t\telse if (acq_i[cand] && count != 0) begin\n"
+	result += "\t\t\t\t\tfound = 1'b1;\n"
+	result += "\t\t\t\t\tsel = cand;\n"
+	result += "\t\t\t\tend\n"
+	result += "\t\t\tend\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+
+	result += "\t// The acknowledges fall with their requests, a release saturates the count\n"
+	result += "\talways @(posedge clk or posedge reset) begin\n"
+	result += "\t\tif(reset) begin\n"
+	result += "\t\t\tcount <= #1 " + rsize + "'d" + strconv.Itoa(sm.Count) + ";\n"
+	result += "\t\t\tack_i <= #1 'b0;\n"
+	result += "\t\t\tlast <= #1 " + strconv.Itoa(num_processors-1) + ";\n"
+	result += "\t\tend\n"
+	result += "\t\telse begin\n"
+	result += "\t\t\tack_i <= #1 ack_i & (acq_i | rel_i);\n"
+	result += "\t\t\tif (found) begin\n"
+	result += "\t\t\t\tack_i[sel] <= #1 1'b1;\n"
+	result += "\t\t\t\tlast <= #1 sel;\n"
+	result += "\t\t\t\tif (!sel_rel)\n"
+	result += "\t\t\t\t\tcount <= #1 count - 1'b1;\n"
+	result += "\t\t\t\telse if (count != {" + rsize + "{1'b1}})\n"
+	result += "\t\t\t\t\tcount <= #1 count + 1'b1;\n"
+	result += "\t\t\tend\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "\n"
+	result += "endmodule\n"
+	result += "\n"
+
+	return result
+}
+
+func (sm Semaphore_instance) Get_wires_perproc(bmach *Bondmachine, proc_id int, so_id int, flavor string) string {
+	result := ""
+	if soname, ok := bmach.Get_so_name(so_id); ok {
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "acq;\n"
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "rel;\n"
+		result += "\twire p" + strconv.Itoa(proc_id) + soname + "ack;\n"
+		result += "\n"
+	}
+	return result
+}
+
+func (sm Semaphore_instance) Get_header_perproc(bmach *Bondmachine, proc_id int, so_id int, flavor string) string {
+	result := ""
+	if soname, ok := bmach.Get_so_name(so_id); ok {
+		result += ", p" + strconv.Itoa(proc_id) + soname + "acq"
+		result += ", p" + strconv.Itoa(proc_id) + soname + "rel"
+		result += ", p" + strconv.Itoa(proc_id) + soname + "ack"
+	}
+	return result
+}
+
+func (sm Semaphore_instance) Estimate_resources(bmach *Bondmachine, so_id int, flavor string) procbuilder.Resources {
+	// The count with its incrementer and decrementer, the arbiter and the acknowledges
+	users := bmach.Shared_users(so_id)
+	rsize := int(bmach.Rsize)
+	return procbuilder.Resources{Luts: 2*rsize + 3*users, Ffs: rsize + users + Needed_bits(users), Levels: 3}
+}
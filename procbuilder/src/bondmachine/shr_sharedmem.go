@@ -1,6 +1,7 @@
 package bondmachine
 
 import (
+	"procbuilder"
 	//"fmt"
 	"math"
 	"strconv"
@@ -242,3 +243,15 @@ func (sm Sharedmem_instance) Get_header_perproc(bmach *Bondmachine, proc_id int,
 	}
 	return result
 }
+
+func (sm Sharedmem_instance) Estimate_resources(bmach *Bondmachine, so_id int, flavor string) procbuilder.Resources {
+	// The memory and the arbiter among the processors requests
+	users := bmach.Shared_users(so_id)
+	addrbits := 1
+	for 1<<uint(addrbits) < sm.Depth {
+		addrbits++
+	}
+	mem := procbuilder.Memory_cost(sm.Depth, int(bmach.Rsize), flavor)
+	arbiter := procbuilder.Resources{Luts: 2 * users, Ffs: users + 2, Levels: 1}.Chain(procbuilder.Mux_cost(users, int(bmach.Rsize)+addrbits+1))
+	return mem.Chain(arbiter)
+}
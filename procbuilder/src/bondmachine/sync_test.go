@@ -0,0 +1,72 @@
+package bondmachine
+
+import (
+	"fmt"
+	"procbuilder"
+	"sort"
+	"testing"
+)
+
+// Two processors increment a shared counter within a critical section guarded by a mutex, they are never both inside
+// and both get in
+func TestMutexCounter(t *testing.T) {
+	mach := new(procbuilder.Machine)
+	arch := &mach.Arch
+	arch.Modes = []string{"ha"}
+	arch.Rsize = 8
+	arch.R = 2
+	arch.L = 4
+	arch.O = 4
+	arch.Shared_constraints = "mutex:,counter:"
+	arch.Op = make([]procbuilder.Opcode, 0)
+	for _, op := range procbuilder.Allopcodes {
+		switch op.Op_get_name() {
+		case "fadd", "inc", "j", "lock", "rset", "unlock":
+			arch.Op = append(arch.Op, op)
+		}
+	}
+	sort.Sort(procbuilder.ByName(arch.Op))
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("lock mx0\nrset r1 1\nfadd r1 ct0\ninc r2\ninc r2\nunlock mx0\nj 0\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	bmach := new(Bondmachine)
+	bmach.Rsize = 8
+	bmach.Init()
+	bmach.Domains = append(bmach.Domains, mach)
+	bmach.Add_processor(0)
+	bmach.Add_processor(0)
+	bmach.Add_shared_objects([]string{"mutex:", "counter:"})
+	for _, link := range [][]string{{"0", "0"}, {"0", "1"}, {"1", "0"}, {"1", "1"}} {
+		bmach.Connect_processor_shared_object(link)
+	}
+
+	vm := new(VM)
+	vm.Bmach = bmach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	vm.Launch_processors(nil)
+
+	for i := 0; i < 60; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+		if vm.Processors[0].Pc >= 1 && vm.Processors[0].Pc <= 5 && vm.Processors[1].Pc >= 1 && vm.Processors[1].Pc <= 5 {
+			t.Fatal("Both processors in the critical section at step", i)
+		}
+	}
+
+	done0 := int(vm.Processors[0].Registers[2].(uint8)) / 2
+	done1 := int(vm.Processors[1].Registers[2].(uint8)) / 2
+	count := int(vm.Sync.Objects[1].Value)
+	fmt.Println("sections:", done0, done1, "counter:", count, vm.Perf_report())
+
+	if done0 == 0 || done1 == 0 {
+		t.Error("A processor never got the mutex")
+	}
+	if count < done0+done1 || count > done0+done1+1 {
+		t.Error("Wrong counter", count)
+	}
+}
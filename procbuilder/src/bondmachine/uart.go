@@ -0,0 +1,154 @@
+package bondmachine
+
+import (
+	"io"
+	"strconv"
+)
+
+// The serial line protocol of the uart extra module. Every frame starts with the sync byte followed by a header
+// carrying the frame kind in the two high bits and the IO number in the others, a value frame carries the IO value
+// too, in as many bytes as the register size needs, the most significant first.
+//
+//   - the host sends a value frame to set a bondmachine input, the bondmachine answers with an ack of the input
+//   - the bondmachine sends a value frame when an output changes, and it sends the next change of that output only
+//     after the host ack
+//   - the host sends a read frame to get an output at once, the answer is a value frame to ack as usual
+
+const (
+	UART_SYNC = 0xA5
+)
+
+const (
+	UART_VALUE = uint8(0)
+	UART_ACK   = uint8(1)
+	UART_READ  = uint8(2)
+)
+
+const UART_MAX_IO = 64
+
+type Uart_frame struct {
+	Kind  uint8
+	Index int
+	Value uint64
+}
+
+func Uart_value_bytes(rsize uint8) int {
+	return (int(rsize) + 7) / 8
+}
+
+func (frame Uart_frame) String() string {
+	switch frame.Kind {
+	case UART_VALUE:
+		return "value " + strconv.Itoa(frame.Index) + " " + strconv.FormatUint(frame.Value, 10)
+	case UART_ACK:
+		return "ack " + strconv.Itoa(frame.Index)
+	case UART_READ:
+		return "read " + strconv.Itoa(frame.Index)
+	}
+	return "unknown"
+}
+
+func (frame Uart_frame) Encode(rsize uint8) []byte {
+	result := []byte{UART_SYNC, frame.Kind<<6 | uint8(frame.Index&0x3f)}
+	if frame.Kind == UART_VALUE {
+		for i := Uart_value_bytes(rsize) - 1; i >= 0; i-- {
+			result = append(result, byte(frame.Value>>(8*uint(i))))
+		}
+	}
+	return result
+}
+
+// Uart_read_frame waits for the next frame, the bytes before the sync are skipped
+func Uart_read_frame(port io.Reader, rsize uint8) (Uart_frame, error) {
+	buf := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(port, buf); err != nil {
+			return Uart_frame{}, err
+		}
+		if buf[0] == UART_SYNC {
+			break
+		}
+	}
+	if _, err := io.ReadFull(port, buf); err != nil {
+		return Uart_frame{}, err
+	}
+	frame := Uart_frame{Kind: buf[0] >> 6, Index: int(buf[0] & 0x3f)}
+	if frame.Kind == UART_VALUE {
+		value := make([]byte, Uart_value_bytes(rsize))
+		if _, err := io.ReadFull(port, value); err != nil {
+			return Uart_frame{}, err
+		}
+		for _, b := range value {
+			frame.Value = frame.Value<<8 | uint64(b)
+		}
+	}
+	return frame, nil
+}
+
+// The host side of the protocol, on a serial port or on the pty of an emulated bondmachine
+type Uart_client struct {
+	Port    io.ReadWriter
+	Rsize   uint8
+	Outputs map[int]uint64 // The last value received for every output
+}
+
+func (cl *Uart_client) send(frame Uart_frame) error {
+	_, err := cl.Port.Write(frame.Encode(cl.Rsize))
+	return err
+}
+
+// Wait returns the next frame, the value frames are acknowledged and recorded in Outputs
+func (cl *Uart_client) Wait() (Uart_frame, error) {
+	frame, err := Uart_read_frame(cl.Port, cl.Rsize)
+	if err != nil {
+		return frame, err
+	}
+	if frame.Kind == UART_VALUE {
+		if cl.Outputs == nil {
+			cl.Outputs = make(map[int]uint64)
+		}
+		cl.Outputs[frame.Index] = frame.Value
+		if err := cl.send(Uart_frame{Kind: UART_ACK, Index: frame.Index}); err != nil {
+			return frame, err
+		}
+	}
+	return frame, nil
+}
+
+// Set writes an input and waits for its ack
+func (cl *Uart_client) Set(input int, value uint64) error {
+	if input < 0 || input >= UART_MAX_IO {
+		return Prerror{"Input " + strconv.Itoa(input) + " out of the protocol range"}
+	}
+	if err := cl.send(Uart_frame{Kind: UART_VALUE, Index: input, Value: value}); err != nil {
+		return err
+	}
+	for {
+		frame, err := cl.Wait()
+		if err != nil {
+			return err
+		}
+		if frame.Kind == UART_ACK && frame.Index == input {
+			return nil
+		}
+	}
+}
+
+// Get reads an output
+func (cl *Uart_client) Get(output int) (uint64, error) {
+	if output < 0 || output >= UART_MAX_IO {
+		return 0, Prerror{"Output " + strconv.Itoa(output) + " out of the protocol range"}
+	}
+	if err := cl.send(Uart_frame{Kind: UART_READ, Index: output}); err != nil {
+		return 0, err
+	}
+	for {
+		frame, err := cl.Wait()
+		if err != nil {
+			return 0, err
+		}
+		if frame.Kind == UART_VALUE && frame.Index == output {
+			return frame.Value, nil
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package bondmachine
+
+import (
+	"io"
+)
+
+// Uart_bridge serves the uart protocol for an emulated bondmachine, the host sees the IOs of the uart module as
+// it would on the board. Step is called after every step of the VM.
+type Uart_bridge struct {
+	Vm     *VM
+	Port   io.ReadWriter
+	Module *Uart_extra
+
+	inputs  map[int]bool
+	outputs []int
+	sent    map[int]uint64
+	pending map[int]bool
+	read    map[int]bool
+	frames  chan Uart_frame
+	errs    chan error
+}
+
+func io_value(reg interface{}) uint64 {
+	switch v := reg.(type) {
+	case uint8:
+		return uint64(v)
+	case uint16:
+		return uint64(v)
+	case uint32:
+		return uint64(v)
+	case uint64:
+		return v
+	}
+	return 0
+}
+
+func (vm *VM) io_reg(value uint64) interface{} {
+	switch vm.Bmach.Rsize {
+	case 8:
+		return uint8(value)
+	case 16:
+		return uint16(value)
+	case 32:
+		return uint32(value)
+	}
+	return value
+}
+
+func (br *Uart_bridge) Init() error {
+	if err := br.Module.Check(br.Vm.Bmach); err != nil {
+		return err
+	}
+	br.inputs = make(map[int]bool)
+	for _, iname := range br.Module.Inputs {
+		index, _ := uart_io_index(iname)
+		br.inputs[index] = true
+	}
+	br.outputs = make([]int, 0)
+	for _, oname := range br.Module.Outputs {
+		index, _ := uart_io_index(oname)
+		br.outputs = append(br.outputs, index)
+	}
+	br.sent = make(map[int]uint64)
+	br.pending = make(map[int]bool)
+	br.read = make(map[int]bool)
+	br.frames = make(chan Uart_frame, 64)
+	br.errs = make(chan error, 1)
+
+	go func() {
+		for {
+			frame, err := Uart_read_frame(br.Port, br.Vm.Bmach.Rsize)
+			if err != nil {
+				br.errs <- err
+				return
+			}
+			br.frames <- frame
+		}
+	}()
+	return nil
+}
+
+func (br *Uart_bridge) send(frame Uart_frame) error {
+	_, err := br.Port.Write(frame.Encode(br.Vm.Bmach.Rsize))
+	return err
+}
+
+// Step applies the frames received from the host and sends the changed outputs
+func (br *Uart_bridge) Step() error {
+	for done := false; !done; {
+		select {
+		case frame := <-br.frames:
+			switch frame.Kind {
+			case UART_VALUE:
+				if br.inputs[frame.Index] {
+					br.Vm.Inputs_regs[frame.Index] = br.Vm.io_reg(frame.Value)
+					if err := br.send(Uart_frame{Kind: UART_ACK, Index: frame.Index}); err != nil {
+						return err
+					}
+				}
+			case UART_ACK:
+				delete(br.pending, frame.Index)
+			case UART_READ:
+				br.read[frame.Index] = true
+			}
+		case err := <-br.errs:
+			return err
+		default:
+			done = true
+		}
+	}
+
+	for _, index := range br.outputs {
+		value := io_value(br.Vm.Outputs_regs[index])
+		if br.read[index] || !br.pending[index] && value != br.sent[index] {
+			if err := br.send(Uart_frame{Kind: UART_VALUE, Index: index, Value: value}); err != nil {
+				return err
+			}
+			br.sent[index] = value
+			br.pending[index] = true
+			delete(br.read, index)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package bondmachine
+
+import (
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+// The serial ports and the pseudo terminals of the uart protocol, both in raw mode
+
+const uart_cbaud = 0x100f
+
+var uart_speeds = map[int]uint32{
+	9600:    syscall.B9600,
+	19200:   syscall.B19200,
+	38400:   syscall.B38400,
+	57600:   syscall.B57600,
+	115200:  syscall.B115200,
+	230400:  syscall.B230400,
+	460800:  syscall.B460800,
+	921600:  syscall.B921600,
+	1000000: syscall.B1000000,
+}
+
+func uart_ioctl(fd uintptr, req uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// uart_raw disables the line discipline processing, a speed of 0 leaves the speed unchanged
+func uart_raw(f *os.File, speed uint32) error {
+	var tio syscall.Termios
+	if err := uart_ioctl(f.Fd(), syscall.TCGETS, unsafe.Pointer(&tio)); err != nil {
+		return err
+	}
+	tio.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	tio.Oflag &^= syscall.OPOST
+	tio.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	tio.Cflag &^= syscall.CSIZE | syscall.PARENB
+	tio.Cflag |= syscall.CS8 | syscall.CREAD | syscall.CLOCAL
+	tio.Cc[syscall.VMIN] = 1
+	tio.Cc[syscall.VTIME] = 0
+	if speed != 0 {
+		tio.Cflag &^= uart_cbaud
+		tio.Cflag |= speed
+		tio.Ispeed = speed
+		tio.Ospeed = speed
+	}
+	return uart_ioctl(f.Fd(), syscall.TCSETS, unsafe.Pointer(&tio))
+}
+
+// Uart_open opens a serial port at the given baud rate, a pty is opened the same way
+func Uart_open(path string, baud int) (*os.File, error) {
+	speed, ok := uart_speeds[baud]
+	if !ok {
+		return nil, Prerror{"Unsupported baud rate " + strconv.Itoa(baud)}
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := uart_raw(f, speed); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// Uart_pty creates a pseudo terminal, the master side is returned with the name of the slave one the host clients
+// open. The slave is kept open too, so the master reads wait for the clients instead of failing.
+func Uart_pty() (*os.File, *os.File, string, error) {
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	unlock := int32(0)
+	if err := uart_ioctl(master.Fd(), syscall.TIOCSPTLCK, unsafe.Pointer(&unlock)); err != nil {
+		master.Close()
+		return nil, nil, "", err
+	}
+	var ptn uint32
+	if err := uart_ioctl(master.Fd(), syscall.TIOCGPTN, unsafe.Pointer(&ptn)); err != nil {
+		master.Close()
+		return nil, nil, "", err
+	}
+	name := "/dev/pts/" + strconv.Itoa(int(ptn))
+	slave, err := os.OpenFile(name, os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		master.Close()
+		return nil, nil, "", err
+	}
+	if err := uart_raw(slave, 0); err != nil {
+		master.Close()
+		slave.Close()
+		return nil, nil, "", err
+	}
+	return master, slave, name, nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package bondmachine
+
+import (
+	"os"
+	"runtime"
+)
+
+// The serial ports and the pseudo terminals are implemented only on linux, elsewhere the uart protocol fails to open
+
+// Uart_open opens a serial port at the given baud rate, a pty is opened the same way
+func Uart_open(path string, baud int) (*os.File, error) {
+	return nil, Prerror{"Serial ports are unsupported on this platform (" + runtime.GOOS + ")"}
+}
+
+// Uart_pty creates a pseudo terminal, the master side is returned with the name of the slave one the host clients
+// open. The slave is kept open too, so the master reads wait for the clients instead of failing.
+func Uart_pty() (*os.File, *os.File, string, error) {
+	return nil, nil, "", Prerror{"Pseudo terminals are unsupported on this platform (" + runtime.GOOS + ")"}
+}
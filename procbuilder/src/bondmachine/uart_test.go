@@ -0,0 +1,141 @@
+package bondmachine
+
+import (
+	"fmt"
+	"procbuilder"
+	"sort"
+	"testing"
+	"time"
+)
+
+// A processor writing o0 = i0 + 1, driven by the host through the uart protocol on a pty
+func TestUartPty(t *testing.T) {
+	mach := new(procbuilder.Machine)
+	arch := &mach.Arch
+	arch.Modes = []string{"ha"}
+	arch.Rsize = 8
+	arch.R = 1
+	arch.N = 1
+	arch.M = 1
+	arch.L = 1
+	arch.O = 4
+	for _, op := range procbuilder.Allopcodes {
+		switch op.Op_get_name() {
+		case "i2r", "inc", "j", "r2o":
+			arch.Op = append(arch.Op, op)
+		}
+	}
+	sort.Sort(procbuilder.ByName(arch.Op))
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("i2r r0 i0\ninc r0\nr2o r0 o0\nj 0\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	bmach := new(Bondmachine)
+	bmach.Rsize = 8
+	bmach.Init()
+	bmach.Domains = append(bmach.Domains, mach)
+	bmach.Add_processor(0)
+	bmach.Add_input()
+	bmach.Add_output()
+	bmach.Add_bond([]string{"i0", "p0i0"})
+	bmach.Add_bond([]string{"p0o0", "o0"})
+
+	vm := new(VM)
+	vm.Bmach = bmach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := vm.Launch_processors(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	master, slave, name, err := Uart_pty()
+	if err != nil {
+		t.Skip("No pty available: ", err)
+	}
+	defer master.Close()
+	defer slave.Close()
+
+	bridge := &Uart_bridge{Vm: vm, Port: master, Module: &Uart_extra{Inputs: []string{"i0"}, Outputs: []string{"o0"}}}
+	if err := bridge.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan bool)
+	stepped := make(chan error, 1)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				stepped <- nil
+				return
+			default:
+			}
+			if _, err := vm.Step(nil); err != nil {
+				stepped <- err
+				return
+			}
+			if err := bridge.Step(); err != nil {
+				stepped <- err
+				return
+			}
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	port, err := Uart_open(name, 115200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer port.Close()
+	client := &Uart_client{Port: port, Rsize: 8}
+
+	for _, value := range []uint64{41, 99} {
+		if err := client.Set(0, value); err != nil {
+			t.Fatal(err)
+		}
+		for client.Outputs[0] != value+1 {
+			if _, err := client.Wait(); err != nil {
+				t.Fatal(err)
+			}
+		}
+		fmt.Println("i0", value, "o0", client.Outputs[0])
+	}
+	if value, err := client.Get(0); err != nil || value != 100 {
+		t.Error("Wrong output read", value, err)
+	}
+
+	close(stop)
+	if err := <-stepped; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUartVerilog(t *testing.T) {
+	bmach := new(Bondmachine)
+	bmach.Rsize = 12
+	bmach.Init()
+	bmach.Add_input()
+	bmach.Add_output()
+	bmach.Add_output()
+
+	uart := &Uart_extra{Inputs: []string{"i0"}, Outputs: []string{"o0", "o1"}}
+	if err := uart.Check(bmach); err != nil {
+		t.Fatal(err)
+	}
+	iomaps := &IOmap{Assoc: map[string]string{}}
+	top, err := bmach.Write_verilog_board("bondmachine", "basys3", iomaps, []ExtraModule{uart})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(top)
+	cons, err := bmach.Board_constraints(Allboards["basys3"], iomaps, []ExtraModule{uart})
+	if err != nil || len(cons) != 4 {
+		t.Error("Wrong uart constraints", cons, err)
+	}
+
+	if err := (&Uart_extra{Inputs: []string{"i3"}}).Check(bmach); err == nil {
+		t.Error("Unknown input accepted")
+	}
+}
@@ -3,7 +3,6 @@ package bondmachine
 import (
 	"errors"
 	"fmt"
-	"os"
 	"procbuilder"
 	"regexp"
 	"simbox"
@@ -35,6 +34,14 @@ func (bmach *Bondmachine) Write_verilog(conf *Config, flavor string, iomaps *IOm
 
 		pconf := conf.ProcbuilderConfig()
 
+		out := conf.Output
+		if out == nil {
+			out = new(procbuilder.Output)
+		}
+		if err := out.Init(); err != nil {
+			return err
+		}
+
 		//Instatiation of the Processor
 		for i, dom_id := range bmach.Processors {
 
@@ -60,40 +67,20 @@ func (bmach *Bondmachine) Write_verilog(conf *Config, flavor string, iomaps *IOm
 
 			arch_names := map[string]string{"processor": "p" + strconv.Itoa(i), "rom": "p" + strconv.Itoa(i) + "rom", "ram": "p" + strconv.Itoa(i) + "ram"}
 
-			if _, err := os.Stat(arch_filename + ".v"); os.IsNotExist(err) {
-				f, err := os.Create(arch_filename + ".v")
-				check(err)
-				//defer f.Close()
-				_, err = f.WriteString(dom.Arch.Write_verilog(arch_mod_name, arch_names, flavor))
-				check(err)
-				f.Close()
+			if err := out.Write(arch_filename+".v", dom.Arch.Write_verilog(arch_mod_name, arch_names, flavor)); err != nil {
+				return err
 			}
 
-			if _, err := os.Stat(arch_names["processor"] + ".v"); os.IsNotExist(err) {
-				f, err := os.Create(arch_names["processor"] + ".v")
-				check(err)
-				//defer f.Close()
-				_, err = f.WriteString(dom.Arch.Conproc.Write_verilog(pconf, &dom.Arch, arch_names["processor"], flavor))
-				check(err)
-				f.Close()
+			if err := out.Write(arch_names["processor"]+".v", dom.Arch.Conproc.Write_verilog(pconf, &dom.Arch, arch_names["processor"], flavor)); err != nil {
+				return err
 			}
 
-			if _, err := os.Stat(arch_names["ram"] + ".v"); os.IsNotExist(err) {
-				f, err := os.Create(arch_names["ram"] + ".v")
-				check(err)
-				//defer f.Close()
-				_, err = f.WriteString(dom.Arch.Ram.Write_verilog(dom, arch_names["ram"], flavor))
-				check(err)
-				f.Close()
+			if err := out.Write(arch_names["ram"]+".v", dom.Arch.Ram.Write_verilog(dom, arch_names["ram"], flavor)); err != nil {
+				return err
 			}
 
-			if _, err := os.Stat(arch_names["rom"] + ".v"); os.IsNotExist(err) {
-				f, err := os.Create(arch_names["rom"] + ".v")
-				check(err)
-				//defer f.Close()
-				_, err = f.WriteString(dom.Arch.Rom.Write_verilog(dom, arch_names["rom"], flavor))
-				check(err)
-				f.Close()
+			if err := out.Write(arch_names["rom"]+".v", dom.Arch.Rom.Write_verilog(dom, arch_names["rom"], flavor)); err != nil {
+				return err
 			}
 
 		}
@@ -108,56 +95,62 @@ func (bmach *Bondmachine) Write_verilog(conf *Config, flavor string, iomaps *IOm
 					seq[sname] = 0
 				}
 
-				if _, err := os.Stat(sname + strconv.Itoa(seq[sname]) + ".v"); os.IsNotExist(err) {
-					f, err := os.Create(sname + strconv.Itoa(seq[sname]) + ".v")
-					check(err)
-					defer f.Close()
-					_, err = f.WriteString(so.Write_verilog(bmach, i, sname+strconv.Itoa(seq[sname]), flavor))
-					check(err)
+				if err := out.Write(sname+strconv.Itoa(seq[sname])+".v", so.Write_verilog(bmach, i, sname+strconv.Itoa(seq[sname]), flavor)); err != nil {
+					return err
 				}
 
 				seq[sname]++
 			}
 		}
 
-		if _, err := os.Stat("bondmachine.v"); os.IsNotExist(err) {
-			f, err := os.Create("bondmachine.v")
-			check(err)
-			defer f.Close()
-			_, err = f.WriteString(bmach.Write_verilog_main(conf, "bondmachine", flavor))
-			check(err)
+		if err := out.Write("bondmachine.v", bmach.Write_verilog_main(conf, "bondmachine", flavor)); err != nil {
+			return err
+		}
+
+		if conf.Bus_wrapper != "" {
+			if err := bmach.Write_bus_wrapper(out, conf.Bus_wrapper, "bondmachine"); err != nil {
+				return err
+			}
 		}
 
 		for _, mod := range extramods {
 			files, filescode := mod.ExtraFiles()
 			for i, file := range files {
-				f, err := os.Create(file)
-				check(err)
-				_, err = f.WriteString(filescode[i])
-				check(err)
-				f.Close()
+				if err := out.Write(file, filescode[i]); err != nil {
+					return err
+				}
 			}
 		}
 
+		target := procbuilder.Target{}
+
 		switch flavor {
 		case "iverilog_simulation", "iverilog":
-			if _, err := os.Stat("bondmachine_tb.v"); os.IsNotExist(err) {
-				f, err := os.Create("bondmachine_tb.v")
-				check(err)
-				defer f.Close()
-				_, err = f.WriteString(bmach.Write_verilog_testbench("bondmachine", flavor, iomaps, extramods, sbox))
-				check(err)
-			}
-		case "basys3", "kintex7":
-			if _, err := os.Stat("bondmachine_main.v"); os.IsNotExist(err) {
-				f, err := os.Create("bondmachine_main.v")
-				check(err)
-				defer f.Close()
-				_, err = f.WriteString(bmach.Write_verilog_board("bondmachine", flavor, iomaps, extramods))
-				check(err)
+			if err := out.Write("bondmachine_tb.v", bmach.Write_verilog_testbench("bondmachine", flavor, iomaps, extramods, sbox)); err != nil {
+				return err
 			}
+			target.Top = "bondmachine_tb"
 		default:
-			return Prerror{"Verilog flavor unknown"}
+			// Any board of the database, the built-in ones and the loaded with -board-file
+			board, ok := Allboards[flavor]
+			if !ok {
+				return Prerror{"Verilog flavor unknown"}
+			}
+			top_verilog, err := bmach.Write_verilog_board("bondmachine", flavor, iomaps, extramods)
+			if err != nil {
+				return err
+			}
+			if err := out.Write("bondmachine_main.v", top_verilog); err != nil {
+				return err
+			}
+			if err := bmach.Write_board_constraints(out, "bondmachine_main", flavor, iomaps, extramods); err != nil {
+				return err
+			}
+			target = procbuilder.Target{Top: "bondmachine_main", Toolchain: board.Toolchain, Part: board.Part, Family: board.Family}
+		}
+
+		if err := out.Write_manifest(target); err != nil {
+			return err
 		}
 	} else {
 		return Prerror{"No defined domains"}
@@ -1119,27 +1112,11 @@ func (bmach *Bondmachine) Write_verilog_udpbond(module_name string, flavor strin
 	return "", errors.New("No udpbond module found")
 }
 
-func (bmach *Bondmachine) Write_verilog_board(module_name string, flavor string, iomaps *IOmap, extramods []ExtraModule) string {
-
-	resolved_io := make(map[string]string)
-
-	slow_module := false
-	var slow_params map[string]string
-
-	// etherbond and udpbond are exclusive
-
-	etherbond_module := false
-	var etherbond_params map[string]string
-
-	udpbond_module := false
-	var udpbond_params map[string]string
-
-	var inames []string
-	var onames []string
+func (bmach *Bondmachine) Write_verilog_board(module_name string, flavor string, iomaps *IOmap, extramods []ExtraModule) (string, error) {
 
-	basys3_7segment_module := false
-	var basys3_7segment_params map[string]string
-	basys3_7segment_mapped := ""
+	if err := bmach.Check_extra_modules(iomaps, extramods); err != nil {
+		return "", err
+	}
 
 	result := ""
 	result_headers := ""
@@ -1150,41 +1127,11 @@ func (bmach *Bondmachine) Write_verilog_board(module_name string, flavor string,
 
 		result += mod.Static_verilog()
 
-		if mod.Get_Name() == "slow" {
-			slow_module = true
-			slow_params = mod.Get_Params().Params
-		}
-		if mod.Get_Name() == "etherbond" {
-			etherbond_module = true
-			etherbond_params = mod.Get_Params().Params
-			//fmt.Println(etherbond_params)
-			if subresult, ok := bmach.Write_verilog_etherbond("etherbond", flavor, iomaps, extramods); ok == nil {
+		if writer := extra_module_verilog(mod.Get_Name()); writer != nil {
+			if subresult, err := writer(bmach, mod.Get_Name(), flavor, iomaps, extramods); err == nil {
 				result += subresult
 			} else {
-				// TODO proper error handling
-			}
-			inames = strings.Split(etherbond_params["inputs"], ",")
-			onames = strings.Split(etherbond_params["outputs"], ",")
-		}
-		if mod.Get_Name() == "udpbond" {
-			udpbond_module = true
-			udpbond_params = mod.Get_Params().Params
-			//fmt.Println(udpbond_params)
-			if subresult, ok := bmach.Write_verilog_udpbond("udpbond", flavor, iomaps, extramods); ok == nil {
-				result += subresult
-			} else {
-				// TODO proper error handling
-			}
-			inames = strings.Split(udpbond_params["inputs"], ",")
-			onames = strings.Split(udpbond_params["outputs"], ",")
-		}
-		if mod.Get_Name() == "basys3_7segment" {
-			basys3_7segment_module = true
-			basys3_7segment_params = mod.Get_Params().Params
-			if subresult, ok := bmach.Write_verilog_basys3_7segment("basys3_7segment", flavor, iomaps, extramods); ok == nil {
-				result += subresult
-			} else {
-				// TODO proper error handling
+				return "", err
 			}
 		}
 	}
@@ -1207,49 +1154,26 @@ func (bmach *Bondmachine) Write_verilog_board(module_name string, flavor string,
 	result += "\tinput " + clk_name + ",\n"
 	result += "\tinput " + rst_name + ",\n"
 
+	board_io := false
+
 	for i := 0; i < bmach.Inputs; i++ {
-		iname := Get_input_name(i)
-		resolved_io[iname] = "none"
-		subresult := ""
-		if rname, ok := iomaps.Assoc[iname]; ok {
-			resolved_io[iname] = "board"
-			subresult = "\tinput " + rname + ",\n"
+		if rname, ok := iomaps.Assoc[Get_input_name(i)]; ok {
+			board_io = true
+			result += "\tinput " + rname + ",\n"
 		}
-		result += subresult
 	}
 
 	for i := 0; i < bmach.Outputs; i++ {
-		oname := Get_output_name(i)
-		resolved_io[oname] = "none"
-		subresult := ""
-		if rname, ok := iomaps.Assoc[oname]; ok {
-			resolved_io[oname] = "board"
-			subresult = "\toutput reg " + rname + ",\n"
+		if rname, ok := iomaps.Assoc[Get_output_name(i)]; ok {
+			board_io = true
+			result += "\toutput reg " + rname + ",\n"
 		}
-		result += subresult
-	}
-
-	if basys3_7segment_module {
-		result += "\toutput [6:0] segment,\n"
-		result += "\toutput enable_D1,\n"
-		result += "\toutput enable_D2,\n"
-		result += "\toutput enable_D3,\n"
-		result += "\toutput enable_D4,\n"
-		result += "\toutput dp,\n"
 	}
 
-	if etherbond_module {
-		result += "\toutput sck,\n"
-		result += "\toutput mosi,\n"
-		result += "\toutput cs_n,\n"
-		result += "\tinput miso,\n"
-		result += "\tinput int_n,\n"
-	}
-
-	if udpbond_module {
-		result += "\toutput wifi_enable,\n"
-		result += "\tinput wifi_rx,\n"
-		result += "\toutput wifi_tx,\n"
+	for _, mod := range extramods {
+		for _, port := range mod.Get_Ports() {
+			result += "\t" + port.String() + ",\n"
+		}
 	}
 
 	result = result[0:len(result)-2] + "\n);\n\n"
@@ -1258,87 +1182,60 @@ func (bmach *Bondmachine) Write_verilog_board(module_name string, flavor string,
 		result += "\tassign  reset = " + rst_name + ";\n"
 	}
 
-	// The External_inputs connected are defined as input port
+	// The IOs used by the extra modules
+	used := make(map[string]bool)
+	for _, mod := range extramods {
+		inputs, outputs := mod.Get_IOs(bmach)
+		for _, ioname := range append(inputs, outputs...) {
+			used[ioname] = true
+		}
+	}
+
+	// The wires of the bondmachine IOs, the board ones are sampled on the clock
+	ios := make(map[string]string)
 	for i := 0; i < bmach.Inputs; i++ {
 		iname := Get_input_name(i)
+		ios[iname] = "Input" + strconv.Itoa(i)
 		if _, ok := iomaps.Assoc[iname]; ok {
 			result += "\treg [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] Input" + strconv.Itoa(i) + ";\n"
-			continue
-		}
-
-		if etherbond_module {
-			for _, ethiname := range inames {
-				if iname == ethiname {
-					resolved_io[iname] = "etherbond"
-					result += "\twire [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] Input" + strconv.Itoa(i) + ";\n"
-					result += "\twire Input" + strconv.Itoa(i) + "_valid;\n"
-					result += "\twire Input" + strconv.Itoa(i) + "_received;\n"
-					break
-				}
-			}
-		}
-
-		if udpbond_module {
-			for _, ethiname := range inames {
-				if iname == ethiname {
-					resolved_io[iname] = "udpbond"
-					result += "\twire [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] Input" + strconv.Itoa(i) + ";\n"
-					result += "\twire Input" + strconv.Itoa(i) + "_valid;\n"
-					result += "\twire Input" + strconv.Itoa(i) + "_received;\n"
-					break
-				}
-			}
+		} else if used[iname] {
+			result += "\twire [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] Input" + strconv.Itoa(i) + ";\n"
 		}
 	}
 
-	// The External_inputs connected are defined as input port
 	for i := 0; i < bmach.Outputs; i++ {
 		oname := Get_output_name(i)
-		if _, ok := iomaps.Assoc[oname]; ok {
+		ios[oname] = "Output" + strconv.Itoa(i)
+		if _, ok := iomaps.Assoc[oname]; ok || used[oname] {
 			result += "\twire [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] Output" + strconv.Itoa(i) + ";\n"
-			continue
 		}
+	}
 
-		if etherbond_module {
-			for _, ethoname := range onames {
-				if oname == ethoname {
-					resolved_io[oname] = "etherbond"
-					result += "\twire [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] Output" + strconv.Itoa(i) + ";\n"
-					result += "\twire Onput" + strconv.Itoa(i) + "_valid;\n"
-					result += "\twire Onput" + strconv.Itoa(i) + "_received;\n"
-					break
-				}
-			}
-		}
+	result += "\n"
 
-		if udpbond_module {
-			for _, ethoname := range onames {
-				if oname == ethoname {
-					resolved_io[oname] = "udpbond"
-					result += "\twire [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] Output" + strconv.Itoa(i) + ";\n"
-					result += "\twire Onput" + strconv.Itoa(i) + "_valid;\n"
-					result += "\twire Onput" + strconv.Itoa(i) + "_received;\n"
-					break
-				}
-			}
-		}
+	clock_string := clk_name
 
-		if basys3_7segment_module {
-			if oname == basys3_7segment_params["mapped_output"] {
-				resolved_io[oname] = "basys3_7segment"
-				result += "\twire [" + strconv.Itoa(int(bmach.Rsize)-1) + ":0] Output" + strconv.Itoa(i) + ";\n"
-				basys3_7segment_mapped = "Output" + strconv.Itoa(i)
-			}
+	for _, mod := range extramods {
+		if clocking := mod.Get_Clocking(); clocking.Drive_clock != "" {
+			clock_string = clocking.Drive_clock
 		}
 	}
 
-	result += "\n"
+	instances := ""
 
-	clock_string := clk_name
-
-	if slow_module {
-		result += "\treg [31:0] divider;\n\n"
-		clock_string = "divider[" + slow_params["slow_factor"] + "]"
+	for _, mod := range extramods {
+		clocking := mod.Get_Clocking()
+		clock := clk_name
+		if clocking.Clock == "bondmachine" {
+			clock = clock_string
+		}
+		reset := "reset"
+		if !clocking.Reset {
+			reset = "1'b0"
+		}
+		declarations, instance := mod.Board_verilog(bmach, clock, reset, ios)
+		result += declarations
+		instances += instance
 	}
 
 	result += "\t" + module_name + " " + module_name + "_inst " + "(" + clock_string + ", reset"
@@ -1355,105 +1252,43 @@ func (bmach *Bondmachine) Write_verilog_board(module_name string, flavor string,
 
 	result += ");\n\n"
 
-	if etherbond_module {
-		result += "\tetherbond_main etherbond_main_inst " + "(" + clk_name + ", reset"
-		result += ", sck"
-		result += ", mosi"
-		result += ", cs_n"
-		result += ", miso"
-		result += ", int_n"
-		for _, iname := range inames {
-			for i := 0; i < bmach.Inputs; i++ {
-				iiname := Get_input_name(i)
-				if iiname == iname {
-					result += ", Input" + strconv.Itoa(i)
-				}
-			}
-		}
+	result += instances
 
-		for _, oname := range onames {
-			for i := 0; i < bmach.Outputs; i++ {
-				ooname := Get_output_name(i)
-				if ooname == oname {
-					result += ", Output" + strconv.Itoa(i)
-				}
-			}
-		}
+	if board_io {
 
-		result += ");\n"
-	}
+		result += "\talways @ (posedge " + clk_name + ") begin\n"
 
-	if udpbond_module {
-		result += "\tudpbond_main udpbond_main (.clk100(" + clk_name + "), .reset(reset), .wifi_enable(wifi_enable), .wifi_rx(wifi_rx), .wifi_tx(wifi_tx)"
-		for _, iname := range inames {
-			for i := 0; i < bmach.Inputs; i++ {
-				iiname := Get_input_name(i)
-				if iiname == iname {
-					result += ", .input_" + iname + "(Input" + strconv.Itoa(i) + ")"
-				}
+		for i := 0; i < bmach.Inputs; i++ {
+			iname := Get_input_name(i)
+			tpname := "Input" + strconv.Itoa(i)
+			aname := iname
+			if rname, ok := iomaps.Assoc[iname]; ok {
+				aname = rname
 			}
-		}
-
-		for _, oname := range onames {
-			for i := 0; i < bmach.Outputs; i++ {
-				ooname := Get_output_name(i)
-				if ooname == oname {
-					result += ", .output_" + oname + "(Output" + strconv.Itoa(i) + ")"
+			if aname != iname {
+				for j := 0; j < int(bmach.Rsize); j++ {
+					result += "\t\t" + tpname + "[" + strconv.Itoa(j) + "] <= " + nth_assoc(aname, j) + ";\n"
 				}
 			}
 		}
 
-		result += ");\n"
-	}
-
-	if basys3_7segment_module {
-		result += "\tbond2seg bond2seg_inst(" + clk_name + ", reset, " + basys3_7segment_mapped + ", segment ,enable_D1, enable_D2, enable_D3, enable_D4, dp);\n"
-	}
-
-	for _, iores := range resolved_io {
-
-		if iores == "board" {
-
-			result += "\talways @ (posedge clk) begin\n"
-
-			if slow_module {
-				result += "\t\tdivider <= divider + 1;\n"
+		for i := 0; i < bmach.Outputs; i++ {
+			oname := Get_output_name(i)
+			tpname := "Output" + strconv.Itoa(i)
+			aname := oname
+			if rname, ok := iomaps.Assoc[oname]; ok {
+				aname = rname
 			}
-
-			for i := 0; i < bmach.Inputs; i++ {
-				iname := Get_input_name(i)
-				tpname := "Input" + strconv.Itoa(i)
-				aname := iname
-				if rname, ok := iomaps.Assoc[iname]; ok {
-					aname = rname
-				}
-				if aname != iname {
-					for j := 0; j < int(bmach.Rsize); j++ {
-						result += "\t\t" + tpname + "[" + strconv.Itoa(j) + "] <= " + nth_assoc(aname, j) + ";\n"
-					}
+			if aname != oname {
+				for j := 0; j < int(bmach.Rsize); j++ {
+					result += "\t\t" + nth_assoc(aname, j) + " <= " + tpname + "[" + strconv.Itoa(j) + "]" + ";\n"
 				}
 			}
-
-			for i := 0; i < bmach.Outputs; i++ {
-				oname := Get_output_name(i)
-				tpname := "Output" + strconv.Itoa(i)
-				aname := oname
-				if rname, ok := iomaps.Assoc[oname]; ok {
-					aname = rname
-				}
-				if aname != oname {
-					for j := 0; j < int(bmach.Rsize); j++ {
-						result += "\t\t" + nth_assoc(aname, j) + " <= " + tpname + "[" + strconv.Itoa(j) + "]" + ";\n"
-					}
-				}
-			}
-
-			result += "\tend\n"
-
-			break
 		}
+
+		result += "\tend\n"
 	}
 
 	result += "endmodule\n"
-	return result_headers + result
+	return result_headers + result, nil
 }
@@ -22,13 +22,24 @@ type VM struct {
 	wait_proc int
 
 	abs_tick uint64
+
+	Timing bool // Model the opcodes latencies in the processors
+
+	Debug_info *procbuilder.Debug_info // Source level debug info of the processors
+
+	Channels *procbuilder.Channel_hub // The channel shared objects, in the order of the shared objects
+	Sync     *procbuilder.Sync_hub    // The mutexes, semaphores, counters and lfsrn, in the order of the shared objects
 }
 
 func (vm *VM) CopyState(vmsource *VM) {
 	for i, pstate := range vmsource.Processors {
 		vm.Processors[i].CopyState(pstate)
 	}
-	// TODO Finish
+	copy(vm.Inputs_regs, vmsource.Inputs_regs)
+	copy(vm.Outputs_regs, vmsource.Outputs_regs)
+	copy(vm.Internal_inputs_regs, vmsource.Internal_inputs_regs)
+	copy(vm.Internal_outputs_regs, vmsource.Internal_outputs_regs)
+	vm.abs_tick = vmsource.abs_tick
 }
 
 type Sim_config struct {
@@ -83,9 +94,65 @@ func (vm *VM) Init() error {
 	vm.Internal_outputs_regs = make([]interface{}, len(vm.Bmach.Internal_outputs))
 	vm.abs_tick = uint64(0)
 
+	// The hub gets the channels, every processor maps its local channels to them following its links
+	hub_id := make(map[int]int)
+	depths := make([]int, 0)
+	for so_id, so := range vm.Bmach.Shared_objects {
+		if ch, ok := so.(Channel_instance); ok {
+			hub_id[so_id] = len(depths)
+			depths = append(depths, ch.Depth)
+		}
+	}
+	vm.Channels = nil
+	if len(depths) > 0 {
+		vm.Channels = new(procbuilder.Channel_hub)
+		vm.Channels.Init(depths)
+	}
+
+	// The same for the synchronization objects, mapped by kind
+	sync_id := make(map[int]int)
+	objects := make([]procbuilder.Sync_state, 0)
+	for so_id, so := range vm.Bmach.Shared_objects {
+		if state, ok := procbuilder.Sync_object(so.String(), vm.Bmach.Rsize); ok {
+			sync_id[so_id] = len(objects)
+			objects = append(objects, state)
+		}
+	}
+	vm.Sync = nil
+	if len(objects) > 0 {
+		vm.Sync = new(procbuilder.Sync_hub)
+		vm.Sync.Init(objects)
+	}
+
 	for i, proc_dom_id := range vm.Bmach.Processors {
 		pvm := new(procbuilder.VM)
 		pvm.Mach = vm.Bmach.Domains[proc_dom_id]
+		pvm.Timing = vm.Timing
+		pvm.Source = vm.Debug_info.Processor(i)
+		pvm.Channel_owner = i
+		if vm.Channels != nil {
+			pvm.Channels = vm.Channels
+			pvm.Channel_map = make([]int, 0)
+			if i < len(vm.Bmach.Shared_links) {
+				for _, so_id := range vm.Bmach.Shared_links[i] {
+					if ch, ok := hub_id[so_id]; ok {
+						pvm.Channel_map = append(pvm.Channel_map, ch)
+					}
+				}
+			}
+		}
+		if vm.Sync != nil {
+			pvm.Sync = vm.Sync
+			pvm.Sync_map = make(map[string][]int)
+			if i < len(vm.Bmach.Shared_links) {
+				for _, so_id := range vm.Bmach.Shared_links[i] {
+					if id, ok := sync_id[so_id]; ok {
+						kind := objects[id].Kind
+						pvm.Sync_map[kind] = append(pvm.Sync_map[kind], id)
+					}
+				}
+			}
+		}
 		pvm.Init()
 
 		vm.Processors[i] = pvm
@@ -258,6 +325,15 @@ func (vm *VM) Step(sc *Sim_config) (string, error) {
 	return result, nil
 }
 
+// Perf_report returns the performance counters of the processors
+func (vm *VM) Perf_report() string {
+	result := "Absolute ticks: " + strconv.Itoa(int(vm.abs_tick)) + "\n"
+	for i, pvm := range vm.Processors {
+		result += "Proc " + strconv.Itoa(i) + " " + pvm.Counters.String()
+	}
+	return result
+}
+
 func (vm *VM) Dump_io() string {
 	result := ""
 	for i, reg := range vm.Inputs_regs {
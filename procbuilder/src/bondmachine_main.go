@@ -35,6 +35,18 @@ func (i *string_slice) Set(value string) error {
 	return nil
 }
 
+// A repeatable flag whose values may contain commas
+type string_list []string
+
+func (i *string_list) String() string {
+	return fmt.Sprint(*i)
+}
+
+func (i *string_list) Set(value string) error {
+	*i = append(*i, value)
+	return nil
+}
+
 var debug = flag.Bool("d", false, "Debug")
 var verbose = flag.Bool("v", false, "Verbose")
 var commentedverilog = flag.Bool("comment-verilog", false, "Comment generated verilog")
@@ -45,12 +57,25 @@ var bondmachine_file = flag.String("bondmachine-file", "", "Filename of the bond
 
 // Verilog processing
 var create_verilog = flag.Bool("create-verilog", false, "Create default verilog files")
-var verilog_flavor = flag.String("verilog-flavor", "iverilog", "Choose the type of verilog device. currently supported: iverilog,de10nano.")
+var verilog_flavor = flag.String("verilog-flavor", "iverilog", "Choose the type of verilog device. currently supported: iverilog and the boards of the database (basys3,kintex7,de10nano,icestick,ulx3s or loaded with -board-file).")
 var verilog_mapfile = flag.String("verilog-mapfile", "", "File mapping the device IO to bondmachine IO")
 var verilog_simulation = flag.Bool("verilog-simulation", false, "Create simulation oriented verilog as default.")
+var output_dir = flag.String("output-dir", ".", "Directory where the generated files are written")
+var force = flag.Bool("force", false, "Overwrite the generated files already present in the output directory")
+var keep = flag.Bool("keep", false, "Keep the generated files already present in the output directory")
+var board_file = flag.String("board-file", "", "JSON file with extra board definitions for the pin constraints")
+var list_boards = flag.Bool("list-boards", false, "List the known boards")
+var list_shared_object_types = flag.Bool("list-shared-object-types", false, "List the registered shared object types")
+var list_extra_modules = flag.Bool("list-extra-modules", false, "List the registered extra modules")
+var extra_modules string_list
+var bus_wrapper = flag.String("bus-wrapper", "", "Wrap the bondmachine as a bus slave with a C and a Go register map, the buses are: "+strings.Join(bondmachine.Bus_names(), ","))
 
 var show_program_alias = flag.Bool("show-program-alias", false, "Show program alias for the processor")
 
+// Resources estimation, the extra modules are included only when the verilog is created too
+var show_resources = flag.Bool("show-resources", false, "Show the estimated resources for the verilog flavor")
+var save_resources = flag.String("save-resources", "", "Save the estimated resources for the verilog flavor in JSON format")
+
 // Domains processing
 var list_domains = flag.Bool("list-domains", false, "Domain list")
 var add_domains string_slice
@@ -99,9 +124,21 @@ var simbox_file = flag.String("simbox-file", "", "Filename of the simulation dat
 
 var sim = flag.Bool("sim", false, "Simulate bond machine")
 var sim_interactions = flag.Int("sim-interactions", 10, "Simulation interaction")
+var debugger = flag.Bool("debugger", false, "Debug the bondmachine interactively, the simbox drives the inputs")
+var debugger_gdb_port = flag.Int("debugger-gdb-port", 0, "Serve the debugger with the GDB remote protocol on this localhost port instead of the console")
+var debug_info = flag.String("debug-info", "", "Debug info JSON file from bondgo, the disassembly shows the source of the instructions")
+var sim_timing = flag.Bool("sim-timing", false, "Model the opcodes latencies in the simulation (a tick is a clock cycle) and show the performance counters")
 
 var emu = flag.Bool("emu", false, "Emulate bond machine")
 var emu_interactions = flag.Int("emu-interactions", 10, "Emulation interaction (0 means forever)")
+var emu_uart = flag.Bool("emu-uart", false, "Serve the IOs of the uart extra module on a pty during the emulation")
+
+// Uart host client
+var uart_port = flag.String("uart-port", "", "Serial port or pty of a bondmachine with the uart extra module")
+var uart_baud = flag.Int("uart-baud", 115200, "Baud rate of the uart port")
+var uart_set string_slice
+var uart_get string_slice
+var uart_monitor = flag.Int("uart-monitor", 0, "Show this number of frames received from the uart port")
 
 var cluster_spec = flag.String("cluster-spec", "", "Etherbond or udpbond cluster Spec File ")
 var peer_id = flag.Int("peer-id", -1, "Etherbond or udpbond Peer ID")
@@ -125,12 +162,44 @@ var basys3_7segment_map = flag.String("basys3-7segment-map", "", "Basys3 7 segme
 
 var attach_benchmark_core string_slice
 
+// Batch processing
+var batch_file = flag.String("batch-file", "", "Script of bondmachine operations to apply atomically")
+var batch_diff = flag.Bool("batch-diff", false, "Show the changes made by the batch")
+var batch_dry_run = flag.Bool("batch-dry-run", false, "Validate the batch without writing the bondmachine file")
+
 func check(e error) {
 	if e != nil {
 		panic(e)
 	}
 }
 
+func load_simbox() *simbox.Simbox {
+	var sbox *simbox.Simbox
+	if *simbox_file != "" {
+		sbox = new(simbox.Simbox)
+		if _, err := os.Stat(*simbox_file); err == nil {
+			// Open the simbox file is exists
+			if simbox_json, err := ioutil.ReadFile(*simbox_file); err == nil {
+				if err := json.Unmarshal([]byte(simbox_json), sbox); err != nil {
+					panic(err)
+				}
+			} else {
+				panic(err)
+			}
+		}
+	}
+	return sbox
+}
+
+func load_debug_info() *procbuilder.Debug_info {
+	if *debug_info == "" {
+		return nil
+	}
+	di, err := procbuilder.Load_debug_info(*debug_info)
+	check(err)
+	return di
+}
+
 func init() {
 	rand.Seed(int64(time.Now().Unix()))
 
@@ -145,6 +214,9 @@ func init() {
 	flag.Var(&connect_processor_shared_object, "connect-processor-shared-object", "Connect a processor to a shared object")
 	flag.Var(&disconnect_processor_shared_object, "disconnect-processor-shared-object", "Disconnect a processor from a shared object")
 	flag.Var(&attach_benchmark_core, "attach-benchmark-core", "Attach a benchmark core")
+	flag.Var(&uart_set, "uart-set", "Comma-separated list of inputs to set through the uart port, as i0=value")
+	flag.Var(&uart_get, "uart-get", "Comma-separated list of outputs to read through the uart port")
+	flag.Var(&extra_modules, "extra-module", "Add a registered extra module to the verilog, as name or name:JSON configuration")
 
 	flag.Parse()
 }
@@ -156,11 +228,94 @@ func lastAddr(n *net.IPNet) (net.IP, error) { // works when the n is a prefix, o
 	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(n.IP.To4())|^binary.BigEndian.Uint32(net.IP(n.Mask).To4()))
 	return ip, nil
 }
+// The uart module among the extra modules of the command line
+func uart_module() *bondmachine.Uart_extra {
+	for _, spec := range extra_modules {
+		if name := strings.SplitN(spec, ":", 2)[0]; name == "uart" {
+			em, err := bondmachine.New_extra_module(name, strings.TrimPrefix(spec[len(name):], ":"))
+			check(err)
+			return em.(*bondmachine.Uart_extra)
+		}
+	}
+	panic("The uart extra module is needed")
+}
+
+func uart_client() error {
+	port, err := bondmachine.Uart_open(*uart_port, *uart_baud)
+	if err != nil {
+		return err
+	}
+	defer port.Close()
+	client := &bondmachine.Uart_client{Port: port, Rsize: uint8(*register_size)}
+
+	for _, set := range uart_set {
+		words := strings.Split(set, "=")
+		if len(words) != 2 || len(words[0]) < 2 || words[0][0] != 'i' {
+			return errors.New("Wrong input setting " + set)
+		}
+		input, err := strconv.Atoi(words[0][1:])
+		if err != nil {
+			return err
+		}
+		value, err := strconv.ParseUint(words[1], 0, 64)
+		if err != nil {
+			return err
+		}
+		if err := client.Set(input, value); err != nil {
+			return err
+		}
+	}
+
+	for _, get := range uart_get {
+		if len(get) < 2 || get[0] != 'o' {
+			return errors.New("Wrong output " + get)
+		}
+		output, err := strconv.Atoi(get[1:])
+		if err != nil {
+			return err
+		}
+		value, err := client.Get(output)
+		if err != nil {
+			return err
+		}
+		fmt.Println(get, value)
+	}
+
+	for i := 0; i < *uart_monitor; i++ {
+		frame, err := client.Wait()
+		if err != nil {
+			return err
+		}
+		fmt.Println(frame)
+	}
+	return nil
+}
+
 func main() {
 	conf := new(bondmachine.Config)
 	conf.Debug = *debug
 	conf.Dotdetail = uint8(*dot_detail)
 	conf.Commented_verilog = *commentedverilog
+	conf.Bus_wrapper = *bus_wrapper
+
+	// The registered components, used by the shell completion too
+	if *list_shared_object_types {
+		for _, name := range bondmachine.Shared_object_names() {
+			fmt.Println(name)
+		}
+		return
+	}
+	if *list_extra_modules {
+		for _, name := range bondmachine.Extra_module_names() {
+			fmt.Println(name)
+		}
+		return
+	}
+
+	if *uart_port != "" {
+		check(uart_client())
+		return
+	}
 
 	var bmach *bondmachine.Bondmachine
 
@@ -190,6 +345,12 @@ func main() {
 			check(err)
 		}
 
+		if *board_file != "" {
+			check(bondmachine.Load_boards(*board_file))
+		}
+
+		extramodules := make([]bondmachine.ExtraModule, 0)
+
 		// Eventually create verilog files
 		if *create_verilog {
 			iomap := new(bondmachine.IOmap)
@@ -206,7 +367,6 @@ func main() {
 			//fmt.Println(iomap)
 
 			// Precess the possible extra modules
-			extramodules := make([]bondmachine.ExtraModule, 0)
 
 			// Slower
 			if *board_slow {
@@ -358,6 +518,16 @@ func main() {
 				b37s.Mapped_output = *basys3_7segment_map
 				extramodules = append(extramodules, b37s)
 			}
+			for _, spec := range extra_modules {
+				name, config := spec, ""
+				if i := strings.Index(spec, ":"); i >= 0 {
+					name, config = spec[:i], spec[i+1:]
+				}
+				em, err := bondmachine.New_extra_module(name, config)
+				check(err)
+				check(em.Check(bmach))
+				extramodules = append(extramodules, em)
+			}
 
 			var flavor string
 
@@ -386,11 +556,54 @@ func main() {
 				}
 			}
 
-			bmach.Write_verilog(conf, flavor, iomap, extramodules, sbox)
+			policy, err := procbuilder.Output_policy(*force, *keep)
+			check(err)
+			conf.Output = &procbuilder.Output{Dir: *output_dir, Policy: policy}
+
+			check(bmach.Write_verilog(conf, flavor, iomap, extramodules, sbox))
+
+			for _, stale := range conf.Output.Stale() {
+				fmt.Println("Warning: kept stale file " + stale)
+			}
+		}
+
+		if *show_resources || (*verbose && *create_verilog) {
+			fmt.Print(bmach.Estimate_resources(*verilog_flavor, extramodules))
+		}
+
+		if *save_resources != "" {
+			b, err := json.MarshalIndent(bmach.Estimate_resources(*verilog_flavor, extramodules), "", "  ")
+			check(err)
+			check(ioutil.WriteFile(*save_resources, b, 0644))
 		}
 
 		// All the operation are exclusive
-		if *list_domains {
+		if *batch_file != "" {
+			script, err := ioutil.ReadFile(*batch_file)
+			check(err)
+			ops, err := bondmachine.Parse_batch(script)
+			check(err)
+			newbmach, err := bmach.Apply_batch(ops)
+			check(err)
+			if *batch_diff {
+				diff, err := bmach.Diff(newbmach)
+				check(err)
+				fmt.Print(diff)
+			}
+			if *batch_dry_run {
+				return
+			}
+			bmach = newbmach
+		} else if *list_boards {
+			names := make([]string, 0)
+			for name, _ := range bondmachine.Allboards {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Print(bondmachine.Allboards[name])
+			}
+		} else if *list_domains {
 			fmt.Println(bmach.List_domains())
 		} else if &add_domains != nil && len(add_domains) != 0 {
 			for _, load_machine := range add_domains {
@@ -563,24 +776,12 @@ func main() {
 		} else if &connect_processor_shared_object != nil && len(connect_processor_shared_object) == 2 {
 			bmach.Connect_processor_shared_object(connect_processor_shared_object)
 		} else if *sim {
-			var sbox *simbox.Simbox
-			if *simbox_file != "" {
-				sbox = new(simbox.Simbox)
-				if _, err := os.Stat(*simbox_file); err == nil {
-					// Open the simbox file is exists
-					if simbox_json, err := ioutil.ReadFile(*simbox_file); err == nil {
-						if err := json.Unmarshal([]byte(simbox_json), sbox); err != nil {
-							panic(err)
-						}
-					} else {
-						panic(err)
-					}
-				}
-
-			}
+			sbox := load_simbox()
 
 			vm := new(bondmachine.VM)
 			vm.Bmach = bmach
+			vm.Timing = *sim_timing
+			vm.Debug_info = load_debug_info()
 			err := vm.Init()
 			check(err)
 
@@ -674,9 +875,41 @@ func main() {
 				// TODO Write to a yet to be created report data structure
 
 			}
+
+			if *sim_timing {
+				fmt.Print(vm.Perf_report())
+			}
+		} else if *debugger {
+			sbox := load_simbox()
+
+			vm := new(bondmachine.VM)
+			vm.Bmach = bmach
+			vm.Timing = *sim_timing
+			vm.Debug_info = load_debug_info()
+			err := vm.Init()
+			check(err)
+
+			target := new(bondmachine.Vm_target)
+			target.Vm = vm
+			if sbox != nil {
+				target.Drive = new(bondmachine.Sim_drive)
+				check(target.Drive.Init(sbox, vm))
+			}
+
+			check(vm.Launch_processors(nil))
+
+			dbg := new(procbuilder.Debugger)
+			dbg.Target = target
+			if *debugger_gdb_port != 0 {
+				check(dbg.Serve_gdb(*debugger_gdb_port))
+			} else {
+				dbg.Repl(os.Stdin, os.Stdout)
+			}
 		} else if *emu {
 			vm := new(bondmachine.VM)
 			vm.Bmach = bmach
+			vm.Timing = *sim_timing
+			vm.Debug_info = load_debug_info()
 			err := vm.Init()
 			check(err)
 
@@ -688,6 +921,17 @@ func main() {
 			lerr := vm.Launch_processors(nil)
 			check(lerr)
 
+			var bridge *bondmachine.Uart_bridge
+			if *emu_uart {
+				master, slave, name, err := bondmachine.Uart_pty()
+				check(err)
+				defer master.Close()
+				defer slave.Close()
+				bridge = &bondmachine.Uart_bridge{Vm: vm, Port: master, Module: uart_module()}
+				check(bridge.Init())
+				fmt.Println("Uart on " + name)
+			}
+
 			for i := uint64(0); ; {
 				if *emu_interactions != 0 {
 					if i >= uint64(*emu_interactions) {
@@ -698,11 +942,19 @@ func main() {
 				_, err := vm.Step(sconfig)
 				check(err)
 
+				if bridge != nil {
+					check(bridge.Step())
+				}
+
 				if *emu_interactions != 0 {
 					i++
 				}
 
 			}
+
+			if *sim_timing {
+				fmt.Print(vm.Perf_report())
+			}
 		}
 
 		// Write the bondmachine file
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"procbuilder"
+	"simbox"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var verbose = flag.Bool("v", false, "Verbose")
+
+var input_assembly = flag.String("input-assembly", "", "Assembly program to explore")
+var input_go = flag.String("input-go", "", "Go program (file or package directory) to explore, compiled with bondgo for every point")
+var bondgo = flag.String("bondgo", "bondgo", "The bondgo executable")
+
+var simbox_file = flag.String("simbox-file", "", "Filename of the simulation data file with the inputs and the expected outputs")
+var max_ticks = flag.Int("max-ticks", 100000, "Maximum simulation ticks per point")
+
+var register_sizes = flag.String("register-sizes", "8", "Comma separated register sizes")
+var rbits = flag.String("registers", "1,2,3", "Comma separated numbers of registers 2^")
+var lbits = flag.String("ram", "0", "Comma separated numbers of RAM memory cells 2^")
+var obits = flag.String("rom", "4,6,8", "Comma separated numbers of ROM memory cells 2^")
+var opcode_sets = flag.String("opcodes", "auto", "Semicolon separated opcode sets, each one comma separated, auto for the opcodes used by the program")
+var nbit = flag.Int("inputs", 1, "Number of n-bit inputs")
+var mbit = flag.Int("outputs", 1, "Number of n-bit outputs")
+
+var verilog_flavor = flag.String("verilog-flavor", "iverilog", "The verilog flavor the resources are estimated for")
+
+var strategy = flag.String("strategy", "exhaustive", "Search strategy: exhaustive, evolutionary")
+var population = flag.Int("population", 10, "Evolutionary strategy population")
+var generations = flag.Int("generations", 10, "Evolutionary strategy generations")
+
+var save_csv = flag.String("save-csv", "", "Save the Pareto front in CSV format")
+var save_json = flag.String("save-json", "", "Save the Pareto front in JSON format")
+var save_all = flag.Bool("save-all", false, "Save all the evaluated points instead of the Pareto front only")
+
+func check(e error) {
+	if e != nil {
+		panic(e)
+	}
+}
+
+func int_list(value string) []int {
+	result := make([]int, 0)
+	for _, s := range strings.Split(value, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		check(err)
+		result = append(result, v)
+	}
+	return result
+}
+
+func load_simbox() *simbox.Simbox {
+	var sbox *simbox.Simbox
+	if *simbox_file != "" {
+		sbox = new(simbox.Simbox)
+		simbox_json, err := ioutil.ReadFile(*simbox_file)
+		check(err)
+		check(json.Unmarshal(simbox_json, sbox))
+	}
+	return sbox
+}
+
+// bondgo_compiler compiles the Go program for the register size and the registers of every point
+func bondgo_compiler(tmpdir string) procbuilder.Dse_compiler {
+	return func(arch *procbuilder.Arch) ([]byte, error) {
+		asmfile := filepath.Join(tmpdir, fmt.Sprintf("p_%d_%d.asm", arch.Rsize, arch.R))
+		if asm, err := ioutil.ReadFile(asmfile); err == nil {
+			return asm, nil
+		}
+		cmd := exec.Command(*bondgo, "-input-file", *input_go, "-register-size", strconv.Itoa(int(arch.Rsize)), "-max-registers", strconv.Itoa(1<<arch.R), "-save-assembly", asmfile)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			return nil, err
+		}
+		asm, err := ioutil.ReadFile(asmfile)
+		if err != nil {
+			return nil, errors.New("bondgo failed: " + strings.TrimSpace(strings.Replace(string(out), "\n", " ", -1)))
+		}
+		return asm, nil
+	}
+}
+
+func init() {
+	rand.Seed(int64(time.Now().Unix()))
+	flag.Parse()
+}
+
+func main() {
+	dse := new(procbuilder.Dse)
+	dse.Space.Rsize = int_list(*register_sizes)
+	dse.Space.R = int_list(*rbits)
+	dse.Space.L = int_list(*lbits)
+	dse.Space.O = int_list(*obits)
+	dse.Space.Opcodes = strings.Split(*opcode_sets, ";")
+	dse.Space.N = uint8(*nbit)
+	dse.Space.M = uint8(*mbit)
+	dse.Space.Flavor = *verilog_flavor
+	dse.Sbox = load_simbox()
+	dse.Max_ticks = *max_ticks
+
+	if *input_assembly != "" {
+		asm, err := ioutil.ReadFile(*input_assembly)
+		check(err)
+		dse.Compile = func(arch *procbuilder.Arch) ([]byte, error) {
+			return asm, nil
+		}
+	} else if *input_go != "" {
+		tmpdir, err := ioutil.TempDir("", "dse")
+		check(err)
+		defer os.RemoveAll(tmpdir)
+		dse.Compile = bondgo_compiler(tmpdir)
+	} else {
+		fmt.Println("An assembly or Go program is needed")
+		os.Exit(1)
+	}
+
+	switch *strategy {
+	case "exhaustive":
+		check(dse.Exhaustive())
+	case "evolutionary":
+		dse.Evolutionary(*population, *generations)
+	default:
+		fmt.Println("Unknown search strategy " + *strategy)
+		os.Exit(1)
+	}
+
+	front := procbuilder.Pareto_front(dse.Points)
+
+	if *verbose {
+		for _, p := range dse.Points {
+			if !p.Feasible {
+				fmt.Println(p.Key() + ": " + p.Error)
+			}
+		}
+	}
+
+	fmt.Printf("%d points evaluated, %d on the Pareto front\n", len(dse.Points), len(front))
+	for _, p := range front {
+		fmt.Printf("\t%s: %d cycles, %d MHz, %d LUTs, %d FFs, %d BRAMs, %d DSPs\n", p.Key(), p.Cycles, p.Fmax_mhz, p.Luts, p.Ffs, p.Brams, p.Dsps)
+	}
+
+	saved := front
+	if *save_all {
+		saved = dse.Points
+	}
+
+	if *save_csv != "" {
+		check(ioutil.WriteFile(*save_csv, []byte(procbuilder.Dse_csv(saved)), 0644))
+	}
+
+	if *save_json != "" {
+		b, err := json.Marshal(saved)
+		check(err)
+		check(ioutil.WriteFile(*save_json, b, 0644))
+	}
+}
@@ -22,7 +22,7 @@ func (arch *Arch) Shared_num(soname string) int {
 	counted := 0
 	for _, sos := range so {
 		splitted := strings.Split(sos, ":")
-		if len(splitted) == 2 {
+		if len(splitted) >= 2 {
 			if splitted[0] == soname {
 				counted++
 			}
@@ -63,6 +63,30 @@ func (arch *Arch) String() string {
 	return result
 }
 
+// Execution_model returns the memory organization: ha (Harvard), vn (Von Neumann, program and data in one memory)
+// or hy (Hybrid, split memories with a shared window)
+func (arch *Arch) Execution_model() string {
+	for _, mode := range arch.Modes {
+		switch mode {
+		case "ha", "vn", "hy":
+			return mode
+		}
+	}
+	return "ha"
+}
+
+// Window_size returns the number of cells of the hy model window, they are the last ones of both the ROM and the RAM
+func (arch *Arch) Window_size() int {
+	bits := arch.O
+	if arch.L < bits {
+		bits = arch.L
+	}
+	if bits == 0 {
+		return 0
+	}
+	return 1 << (bits - 1)
+}
+
 func (arch *Arch) Max_word() int {
 	now := 1
 	for _, op := range arch.Op {
@@ -72,6 +96,11 @@ func (arch *Arch) Max_word() int {
 		}
 	}
 
+	// In the vn and hy models a memory word holds either an instruction or a data value
+	if arch.Execution_model() != "ha" && int(arch.Rsize) > now {
+		now = int(arch.Rsize)
+	}
+
 	//	served := 1
 	//	for bits := 1; bits < 16; bits++ {
 	//		if served<<uint8(bits) >= now {
@@ -198,11 +227,16 @@ func (arch *Arch) Write_verilog(arch_module_name string, modules_names map[strin
 	// Processor
 	result += "\t" + procname + " " + procname + "_instance(clock_signal, reset_signal, rom_bus, rom_value" + ramh + ioh + header + ");\n"
 
-	// Rom
-	result += "\t" + romname + " " + romname + "_instance(rom_bus, rom_value);\n"
+	if arch.Execution_model() == "ha" {
+		// Rom
+		result += "\t" + romname + " " + romname + "_instance(rom_bus, rom_value);\n"
 
-	// Ram
-	result += "\t" + ramname + " " + ramname + "_instance(clock_signal, reset_signal" + ramh + ");\n"
+		// Ram
+		result += "\t" + ramname + " " + ramname + "_instance(clock_signal, reset_signal" + ramh + ");\n"
+	} else {
+		// Unified memory, the rom module has both the program and the data ports
+		result += "\t" + romname + " " + romname + "_instance(clock_signal, reset_signal, rom_bus, rom_value" + ramh + ");\n"
+	}
 
 	result += "\n"
 
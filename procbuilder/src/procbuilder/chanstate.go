@@ -0,0 +1,225 @@
+package procbuilder
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The simulated channels. The wwr and wrd opcodes queue the operations of the processor, chw waits until one of them
+// completes and chc tries them once. The completed operation is reported by its sequence number and the others are
+// dropped, as the RTL does. A buffered channel completes a write while it has room and a read while it holds data, a
+// rendezvous one pairs a write with a read of another processor: the processor blocked in chw leaves an offer on the
+// channel and the partner completes it. The queued operations are tried in order and the first one that can complete
+// wins, as the RTL takes the first matching entry of a channel; the RTL visits the channels following a free running
+// LFSR, a timing the VM does not model. A closed channel refuses the writes and, once drained, completes the reads
+// with zero: chw and chc report them past the queued operations, at their sequence number plus the number of
+// operations.
+
+type Channel_hub struct {
+	Channels []Channel_state
+	Done     map[int]Channel_done // Offers completed by a partner, by owner
+	lock     sync.Mutex
+}
+
+type Channel_state struct {
+	Depth  int // 0 for the rendezvous channels
+	Fifo   []interface{}
+	Offers []Channel_offer
+	Closed bool
+}
+
+type Channel_offer struct {
+	Owner int
+	Index int // Sequence number of the operation within the owner chw
+	Write bool
+	Value interface{}
+}
+
+type Channel_done struct {
+	Index int
+	Value interface{}
+}
+
+// A queued wwr or wrd, the channel is the local one of the processor
+type Channel_op struct {
+	Channel int
+	Reg     int
+	Write   bool
+	Offered bool
+}
+
+// Channel_depths returns the buffer depths of the channels of a shared objects constraint, in order
+func Channel_depths(constraints string) []int {
+	result := make([]int, 0)
+	if constraints == "" {
+		return result
+	}
+	for _, constraint := range strings.Split(constraints, ",") {
+		if words := strings.Split(constraint, ":"); words[0] == "channel" {
+			depth := 0
+			if len(words) > 1 && words[1] != "" {
+				depth, _ = strconv.Atoi(words[1])
+			}
+			result = append(result, depth)
+		}
+	}
+	return result
+}
+
+func (hub *Channel_hub) Init(depths []int) {
+	hub.Channels = make([]Channel_state, len(depths))
+	for i, depth := range depths {
+		hub.Channels[i].Depth = depth
+		hub.Channels[i].Fifo = make([]interface{}, 0, depth)
+		hub.Channels[i].Offers = make([]Channel_offer, 0)
+	}
+	hub.Done = make(map[int]Channel_done)
+}
+
+func (hub *Channel_hub) CopyState(source *Channel_hub) {
+	hub.Channels = make([]Channel_state, len(source.Channels))
+	for i, ch := range source.Channels {
+		hub.Channels[i].Depth = ch.Depth
+		hub.Channels[i].Fifo = append(make([]interface{}, 0, ch.Depth), ch.Fifo...)
+		hub.Channels[i].Offers = append(make([]Channel_offer, 0), ch.Offers...)
+		hub.Channels[i].Closed = ch.Closed
+	}
+	hub.Done = make(map[int]Channel_done)
+	for owner, done := range source.Done {
+		hub.Done[owner] = done
+	}
+}
+
+// withdraw removes the offers of a processor from every channel
+func (hub *Channel_hub) withdraw(owner int) {
+	for i := range hub.Channels {
+		offers := hub.Channels[i].Offers[:0]
+		for _, offer := range hub.Channels[i].Offers {
+			if offer.Owner != owner {
+				offers = append(offers, offer)
+			}
+		}
+		hub.Channels[i].Offers = offers
+	}
+}
+
+// try completes an operation at once if the channel allows it, for a read the value is returned. The last result
+// is set for a read completed by the close of the channel.
+func (hub *Channel_hub) try(owner int, ch int, write bool, value interface{}) (interface{}, bool, bool) {
+	channel := &hub.Channels[ch]
+	if channel.Closed {
+		if write {
+			return nil, false, false
+		} else if len(channel.Fifo) == 0 {
+			return nil, true, true
+		}
+	}
+	if channel.Depth > 0 {
+		if write && len(channel.Fifo) < channel.Depth {
+			channel.Fifo = append(channel.Fifo, value)
+			return nil, true, false
+		} else if !write && len(channel.Fifo) > 0 {
+			value = channel.Fifo[0]
+			channel.Fifo = channel.Fifo[1:]
+			return value, true, false
+		}
+		return nil, false, false
+	}
+	for _, offer := range channel.Offers {
+		if offer.Owner != owner && offer.Write != write {
+			if write {
+				hub.Done[offer.Owner] = Channel_done{offer.Index, value}
+			} else {
+				hub.Done[offer.Owner] = Channel_done{offer.Index, nil}
+				value = offer.Value
+			}
+			hub.withdraw(offer.Owner)
+			return value, true, false
+		}
+	}
+	return nil, false, false
+}
+
+// Channel_close closes a local channel of the processor, the offers left on it are dropped as no partner can
+// complete them anymore
+func (vm *VM) Channel_close(ch int) error {
+	hub := vm.Channels
+	if hub == nil || ch >= len(vm.Channel_map) {
+		return Prerror{"Channel ch" + strconv.Itoa(ch) + " not connected"}
+	}
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+	channel := &hub.Channels[vm.Channel_map[ch]]
+	channel.Closed = true
+	channel.Offers = channel.Offers[:0]
+	return nil
+}
+
+// Channel_wait executes the queued channel operations, it returns the index of the completed one or false if
+// none could complete. When block is set the operations are offered to the rendezvous partners.
+func (vm *VM) Channel_wait(block bool) (int, bool, error) {
+	hub := vm.Channels
+	if hub == nil || len(vm.Channel_ops) == 0 {
+		return 0, false, Prerror{"No channel operation to wait for"}
+	}
+	hub.lock.Lock()
+	defer hub.lock.Unlock()
+
+	complete := func(index int, value interface{}, closed bool) (int, bool, error) {
+		if op := vm.Channel_ops[index]; !op.Write {
+			vm.Registers[op.Reg] = value
+		}
+		if closed {
+			vm.Registers[vm.Channel_ops[index].Reg] = vm.word(0)
+			index += len(vm.Channel_ops)
+		}
+		hub.withdraw(vm.Channel_owner)
+		vm.Channel_ops = vm.Channel_ops[:0]
+		return index, true, nil
+	}
+
+	if done, ok := hub.Done[vm.Channel_owner]; ok {
+		delete(hub.Done, vm.Channel_owner)
+		return complete(done.Index, done.Value, false)
+	}
+
+	for i, op := range vm.Channel_ops {
+		if op.Channel >= len(vm.Channel_map) {
+			return 0, false, Prerror{"Channel ch" + strconv.Itoa(op.Channel) + " not connected"}
+		}
+		if value, ok, closed := hub.try(vm.Channel_owner, vm.Channel_map[op.Channel], op.Write, vm.Registers[op.Reg]); ok {
+			return complete(i, value, closed)
+		}
+	}
+
+	if block {
+		for i, op := range vm.Channel_ops {
+			ch := vm.Channel_map[op.Channel]
+			if !op.Offered && hub.Channels[ch].Depth == 0 && !hub.Channels[ch].Closed {
+				hub.Channels[ch].Offers = append(hub.Channels[ch].Offers, Channel_offer{vm.Channel_owner, i, op.Write, vm.Registers[op.Reg]})
+				vm.Channel_ops[i].Offered = true
+			}
+		}
+	} else {
+		vm.Channel_ops = vm.Channel_ops[:0]
+	}
+	return 0, false, nil
+}
+
+// The channels of a processor simulated alone, connected only to itself
+func (vm *VM) init_channels() {
+	vm.Channel_ops = make([]Channel_op, 0)
+	if vm.Channels == nil {
+		depths := Channel_depths(vm.Mach.Shared_constraints)
+		if len(depths) == 0 {
+			return
+		}
+		vm.Channels = new(Channel_hub)
+		vm.Channels.Init(depths)
+		vm.Channel_map = make([]int, len(depths))
+		for i := range depths {
+			vm.Channel_map[i] = i
+		}
+	}
+}
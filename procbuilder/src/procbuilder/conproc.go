@@ -296,6 +296,11 @@ func (proc *Conproc) Write_verilog(conf *Config, arch *Arch, processor_module_na
 		result += "	(* KEEP = \"TRUE\" *) reg [" + strconv.Itoa(regsize-1) + ":0] _" + strings.ToLower(Get_register_name(i)) + ";\n"
 	}
 
+	events := proc.Has_events()
+	if events {
+		result += proc.events_verilog_header(arch)
+	}
+
 	for _, op := range proc.Op {
 		if conf.Commented_verilog {
 			result += "\n// Start of the component \"header\" for the opcode " + op.Op_get_name() + "\n\n"
@@ -321,6 +326,10 @@ func (proc *Conproc) Write_verilog(conf *Config, arch *Arch, processor_module_na
 		result += op.Op_instruction_verilog_reset(arch, flavor)
 	}
 
+	if events {
+		result += proc.events_verilog_reset(arch)
+	}
+
 	result += "		end\n"
 	result += "		else begin\n"
 	result += "			$display(\"Program Counter:%d\", _pc);\n"
@@ -353,6 +362,10 @@ func (proc *Conproc) Write_verilog(conf *Config, arch *Arch, processor_module_na
 		result += op.Op_instruction_verilog_default_state(arch, flavor)
 	}
 
+	if events {
+		result += proc.events_verilog_dispatch(arch)
+	}
+
 	if opbits == 1 {
 		result += "				case(rom_value[" + strconv.Itoa(rom_word-1) + "])\n"
 	} else {
@@ -373,6 +386,10 @@ func (proc *Conproc) Write_verilog(conf *Config, arch *Arch, processor_module_na
 
 	result += "				endcase\n"
 
+	if events {
+		result += "				end\n"
+	}
+
 	// TODO What are they, maybe needed by some opcode ?
 	//	result += "			end\n"
 
@@ -0,0 +1,398 @@
+package procbuilder
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"simbox"
+	"strconv"
+	"strings"
+)
+
+// Co-simulation runs the same machine and simbox on the VM and on the generated RTL (under iverilog), the two traces are
+// aligned per instruction and the first divergence is reported. The RTL trace is sampled from a dedicated testbench each
+// time the program counter changes, a machine that stays on the same instruction for COSIM_STALL cycles ends its trace.
+// The valids of the inputs are tied high, unless the processor has events: a valid is then pulsed for a cycle when the
+// testbench sets its input, as the VM takes a new value for the valid edge.
+
+const (
+	COSIM_STALL = 64
+	COSIM_TB    = "cosim_tb"
+)
+
+// The state before the execution of the step-th instruction, the values are decimal strings since the RTL ones may be undefined
+type Cosim_state struct {
+	Step      uint64
+	Pc        uint64
+	Registers []string
+	Outputs   []string
+}
+
+type Cosim_trace struct {
+	States []Cosim_state
+	Halted bool // The machine reached the end of the program
+}
+
+func (st Cosim_state) String() string {
+	result := "step " + strconv.Itoa(int(st.Step)) + " pc " + strconv.Itoa(int(st.Pc))
+	for i, reg := range st.Registers {
+		result += " " + strings.ToLower(Get_register_name(i)) + ":" + reg
+	}
+	for i, outp := range st.Outputs {
+		result += " " + Get_output_name(i) + ":" + outp
+	}
+	return result
+}
+
+func cosim_inputs(sbox *simbox.Simbox) map[uint64]map[int]string {
+	result := make(map[uint64]map[int]string)
+	if sbox == nil {
+		return result
+	}
+	for _, rule := range sbox.Rules {
+		if rule.Timec == simbox.TIMEC_ABS && rule.Action == simbox.ACTION_SET && len(rule.Object) > 1 && rule.Object[0] == 'i' {
+			if inp, err := strconv.Atoi(rule.Object[1:]); err == nil {
+				if _, ok := result[rule.Tick]; !ok {
+					result[rule.Tick] = make(map[int]string)
+				}
+				result[rule.Tick][inp] = rule.Extra
+			}
+		}
+	}
+	return result
+}
+
+// Write_verilog_cosim_testbench creates the testbench that drives the inputs from the simbox and displays the state on each new instruction
+func (mach *Machine) Write_verilog_cosim_testbench(arch_module_name string, processor_name string, sbox *simbox.Simbox, steps int) string {
+	arch := &mach.Arch
+	regsize := int(arch.Rsize)
+	reg_num := 1 << arch.R
+	proc := arch_module_name + "_instance." + processor_name + "_instance"
+
+	result := ""
+	result += "`timescale 1ns/1ps\n"
+	result += "module " + COSIM_TB + ";\n"
+	result += "\n"
+	result += "\treg clock_signal, reset_signal;\n"
+
+	events := arch.Conproc.Has_events()
+
+	ports := ""
+	for i := 0; i < int(arch.N); i++ {
+		result += "\treg [" + strconv.Itoa(regsize-1) + ":0] " + Get_input_name(i) + ";\n"
+		result += "\twire " + Get_input_name(i) + "_received;\n"
+		if events {
+			result += "\treg " + Get_input_name(i) + "_valid;\n"
+			ports += ", " + Get_input_name(i) + ", " + Get_input_name(i) + "_valid, " + Get_input_name(i) + "_received"
+		} else {
+			ports += ", " + Get_input_name(i) + ", 1'b1, " + Get_input_name(i) + "_received"
+		}
+	}
+	for i := 0; i < int(arch.M); i++ {
+		result += "\twire [" + strconv.Itoa(regsize-1) + ":0] " + Get_output_name(i) + ";\n"
+		result += "\twire " + Get_output_name(i) + "_valid;\n"
+		ports += ", " + Get_output_name(i) + ", " + Get_output_name(i) + "_valid, 1'b1"
+	}
+
+	result += "\n"
+	result += "\t" + arch_module_name + " " + arch_module_name + "_instance(clock_signal, reset_signal" + ports + ");\n"
+	result += "\n"
+	result += "\tinteger step, stall;\n"
+	result += "\treg [" + strconv.Itoa(int(arch.O)-1) + ":0] lastpc;\n"
+	result += "\n"
+	result += "\tinitial\n"
+	result += "\tbegin\n"
+	result += "\t\tclock_signal = 0;\n"
+	result += "\t\treset_signal = 1;\n"
+	result += "\t\tstep = 0;\n"
+	result += "\t\tstall = 0;\n"
+	for i := 0; i < int(arch.N); i++ {
+		result += "\t\t" + Get_input_name(i) + " = 0;\n"
+		if events {
+			result += "\t\t" + Get_input_name(i) + "_valid = 0;\n"
+		}
+	}
+	result += "\t\t#7 reset_signal = 0;\n"
+	result += "\tend\n"
+	result += "\n"
+	result += "\talways #5 clock_signal = ~clock_signal;\n"
+	result += "\n"
+
+	format := "COSIM %0d %0d"
+	list := ", step, " + proc + "._pc"
+	for i := 0; i < reg_num; i++ {
+		format += " %0d"
+		list += ", " + proc + "._" + strings.ToLower(Get_register_name(i))
+	}
+	for i := 0; i < int(arch.M); i++ {
+		format += " %0d"
+		list += ", " + Get_output_name(i)
+	}
+
+	result += "\talways @(negedge clock_signal)\n"
+	result += "\tbegin\n"
+	result += "\t\tif (!reset_signal)\n"
+	result += "\t\tbegin\n"
+	if events {
+		for i := 0; i < int(arch.N); i++ {
+			result += "\t\t\t" + Get_input_name(i) + "_valid = 0;\n"
+		}
+	}
+	result += "\t\t\tif (step == 0 || " + proc + "._pc != lastpc)\n"
+	result += "\t\t\tbegin\n"
+	result += "\t\t\t\tlastpc = " + proc + "._pc;\n"
+	result += "\t\t\t\tstall = 0;\n"
+	result += "\t\t\t\t$display(\"" + format + "\"" + list + ");\n"
+
+	inputs := cosim_inputs(sbox)
+	if len(inputs) > 0 {
+		result += "\t\t\t\tcase (step)\n"
+		for tick := uint64(0); tick < uint64(steps); tick++ {
+			if sets, ok := inputs[tick]; ok {
+				result += "\t\t\t\t\t" + strconv.Itoa(int(tick)) + ": begin\n"
+				for inp, val := range sets {
+					if inp < int(arch.N) {
+						result += "\t\t\t\t\t\t" + Get_input_name(inp) + " = " + val + ";\n"
+						if events {
+							result += "\t\t\t\t\t\t" + Get_input_name(inp) + "_valid = 1;\n"
+						}
+					}
+				}
+				result += "\t\t\t\t\tend\n"
+			}
+		}
+		result += "\t\t\t\tendcase\n"
+	}
+
+	result += "\t\t\t\tstep = step + 1;\n"
+	result += "\t\t\t\tif (step >= " + strconv.Itoa(steps) + ") $finish;\n"
+	result += "\t\t\tend\n"
+	result += "\t\t\telse\n"
+	result += "\t\t\tbegin\n"
+	result += "\t\t\t\tstall = stall + 1;\n"
+	result += "\t\t\t\tif (stall >= " + strconv.Itoa(COSIM_STALL) + ") $finish;\n"
+	result += "\t\t\tend\n"
+	result += "\t\tend\n"
+	result += "\tend\n"
+	result += "endmodule\n"
+	return result
+}
+
+// Cosim_parse extracts the trace from the output of the cosimulation testbench
+func (mach *Machine) Cosim_parse(output string) (*Cosim_trace, error) {
+	reg_num := 1 << mach.R
+	result := new(Cosim_trace)
+	result.States = make([]Cosim_state, 0)
+	for _, line := range strings.Split(output, "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 || words[0] != "COSIM" {
+			continue
+		}
+		if len(words) != 3+reg_num+int(mach.M) {
+			return nil, Prerror{"Malformed cosimulation line: " + line}
+		}
+		step, err := strconv.Atoi(words[1])
+		if err != nil {
+			return nil, Prerror{"Malformed cosimulation step: " + line}
+		}
+		st := Cosim_state{Step: uint64(step)}
+		if pc, err := strconv.Atoi(words[2]); err == nil {
+			st.Pc = uint64(pc)
+		} else {
+			return nil, Prerror{"Undefined program counter: " + line}
+		}
+		st.Registers = words[3 : 3+reg_num]
+		st.Outputs = words[3+reg_num:]
+		result.States = append(result.States, st)
+	}
+	return result, nil
+}
+
+// Cosim_vm_trace runs the machine on the VM for the given number of steps applying the simbox inputs
+func (mach *Machine) Cosim_vm_trace(sbox *simbox.Simbox, steps int) (*Cosim_trace, error) {
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		return nil, err
+	}
+
+	sdrive := new(Sim_drive)
+	if err := sdrive.Init(sbox, vm); err != nil {
+		return nil, err
+	}
+
+	result := new(Cosim_trace)
+	result.States = make([]Cosim_state, 0)
+	for i := uint64(0); i < uint64(steps); i++ {
+		st := Cosim_state{Step: i, Pc: vm.Pc}
+		st.Registers = make([]string, len(vm.Registers))
+		for j, reg := range vm.Registers {
+			st.Registers[j] = fmt.Sprint(reg)
+		}
+		st.Outputs = make([]string, len(vm.Outputs))
+		for j, outp := range vm.Outputs {
+			st.Outputs[j] = fmt.Sprint(outp)
+		}
+		result.States = append(result.States, st)
+
+		if _, running, err := vm.Fetch(); err != nil {
+			return nil, err
+		} else if !running {
+			result.Halted = true
+			break
+		}
+
+		if act, ok := sdrive.AbsSet[i]; ok {
+			for j, val := range act {
+				*sdrive.Injectables[j] = val
+			}
+		}
+
+		pc := vm.Pc
+		if _, err := vm.Step(nil); err != nil {
+			return nil, err
+		}
+
+		// An instruction that does not move the program counter is where the RTL trace ends too
+		if vm.Pc == pc {
+			break
+		}
+	}
+	return result, nil
+}
+
+// Disassemble_at returns the disassembled instruction at the given ROM address
+func (mach *Machine) Disassemble_at(pc uint64) string {
+	if int(pc) >= len(mach.Program.Slocs) {
+		return "<outside program>"
+	}
+	instr := mach.Program.Slocs[pc]
+	if opcode_id, err := mach.Conproc.Decode_opcode(instr); err == nil {
+		op := mach.Arch.Conproc.Op[opcode_id]
+		if disas, err := op.Disassembler(&mach.Arch, instr[mach.Opcodes_bits():]); err == nil {
+			return op.Op_get_name() + " " + disas
+		}
+	}
+	return "<unknown " + instr + ">"
+}
+
+func cosim_undefined(value string) bool {
+	return strings.ContainsAny(value, "xXzZ")
+}
+
+// Cosim_compare aligns the traces and returns the report and whether they are equivalent.
+// Undefined RTL values (never driven after reset) are not compared.
+func (mach *Machine) Cosim_compare(vmtrace *Cosim_trace, rtltrace *Cosim_trace) (string, bool) {
+	diverged := func(i int, what string, vmval string, rtlval string) (string, bool) {
+		result := "Divergence at step " + strconv.Itoa(i) + " on " + what + ": VM " + vmval + ", RTL " + rtlval + "\n"
+		if i > 0 {
+			prev := vmtrace.States[i-1].Pc
+			result += "\tafter " + strconv.Itoa(int(prev)) + ": " + mach.Disassemble_at(prev) + "\n"
+			result += "\tVM  " + vmtrace.States[i-1].String() + "\n"
+		}
+		result += "\tVM  " + vmtrace.States[i].String() + "\n"
+		result += "\tRTL " + rtltrace.States[i].String() + "\n"
+		return result, false
+	}
+
+	common := len(vmtrace.States)
+	if len(rtltrace.States) < common {
+		common = len(rtltrace.States)
+	}
+
+	for i := 0; i < common; i++ {
+		vmst := vmtrace.States[i]
+		rtlst := rtltrace.States[i]
+		if vmst.Pc != rtlst.Pc {
+			return diverged(i, "pc", strconv.Itoa(int(vmst.Pc)), strconv.Itoa(int(rtlst.Pc)))
+		}
+		for j, reg := range vmst.Registers {
+			if !cosim_undefined(rtlst.Registers[j]) && reg != rtlst.Registers[j] {
+				return diverged(i, strings.ToLower(Get_register_name(j)), reg, rtlst.Registers[j])
+			}
+		}
+		for j, outp := range vmst.Outputs {
+			if !cosim_undefined(rtlst.Outputs[j]) && outp != rtlst.Outputs[j] {
+				return diverged(i, Get_output_name(j), outp, rtlst.Outputs[j])
+			}
+		}
+	}
+
+	if len(vmtrace.States) != len(rtltrace.States) && !(vmtrace.Halted && common == len(vmtrace.States)) {
+		ended := "RTL"
+		last := rtltrace.States
+		if len(vmtrace.States) < len(rtltrace.States) {
+			ended = "VM"
+			last = vmtrace.States
+		}
+		result := "Divergence at step " + strconv.Itoa(common) + ": the " + ended + " trace ends"
+		if len(last) > 0 {
+			pc := last[len(last)-1].Pc
+			result += " on " + strconv.Itoa(int(pc)) + ": " + mach.Disassemble_at(pc)
+		}
+		return result + "\n", false
+	}
+
+	return "Traces equivalent for " + strconv.Itoa(common) + " steps\n", true
+}
+
+// Cosim writes the RTL and the testbench in dir, runs them with iverilog and compares the result with the VM
+func (mach *Machine) Cosim(dir string, sbox *simbox.Simbox, steps int) (string, bool, error) {
+	if mach.Shared_constraints != "" {
+		return "", false, Prerror{"Cosimulation of processors with shared objects is not supported"}
+	}
+	for _, tool := range []string{"iverilog", "vvp"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			return "", false, Prerror{tool + " not found"}
+		}
+	}
+
+	vmtrace, err := mach.Cosim_vm_trace(sbox, steps)
+	if err != nil {
+		return "", false, err
+	}
+
+	out := &Output{Dir: dir, Policy: OUT_FORCE}
+	if err := out.Init(); err != nil {
+		return "", false, err
+	}
+
+	conf := new(Config)
+	conf.Runinfo = new(RuntimeInfo)
+	conf.Runinfo.Init()
+
+	arch := &mach.Arch
+	files := []string{"arch.v", "processor.v", "ram.v", "rom.v", COSIM_TB + ".v"}
+	contents := []string{
+		arch.Write_verilog("a0", map[string]string{"processor": "p0", "rom": "p0rom", "ram": "p0ram"}, "iverilog"),
+		arch.Conproc.Write_verilog(conf, arch, "p0", "iverilog"),
+		arch.Ram.Write_verilog(mach, "p0ram", "iverilog"),
+		arch.Rom.Write_verilog(mach, "p0rom", "iverilog"),
+		mach.Write_verilog_cosim_testbench("a0", "p0", sbox, steps),
+	}
+	for i, file := range files {
+		if err := out.Write(file, contents[i]); err != nil {
+			return "", false, err
+		}
+	}
+
+	compile := exec.Command("iverilog", append([]string{"-o", COSIM_TB + ".vvp", "-s", COSIM_TB}, files...)...)
+	compile.Dir = dir
+	if output, err := compile.CombinedOutput(); err != nil {
+		return string(output), false, Prerror{"iverilog failed: " + err.Error()}
+	}
+
+	run := exec.Command("vvp", "-n", filepath.Join(dir, COSIM_TB+".vvp"))
+	output, err := run.CombinedOutput()
+	if err != nil {
+		return string(output), false, Prerror{"vvp failed: " + err.Error()}
+	}
+
+	rtltrace, err := mach.Cosim_parse(string(output))
+	if err != nil {
+		return "", false, err
+	}
+
+	report, equal := mach.Cosim_compare(vmtrace, rtltrace)
+	return report, equal, nil
+}
@@ -0,0 +1,148 @@
+package procbuilder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"simbox"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestCosim(t *testing.T) {
+	mach := new(Machine)
+	arch := &mach.Arch
+	arch.Modes = []string{"ha"}
+	arch.Rsize = 8
+	arch.R = 2
+	arch.N = 1
+	arch.M = 1
+	arch.L = 4
+	arch.O = 4
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "i2r", "inc", "j", "r2o", "rset":
+			arch.Op = append(arch.Op, op)
+		}
+	}
+	sort.Sort(ByName(arch.Op))
+
+	prog, err := arch.Assembler([]byte("rset r0 5\ninc r0\nr2o r0 o0\ni2r r1 i0\nj 1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mach.Program = prog
+
+	vmtrace, err := mach.Cosim_vm_trace(nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vmtrace.States) != 10 || vmtrace.States[2].Registers[0] != "6" {
+		t.Error("Wrong VM trace", vmtrace.States)
+	}
+
+	// A trace as the RTL would produce it, with the output undriven before the r2o and a wrong increment
+	output := "COSIM 0 0 0 0 0 0 x\nCOSIM 1 1 5 0 0 0 x\nCOSIM 2 2 7 0 0 0 x\n"
+	rtltrace, err := mach.Cosim_parse(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, equal := mach.Cosim_compare(vmtrace, rtltrace)
+	fmt.Print(report)
+	if equal {
+		t.Error("Divergence not detected")
+	}
+
+	if _, equal := mach.Cosim_compare(vmtrace, vmtrace); !equal {
+		t.Error("Identical traces reported as different")
+	}
+
+	if _, err := exec.LookPath("iverilog"); err != nil {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "cosim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	report, equal, err = mach.Cosim(dir, nil, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(report)
+	if !equal {
+		t.Error("The VM and the RTL diverge")
+	}
+}
+
+// An event arrives during a multf, the RTL serves it after the floating point unit is done as the VM does. The
+// registers are zero, so the product leaves them unchanged on both.
+func TestCosimEvents(t *testing.T) {
+	mach := new(Machine)
+	arch := &mach.Arch
+	arch.Modes = []string{"ha"}
+	arch.Rsize = 8
+	arch.R = 2
+	arch.N = 1
+	arch.M = 1
+	arch.L = 4
+	arch.O = 4
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "eve", "evr", "i2r", "inc", "j", "multf":
+			arch.Op = append(arch.Op, op)
+		}
+	}
+	sort.Sort(ByName(arch.Op))
+
+	prog, err := arch.Assembler([]byte("eve i0 4\nmultf r0 r1\ninc r3\nj 2\ni2r r2 i0\nevr\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mach.Program = prog
+
+	sbox := new(simbox.Simbox)
+	sbox.Rules = []simbox.Rule{{Timec: simbox.TIMEC_ABS, Tick: 1, Action: simbox.ACTION_SET, Object: "i0", Extra: "7"}}
+
+	vmtrace, err := mach.Cosim_vm_trace(sbox, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pcs := make([]uint64, 0)
+	for _, st := range vmtrace.States {
+		pcs = append(pcs, st.Pc)
+	}
+	if fmt.Sprint(pcs) != "[0 1 2 4 5 2 3 2]" || vmtrace.States[5].Registers[2] != "7" {
+		t.Error("Wrong VM trace", vmtrace.States)
+	}
+
+	conf := new(Config)
+	conf.Runinfo = new(RuntimeInfo)
+	conf.Runinfo.Init()
+	if rtl := arch.Conproc.Write_verilog(conf, arch, "p0", "iverilog"); !strings.Contains(rtl, "wire ev_multicycle = (rom_value[") || !strings.Contains(rtl, " == MULTF);") {
+		t.Error("The multf is not an instruction the events wait for")
+	}
+
+	if _, err := exec.LookPath("iverilog"); err != nil {
+		t.Skip("iverilog not found, only the VM trace has been tested")
+	}
+
+	dir, err := ioutil.TempDir("", "cosim")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	report, equal, err := mach.Cosim(dir, sbox, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(report)
+	if !equal {
+		t.Error("The VM and the RTL diverge on the event during multf")
+	}
+}
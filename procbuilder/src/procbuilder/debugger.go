@@ -0,0 +1,606 @@
+package procbuilder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Interactive debugger. It drives a target (a single machine or a bondmachine) tick by tick or one processor at a time,
+// it stops on breakpoints and watchpoints and it can go back in time restoring the snapshots taken before every step.
+//
+// The elements are named as the bonds: p0r1 is the register r1 of the processor 0, p0m12 its RAM cell 12, p0i0 and
+// p0o0 its input and output, i0 and o0 are the inputs and the outputs of the target.
+
+const (
+	DEBUG_HISTORY   = 1000    // Snapshots kept for the reverse steps
+	DEBUG_MAX_TICKS = 1 << 20 // Ticks of a continue without limits
+)
+
+type Debug_target interface {
+	Processors() []*VM
+	Tick() error // A clock of the whole target, the simbox actions included
+	Ticks() uint64
+	Snapshot() interface{}
+	Restore(interface{})
+	Element(string) (*interface{}, error) // The inputs and the outputs of the target
+}
+
+type Breakpoint struct {
+	Processor int // -1 for every processor
+	Pc        int // -1 if the breakpoint is on an opcode
+	Opcode    string
+}
+
+type Watchpoint struct {
+	Name  string
+	loc   *interface{}
+	value string
+}
+
+type Debugger struct {
+	Target      Debug_target
+	Current     int // The selected processor
+	Breakpoints []*Breakpoint
+	Watchpoints []*Watchpoint
+	snapshots   []interface{}
+}
+
+func (bp *Breakpoint) String() string {
+	result := "any processor"
+	if bp.Processor != -1 {
+		result = "p" + strconv.Itoa(bp.Processor)
+	}
+	if bp.Pc != -1 {
+		return result + " pc " + strconv.Itoa(bp.Pc)
+	}
+	return result + " opcode " + bp.Opcode
+}
+
+func Debug_value(value interface{}) string {
+	return strconv.Itoa(word_value(value))
+}
+
+// Element_location resolves the name of a processor element or of a target element
+func (d *Debugger) Element_location(name string) (*interface{}, error) {
+	if len(name) > 2 && name[0] == 'p' {
+		i := 1
+		for i < len(name) && name[i] >= '0' && name[i] <= '9' {
+			i++
+		}
+		if i > 1 && i < len(name)-1 {
+			proc, _ := strconv.Atoi(name[1:i])
+			id, err := strconv.Atoi(name[i+1:])
+			if err != nil || proc >= len(d.Target.Processors()) {
+				return nil, Prerror{name + " unknown"}
+			}
+			vm := d.Target.Processors()[proc]
+			var elements []interface{}
+			switch name[i] {
+			case 'r':
+				elements = vm.Registers
+			case 'm':
+				elements = vm.Memory
+			case 'i':
+				elements = vm.Inputs
+			case 'o':
+				elements = vm.Outputs
+			}
+			if id < len(elements) {
+				return &elements[id], nil
+			}
+			return nil, Prerror{name + " unknown"}
+		}
+	}
+	return d.Target.Element(name)
+}
+
+// The instruction at a location of a processor program
+func (d *Debugger) instruction(proc int, pc int) (string, bool) {
+	vm := d.Target.Processors()[proc]
+	if vm.Code != nil {
+		if pc < len(vm.Code) && vm.Code[pc] != "" {
+			return vm.Code[pc], true
+		}
+		return "", false
+	}
+	if pc < len(vm.Mach.Program.Slocs) {
+		return vm.Mach.Program.Slocs[pc], true
+	}
+	return "", false
+}
+
+// Disassemble_instruction returns the assembly of a binary instruction
+func (mach *Machine) Disassemble_instruction(instr string) (string, error) {
+	opcode_id, err := mach.Conproc.Decode_opcode(instr)
+	if err != nil || opcode_id >= len(mach.Op) {
+		return "", Prerror{"Unknown opcode"}
+	}
+	op := mach.Op[opcode_id]
+	part, err := op.Disassembler(&mach.Arch, instr[mach.Opcodes_bits():])
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(op.Op_get_name() + " " + part), nil
+}
+
+// Disassemble the program of a processor around its program counter
+func (d *Debugger) Disassemble(proc int, around int) string {
+	vm := d.Target.Processors()[proc]
+	result := ""
+	for pc := int(vm.Pc) - around; pc <= int(vm.Pc)+around; pc++ {
+		if pc < 0 {
+			continue
+		}
+		instr, ok := d.instruction(proc, pc)
+		if !ok {
+			break
+		}
+		marker := "  "
+		if pc == int(vm.Pc) {
+			marker = "=>"
+		}
+		asm, err := vm.Mach.Disassemble_instruction(instr)
+		if err != nil {
+			asm = instr
+		}
+		result += fmt.Sprintf("%s %4d: %s%s\n", marker, pc, asm, source_comment(vm.Source, pc))
+	}
+	return result
+}
+
+func (d *Debugger) Add_breakpoint(bp *Breakpoint) {
+	d.Breakpoints = append(d.Breakpoints, bp)
+}
+
+func (d *Debugger) Add_watchpoint(name string) error {
+	loc, err := d.Element_location(name)
+	if err != nil {
+		return err
+	}
+	d.Watchpoints = append(d.Watchpoints, &Watchpoint{name, loc, Debug_value(*loc)})
+	return nil
+}
+
+func (d *Debugger) save() {
+	d.snapshots = append(d.snapshots, d.Target.Snapshot())
+	if len(d.snapshots) > DEBUG_HISTORY {
+		d.snapshots = d.snapshots[1:]
+	}
+}
+
+func (d *Debugger) pcs() []uint64 {
+	result := make([]uint64, 0)
+	for _, vm := range d.Target.Processors() {
+		result = append(result, vm.Pc)
+	}
+	return result
+}
+
+func (d *Debugger) Halted() bool {
+	for _, vm := range d.Target.Processors() {
+		if _, running, _ := vm.Fetch(); running {
+			return false
+		}
+	}
+	return true
+}
+
+// The reason to stop before the next tick, empty if none. The breakpoints are checked only on the processors that
+// moved from the given program counters (all of them if nil), a stalled instruction does not hit them again.
+func (d *Debugger) stop_reason(before []uint64) string {
+	for _, wp := range d.Watchpoints {
+		if value := Debug_value(*wp.loc); value != wp.value {
+			result := "Watchpoint " + wp.Name + ": " + wp.value + " -> " + value
+			wp.value = value
+			return result
+		}
+	}
+	for i, vm := range d.Target.Processors() {
+		if before != nil && before[i] == vm.Pc {
+			continue
+		}
+		instr, ok := d.instruction(i, int(vm.Pc))
+		if !ok {
+			continue
+		}
+		for n, bp := range d.Breakpoints {
+			if bp.Processor != -1 && bp.Processor != i {
+				continue
+			}
+			if bp.Pc == int(vm.Pc) {
+				return "Breakpoint " + strconv.Itoa(n) + ": p" + strconv.Itoa(i) + " pc " + strconv.Itoa(int(vm.Pc))
+			}
+			if bp.Pc == -1 {
+				if opcode_id, err := vm.Mach.Conproc.Decode_opcode(instr); err == nil && opcode_id < len(vm.Mach.Op) && vm.Mach.Op[opcode_id].Op_get_name() == bp.Opcode {
+					return "Breakpoint " + strconv.Itoa(n) + ": p" + strconv.Itoa(i) + " " + bp.Opcode + " at pc " + strconv.Itoa(int(vm.Pc))
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// The watched values are read again, after a move in time they must not trigger
+func (d *Debugger) sync_watchpoints() {
+	for _, wp := range d.Watchpoints {
+		wp.value = Debug_value(*wp.loc)
+	}
+}
+
+// Step executes ticks of the whole target, it returns the reason it stopped before the end if any
+func (d *Debugger) Step(ticks int) (string, error) {
+	for i := 0; i < ticks; i++ {
+		if d.Halted() {
+			return "Halted", nil
+		}
+		d.save()
+		before := d.pcs()
+		if err := d.Target.Tick(); err != nil {
+			return "", err
+		}
+		if reason := d.stop_reason(before); reason != "" {
+			return reason, nil
+		}
+	}
+	return "", nil
+}
+
+// Step_processor executes a clock of a single processor, the target IO is not updated
+func (d *Debugger) Step_processor(proc int) (string, error) {
+	if proc < 0 || proc >= len(d.Target.Processors()) {
+		return "", Prerror{"Unknown processor"}
+	}
+	d.save()
+	before := d.pcs()
+	if _, err := d.Target.Processors()[proc].Step(nil); err != nil {
+		return "", err
+	}
+	return d.stop_reason(before), nil
+}
+
+// Continue runs until a breakpoint, a watchpoint, the halt of every processor or the ticks limit
+func (d *Debugger) Continue(max_ticks int) (string, error) {
+	for i := 0; i < max_ticks; i++ {
+		if reason, err := d.Step(1); err != nil || reason != "" {
+			return reason, err
+		}
+	}
+	return "Ticks limit reached", nil
+}
+
+// Reverse restores the state before the last steps, it returns how many steps it went back
+func (d *Debugger) Reverse(steps int) int {
+	done := 0
+	for ; done < steps && len(d.snapshots) > 0; done++ {
+		d.Target.Restore(d.snapshots[len(d.snapshots)-1])
+		d.snapshots = d.snapshots[:len(d.snapshots)-1]
+	}
+	d.sync_watchpoints()
+	return done
+}
+
+// Reverse_continue goes back until a breakpoint or the oldest snapshot
+func (d *Debugger) Reverse_continue() string {
+	for len(d.snapshots) > 0 {
+		d.Reverse(1)
+		if reason := d.stop_reason(nil); strings.HasPrefix(reason, "Breakpoint") {
+			return reason
+		}
+	}
+	return "Beginning of the history"
+}
+
+func (d *Debugger) Registers(proc int) string {
+	vm := d.Target.Processors()[proc]
+	result := "p" + strconv.Itoa(proc) + " pc: " + strconv.Itoa(int(vm.Pc))
+	for i, reg := range vm.Registers {
+		result += " " + strings.ToLower(Get_register_name(i)) + ": " + Debug_value(reg)
+	}
+	for i, inp := range vm.Inputs {
+		result += " " + Get_input_name(i) + ": " + Debug_value(inp)
+	}
+	for i, outp := range vm.Outputs {
+		result += " " + Get_output_name(i) + ": " + Debug_value(outp)
+	}
+	return result + "\n"
+}
+
+const debug_help = `Commands:
+	step [n]                 Execute n ticks of the whole target (s)
+	stepi [proc]             Execute a clock of a single processor (si)
+	continue [n]             Run until a stop condition, at most n ticks (c)
+	reverse [n]              Go back n steps (rs)
+	reverse-continue         Go back until a breakpoint (rc)
+	break [pN] pc|opcode     Stop before a location or an opcode (b)
+	watch element            Stop when an element changes, i.e. p0r1, p0m12, p0o0, o0 (w)
+	delete break|watch n     Remove a breakpoint or a watchpoint (d)
+	info                     List breakpoints and watchpoints
+	proc n                   Select the current processor
+	regs [proc]              Show the registers and the IO of a processor (r)
+	print element            Show an element (p)
+	mem [proc] addr [n]      Show n RAM cells (m)
+	disas [proc] [n]         Disassemble n instructions around the program counter (x)
+	counters [proc]          Show the performance counters
+	quit                     Leave the debugger (q)
+`
+
+// The processor given as first argument of a command, the current one if missing
+func (d *Debugger) proc_arg(args []string) (int, []string, error) {
+	if len(args) > 0 && len(args[0]) > 1 && args[0][0] == 'p' {
+		if proc, err := strconv.Atoi(args[0][1:]); err == nil {
+			if proc >= len(d.Target.Processors()) {
+				return 0, nil, Prerror{"Unknown processor " + args[0]}
+			}
+			return proc, args[1:], nil
+		}
+	}
+	return d.Current, args, nil
+}
+
+func int_arg(args []string, pos int, def int) (int, error) {
+	if len(args) > pos {
+		return strconv.Atoi(args[pos])
+	}
+	return def, nil
+}
+
+func stop_line(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return reason + "\n"
+}
+
+// Command executes a debugger command, it returns the output and false if the debugger has to quit
+func (d *Debugger) Command(line string) (string, bool, error) {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return "", true, nil
+	}
+	cmd := words[0]
+	args := words[1:]
+
+	switch cmd {
+	case "help", "h":
+		return debug_help, true, nil
+	case "quit", "q":
+		return "", false, nil
+	case "step", "s":
+		n, err := int_arg(args, 0, 1)
+		if err != nil {
+			return "", true, err
+		}
+		reason, err := d.Step(n)
+		if err != nil {
+			return "", true, err
+		}
+		return stop_line(reason) + d.Disassemble(d.Current, 0), true, nil
+	case "stepi", "si":
+		proc, _, err := d.proc_arg(args)
+		if err != nil {
+			return "", true, err
+		}
+		reason, err := d.Step_processor(proc)
+		if err != nil {
+			return "", true, err
+		}
+		return stop_line(reason) + d.Disassemble(proc, 0), true, nil
+	case "continue", "c":
+		n, err := int_arg(args, 0, DEBUG_MAX_TICKS)
+		if err != nil {
+			return "", true, err
+		}
+		reason, err := d.Continue(n)
+		if err != nil {
+			return "", true, err
+		}
+		return stop_line(reason) + d.Disassemble(d.Current, 0), true, nil
+	case "reverse", "rs":
+		n, err := int_arg(args, 0, 1)
+		if err != nil {
+			return "", true, err
+		}
+		done := d.Reverse(n)
+		return "Back " + strconv.Itoa(done) + " steps\n" + d.Disassemble(d.Current, 0), true, nil
+	case "reverse-continue", "rc":
+		return stop_line(d.Reverse_continue()) + d.Disassemble(d.Current, 0), true, nil
+	case "break", "b":
+		proc, rest, err := d.proc_arg(args)
+		if err != nil {
+			return "", true, err
+		}
+		if len(rest) != 1 {
+			return "", true, Prerror{"Usage: break [pN] pc|opcode"}
+		}
+		if len(args) == len(rest) {
+			proc = -1
+		}
+		bp := &Breakpoint{Processor: proc, Pc: -1}
+		if pc, err := strconv.Atoi(rest[0]); err == nil {
+			bp.Pc = pc
+		} else {
+			bp.Opcode = rest[0]
+		}
+		d.Add_breakpoint(bp)
+		return "Breakpoint " + strconv.Itoa(len(d.Breakpoints)-1) + ": " + bp.String() + "\n", true, nil
+	case "watch", "w":
+		if len(args) != 1 {
+			return "", true, Prerror{"Usage: watch element"}
+		}
+		if err := d.Add_watchpoint(args[0]); err != nil {
+			return "", true, err
+		}
+		return "Watchpoint " + strconv.Itoa(len(d.Watchpoints)-1) + ": " + args[0] + "\n", true, nil
+	case "delete", "d":
+		if len(args) != 2 {
+			return "", true, Prerror{"Usage: delete break|watch n"}
+		}
+		n, err := strconv.Atoi(args[1])
+		if err != nil {
+			return "", true, err
+		}
+		switch {
+		case strings.HasPrefix(args[0], "b") && n < len(d.Breakpoints):
+			d.Breakpoints = append(d.Breakpoints[:n], d.Breakpoints[n+1:]...)
+		case strings.HasPrefix(args[0], "w") && n < len(d.Watchpoints):
+			d.Watchpoints = append(d.Watchpoints[:n], d.Watchpoints[n+1:]...)
+		default:
+			return "", true, Prerror{"Unknown breakpoint or watchpoint"}
+		}
+		return "", true, nil
+	case "info", "i":
+		result := "Tick " + strconv.Itoa(int(d.Target.Ticks())) + ", current processor p" + strconv.Itoa(d.Current) + "\n"
+		for i, bp := range d.Breakpoints {
+			result += "Breakpoint " + strconv.Itoa(i) + ": " + bp.String() + "\n"
+		}
+		for i, wp := range d.Watchpoints {
+			result += "Watchpoint " + strconv.Itoa(i) + ": " + wp.Name + " = " + wp.value + "\n"
+		}
+		return result, true, nil
+	case "proc":
+		proc, _, err := d.proc_arg([]string{"p" + strings.TrimPrefix(strings.Join(args, ""), "p")})
+		if err != nil {
+			return "", true, err
+		}
+		d.Current = proc
+		return d.Registers(proc), true, nil
+	case "regs", "r":
+		proc, _, err := d.proc_arg(args)
+		if err != nil {
+			return "", true, err
+		}
+		return d.Registers(proc), true, nil
+	case "print", "p":
+		if len(args) != 1 {
+			return "", true, Prerror{"Usage: print element"}
+		}
+		loc, err := d.Element_location(args[0])
+		if err != nil {
+			return "", true, err
+		}
+		return args[0] + " = " + Debug_value(*loc) + "\n", true, nil
+	case "mem", "m":
+		proc, rest, err := d.proc_arg(args)
+		if err != nil {
+			return "", true, err
+		}
+		addr, err := int_arg(rest, 0, 0)
+		if err != nil {
+			return "", true, err
+		}
+		n, err := int_arg(rest, 1, 8)
+		if err != nil {
+			return "", true, err
+		}
+		memory := d.Target.Processors()[proc].Memory
+		result := ""
+		for a := addr; a < addr+n && a < len(memory); a++ {
+			result += fmt.Sprintf("p%dm%d = %s\n", proc, a, Debug_value(memory[a]))
+		}
+		return result, true, nil
+	case "disas", "x":
+		proc, rest, err := d.proc_arg(args)
+		if err != nil {
+			return "", true, err
+		}
+		n, err := int_arg(rest, 0, 4)
+		if err != nil {
+			return "", true, err
+		}
+		return d.Disassemble(proc, n), true, nil
+	case "counters":
+		proc, _, err := d.proc_arg(args)
+		if err != nil {
+			return "", true, err
+		}
+		return d.Target.Processors()[proc].Counters.String(), true, nil
+	}
+	return "", true, Prerror{"Unknown command " + cmd + ", try help"}
+}
+
+// Repl reads the commands from in until quit or the end of the input, an empty line repeats the last command
+func (d *Debugger) Repl(in io.Reader, out io.Writer) {
+	scanner := bufio.NewScanner(in)
+	last := ""
+	fmt.Fprint(out, "(bmdb) ")
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			line = last
+		}
+		last = line
+		result, cont, err := d.Command(line)
+		if err != nil {
+			fmt.Fprintln(out, "Error:", err)
+		}
+		fmt.Fprint(out, result)
+		if !cont {
+			return
+		}
+		fmt.Fprint(out, "(bmdb) ")
+	}
+}
+
+// The debug target of a single machine
+type Machine_target struct {
+	Vm    *VM
+	Drive *Sim_drive
+	tick  uint64
+}
+
+type machine_snapshot struct {
+	vm   *VM
+	tick uint64
+}
+
+func (t *Machine_target) Processors() []*VM {
+	return []*VM{t.Vm}
+}
+
+func (t *Machine_target) Tick() error {
+	if t.Drive != nil {
+		if act, exist_actions := t.Drive.AbsSet[t.tick]; exist_actions {
+			for i, val := range act {
+				*t.Drive.Injectables[i] = val
+			}
+		}
+	}
+	_, err := t.Vm.Step(nil)
+	t.tick++
+	return err
+}
+
+func (t *Machine_target) Ticks() uint64 {
+	return t.tick
+}
+
+func (t *Machine_target) Snapshot() interface{} {
+	state := new(VM)
+	state.Mach = t.Vm.Mach
+	state.Init()
+	state.CopyState(t.Vm)
+	return machine_snapshot{state, t.tick}
+}
+
+func (t *Machine_target) Restore(snapshot interface{}) {
+	state := snapshot.(machine_snapshot)
+	t.Vm.CopyState(state.vm)
+	t.tick = state.tick
+}
+
+// The inputs and the outputs of the machine are the ones of its only processor
+func (t *Machine_target) Element(name string) (*interface{}, error) {
+	if len(name) > 1 && (name[0] == 'i' || name[0] == 'o') {
+		if id, err := strconv.Atoi(name[1:]); err == nil {
+			if name[0] == 'i' && id < len(t.Vm.Inputs) {
+				return &t.Vm.Inputs[id], nil
+			} else if name[0] == 'o' && id < len(t.Vm.Outputs) {
+				return &t.Vm.Outputs[id], nil
+			}
+		}
+	}
+	return nil, Prerror{name + " unknown"}
+}
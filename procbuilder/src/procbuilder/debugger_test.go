@@ -0,0 +1,115 @@
+package procbuilder
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func debug_machine(t *testing.T) *Debugger {
+	mach := model_machine("ha")
+
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("rset r0 5\nr2m r0 2\nm2r r1 2\nrset r0 7\nnop\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := new(Debugger)
+	d.Target = &Machine_target{Vm: vm}
+	return d
+}
+
+func TestDebugger(t *testing.T) {
+	d := debug_machine(t)
+	vm := d.Target.Processors()[0]
+
+	out := new(bytes.Buffer)
+	d.Repl(strings.NewReader("break 3\nwatch p0m2\ncontinue\ncontinue\nregs\nreverse 2\nregs\ndisas 1\nquit\n"), out)
+	fmt.Print(out.String())
+
+	if !strings.Contains(out.String(), "Watchpoint p0m2: 0 -> 5") || !strings.Contains(out.String(), "Breakpoint 0: p0 pc 3") {
+		t.Error("Missing stops")
+	}
+	if vm.Pc != 1 || word_value(vm.Memory[2]) != 0 {
+		t.Error("Reverse step failed")
+	}
+	if !strings.Contains(out.String(), "=>    1: r2m r0 2") {
+		t.Error("Wrong disassembly")
+	}
+
+	d.Breakpoints = nil
+	d.Watchpoints = nil
+	if reason, _ := d.Continue(100); reason != "Halted" || word_value(vm.Registers[1]) != 5 {
+		t.Error("Run to the end failed", reason)
+	}
+}
+
+type gdb_pipe struct {
+	in  *bytes.Buffer
+	out *bytes.Buffer
+}
+
+func (p *gdb_pipe) Read(b []byte) (int, error) {
+	return p.in.Read(b)
+}
+
+func (p *gdb_pipe) Write(b []byte) (int, error) {
+	return p.out.Write(b)
+}
+
+func TestGdbStub(t *testing.T) {
+	d := debug_machine(t)
+
+	packets := []string{"qSupported", "Z0,3,1", "c", "g", "m4,2", "bs", "p4", "H", "k"}
+	in := ""
+	for _, packet := range packets {
+		in += gdb_packet(packet)
+	}
+	pipe := &gdb_pipe{bytes.NewBufferString(in), new(bytes.Buffer)}
+	if err := d.Gdb_session(pipe); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Println(pipe.out.String())
+
+	// At pc 3 r0 and r1 hold 5, the pc is the last register, the RAM cell 2 is at the address 4, a bare H is refused
+	for _, reply := range []string{"$T05thread:1;#", "$05000500000000000300000", "$0500#", "$02000000#", "$E01#"} {
+		if !strings.Contains(pipe.out.String(), reply) {
+			t.Error("Missing reply " + reply)
+		}
+	}
+}
+
+func TestDebugSource(t *testing.T) {
+	mach := model_machine("ha")
+
+	asm := "#@ main.go:4:2 a=r0\nrset r0 5\n\n# comment\nnop\n#@ main.go:5:2 a=r0 b=m2\nr2m r0 2\n"
+	var err error
+	if mach.Program, err = mach.Assembler([]byte(asm)); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	vm.Source = Assembly_debug_lines(strings.Split(asm, "\n"))
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	d := new(Debugger)
+	d.Target = &Machine_target{Vm: vm}
+	disas := d.Disassemble(0, 2)
+	fmt.Print(disas)
+	if !strings.Contains(disas, "rset r0 5\t// main.go:4:2 a=r0\n") || !strings.Contains(disas, "r2m r0 2\t// main.go:5:2 a=r0 b=m2\n") {
+		t.Error("Missing source in the disassembly")
+	}
+	if dl, ok := Parse_debug_directive("#@ c:/main.go:5:2 a=r0:r1"); !ok || dl.File != "c:/main.go" || dl.Vars["a"] != "r0:r1" {
+		t.Error("Wrong directive parsing", dl)
+	}
+}
@@ -0,0 +1,138 @@
+package procbuilder
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Source level debug info. The compilers save a sidecar file that maps the ROM addresses of every processor to the
+// source line that produced the instruction and to the processor objects holding the variables in scope.
+// Within an assembly program the same info is a comment directive preceding the instruction:
+//	#@ main.go:12:3 a=r0 b=m2
+
+const DEBUG_DIRECTIVE = "#@"
+
+type Debug_line struct {
+	File   string
+	Line   int
+	Column int
+	Vars   map[string]string // Variable name -> processor object (r0, m2, i0, o0, r0:r1 for the multi-word ones)
+}
+
+type Debug_info struct {
+	Processors map[int]map[int]*Debug_line // Processor id -> ROM address -> source
+}
+
+func (di *Debug_info) Init() {
+	di.Processors = make(map[int]map[int]*Debug_line)
+}
+
+func (di *Debug_info) Set(proc_id int, address int, dl *Debug_line) {
+	if _, ok := di.Processors[proc_id]; !ok {
+		di.Processors[proc_id] = make(map[int]*Debug_line)
+	}
+	di.Processors[proc_id][address] = dl
+}
+
+// Processor returns the debug lines of a processor, nil if there are none
+func (di *Debug_info) Processor(proc_id int) map[int]*Debug_line {
+	if di == nil {
+		return nil
+	}
+	return di.Processors[proc_id]
+}
+
+func Load_debug_info(filename string) (*Debug_info, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	di := new(Debug_info)
+	di.Init()
+	if err := json.Unmarshal(data, di); err != nil {
+		return nil, err
+	}
+	return di, nil
+}
+
+// The position followed by the variables sorted by name
+func (dl *Debug_line) String() string {
+	result := dl.File + ":" + strconv.Itoa(dl.Line) + ":" + strconv.Itoa(dl.Column)
+	names := make([]string, 0, len(dl.Vars))
+	for name := range dl.Vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		result += " " + name + "=" + dl.Vars[name]
+	}
+	return result
+}
+
+func (dl *Debug_line) Directive() string {
+	return DEBUG_DIRECTIVE + " " + dl.String()
+}
+
+func Parse_debug_directive(line string) (*Debug_line, bool) {
+	words := strings.Fields(line)
+	if len(words) < 2 || words[0] != DEBUG_DIRECTIVE {
+		return nil, false
+	}
+	pos := strings.Split(words[1], ":")
+	if len(pos) < 3 {
+		return nil, false
+	}
+	dl := new(Debug_line)
+	dl.File = strings.Join(pos[:len(pos)-2], ":")
+	var err error
+	if dl.Line, err = strconv.Atoi(pos[len(pos)-2]); err != nil {
+		return nil, false
+	}
+	if dl.Column, err = strconv.Atoi(pos[len(pos)-1]); err != nil {
+		return nil, false
+	}
+	dl.Vars = make(map[string]string)
+	for _, word := range words[2:] {
+		if binding := strings.SplitN(word, "=", 2); len(binding) == 2 {
+			dl.Vars[binding[0]] = binding[1]
+		}
+	}
+	return dl, true
+}
+
+// Assembly_debug_lines collects the directives of an assembly program by ROM address, the addresses are counted
+// skipping the empty and the comment lines like the assembler does
+func Assembly_debug_lines(asm []string) map[int]*Debug_line {
+	result := make(map[int]*Debug_line)
+	var pending *Debug_line
+	address := 0
+	for _, line := range asm {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+		if words[0][0] == '#' {
+			if dl, ok := Parse_debug_directive(line); ok {
+				pending = dl
+			}
+			continue
+		}
+		if pending != nil {
+			result[address] = pending
+			pending = nil
+		}
+		address++
+	}
+	return result
+}
+
+// The source annotation of a disassembled instruction, empty if unknown
+func source_comment(source map[int]*Debug_line, pc int) string {
+	if dl, ok := source[pc]; ok && dl != nil {
+		return "\t// " + dl.String()
+	}
+	return ""
+}
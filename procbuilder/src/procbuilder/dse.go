@@ -0,0 +1,419 @@
+package procbuilder
+
+import (
+	"fmt"
+	"math/rand"
+	"mel"
+	"simbox"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Design-space exploration. Every point of the space is an architecture, the program is compiled for it by a
+// callback (the code may depend on the register size and on the number of registers), then the machine is assembled,
+// simulated against the expected outputs and its resources are estimated. The points not dominated by any other
+// on cycles, Fmax, LUTs, FFs, BRAMs and DSPs form the Pareto front.
+//
+// The expectations are the set rules on the outputs of the simbox that drives the inputs: absolute:T:set:o0:V means
+// o0 has to take the value V within the tick T, the expectations on the same output are met in tick order.
+// Without expectations the program has to halt.
+
+type Dse_space struct {
+	Rsize   []int
+	R       []int
+	L       []int
+	O       []int
+	Opcodes []string // Comma separated opcode sets, "auto" is the set of the opcodes used by the program
+	N       uint8
+	M       uint8
+	Flavor  string
+}
+
+type Dse_point struct {
+	Rsize    int
+	R        int
+	L        int
+	O        int
+	Opcodes  string
+	Feasible bool
+	Error    string
+	Cycles   int
+	Fmax_mhz int
+	Luts     int
+	Ffs      int
+	Brams    int
+	Dsps     int
+}
+
+// Returns the assembly of the program for an architecture
+type Dse_compiler func(arch *Arch) ([]byte, error)
+
+type Dse struct {
+	Space     Dse_space
+	Compile   Dse_compiler
+	Sbox      *simbox.Simbox // Inputs and expected outputs
+	Max_ticks int
+	Points    []*Dse_point // All the evaluated points, in evaluation order
+	evaluated map[string]*Dse_point
+}
+
+func (p *Dse_point) Key() string {
+	return fmt.Sprintf("%d/%d/%d/%d/%s", p.Rsize, p.R, p.L, p.O, p.Opcodes)
+}
+
+// Dominates tells if p is not worse than q on every objective and better on at least one
+func (p *Dse_point) Dominates(q *Dse_point) bool {
+	pv := []int{p.Cycles, -p.Fmax_mhz, p.Luts, p.Ffs, p.Brams, p.Dsps}
+	qv := []int{q.Cycles, -q.Fmax_mhz, q.Luts, q.Ffs, q.Brams, q.Dsps}
+	better := false
+	for i := range pv {
+		if pv[i] > qv[i] {
+			return false
+		} else if pv[i] < qv[i] {
+			better = true
+		}
+	}
+	return better
+}
+
+// Pareto_front returns the feasible points not dominated by other points, ordered by cycles
+func Pareto_front(points []*Dse_point) []*Dse_point {
+	result := make([]*Dse_point, 0)
+	for _, p := range points {
+		if !p.Feasible {
+			continue
+		}
+		dominated := false
+		for _, q := range points {
+			if q.Feasible && q.Dominates(p) {
+				dominated = true
+				break
+			}
+		}
+		if !dominated {
+			result = append(result, p)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Cycles < result[j].Cycles })
+	return result
+}
+
+func Dse_csv(points []*Dse_point) string {
+	result := "rsize,r,l,o,opcodes,feasible,cycles,fmax_mhz,luts,ffs,brams,dsps,error\n"
+	for _, p := range points {
+		result += fmt.Sprintf("%d,%d,%d,%d,\"%s\",%t,%d,%d,%d,%d,%d,%d,\"%s\"\n", p.Rsize, p.R, p.L, p.O, p.Opcodes, p.Feasible, p.Cycles, p.Fmax_mhz, p.Luts, p.Ffs, p.Brams, p.Dsps, strings.Replace(p.Error, "\"", "'", -1))
+	}
+	return result
+}
+
+// Program_opcodes returns the names of the opcodes used by an assembly program
+func Program_opcodes(asm []byte) []string {
+	result := make([]string, 0)
+	for _, line := range strings.Split(string(asm), "\n") {
+		words := strings.Fields(strings.ToLower(line))
+		if len(words) == 0 || words[0][0] == '#' {
+			continue
+		}
+		present := false
+		for _, op := range result {
+			if op == words[0] {
+				present = true
+				break
+			}
+		}
+		if !present {
+			result = append(result, words[0])
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Opcodes_by_name returns the opcodes with the given names ordered by name
+func Opcodes_by_name(names []string) ([]Opcode, error) {
+	result := make([]Opcode, 0)
+	for _, name := range names {
+		found := false
+		for _, op := range Allopcodes {
+			if op.Op_get_name() == name {
+				result = append(result, op)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, Prerror{"Unknown opcode " + name}
+		}
+	}
+	sort.Sort(ByName(result))
+	return result, nil
+}
+
+// Expect_run runs the machine on the VM with the inputs of a simbox until the expected outputs are all seen
+// (or the machine halts if there are none) and returns the number of ticks
+func (mach *Machine) Expect_run(sbox *simbox.Simbox, max_ticks int) (int, error) {
+	type expectation struct {
+		tick  uint64
+		value int
+	}
+
+	pending := make(map[int][]expectation)
+	if sbox != nil {
+		for _, rule := range sbox.Rules {
+			if rule.Timec == simbox.TIMEC_ABS && rule.Action == simbox.ACTION_SET && len(rule.Object) > 1 && rule.Object[0] == 'o' {
+				outp, err := strconv.Atoi(rule.Object[1:])
+				if err != nil || outp >= int(mach.M) {
+					return 0, Prerror{"Unknown output " + rule.Object}
+				}
+				value, err := strconv.Atoi(rule.Extra)
+				if err != nil {
+					return 0, Prerror{"Wrong expected value " + rule.Extra}
+				}
+				pending[outp] = append(pending[outp], expectation{rule.Tick, value})
+			}
+		}
+	}
+	for outp := range pending {
+		exps := pending[outp]
+		sort.SliceStable(exps, func(i, j int) bool { return exps[i].tick < exps[j].tick })
+	}
+
+	if mach.Rsize != 8 && mach.Rsize != 16 {
+		return 0, Prerror{"The VM supports only 8 and 16 bits registers"}
+	}
+	vm := new(VM)
+	vm.Mach = mach
+	vm.Timing = true
+	if err := vm.Init(); err != nil {
+		return 0, err
+	}
+
+	sets := cosim_inputs(sbox)
+	expected := len(pending) > 0
+
+	for tick := uint64(0); tick < uint64(max_ticks); tick++ {
+		for inp, val := range sets[tick] {
+			if value, err := strconv.Atoi(val); err == nil && inp < len(vm.Inputs) {
+				vm.Inputs[inp] = vm.word(value)
+			}
+		}
+
+		_, running, err := vm.Fetch()
+		if err != nil {
+			return 0, err
+		}
+		if !running {
+			if !expected {
+				return int(tick), nil
+			}
+			break
+		}
+		if _, err := vm.Step(nil); err != nil {
+			return 0, err
+		}
+
+		for outp, exps := range pending {
+			if word_value(vm.Outputs[outp]) == exps[0].value {
+				exps = exps[1:]
+			} else if tick >= exps[0].tick {
+				return 0, Prerror{fmt.Sprintf("%s expected %d within tick %d", Get_output_name(outp), exps[0].value, exps[0].tick)}
+			}
+			if len(exps) == 0 {
+				delete(pending, outp)
+			} else {
+				pending[outp] = exps
+			}
+		}
+
+		if expected && len(pending) == 0 {
+			return int(tick) + 1, nil
+		}
+	}
+
+	if expected {
+		for outp, exps := range pending {
+			return 0, Prerror{fmt.Sprintf("%s never took the value %d", Get_output_name(outp), exps[0].value)}
+		}
+	}
+	return 0, Prerror{"The program did not halt within " + strconv.Itoa(max_ticks) + " ticks"}
+}
+
+// The opcode set of an architecture as listed in the space, auto if it has none
+func Dse_opcodes(arch *Arch) string {
+	if len(arch.Op) == 0 {
+		return "auto"
+	}
+	names := make([]string, len(arch.Op))
+	for i, op := range arch.Op {
+		names[i] = op.Op_get_name()
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// Evaluate compiles, simulates and estimates an architecture. An architecture without opcodes gets the ones used by
+// its program. The results are cached by point.
+func (d *Dse) Evaluate(arch *Arch) *Dse_point {
+	if d.evaluated == nil {
+		d.evaluated = make(map[string]*Dse_point)
+	}
+
+	point := &Dse_point{Rsize: int(arch.Rsize), R: int(arch.R), L: int(arch.L), O: int(arch.O), Opcodes: Dse_opcodes(arch)}
+	if cached, ok := d.evaluated[point.Key()]; ok {
+		return cached
+	}
+	d.evaluated[point.Key()] = point
+	d.Points = append(d.Points, point)
+
+	mach := new(Machine)
+	mach.Arch = *arch
+	mach.N = d.Space.N
+	mach.M = d.Space.M
+
+	asm, err := d.Compile(&mach.Arch)
+	if err != nil {
+		point.Error = err.Error()
+		return point
+	}
+
+	if len(mach.Op) == 0 {
+		if mach.Op, err = Opcodes_by_name(Program_opcodes(asm)); err != nil {
+			point.Error = err.Error()
+			return point
+		}
+	}
+
+	if lines := len(strings.Split(strings.TrimSpace(string(asm)), "\n")); lines > 1<<mach.O {
+		point.Error = "The program does not fit the ROM"
+		return point
+	}
+
+	if !strings.HasSuffix(string(asm), "\n") {
+		asm = append(asm, '\n')
+	}
+	if mach.Program, err = mach.Assembler(asm); err != nil {
+		point.Error = err.Error()
+		return point
+	}
+	if _, ok := mach.Constraint_check(); !ok {
+		point.Error = "Constraint check failed"
+		return point
+	}
+
+	if point.Cycles, err = mach.Expect_run(d.Sbox, d.Max_ticks); err != nil {
+		point.Error = err.Error()
+		return point
+	}
+
+	report := mach.Estimate_resources(d.Space.Flavor)
+	point.Fmax_mhz = report.Fmax_mhz
+	point.Luts = report.Total.Luts
+	point.Ffs = report.Total.Ffs
+	point.Brams = report.Total.Brams
+	point.Dsps = report.Total.Dsps
+	point.Feasible = true
+	return point
+}
+
+// The architecture of a space point, the auto opcode set leaves the opcodes empty
+func (d *Dse) point_arch(rsize int, r int, l int, o int, opcodes string) (*Arch, error) {
+	arch := new(Arch)
+	arch.Modes = []string{"ha"}
+	arch.Rsize = uint8(rsize)
+	arch.R = uint8(r)
+	arch.L = uint8(l)
+	arch.O = uint8(o)
+	if opcodes != "auto" {
+		ops, err := Opcodes_by_name(strings.Split(opcodes, ","))
+		if err != nil {
+			return nil, err
+		}
+		arch.Op = ops
+	}
+	return arch, nil
+}
+
+// Exhaustive evaluates every point of the space
+func (d *Dse) Exhaustive() error {
+	for _, rsize := range d.Space.Rsize {
+		for _, r := range d.Space.R {
+			for _, l := range d.Space.L {
+				for _, o := range d.Space.O {
+					for _, opcodes := range d.Space.Opcodes {
+						arch, err := d.point_arch(rsize, r, l, o, opcodes)
+						if err != nil {
+							return err
+						}
+						d.Evaluate(arch)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// The evolution parameters listing the values of the space, for the architecture evolution hooks
+func (d *Dse) Evolution_parameters() *mel.Evolution_parameters {
+	ep := new(mel.Evolution_parameters)
+	ep.Pars = make(map[string]string)
+	join := func(values []int) string {
+		result := make([]string, len(values))
+		for i, v := range values {
+			result[i] = strconv.Itoa(v)
+		}
+		return strings.Join(result, ",")
+	}
+	ep.Pars["dse:rsize"] = join(d.Space.Rsize)
+	ep.Pars["dse:r"] = join(d.Space.R)
+	ep.Pars["dse:l"] = join(d.Space.L)
+	ep.Pars["dse:o"] = join(d.Space.O)
+	ep.Pars["dse:opcodes"] = strings.Join(d.Space.Opcodes, ";")
+	ep.Pars["procbuilder:n"] = strconv.Itoa(int(d.Space.N))
+	ep.Pars["procbuilder:m"] = strconv.Itoa(int(d.Space.M))
+	return ep
+}
+
+// Evolutionary searches the space with the architecture evolution hooks: every generation the parents are taken
+// from the current Pareto front and the children are made by crossover and mutation
+func (d *Dse) Evolutionary(population int, generations int) {
+	ep := d.Evolution_parameters()
+
+	for i := 0; i < population; i++ {
+		d.Evaluate(&Machine_Arch_Generate(ep).(*Machine).Arch)
+	}
+
+	for g := 0; g < generations; g++ {
+		parents := Pareto_front(d.Points)
+		if len(parents) < 2 {
+			// Not enough good points yet, keep exploring
+			for i := 0; i < population; i++ {
+				d.Evaluate(&Machine_Arch_Generate(ep).(*Machine).Arch)
+			}
+			continue
+		}
+
+		archs := make([]*Machine, 0)
+		for i := 0; i < population; i++ {
+			p := parents[rand.Intn(len(parents))]
+			q := parents[rand.Intn(len(parents))]
+			child := Machine_Arch_Crossover(d.point_machine(p), d.point_machine(q), ep)
+			child = Machine_Arch_Mutate(child, ep)
+			archs = append(archs, child.(*Machine))
+		}
+		for _, mach := range archs {
+			d.Evaluate(&mach.Arch)
+		}
+	}
+}
+
+func (d *Dse) point_machine(p *Dse_point) *Machine {
+	mach := new(Machine)
+	if arch, err := d.point_arch(p.Rsize, p.R, p.L, p.O, p.Opcodes); err == nil {
+		mach.Arch = *arch
+	}
+	mach.N = d.Space.N
+	mach.M = d.Space.M
+	return mach
+}
@@ -0,0 +1,61 @@
+package procbuilder
+
+import (
+	"fmt"
+	"simbox"
+	"testing"
+)
+
+func TestDse(t *testing.T) {
+	asm := []byte("i2r r0 i0\ninc r0\ninc r0\nr2o r0 o0\n")
+
+	dse := new(Dse)
+	dse.Space = Dse_space{Rsize: []int{8, 16}, R: []int{1, 2}, L: []int{0}, O: []int{1, 3}, Opcodes: []string{"auto", "i2r,inc,r2o,add"}, N: 1, M: 1, Flavor: "basys3"}
+	dse.Compile = func(arch *Arch) ([]byte, error) { return asm, nil }
+	dse.Sbox = &simbox.Simbox{Rules: []simbox.Rule{{Timec: simbox.TIMEC_ABS, Tick: 0, Action: simbox.ACTION_SET, Object: "i0", Extra: "5"}, {Timec: simbox.TIMEC_ABS, Tick: 10, Action: simbox.ACTION_SET, Object: "o0", Extra: "7"}}}
+	dse.Max_ticks = 100
+
+	if err := dse.Exhaustive(); err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(Dse_csv(dse.Points))
+
+	if len(dse.Points) != 16 {
+		t.Error("Wrong number of points", len(dse.Points))
+	}
+	for _, p := range dse.Points {
+		if p.O == 1 && p.Feasible {
+			t.Error("A 4 instructions program does not fit a 2 cells ROM")
+		}
+	}
+
+	front := Pareto_front(dse.Points)
+	if len(front) != 1 || front[0].Key() != "8/1/0/3/auto" {
+		t.Error("Wrong Pareto front", front)
+	} else if front[0].Cycles != 4 {
+		t.Error("Wrong cycles", front[0].Cycles)
+	}
+
+	wrong := new(Dse)
+	wrong.Space = dse.Space
+	wrong.Compile = dse.Compile
+	wrong.Sbox = &simbox.Simbox{Rules: []simbox.Rule{{Timec: simbox.TIMEC_ABS, Tick: 0, Action: simbox.ACTION_SET, Object: "i0", Extra: "5"}, {Timec: simbox.TIMEC_ABS, Tick: 10, Action: simbox.ACTION_SET, Object: "o0", Extra: "8"}}}
+	wrong.Max_ticks = 100
+	arch, _ := wrong.point_arch(8, 1, 0, 3, "auto")
+	if p := wrong.Evaluate(arch); p.Feasible {
+		t.Error("Wrong output not detected")
+	} else {
+		fmt.Println(p.Error)
+	}
+
+	evo := new(Dse)
+	evo.Space = dse.Space
+	evo.Compile = dse.Compile
+	evo.Max_ticks = 100
+	evo.Evolutionary(4, 3)
+	for _, p := range evo.Points {
+		if p.Opcodes != "auto" && p.Opcodes != "add,i2r,inc,r2o" {
+			t.Error("Evolution out of the space", p.Key())
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package procbuilder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The vectored input events. The eve opcode enables the event of an input and sets its handler, evd disables it and
+// evr returns from the handler. A rising edge of the valid of an enabled input makes its event pending; when the
+// processor is not serving another event the pending one of the lowest input is dispatched at the next instruction
+// boundary, in place of the instruction: the program counter is saved and the handler starts, the instruction is
+// executed when the handler returns. The instructions taking more than one cycle in the timing model are never
+// interrupted, also while they wait, so their handshakes are not left half done. Events do not nest, the ones
+// arriving during a handler stay pending. The VM has no valid signals, a new value of the input stands for the valid
+// edge.
+
+const (
+	STALL_EVENT = "event" // Event dispatch
+)
+
+// Event_opcodes are the opcodes using the events, any of them in a processor generates the mechanism
+var Event_opcodes = []string{"eve", "evd", "evr"}
+
+type Event_state struct {
+	Enabled []bool
+	Handler []uint64
+	Pending []bool
+	Active  bool          // A handler is running
+	Return  uint64        // The program location to resume
+	Started bool          // A multi-cycle instruction is in progress, the events wait for its end
+	last    []interface{} // The inputs at the previous step
+}
+
+// Has_events tells if the processor has the event mechanism
+func (proc *Conproc) Has_events() bool {
+	if proc.N == 0 {
+		return false
+	}
+	for _, op := range proc.Op {
+		for _, name := range Event_opcodes {
+			if op.Op_get_name() == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (vm *VM) init_events() {
+	vm.Events = nil
+	if !vm.Mach.Has_events() {
+		return
+	}
+	n := int(vm.Mach.N)
+	vm.Events = &Event_state{make([]bool, n), make([]uint64, n), make([]bool, n), false, 0, false, make([]interface{}, n)}
+	copy(vm.Events.last, vm.Inputs)
+}
+
+func (es *Event_state) CopyState(essource *Event_state) {
+	copy(es.Enabled, essource.Enabled)
+	copy(es.Handler, essource.Handler)
+	copy(es.Pending, essource.Pending)
+	es.Active = essource.Active
+	es.Return = essource.Return
+	es.Started = essource.Started
+	copy(es.last, essource.last)
+}
+
+// event_dispatch samples the inputs and, at an instruction boundary, starts the handler of a pending event. It returns
+// the input served or -1. As in the RTL the dispatch uses the events pending before the sampling.
+func (vm *VM) event_dispatch(boundary bool) int {
+	es := vm.Events
+	served := -1
+	if boundary && !es.Active {
+		for i, pending := range es.Pending {
+			if pending {
+				served = i
+				break
+			}
+		}
+	}
+	for i, inp := range vm.Inputs {
+		if es.Enabled[i] && inp != es.last[i] {
+			es.Pending[i] = true
+		}
+		es.last[i] = inp
+	}
+	if served != -1 {
+		es.Pending[served] = false
+		es.Active = true
+		es.Return = vm.Pc
+		vm.Pc = es.Handler[served]
+	}
+	return served
+}
+
+// Event_multicycle tells if an opcode takes more than one cycle, the events are not dispatched until it is done
+func Event_multicycle(arch *Arch, op Opcode) bool {
+	cycles, _ := op.Op_instruction_latency(arch)
+	return cycles > 1
+}
+
+// Event_enable sets the handler of an input and enables its event, the edges before are not recorded
+func (vm *VM) Event_enable(inp int, handler uint64) {
+	vm.Events.Enabled[inp] = true
+	vm.Events.Handler[inp] = handler
+}
+
+// Event_disable disables the event of an input, dropping it if pending
+func (vm *VM) Event_disable(inp int) {
+	vm.Events.Enabled[inp] = false
+	vm.Events.Pending[inp] = false
+}
+
+// Event_return resumes the program interrupted by the handler, outside a handler it does nothing
+func (vm *VM) Event_return() {
+	if vm.Events.Active {
+		vm.Events.Active = false
+		vm.Pc = vm.Events.Return
+	} else {
+		vm.Pc = vm.Pc + 1
+	}
+}
+
+// The registers of the events
+func (proc *Conproc) events_verilog_header(arch *Arch) string {
+	n := strconv.Itoa(int(proc.N) - 1)
+	o := strconv.Itoa(int(arch.O) - 1)
+	valids := make([]string, 0)
+	for i := int(proc.N) - 1; i >= 0; i-- {
+		valids = append(valids, strings.ToLower(Get_input_name(i))+"_valid")
+	}
+
+	result := "\n"
+	result += "	// Vectored input events\n"
+	result += "	reg [" + n + ":0] ev_enabled;\n"
+	result += "	reg [" + n + ":0] ev_pending;\n"
+	result += "	reg [" + n + ":0] ev_valid_prev;\n"
+	result += "	reg ev_active;\n"
+	result += "	reg [" + o + ":0] ev_ret;\n"
+	result += "	reg ev_busy;\n"
+	result += "	reg [" + o + ":0] ev_pc_prev;\n"
+	for i := 0; i < int(proc.N); i++ {
+		result += "	reg [" + o + ":0] ev_handler_" + strings.ToLower(Get_input_name(i)) + ";\n"
+	}
+	result += "	wire [" + n + ":0] ev_valids = {" + strings.Join(valids, ", ") + "};\n"
+	result += "	wire [" + n + ":0] ev_edges = ev_valids & ~ev_valid_prev & ev_enabled;\n"
+
+	// The opcodes taking more than one cycle, a boundary is when none of them is in progress or the program counter moved
+	rom_word := arch.Max_word()
+	opbits := arch.Opcodes_bits()
+	opfield := "rom_value[" + strconv.Itoa(rom_word-1) + "]"
+	if opbits > 1 {
+		opfield = "rom_value[" + strconv.Itoa(rom_word-1) + ":" + strconv.Itoa(rom_word-opbits) + "]"
+	}
+	multicycle := make([]string, 0)
+	for _, op := range proc.Op {
+		if Event_multicycle(arch, op) {
+			multicycle = append(multicycle, "("+opfield+" == "+strings.ToUpper(op.Op_get_name())+")")
+		}
+	}
+	if len(multicycle) == 0 {
+		multicycle = append(multicycle, "1'b0")
+	}
+	result += "	wire ev_multicycle = " + strings.Join(multicycle, " || ") + ";\n"
+	result += "	wire ev_boundary = !ev_busy || _pc != ev_pc_prev;\n"
+	return result
+}
+
+func (proc *Conproc) events_verilog_reset(arch *Arch) string {
+	result := ""
+	result += "			ev_enabled <= #1 'b0;\n"
+	result += "			ev_pending <= #1 'b0;\n"
+	result += "			ev_valid_prev <= #1 'b0;\n"
+	result += "			ev_active <= #1 1'b0;\n"
+	result += "			ev_ret <= #1 'b0;\n"
+	result += "			ev_busy <= #1 1'b0;\n"
+	result += "			ev_pc_prev <= #1 'b0;\n"
+	for i := 0; i < int(proc.N); i++ {
+		result += "			ev_handler_" + strings.ToLower(Get_input_name(i)) + " <= #1 'b0;\n"
+	}
+	return result
+}
+
+// The edges sampling and the dispatch at the instruction boundaries, the caller closes the block opened by the last
+// else around the opcodes state machine
+func (proc *Conproc) events_verilog_dispatch(arch *Arch) string {
+	result := ""
+	result += "				ev_valid_prev <= #1 ev_valids;\n"
+	result += "				ev_pending <= #1 ev_pending | ev_edges;\n"
+	result += "				ev_pc_prev <= #1 _pc;\n"
+	for i := 0; i < int(proc.N); i++ {
+		inp := strings.ToLower(Get_input_name(i))
+		if i == 0 {
+			result += "				if (ev_boundary && !ev_active && ev_pending[" + strconv.Itoa(i) + "])\n"
+		} else {
+			result += "				else if (ev_boundary && !ev_active && ev_pending[" + strconv.Itoa(i) + "])\n"
+		}
+		result += "				begin\n"
+		result += "					ev_pending[" + strconv.Itoa(i) + "] <= #1 1'b0;\n"
+		result += "					ev_active <= #1 1'b1;\n"
+		result += "					ev_ret <= #1 _pc;\n"
+		result += "					_pc <= #1 ev_handler_" + inp + ";\n"
+		result += "					$display(\"EVENT " + strings.ToUpper(inp) + "\");\n"
+		result += "				end\n"
+	}
+	result += "				else\n"
+	result += "				begin\n"
+	result += "				ev_busy <= #1 ev_multicycle;\n"
+	return result
+}
@@ -2,6 +2,7 @@ package procbuilder
 
 import (
 	//"fmt"
+	"math/rand"
 	"mel"
 	"sort"
 	"strconv"
@@ -121,3 +122,77 @@ func Machine_Program_Crossover(p mel.Me3li, q mel.Me3li, ep *mel.Evolution_param
 	var result mel.Me3li
 	return result
 }
+
+// These 3 handle the architecture evolution used by the design-space exploration, the programs are not evolved.
+// The values are picked from the lists in the dse:rsize, dse:r, dse:l and dse:o parameters (comma separated) and
+// dse:opcodes (semicolon separated opcode sets, auto for the opcodes used by the program)
+
+func evolution_choices(ep *mel.Evolution_parameters, name string, sep string) []string {
+	if value, ok := ep.Get_value(name); ok && value != "" {
+		return strings.Split(value, sep)
+	}
+	return []string{}
+}
+
+func arch_gene(mach *Machine, ep *mel.Evolution_parameters, gene int) {
+	names := []string{"dse:rsize", "dse:r", "dse:l", "dse:o"}
+	fields := []*uint8{&mach.Rsize, &mach.R, &mach.L, &mach.O}
+	if gene < len(names) {
+		if choices := evolution_choices(ep, names[gene], ","); len(choices) > 0 {
+			valuei, _ := strconv.Atoi(choices[rand.Intn(len(choices))])
+			*fields[gene] = uint8(valuei)
+		}
+		return
+	}
+	if choices := evolution_choices(ep, "dse:opcodes", ";"); len(choices) > 0 {
+		choice := choices[rand.Intn(len(choices))]
+		mach.Op = make([]Opcode, 0)
+		if choice != "auto" {
+			for _, opname := range strings.Split(choice, ",") {
+				for _, op := range Allopcodes {
+					if op.Op_get_name() == opname {
+						mach.Op = append(mach.Op, op)
+					}
+				}
+			}
+			sort.Sort(ByName(mach.Op))
+		}
+	}
+}
+
+func Machine_Arch_Generate(ep *mel.Evolution_parameters) mel.Me3li {
+	eobj := new(Machine)
+	eobj.Mel_init(ep)
+	for gene := 0; gene < 5; gene++ {
+		arch_gene(eobj, ep, gene)
+	}
+	return eobj
+}
+
+func Machine_Arch_Mutate(p mel.Me3li, ep *mel.Evolution_parameters) mel.Me3li {
+	eobj := p.Mel_copy().(*Machine)
+	arch_gene(eobj, ep, rand.Intn(5))
+	return eobj
+}
+
+func Machine_Arch_Crossover(p mel.Me3li, q mel.Me3li, ep *mel.Evolution_parameters) mel.Me3li {
+	eobj := p.Mel_copy().(*Machine)
+	other := q.(*Machine)
+	if rand.Intn(2) == 0 {
+		eobj.Rsize = other.Rsize
+	}
+	if rand.Intn(2) == 0 {
+		eobj.R = other.R
+	}
+	if rand.Intn(2) == 0 {
+		eobj.L = other.L
+	}
+	if rand.Intn(2) == 0 {
+		eobj.O = other.O
+	}
+	if rand.Intn(2) == 0 {
+		eobj.Op = make([]Opcode, len(other.Op))
+		copy(eobj.Op, other.Op)
+	}
+	return eobj
+}
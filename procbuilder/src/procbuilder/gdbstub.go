@@ -0,0 +1,284 @@
+package procbuilder
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// GDB remote serial protocol. The processors are the threads (thread n is the processor n-1), the registers are the
+// machine registers followed by a 32 bits program counter, all little endian, and the memory is the RAM of the
+// selected processor with Rsize/8 bytes per cell. Breakpoints (Z0), RAM write watchpoints (Z2) and the reverse
+// execution (bs, bc) are supported.
+
+// Serve_gdb waits for a GDB connection on localhost and serves it until it is closed
+func (d *Debugger) Serve_gdb(port int) error {
+	listener, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(port))
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+	fmt.Println("Waiting for GDB on 127.0.0.1:" + strconv.Itoa(port))
+	conn, err := listener.Accept()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return d.Gdb_session(conn)
+}
+
+func gdb_packet(data string) string {
+	sum := 0
+	for i := 0; i < len(data); i++ {
+		sum += int(data[i])
+	}
+	return fmt.Sprintf("$%s#%02x", data, sum&0xff)
+}
+
+// The bytes of a cell, little endian
+func (d *Debugger) gdb_word(value interface{}, width int) string {
+	v := word_value(value)
+	result := ""
+	for i := 0; i < width; i++ {
+		result += fmt.Sprintf("%02x", (v>>(8*uint(i)))&0xff)
+	}
+	return result
+}
+
+func (d *Debugger) cell_bytes() int {
+	vm := d.Target.Processors()[d.Current]
+	return (int(vm.Mach.Rsize) + 7) / 8
+}
+
+func (d *Debugger) gdb_registers() string {
+	vm := d.Target.Processors()[d.Current]
+	result := ""
+	for _, reg := range vm.Registers {
+		result += d.gdb_word(reg, d.cell_bytes())
+	}
+	return result + d.gdb_word(int(vm.Pc), 4)
+}
+
+func (d *Debugger) gdb_register(n int) string {
+	vm := d.Target.Processors()[d.Current]
+	if n < len(vm.Registers) {
+		return d.gdb_word(vm.Registers[n], d.cell_bytes())
+	} else if n == len(vm.Registers) {
+		return d.gdb_word(int(vm.Pc), 4)
+	}
+	return "E01"
+}
+
+func (d *Debugger) gdb_memory(args string) string {
+	parts := strings.Split(args, ",")
+	if len(parts) != 2 {
+		return "E01"
+	}
+	addr, err1 := strconv.ParseUint(parts[0], 16, 32)
+	length, err2 := strconv.ParseUint(parts[1], 16, 32)
+	if err1 != nil || err2 != nil {
+		return "E01"
+	}
+	memory := d.Target.Processors()[d.Current].Memory
+	cell := d.cell_bytes()
+	result := ""
+	for a := int(addr); a < int(addr+length); a++ {
+		if a/cell >= len(memory) {
+			break
+		}
+		result += d.gdb_word(word_value(memory[a/cell])>>(8*uint(a%cell)), 1)
+	}
+	if result == "" {
+		return "E01"
+	}
+	return result
+}
+
+// The stop reply, for the watchpoints the RAM address is reported
+func (d *Debugger) gdb_stop(reason string) string {
+	if d.Halted() {
+		return "W00"
+	}
+	if strings.HasPrefix(reason, "Watchpoint p") {
+		name := strings.Fields(reason)[1]
+		name = strings.TrimSuffix(name, ":")
+		if i := strings.Index(name, "m"); i > 0 {
+			if cell, err := strconv.Atoi(name[i+1:]); err == nil {
+				return fmt.Sprintf("T05watch:%x;thread:%x;", cell*d.cell_bytes(), d.Current+1)
+			}
+		}
+	}
+	if reason == "Beginning of the history" {
+		return "T05replaylog:begin;"
+	}
+	return fmt.Sprintf("T05thread:%x;", d.Current+1)
+}
+
+// A breakpoint or a watchpoint insertion or removal, z is the packet without the leading Z or z
+func (d *Debugger) gdb_point(insert bool, z string) string {
+	parts := strings.Split(z, ",")
+	if len(parts) < 2 {
+		return "E01"
+	}
+	addr, err := strconv.ParseUint(parts[1], 16, 32)
+	if err != nil {
+		return "E01"
+	}
+	switch parts[0] {
+	case "0", "1":
+		for i, bp := range d.Breakpoints {
+			if bp.Pc == int(addr) && bp.Processor == -1 {
+				if !insert {
+					d.Breakpoints = append(d.Breakpoints[:i], d.Breakpoints[i+1:]...)
+				}
+				return "OK"
+			}
+		}
+		if insert {
+			d.Add_breakpoint(&Breakpoint{Processor: -1, Pc: int(addr)})
+		}
+		return "OK"
+	case "2":
+		name := "p" + strconv.Itoa(d.Current) + "m" + strconv.Itoa(int(addr)/d.cell_bytes())
+		for i, wp := range d.Watchpoints {
+			if wp.Name == name {
+				if !insert {
+					d.Watchpoints = append(d.Watchpoints[:i], d.Watchpoints[i+1:]...)
+				}
+				return "OK"
+			}
+		}
+		if insert {
+			if err := d.Add_watchpoint(name); err != nil {
+				return "E01"
+			}
+		}
+		return "OK"
+	}
+	return ""
+}
+
+func (d *Debugger) gdb_thread(id string) string {
+	if id == "0" || id == "-1" {
+		return "OK"
+	}
+	n, err := strconv.ParseInt(id, 16, 32)
+	if err != nil || n < 1 || int(n) > len(d.Target.Processors()) {
+		return "E01"
+	}
+	d.Current = int(n) - 1
+	return "OK"
+}
+
+// gdb_command returns the reply to a packet and false if the session is over
+func (d *Debugger) gdb_command(packet string) (string, bool) {
+	switch {
+	case packet == "?":
+		return d.gdb_stop(""), true
+	case strings.HasPrefix(packet, "qSupported"):
+		return "PacketSize=4000;ReverseStep+;ReverseContinue+", true
+	case packet == "qAttached":
+		return "1", true
+	case packet == "qC":
+		return fmt.Sprintf("QC%x", d.Current+1), true
+	case packet == "qfThreadInfo":
+		ids := make([]string, 0)
+		for i := range d.Target.Processors() {
+			ids = append(ids, fmt.Sprintf("%x", i+1))
+		}
+		return "m" + strings.Join(ids, ","), true
+	case packet == "qsThreadInfo":
+		return "l", true
+	case strings.HasPrefix(packet, "H"):
+		if len(packet) < 2 {
+			return "E01", true
+		}
+		return d.gdb_thread(packet[2:]), true
+	case strings.HasPrefix(packet, "T"):
+		if n, err := strconv.ParseInt(packet[1:], 16, 32); err == nil && n >= 1 && int(n) <= len(d.Target.Processors()) {
+			return "OK", true
+		}
+		return "E01", true
+	case packet == "g":
+		return d.gdb_registers(), true
+	case strings.HasPrefix(packet, "p"):
+		n, err := strconv.ParseInt(packet[1:], 16, 32)
+		if err != nil {
+			return "E01", true
+		}
+		return d.gdb_register(int(n)), true
+	case strings.HasPrefix(packet, "m"):
+		return d.gdb_memory(packet[1:]), true
+	case packet == "c" || strings.HasPrefix(packet, "vCont;c"):
+		reason, err := d.Continue(DEBUG_MAX_TICKS)
+		if err != nil {
+			return "E01", true
+		}
+		return d.gdb_stop(reason), true
+	case packet == "s" || strings.HasPrefix(packet, "vCont;s"):
+		reason, err := d.Step_processor(d.Current)
+		if err != nil {
+			return "E01", true
+		}
+		return d.gdb_stop(reason), true
+	case packet == "vCont?":
+		return "vCont;c;s", true
+	case packet == "bs":
+		if d.Reverse(1) == 0 {
+			return "T05replaylog:begin;", true
+		}
+		return d.gdb_stop(""), true
+	case packet == "bc":
+		return d.gdb_stop(d.Reverse_continue()), true
+	case strings.HasPrefix(packet, "Z"):
+		return d.gdb_point(true, packet[1:]), true
+	case strings.HasPrefix(packet, "z"):
+		return d.gdb_point(false, packet[1:]), true
+	case packet == "D":
+		return "OK", false
+	case packet == "k":
+		return "", false
+	}
+	return "", true
+}
+
+// Gdb_session serves a GDB remote serial protocol session
+func (d *Debugger) Gdb_session(rw io.ReadWriter) error {
+	reader := bufio.NewReader(rw)
+	for {
+		ch, err := reader.ReadByte()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		switch ch {
+		case '$':
+			data, err := reader.ReadString('#')
+			if err != nil {
+				return err
+			}
+			if _, err := io.ReadFull(reader, make([]byte, 2)); err != nil {
+				return err
+			}
+			if _, err := io.WriteString(rw, "+"); err != nil {
+				return err
+			}
+			reply, cont := d.gdb_command(strings.TrimSuffix(data, "#"))
+			if _, err := io.WriteString(rw, gdb_packet(reply)); err != nil {
+				return err
+			}
+			if !cont {
+				return nil
+			}
+		case 0x03:
+			// The interrupt arrives only between the commands, the target is already stopped
+			if _, err := io.WriteString(rw, gdb_packet(d.gdb_stop(""))); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -30,71 +30,26 @@ var Allshared []Sharedel
 func init() {
 	rand.Seed(int64(time.Now().Unix()))
 
-	// Keep in mind the lists og opcodes has to be kept ordered by name
+	// The built in opcodes and shared objects, see registry.go
 	Allopcodes = make([]Opcode, 0)
-
-	Allopcodes = append(Allopcodes, Adc{})
-	Allopcodes = append(Allopcodes, Add{})
-	Allopcodes = append(Allopcodes, Addf{})
-	Allopcodes = append(Allopcodes, Addi{})
-	Allopcodes = append(Allopcodes, And{})
-	Allopcodes = append(Allopcodes, Cil{})
-	Allopcodes = append(Allopcodes, Cilc{})
-	Allopcodes = append(Allopcodes, Cir{})
-	Allopcodes = append(Allopcodes, Cirn{})
-	Allopcodes = append(Allopcodes, Chc{})
-	Allopcodes = append(Allopcodes, Chw{})
-	Allopcodes = append(Allopcodes, Clc{})
-	Allopcodes = append(Allopcodes, Clr{})
-	Allopcodes = append(Allopcodes, Cpy{})
-	Allopcodes = append(Allopcodes, Cset{})
-	Allopcodes = append(Allopcodes, Dec{})
-	Allopcodes = append(Allopcodes, Div{})
-	Allopcodes = append(Allopcodes, Divf{})
-	Allopcodes = append(Allopcodes, Dpc{})
-	Allopcodes = append(Allopcodes, Hit{})
-	Allopcodes = append(Allopcodes, Hlt{})
-	Allopcodes = append(Allopcodes, I2r{})
-	Allopcodes = append(Allopcodes, I2rw{})
-	Allopcodes = append(Allopcodes, Incc{})
-	Allopcodes = append(Allopcodes, Inc{})
-	Allopcodes = append(Allopcodes, Jc{})
-	Allopcodes = append(Allopcodes, Je{})
-	Allopcodes = append(Allopcodes, Jz{})
-	Allopcodes = append(Allopcodes, J{})
-	Allopcodes = append(Allopcodes, Lfsr82r{})
-	Allopcodes = append(Allopcodes, M2r{})
-	Allopcodes = append(Allopcodes, Mod{})
-	Allopcodes = append(Allopcodes, Mulc{})
-	Allopcodes = append(Allopcodes, Mult{})
-	Allopcodes = append(Allopcodes, Multf{})
-	Allopcodes = append(Allopcodes, Nand{})
-	Allopcodes = append(Allopcodes, Nop{})
-	Allopcodes = append(Allopcodes, Nor{})
-	Allopcodes = append(Allopcodes, Not{})
-	Allopcodes = append(Allopcodes, Or{})
-	Allopcodes = append(Allopcodes, R2m{})
-	Allopcodes = append(Allopcodes, R2o{})
-	Allopcodes = append(Allopcodes, R2owa{})
-	Allopcodes = append(Allopcodes, R2owaa{})
-	Allopcodes = append(Allopcodes, R2s{})
-	Allopcodes = append(Allopcodes, Rsc{})
-	Allopcodes = append(Allopcodes, Rset{})
-	Allopcodes = append(Allopcodes, Sic{})
-	Allopcodes = append(Allopcodes, S2r{})
-	Allopcodes = append(Allopcodes, Saj{})
-	Allopcodes = append(Allopcodes, Sbc{})
-	Allopcodes = append(Allopcodes, Sub{})
-	Allopcodes = append(Allopcodes, Wrd{})
-	Allopcodes = append(Allopcodes, Wwr{})
-	Allopcodes = append(Allopcodes, Xnor{})
-	Allopcodes = append(Allopcodes, Xor{})
+	for _, op := range []Opcode{
+		Adc{}, Add{}, Addf{}, Addi{}, And{}, Chc{}, Chclose{}, Chw{}, Cil{}, Cilc{}, Cir{}, Cirn{}, Clc{}, Clr{},
+		Cpy{}, Cset{}, Dec{}, Div{}, Divf{}, Dpc{}, Evd{}, Eve{}, Evr{}, Fadd{}, Fswp{}, Hit{}, Hlt{}, I2r{}, I2rw{},
+		Inc{}, Incc{}, J{}, Jc{}, Je{}, Jz{}, Lfsr82r{}, Lfsrn2r{}, Lock{}, M2r{}, Mod{}, Mulc{}, Mult{}, Multf{},
+		Nand{}, Nop{}, Nor{}, Not{}, Or{}, R2m{}, R2o{}, R2owa{}, R2owaa{}, R2s{}, Rsc{}, Rset{}, S2r{}, Saj{}, Sbc{},
+		Semacq{}, Semrel{}, Sic{}, Sub{}, Tma{}, Tmp{}, Tmr{}, Tmw{}, Unlock{}, Wrd{}, Wwr{}, Xnor{}, Xor{},
+	} {
+		if err := Register_opcode(op); err != nil {
+			panic(err)
+		}
+	}
 
 	Allshared = make([]Sharedel, 0)
-	Allshared = append(Allshared, Sharedmem{})
-	Allshared = append(Allshared, Channel{})
-	Allshared = append(Allshared, Barrier{})
-	Allshared = append(Allshared, Lfsr8{})
+	for _, so := range []Sharedel{Sharedmem{}, Channel{}, Barrier{}, Lfsr8{}, Mutex{}, Semaphore{}, Counter{}, Lfsrn{}} {
+		if err := Register_shared(so); err != nil {
+			panic(err)
+		}
+	}
 }
 
 func (mach *Machine) String() string {
@@ -224,9 +179,10 @@ func (mach *Machine) Constraint_check() (string, bool) {
 	// TODO Finish
 	for _, mode := range required {
 		switch mode {
-		case "ha":
-		case "vn":
-		case "hy":
+		case "ha", "vn", "hy":
+			if mach.Execution_model() != mode {
+				return "Execution model " + mode + " required", false
+			}
 		case "ramabs":
 		case "romabs":
 		case "ramind":
@@ -240,6 +196,18 @@ func (mach *Machine) Constraint_check() (string, bool) {
 		}
 	}
 
+	// Checking the execution model
+	switch mach.Execution_model() {
+	case "vn":
+		if mach.L != mach.O {
+			return "The Von Neumann model needs the same RAM and ROM address width", false
+		}
+	case "hy":
+		if mach.L == 0 || mach.O == 0 {
+			return "The Hybrid model needs both RAM and ROM", false
+		}
+	}
+
 	// Checking conflitting modes
 	for _, mode := range forbidden {
 		for _, req := range required {
@@ -138,14 +138,7 @@ func (op Adc) Simulate(vm *VM, instr string) error {
 	reg_bits := vm.Mach.R
 	regdest := get_id(instr[:reg_bits])
 	regsrc := get_id(instr[reg_bits : reg_bits*2])
-	switch vm.Mach.Rsize {
-	case 8:
-		vm.Registers[regdest] = vm.Registers[regdest].(uint8) + vm.Registers[regsrc].(uint8)
-	case 16:
-		vm.Registers[regdest] = vm.Registers[regdest].(uint16) + vm.Registers[regsrc].(uint16)
-	default:
-		// TODO Fix
-	}
+	vm.carry_result(regdest, word_value(vm.Registers[regdest])+word_value(vm.Registers[regsrc]))
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -218,3 +211,12 @@ func (Op Adc) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Adc) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// Adder with carry in and carry out
+	return Regfile_cost(arch, 2, true).Chain(Adder_cost(arch)).Chain(Resources{Luts: 1, Ffs: 1})
+}
+
+func (op Adc) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
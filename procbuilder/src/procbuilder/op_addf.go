@@ -492,3 +492,12 @@ func (Op Addf) Op_instruction_verilog_extra_block(arch *Arch, flavor string, lev
 	}
 	return result
 }
+
+func (op Addf) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 2, true).Chain(Float_cost(arch, flavor, "add"))
+}
+
+func (op Addf) Op_instruction_latency(arch *Arch) (int, string) {
+	// The FPU state machine on normalized operands, operands load and result handshake included
+	return 14, STALL_FPU
+}
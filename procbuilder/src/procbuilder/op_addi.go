@@ -186,3 +186,12 @@ func (Op Addi) Op_instruction_verilog_extra_block(arch *Arch, flavor string, lev
 	}
 	return result
 }
+
+func (op Addi) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// Adder tree on all the inputs
+	return Regfile_cost(arch, 0, true).Chain(Resources{Luts: int(arch.Rsize) * (int(arch.N) - 1), Levels: (1 + int(arch.Rsize)/16) * mux_levels(int(arch.N)) * 2})
+}
+
+func (op Addi) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -139,9 +139,10 @@ func (op Chc) Op_instruction_verilog_state_machine(arch *Arch, flavor string) st
 	}
 	result += "\t						endcase\n"
 	result += "\t						case (rom_value[" + strconv.Itoa(rom_word-opbits-int(arch.R)-1) + ":" + strconv.Itoa(rom_word-opbits-int(arch.R)-int(arch.R)) + "])\n"
+	// A read completed by a closed channel is reported past the queued operations
 	for i := 0; i < reg_num; i++ {
 		result += "\t							" + strings.ToUpper(Get_register_name(i)) + " : begin\n"
-		result += "\t								_" + strings.ToLower(Get_register_name(i)) + " <= #1 stat_op_int;\n" //CHECK
+		result += "\t								_" + strings.ToLower(Get_register_name(i)) + " <= #1 (wrd_ch_ok & ch_closed_i[ch_num_ack]) ? stat_op_int + count_seq_ch : stat_op_int;\n" //CHECK
 		result += "\t								$display(\"CHC " + strings.ToUpper(Get_register_name(i)) + " \",_" + strings.ToLower(Get_register_name(i)) + ");\n"
 		result += "\t							end\n"
 	}
@@ -441,9 +442,22 @@ func (op Chc) Disassembler(arch *Arch, instr string) (string, error) {
 	return result, nil
 }
 
-// The simulation does nothing
+// chc tries the queued channel operations once, the first register is 1 if one completed and the second gets its sequence number,
+// plus the number of queued operations if it is a read of a closed channel
 func (op Chc) Simulate(vm *VM, instr string) error {
-	// TODO
+	reg_bits := int(vm.Mach.R)
+	occurred := get_id(instr[:reg_bits])
+	event := get_id(instr[reg_bits : 2*reg_bits])
+	index, done, err := vm.Channel_wait(false)
+	if err != nil {
+		return err
+	}
+	if done {
+		vm.Registers[occurred] = vm.word(1)
+		vm.Registers[event] = vm.word(index)
+	} else {
+		vm.Registers[occurred] = vm.word(0)
+	}
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -488,3 +502,12 @@ func (Op Chc) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Chc) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, true).Chain(Handshake_cost(arch))
+}
+
+func (op Chc) Op_instruction_latency(arch *Arch) (int, string) {
+	// The ready scan over the channels
+	return 4, STALL_CHANNEL
+}
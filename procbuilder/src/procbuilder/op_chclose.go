@@ -0,0 +1,163 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The Chclose opcode pulses the close strobe of a channel SO. Once closed the channel does not accept writes anymore
+// and, when drained, completes every read reporting the close to the chw or chc.
+type Chclose struct{}
+
+func (op Chclose) Op_get_name() string {
+	return "chclose"
+}
+
+func (op Chclose) Op_get_desc() string {
+	return "Close a channel SO"
+}
+
+func (op Chclose) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	chanbits := arch.Shared_bits("channel")
+	result := "chclose [" + strconv.Itoa(chanbits) + "(Channel)]	// Close a channel SO [" + strconv.Itoa(opbits+chanbits) + "]\n"
+	return result
+}
+
+func (op Chclose) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	chanbits := arch.Shared_bits("channel")
+	return opbits + chanbits // The bits for the opcode + bits for the channel id
+}
+
+func (op Chclose) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Chclose) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	result := ""
+	if arch.Shared_num("channel") > 0 {
+		result += "\t\t\tch_close_i <= #1 'b0;\n"
+	}
+	return result
+}
+
+func (Op Chclose) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+// The strobe lasts a single cycle
+func (Op Chclose) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	result := ""
+	if arch.Shared_num("channel") > 0 {
+		result += "\t\t\t\tch_close_i <= #1 'b0;\n"
+	}
+	return result
+}
+
+func (op Chclose) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	chanbits := arch.Shared_bits("channel")
+	ch := rom_field(arch, 0, chanbits)
+
+	result := ""
+	result += "					CHCLOSE: begin\n"
+	if arch.Shared_num("channel") > 0 {
+		result += "						ch_close_i[" + ch + "] <= #1 1'b1;\n"
+		result += "						$display(\"CHCLOSE CH\", " + ch + ");\n"
+	} else {
+		result += "						$display(\"NOP\");\n"
+	}
+	result += "						_pc <= #1 _pc + 1'b1;\n"
+	result += "					end\n"
+	return result
+}
+
+func (op Chclose) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Chclose) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	chso := Channel{}
+	channum := arch.Shared_num(chso.Shr_get_name())
+	chanbits := arch.Shared_bits(chso.Shr_get_name())
+	rom_word := arch.Max_word()
+
+	if len(words) != 1 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	if partial, err := Process_shared(chso.Shortname(), words[0], channum); err == nil {
+		result += zeros_prefix(chanbits, partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	for i := opbits + chanbits; i < rom_word; i++ {
+		result += "0"
+	}
+
+	return result, nil
+}
+
+func (op Chclose) Disassembler(arch *Arch, instr string) (string, error) {
+	chso := Channel{}
+	chanbits := arch.Shared_bits(chso.Shr_get_name())
+	ch_id := get_id(instr[:chanbits])
+	result := chso.Shortname() + strconv.Itoa(ch_id)
+	return result, nil
+}
+
+// Closing a channel already closed does nothing
+func (op Chclose) Simulate(vm *VM, instr string) error {
+	chanbits := vm.Mach.Shared_bits("channel")
+	ch := get_id(instr[:chanbits])
+	if err := vm.Channel_close(ch); err != nil {
+		return err
+	}
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+// The random genaration does nothing
+func (op Chclose) Generate(arch *Arch) string {
+	return ""
+}
+
+func (op Chclose) Required_shared() (bool, []string) {
+	return true, []string{"channel"}
+}
+
+func (op Chclose) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Chclose) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Chclose) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Chclose) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	result := make([]UsageNotify, 0)
+	return result, nil
+}
+
+func (Op Chclose) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Chclose) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Handshake_cost(arch)
+}
+
+func (op Chclose) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
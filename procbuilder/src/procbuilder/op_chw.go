@@ -129,9 +129,10 @@ func (op Chw) Op_instruction_verilog_state_machine(arch *Arch, flavor string) st
 	result += "\t					ch_op_ready_i <= #1 op_channel;\n"
 	result += "\t					if(finish_channel_i[ch_num_ack]) begin\n"
 	result += "\t						case (rom_value[" + strconv.Itoa(rom_word-opbits-1) + ":" + strconv.Itoa(rom_word-opbits-int(arch.R)) + "])\n"
+	// A read completed by a closed channel is reported past the queued operations
 	for i := 0; i < reg_num; i++ {
 		result += "\t							" + strings.ToUpper(Get_register_name(i)) + " : begin\n"
-		result += "\t								_" + strings.ToLower(Get_register_name(i)) + " <= #1 stat_op_int;\n"
+		result += "\t								_" + strings.ToLower(Get_register_name(i)) + " <= #1 (wrd_ch_ok & ch_closed_i[ch_num_ack]) ? stat_op_int + count_seq_ch : stat_op_int;\n"
 		result += "\t								$display(\"CHC " + strings.ToUpper(Get_register_name(i)) + " \",_" + strings.ToLower(Get_register_name(i)) + ");\n"
 		result += "\t						end\n"
 	}
@@ -400,9 +401,19 @@ func (op Chw) Disassembler(arch *Arch, instr string) (string, error) {
 	return result, nil
 }
 
-// The simulation does nothing
+// chw waits until one of the queued channel operations completes, the register gets its sequence number, plus the
+// number of queued operations if it is a read of a closed channel
 func (op Chw) Simulate(vm *VM, instr string) error {
-	// TODO
+	reg := get_id(instr[:vm.Mach.R])
+	index, done, err := vm.Channel_wait(true)
+	if err != nil {
+		return err
+	}
+	if !done {
+		vm.waiting = STALL_CHANNEL
+		return nil
+	}
+	vm.Registers[reg] = vm.word(index)
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -447,3 +458,12 @@ func (Op Chw) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Chw) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Handshake_cost(arch)
+}
+
+func (op Chw) Op_instruction_latency(arch *Arch) (int, string) {
+	// The finish signal and its two delayed copies
+	return 3, STALL_CHANNEL
+}
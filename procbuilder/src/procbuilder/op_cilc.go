@@ -119,14 +119,7 @@ func (op Cilc) Disassembler(arch *Arch, instr string) (string, error) {
 func (op Cilc) Simulate(vm *VM, instr string) error {
 	reg_bits := vm.Mach.R
 	reg := get_id(instr[:reg_bits])
-	switch vm.Mach.Rsize {
-	case 8:
-		vm.Registers[reg] = vm.Registers[reg].(uint8) << 1
-	case 16:
-		vm.Registers[reg] = vm.Registers[reg].(uint16) << 1
-	default:
-		// TODO Fix
-	}
+	vm.carry_result(reg, word_value(vm.Registers[reg])<<1)
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -177,3 +170,11 @@ func (Op Cilc) Op_instruction_verilog_extra_block(arch *Arch, flavor string, lev
 	}
 	return result
 }
+
+func (op Cilc) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, true).Chain(Resources{Luts: 1, Ffs: 1})
+}
+
+func (op Cilc) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -101,16 +101,8 @@ func (op Cir) Disassembler(arch *Arch, instr string) (string, error) {
 // The simulation does nothing
 func (op Cir) Simulate(vm *VM, instr string) error {
 	reg_bits := vm.Mach.R
-	regdest := get_id(instr[:reg_bits])
-	regsrc := get_id(instr[reg_bits : reg_bits*2])
-	switch vm.Mach.Rsize {
-	case 8:
-		vm.Registers[regdest] = vm.Registers[regsrc].(uint8) >> 1
-	case 16:
-		vm.Registers[regdest] = vm.Registers[regsrc].(uint16) >> 1
-	default:
-		// TODO Fix
-	}
+	reg := get_id(instr[:reg_bits])
+	vm.Registers[reg] = vm.word(word_value(vm.Registers[reg]) >> 1)
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -179,3 +171,12 @@ func (Op Cir) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Cir) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// Shifting by one is only wiring
+	return Regfile_cost(arch, 1, true)
+}
+
+func (op Cir) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
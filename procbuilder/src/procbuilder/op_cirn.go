@@ -178,3 +178,11 @@ func (Op Cirn) Op_instruction_verilog_extra_block(arch *Arch, flavor string, lev
 	}
 	return result
 }
+
+func (op Cirn) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, true)
+}
+
+func (op Cirn) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -75,10 +75,7 @@ func (op Clc) Disassembler(arch *Arch, instr string) (string, error) {
 }
 
 func (op Clc) Simulate(vm *VM, instr string) error {
-	//TODO FARE	
-	reg_bits := vm.Mach.R
-	reg := get_id(instr[:reg_bits])
-	vm.Registers[reg] = 0
+	vm.Extra_states["carryflag"] = false
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -121,3 +118,11 @@ func (Op Clc) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Clc) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Resources{Luts: 1, Levels: 1}
+}
+
+func (op Clc) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
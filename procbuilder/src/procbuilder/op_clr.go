@@ -110,7 +110,7 @@ func (op Clr) Disassembler(arch *Arch, instr string) (string, error) {
 func (op Clr) Simulate(vm *VM, instr string) error {
 	reg_bits := vm.Mach.R
 	reg := get_id(instr[:reg_bits])
-	vm.Registers[reg] = 0
+	vm.Registers[reg] = vm.word(0)
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -162,3 +162,11 @@ func (Op Clr) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Clr) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 0, true)
+}
+
+func (op Clr) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -123,9 +123,11 @@ func (op Cpy) Disassembler(arch *Arch, instr string) (string, error) {
 	return result, nil
 }
 
-// The simulation does nothing
 func (op Cpy) Simulate(vm *VM, instr string) error {
-	// TODO
+	reg_bits := vm.Mach.R
+	regdest := get_id(instr[:reg_bits])
+	regsrc := get_id(instr[reg_bits : reg_bits*2])
+	vm.Registers[regdest] = vm.Registers[regsrc]
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -181,3 +183,11 @@ func (Op Cpy) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Cpy) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, true)
+}
+
+func (op Cpy) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -121,3 +121,11 @@ func (Op Cset) Op_instruction_verilog_extra_block(arch *Arch, flavor string, lev
 	}
 	return result
 }
+
+func (op Cset) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Resources{Luts: 1, Levels: 1}
+}
+
+func (op Cset) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -212,3 +212,11 @@ func (Op Div) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Div) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 2, true).Chain(Divider_cost(arch))
+}
+
+func (op Div) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -526,3 +526,12 @@ func (Op Divf) Op_instruction_verilog_extra_block(arch *Arch, flavor string, lev
 	}
 	return result
 }
+
+func (op Divf) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 2, true).Chain(Float_cost(arch, flavor, "div"))
+}
+
+func (op Divf) Op_instruction_latency(arch *Arch) (int, string) {
+	// The mantissas division loop takes most of it
+	return 63, STALL_FPU
+}
@@ -0,0 +1,171 @@
+package procbuilder
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// The Evd opcode disables the event of an input, a pending one is dropped
+type Evd struct{}
+
+func (op Evd) Op_get_name() string {
+	return "evd"
+}
+
+func (op Evd) Op_get_desc() string {
+	return "Disable the event of an input"
+}
+
+func (op Evd) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	inpbits := arch.Inputs_bits()
+	result := "evd [" + strconv.Itoa(inpbits) + "(Input)]	// Disable the event of the input [" + strconv.Itoa(opbits+inpbits) + "]\n"
+	return result
+}
+
+func (op Evd) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	inpbits := arch.Inputs_bits()
+	return opbits + inpbits // The bits for the opcode + bits for the input
+}
+
+func (op Evd) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Evd) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Evd) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Evd) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Evd) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	inpbits := arch.Inputs_bits()
+	inp := rom_field(arch, 0, inpbits)
+
+	result := ""
+	result += "					EVD: begin\n"
+	if arch.Has_events() {
+		result += "						case (" + inp + ")\n"
+		for j := 0; j < int(arch.N); j++ {
+			result += "						" + strings.ToUpper(Get_input_name(j)) + " : begin\n"
+			result += "							ev_enabled[" + strconv.Itoa(j) + "] <= #1 1'b0;\n"
+			result += "							ev_pending[" + strconv.Itoa(j) + "] <= #1 1'b0;\n"
+			result += "							$display(\"EVD " + strings.ToUpper(Get_input_name(j)) + "\");\n"
+			result += "						end\n"
+		}
+		result += "						endcase\n"
+	} else {
+		result += "						$display(\"NOP\");\n"
+	}
+	result += "						_pc <= #1 _pc + 1'b1;\n"
+	result += "					end\n"
+	return result
+}
+
+func (op Evd) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Evd) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	inpbits := arch.Inputs_bits()
+	rom_word := arch.Max_word()
+
+	if len(words) != 1 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	if partial, err := Process_input(words[0], int(arch.N)); err == nil {
+		result += zeros_prefix(inpbits, partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	for i := opbits + inpbits; i < rom_word; i++ {
+		result += "0"
+	}
+
+	return result, nil
+}
+
+func (op Evd) Disassembler(arch *Arch, instr string) (string, error) {
+	inpbits := arch.Inputs_bits()
+	inp_id := get_id(instr[:inpbits])
+	result := strings.ToLower(Get_input_name(inp_id))
+	return result, nil
+}
+
+func (op Evd) Simulate(vm *VM, instr string) error {
+	inpbits := vm.Mach.Inputs_bits()
+	inp := get_id(instr[:inpbits])
+	if vm.Events != nil && inp < len(vm.Events.Enabled) {
+		vm.Event_disable(inp)
+	}
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+func (op Evd) Generate(arch *Arch) string {
+	inpbits := arch.Inputs_bits()
+	inp := rand.Intn(int(arch.N))
+	return zeros_prefix(inpbits, get_binary(inp))
+}
+
+func (op Evd) Required_shared() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Evd) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Evd) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Evd) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Evd) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	seq0, types0 := Sequence_to_0(words[0])
+
+	if len(seq0) > 0 && types0 == O_INPUT {
+		result := make([]UsageNotify, 1+len(seq0))
+		result[0] = UsageNotify{C_OPCODE, "evd", I_NIL}
+		for i, _ := range seq0 {
+			result[i+1] = UsageNotify{C_INPUT, S_NIL, i + 1}
+		}
+		return result, nil
+	}
+
+	return []UsageNotify{}, errors.New("Wrong parameters")
+}
+
+func (Op Evd) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Evd) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// The enable and pending bits, the registers are accounted by eve
+	return Resources{Luts: int(arch.N), Levels: 1}
+}
+
+func (op Evd) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
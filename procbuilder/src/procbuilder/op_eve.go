@@ -0,0 +1,181 @@
+package procbuilder
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// The Eve opcode enables the event of an input, a rising edge of its valid jumps to the given handler
+type Eve struct{}
+
+func (op Eve) Op_get_name() string {
+	return "eve"
+}
+
+func (op Eve) Op_get_desc() string {
+	return "Enable the event of an input"
+}
+
+func (op Eve) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	inpbits := arch.Inputs_bits()
+	result := "eve [" + strconv.Itoa(inpbits) + "(Input)] [" + strconv.Itoa(int(arch.O)) + "(Location)]	// Enable the event of the input with the handler at the location [" + strconv.Itoa(opbits+inpbits+int(arch.O)) + "]\n"
+	return result
+}
+
+func (op Eve) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	inpbits := arch.Inputs_bits()
+	return opbits + inpbits + int(arch.O) // The bits for the opcode + bits for the input + bits for a location
+}
+
+func (op Eve) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Eve) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Eve) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Eve) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Eve) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	inpbits := arch.Inputs_bits()
+	inp := rom_field(arch, 0, inpbits)
+	handler := rom_field(arch, inpbits, int(arch.O))
+
+	result := ""
+	result += "					EVE: begin\n"
+	if arch.Has_events() {
+		result += "						case (" + inp + ")\n"
+		for j := 0; j < int(arch.N); j++ {
+			result += "						" + strings.ToUpper(Get_input_name(j)) + " : begin\n"
+			result += "							ev_enabled[" + strconv.Itoa(j) + "] <= #1 1'b1;\n"
+			result += "							ev_handler_" + strings.ToLower(Get_input_name(j)) + " <= #1 " + handler + ";\n"
+			result += "							$display(\"EVE " + strings.ToUpper(Get_input_name(j)) + " \", " + handler + ");\n"
+			result += "						end\n"
+		}
+		result += "						endcase\n"
+	} else {
+		result += "						$display(\"NOP\");\n"
+	}
+	result += "						_pc <= #1 _pc + 1'b1;\n"
+	result += "					end\n"
+	return result
+}
+
+func (op Eve) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Eve) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	inpbits := arch.Inputs_bits()
+	rom_word := arch.Max_word()
+
+	if len(words) != 2 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	if partial, err := Process_input(words[0], int(arch.N)); err == nil {
+		result += zeros_prefix(inpbits, partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	if partial, err := Process_number(words[1]); err == nil {
+		result += zeros_prefix(int(arch.O), partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	for i := opbits + inpbits + int(arch.O); i < rom_word; i++ {
+		result += "0"
+	}
+
+	return result, nil
+}
+
+func (op Eve) Disassembler(arch *Arch, instr string) (string, error) {
+	inpbits := arch.Inputs_bits()
+	inp_id := get_id(instr[:inpbits])
+	value := get_id(instr[inpbits : inpbits+int(arch.O)])
+	result := strings.ToLower(Get_input_name(inp_id)) + " " + strconv.Itoa(value)
+	return result, nil
+}
+
+func (op Eve) Simulate(vm *VM, instr string) error {
+	inpbits := vm.Mach.Inputs_bits()
+	inp := get_id(instr[:inpbits])
+	value := get_id(instr[inpbits : inpbits+int(vm.Mach.O)])
+	if vm.Events != nil && inp < len(vm.Events.Enabled) {
+		vm.Event_enable(inp, uint64(value))
+	}
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+func (op Eve) Generate(arch *Arch) string {
+	inpbits := arch.Inputs_bits()
+	inp := rand.Intn(int(arch.N))
+	value := rand.Intn(1 << arch.O)
+	return zeros_prefix(inpbits, get_binary(inp)) + zeros_prefix(int(arch.O), get_binary(value))
+}
+
+func (op Eve) Required_shared() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Eve) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Eve) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Eve) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Eve) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	seq0, types0 := Sequence_to_0(words[0])
+
+	if len(seq0) > 0 && types0 == O_INPUT {
+		result := make([]UsageNotify, 1+len(seq0))
+		result[0] = UsageNotify{C_OPCODE, "eve", I_NIL}
+		for i, _ := range seq0 {
+			result[i+1] = UsageNotify{C_INPUT, S_NIL, i + 1}
+		}
+		return result, nil
+	}
+
+	return []UsageNotify{}, errors.New("Wrong parameters")
+}
+
+func (Op Eve) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Eve) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// The enable bits, the handlers and the dispatch of the events
+	return Jump_cost(arch).Chain(Mux_cost(int(arch.N)+1, int(arch.O))).Chain(Resources{Luts: 2*int(arch.N) + 2, Ffs: (int(arch.N)+1)*int(arch.O) + 3*int(arch.N) + 1, Levels: 1})
+}
+
+func (op Eve) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
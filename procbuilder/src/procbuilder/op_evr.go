@@ -0,0 +1,142 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The Evr opcode returns from an event handler to the instruction interrupted, outside a handler it does nothing
+type Evr struct{}
+
+func (op Evr) Op_get_name() string {
+	return "evr"
+}
+
+func (op Evr) Op_get_desc() string {
+	return "Return from an event handler"
+}
+
+func (op Evr) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	result := "evr [" + strconv.Itoa(opbits) + "]	// Return from an event handler [" + strconv.Itoa(opbits) + "]\n"
+	return result
+}
+
+func (op Evr) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	return opbits
+}
+
+func (op Evr) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Evr) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Evr) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Evr) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Evr) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	result := ""
+	result += "					EVR: begin\n"
+	if arch.Has_events() {
+		result += "						if (ev_active)\n"
+		result += "						begin\n"
+		result += "							ev_active <= #1 1'b0;\n"
+		result += "							_pc <= #1 ev_ret;\n"
+		result += "						end\n"
+		result += "						else\n"
+		result += "						begin\n"
+		result += "							_pc <= #1 _pc + 1'b1;\n"
+		result += "						end\n"
+		result += "						$display(\"EVR\");\n"
+	} else {
+		result += "						$display(\"NOP\");\n"
+		result += "						_pc <= #1 _pc + 1'b1;\n"
+	}
+	result += "					end\n"
+	return result
+}
+
+func (op Evr) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Evr) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	rom_word := arch.Max_word()
+
+	if len(words) != 0 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	for i := opbits; i < rom_word; i++ {
+		result += "0"
+	}
+	return result, nil
+}
+
+func (op Evr) Disassembler(arch *Arch, instr string) (string, error) {
+	return "", nil
+}
+
+func (op Evr) Simulate(vm *VM, instr string) error {
+	if vm.Events != nil {
+		vm.Event_return()
+	} else {
+		vm.Pc = vm.Pc + 1
+	}
+	return nil
+}
+
+// The random genaration does nothing
+func (op Evr) Generate(arch *Arch) string {
+	return ""
+}
+
+func (op Evr) Required_shared() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Evr) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Evr) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Evr) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Evr) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	result := make([]UsageNotify, 1)
+	newnot := UsageNotify{C_OPCODE, "evr", I_NIL}
+	result[0] = newnot
+	return result, nil
+}
+
+func (Op Evr) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Evr) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Jump_cost(arch)
+}
+
+func (op Evr) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
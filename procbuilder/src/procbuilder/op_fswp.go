@@ -0,0 +1,211 @@
+package procbuilder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The Fswp opcode stores a register in a counter SO and loads the register with the previous value of the counter.
+// It is the fadd request with the swap flag set, the reset of the counter requests is shared with fadd.
+type Fswp struct{}
+
+func (op Fswp) Op_get_name() string {
+	return "fswp"
+}
+
+func (op Fswp) Op_get_desc() string {
+	return "Atomic swap on a counter SO"
+}
+
+func (op Fswp) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	ctbits := arch.Shared_bits("counter")
+	result := "fswp [" + strconv.Itoa(int(arch.R)) + "(Reg)] [" + strconv.Itoa(ctbits) + "(Counter)]	// Store the register in a counter SO, the register gets the previous count [" + strconv.Itoa(opbits+int(arch.R)+ctbits) + "]\n"
+	return result
+}
+
+func (op Fswp) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	ctbits := arch.Shared_bits("counter")
+	return opbits + int(arch.R) + ctbits // The bits for the opcode + bits for a register + bits for the counter id
+}
+
+func (op Fswp) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Fswp) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	result := ""
+
+	setflag := true
+	for _, currop := range arch.Op {
+		if currop.Op_get_name() == "fadd" {
+			setflag = false
+			break
+		} else if currop.Op_get_name() == "fswp" {
+			break
+		}
+	}
+	if setflag {
+		result += Counter_verilog_reset(arch)
+	}
+	return result
+}
+
+func (Op Fswp) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Fswp) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Fswp) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	ctbits := arch.Shared_bits("counter")
+	reg := rom_field(arch, 0, int(arch.R))
+	ct := rom_field(arch, int(arch.R), ctbits)
+
+	reg_num := 1 << arch.R
+
+	result := ""
+	result += "					FSWP: begin\n"
+	if arch.Shared_num("counter") > 0 {
+		result += "						if (ct_req_i[" + ct + "] && ct_ack_i[" + ct + "]) begin\n"
+		result += "							case (" + reg + ")\n"
+		for i := 0; i < reg_num; i++ {
+			result += "							" + strings.ToUpper(Get_register_name(i)) + " : begin\n"
+			result += "								_" + strings.ToLower(Get_register_name(i)) + " <= #1 ct_out_i[" + ct + "];\n"
+			result += "								$display(\"FSWP " + strings.ToUpper(Get_register_name(i)) + " \", ct_out_i[" + ct + "]);\n"
+			result += "							end\n"
+		}
+		result += "							endcase\n"
+		result += "							ct_req_i[" + ct + "] <= #1 1'b0;\n"
+		result += "							_pc <= #1 _pc + 1'b1;\n"
+		result += "						end\n"
+		result += "						else if (!ct_req_i[" + ct + "] && !ct_ack_i[" + ct + "]) begin\n"
+		result += "							case (" + reg + ")\n"
+		for i := 0; i < reg_num; i++ {
+			result += "							" + strings.ToUpper(Get_register_name(i)) + " : begin\n"
+			result += "								ct_in_i[" + ct + "] <= #1 _" + strings.ToLower(Get_register_name(i)) + ";\n"
+			result += "							end\n"
+		}
+		result += "							endcase\n"
+		result += "							ct_swp_i[" + ct + "] <= #1 1'b1;\n"
+		result += "							ct_req_i[" + ct + "] <= #1 1'b1;\n"
+		result += "						end\n"
+	} else {
+		result += "						$display(\"NOP\");\n"
+		result += "						_pc <= #1 _pc + 1'b1;\n"
+	}
+	result += "					end\n"
+	return result
+}
+
+func (op Fswp) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Fswp) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	ctso := Counter{}
+	ctnum := arch.Shared_num(ctso.Shr_get_name())
+	ctbits := arch.Shared_bits(ctso.Shr_get_name())
+	rom_word := arch.Max_word()
+
+	reg_num := 1 << arch.R
+
+	if len(words) != 2 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	for i := 0; i < reg_num; i++ {
+		if words[0] == strings.ToLower(Get_register_name(i)) {
+			result += zeros_prefix(int(arch.R), get_binary(i))
+			break
+		}
+	}
+
+	if result == "" {
+		return "", Prerror{"Unknown register name " + words[0]}
+	}
+
+	if partial, err := Process_shared(ctso.Shortname(), words[1], ctnum); err == nil {
+		result += zeros_prefix(ctbits, partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	for i := opbits + int(arch.R) + ctbits; i < rom_word; i++ {
+		result += "0"
+	}
+
+	return result, nil
+}
+
+func (op Fswp) Disassembler(arch *Arch, instr string) (string, error) {
+	ctso := Counter{}
+	ctbits := arch.Shared_bits(ctso.Shr_get_name())
+	reg_id := get_id(instr[:arch.R])
+	result := strings.ToLower(Get_register_name(reg_id)) + " "
+	ct_id := get_id(instr[arch.R : int(arch.R)+ctbits])
+	result += ctso.Shortname() + strconv.Itoa(ct_id)
+	return result, nil
+}
+
+func (op Fswp) Simulate(vm *VM, instr string) error {
+	ctbits := vm.Mach.Shared_bits("counter")
+	reg := get_id(instr[:vm.Mach.R])
+	ct := get_id(instr[vm.Mach.R : int(vm.Mach.R)+ctbits])
+	previous, err := vm.Sync_swap(ct, uint64(word_value(vm.Registers[reg])))
+	if err != nil {
+		return err
+	}
+	vm.Registers[reg] = vm.word(int(previous))
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+// The random genaration does nothing
+func (op Fswp) Generate(arch *Arch) string {
+	return ""
+}
+
+func (op Fswp) Required_shared() (bool, []string) {
+	return true, []string{"counter"}
+}
+
+func (op Fswp) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Fswp) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Fswp) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Fswp) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	result := make([]UsageNotify, 0)
+	return result, nil
+}
+
+func (Op Fswp) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Fswp) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, true).Chain(Handshake_cost(arch))
+}
+
+func (op Fswp) Op_instruction_latency(arch *Arch) (int, string) {
+	// The request and the acknowledge
+	return 2, STALL_SHARED
+}
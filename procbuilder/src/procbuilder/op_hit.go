@@ -149,3 +149,11 @@ func (Op Hit) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Hit) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, false).Chain(Handshake_cost(arch))
+}
+
+func (op Hit) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -110,3 +110,11 @@ func (Op Hlt) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Hlt) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Resources{Luts: 1, Ffs: 1, Levels: 1}
+}
+
+func (op Hlt) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -291,3 +291,11 @@ func (Op I2r) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op I2r) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 0, true).Chain(Mux_cost(int(arch.N), int(arch.Rsize)))
+}
+
+func (op I2r) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
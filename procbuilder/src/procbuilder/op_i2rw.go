@@ -312,3 +312,13 @@ func (Op I2rw) Op_instruction_verilog_extra_block(arch *Arch, flavor string, lev
 	}
 	return result
 }
+
+func (op I2rw) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// The input valid and received signals
+	return Regfile_cost(arch, 0, true).Chain(Mux_cost(int(arch.N), int(arch.Rsize))).Chain(Resources{Luts: int(arch.N) + 2, Ffs: 2, Levels: 1})
+}
+
+func (op I2rw) Op_instruction_latency(arch *Arch) (int, string) {
+	// The valid handshake with no wait on the other side
+	return 2, STALL_IO
+}
@@ -170,3 +170,11 @@ func (Op Inc) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Inc) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, true).Chain(Adder_cost(arch))
+}
+
+func (op Inc) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -95,7 +95,7 @@ func (op J) Disassembler(arch *Arch, instr string) (string, error) {
 
 func (op J) Simulate(vm *VM, instr string) error {
 	value := get_id(instr[:vm.Mach.O])
-	if value < len(vm.Mach.Slocs) {
+	if value < vm.Program_len() {
 		vm.Pc = uint64(value)
 	} else {
 		vm.Pc = vm.Pc + 1
@@ -140,3 +140,11 @@ func (Op J) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level
 	}
 	return result
 }
+
+func (op J) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Jump_cost(arch)
+}
+
+func (op J) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -68,6 +68,9 @@ func (op Jc) Op_instruction_verilog_state_machine(arch *Arch, flavor string) str
 		result += "						$display(\"JC \", rom_value[" + strconv.Itoa(rom_word-opbits-1) + ":" + strconv.Itoa(rom_word-opbits-int(arch.O)) + "]);\n"
 		result += " end \n"
 	}
+	result += "					else begin\n"
+	result += "						_pc <= #1 _pc + 1'b1;\n"
+	result += "					end\n"
 	result += "					end\n"
 	return result
 }
@@ -106,7 +109,7 @@ func (op Jc) Disassembler(arch *Arch, instr string) (string, error) {
 
 func (op Jc) Simulate(vm *VM, instr string) error {
 	value := get_id(instr[:vm.Mach.O])
-	if value < len(vm.Mach.Slocs) {
+	if !vm.Carry() && value < vm.Program_len() {
 		vm.Pc = uint64(value)
 	} else {
 		vm.Pc = vm.Pc + 1
@@ -151,3 +154,11 @@ func (Op Jc) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level
 	}
 	return result
 }
+
+func (op Jc) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Jump_cost(arch).Chain(Resources{Luts: 1})
+}
+
+func (op Jc) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -2,79 +2,140 @@ package procbuilder
 
 import (
 	"strconv"
+	"strings"
 )
 
 // The Je opcode is both a basic instruction and a template for other instructions.
 type Je struct{}
 
 func (op Je) Op_get_name() string {
-	// TODO
 	return "je"
 }
 
 func (op Je) Op_get_desc() string {
-	// TODO
-	return "No operation"
+	return "Equality conditional jump"
 }
 
 func (op Je) Op_show_assembler(arch *Arch) string {
-	// TODO
 	opbits := arch.Opcodes_bits()
-	result := "je [" + strconv.Itoa(opbits) + "]	// No operation [" + strconv.Itoa(opbits) + "]\n"
+	result := "je [" + strconv.Itoa(int(arch.R)) + "(Reg)] [" + strconv.Itoa(int(arch.R)) + "(Reg)] [" + strconv.Itoa(int(arch.O)) + "(ROM Address)]	// Jump if the registers are equal [" + strconv.Itoa(opbits+2*int(arch.R)+int(arch.O)) + "]\n"
 	return result
 }
 
 func (op Je) Op_get_instruction_len(arch *Arch) int {
-	// TODO
 	opbits := arch.Opcodes_bits()
-	return opbits
+	return opbits + 2*int(arch.R) + int(arch.O) // The bits for the opcode + bits for two registers + bits for the rom address
 }
 
 func (op Je) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
-	// TODO
 	return ""
 }
 
 func (op Je) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
-	// TODO
 	result := ""
-	result += "				JE: begin\n"
-	result += "					$display(\"JE\");\n"
-	result += "					_pc <= _pc + 1'b1 ;\n"
-	result += "				end\n"
+	rom_word := arch.Max_word()
+	opbits := arch.Opcodes_bits()
+
+	reg_num := 1 << arch.R
+
+	result += "					JE: begin\n"
+	if arch.R == 1 {
+		result += "						case (rom_value[" + strconv.Itoa(rom_word-opbits-1) + "])\n"
+	} else {
+		result += "						case (rom_value[" + strconv.Itoa(rom_word-opbits-1) + ":" + strconv.Itoa(rom_word-opbits-int(arch.R)) + "])\n"
+	}
+	for i := 0; i < reg_num; i++ {
+		result += "						" + strings.ToUpper(Get_register_name(i)) + " : begin\n"
+		if arch.R == 1 {
+			result += "							case (rom_value[" + strconv.Itoa(rom_word-opbits-int(arch.R)-1) + "])\n"
+		} else {
+			result += "							case (rom_value[" + strconv.Itoa(rom_word-opbits-int(arch.R)-1) + ":" + strconv.Itoa(rom_word-opbits-2*int(arch.R)) + "])\n"
+		}
+		for j := 0; j < reg_num; j++ {
+			result += "							" + strings.ToUpper(Get_register_name(j)) + " : begin\n"
+			result += "								if(_" + strings.ToLower(Get_register_name(i)) + " == _" + strings.ToLower(Get_register_name(j)) + ")\n"
+			result += "									_pc <= #1 rom_value[" + strconv.Itoa(rom_word-opbits-1-2*int(arch.R)) + ":" + strconv.Itoa(rom_word-opbits-int(arch.O)-2*int(arch.R)) + "];\n"
+			result += "								else\n"
+			result += "									_pc <= #1 _pc + 1'b1;\n"
+			result += "								$display(\"JE " + strings.ToUpper(Get_register_name(i)) + " " + strings.ToUpper(Get_register_name(j)) + "\");\n"
+			result += "							end\n"
+		}
+		result += "							endcase\n"
+		result += "						end\n"
+	}
+	result += "						endcase\n"
+	result += "					end\n"
 
 	return result
 }
 
 func (op Je) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
-	// TODO
 	return ""
 }
 
 func (op Je) Assembler(arch *Arch, words []string) (string, error) {
-	// TODO
 	opbits := arch.Opcodes_bits()
 	rom_word := arch.Max_word()
+	osize := int(arch.O)
+
+	reg_num := 1 << arch.R
+
+	if len(words) != 3 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
 	result := ""
-	for i := opbits; i < rom_word; i++ {
+	for _, word := range words[:2] {
+		partial := ""
+		for i := 0; i < reg_num; i++ {
+			if word == strings.ToLower(Get_register_name(i)) {
+				partial = zeros_prefix(int(arch.R), get_binary(i))
+				break
+			}
+		}
+		if partial == "" {
+			return "", Prerror{"Unknown register name " + word}
+		}
+		result += partial
+	}
+
+	if partial, err := Process_number(words[2]); err == nil {
+		result += zeros_prefix(osize, partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	for i := opbits + 2*int(arch.R) + osize; i < rom_word; i++ {
 		result += "0"
 	}
+
 	return result, nil
 }
 
 func (op Je) Disassembler(arch *Arch, instr string) (string, error) {
-	// TODO
-	return "", nil
+	osize := int(arch.O)
+	reg_id := get_id(instr[:arch.R])
+	result := strings.ToLower(Get_register_name(reg_id)) + " "
+	reg_id = get_id(instr[arch.R : 2*int(arch.R)])
+	result += strings.ToLower(Get_register_name(reg_id)) + " "
+	value := get_id(instr[2*int(arch.R) : 2*int(arch.R)+osize])
+	result += strconv.Itoa(value)
+	return result, nil
 }
 
-// The simulation does nothing
 func (op Je) Simulate(vm *VM, instr string) error {
-	// TODO
-	vm.Pc = vm.Pc + 1
+	reg_bits := int(vm.Mach.R)
+	rega := get_id(instr[:reg_bits])
+	regb := get_id(instr[reg_bits : 2*reg_bits])
+	value := get_id(instr[2*reg_bits : 2*reg_bits+int(vm.Mach.O)])
+	if word_value(vm.Registers[rega]) == word_value(vm.Registers[regb]) && value < vm.Program_len() {
+		vm.Pc = uint64(value)
+	} else {
+		vm.Pc = vm.Pc + 1
+	}
 	return nil
 }
 
-// The random genaration does nothing
 func (op Je) Generate(arch *Arch) string {
 	// TODO
 	return ""
@@ -129,3 +190,12 @@ func (Op Je) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level
 	}
 	return result
 }
+
+func (op Je) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// The equality comparator sits on the carry chain
+	return Regfile_cost(arch, 2, false).Chain(Adder_cost(arch)).Chain(Jump_cost(arch))
+}
+
+func (op Je) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
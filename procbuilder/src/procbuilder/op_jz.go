@@ -114,10 +114,15 @@ func (op Jz) Disassembler(arch *Arch, instr string) (string, error) {
 	return result, nil
 }
 
-// The simulation does nothing
 func (op Jz) Simulate(vm *VM, instr string) error {
-	// TODO
-	vm.Pc = vm.Pc + 1
+	reg_bits := int(vm.Mach.R)
+	reg := get_id(instr[:reg_bits])
+	value := get_id(instr[reg_bits : reg_bits+int(vm.Mach.O)])
+	if word_value(vm.Registers[reg]) == 0 && value < vm.Program_len() {
+		vm.Pc = uint64(value)
+	} else {
+		vm.Pc = vm.Pc + 1
+	}
 	return nil
 }
 
@@ -182,3 +187,12 @@ func (Op Jz) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level
 	}
 	return result
 }
+
+func (op Jz) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// The zero detect is a tree of LUTs
+	return Regfile_cost(arch, 1, false).Chain(Resources{Luts: ceil_div(int(arch.Rsize), 6), Levels: mux_levels(int(arch.Rsize))}).Chain(Jump_cost(arch))
+}
+
+func (op Jz) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -214,3 +214,11 @@ func (Op Lfsr82r) Op_instruction_verilog_extra_block(arch *Arch, flavor string,
 	}
 	return result
 }
+
+func (op Lfsr82r) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 0, true).Chain(Handshake_cost(arch))
+}
+
+func (op Lfsr82r) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -0,0 +1,194 @@
+package procbuilder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The Lfsrn2r opcode reads a lfsrn SO into a register, the value is truncated or zero extended to the register size.
+type Lfsrn2r struct{}
+
+func (op Lfsrn2r) Op_get_name() string {
+	return "lfsrn2r"
+}
+
+func (op Lfsrn2r) Op_get_desc() string {
+	return "Read a pseudo random number from a lfsrn SO"
+}
+
+func (op Lfsrn2r) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	lfbits := arch.Shared_bits("lfsrn")
+	result := "lfsrn2r [" + strconv.Itoa(int(arch.R)) + "(Reg)] [" + strconv.Itoa(lfbits) + "(Lfsrn)]	// Read a pseudo random number from a lfsrn SO [" + strconv.Itoa(opbits+int(arch.R)+lfbits) + "]\n"
+	return result
+}
+
+func (op Lfsrn2r) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	lfbits := arch.Shared_bits("lfsrn")
+	return opbits + int(arch.R) + lfbits // The bits for the opcode + bits for a register + bits for the lfsrn id
+}
+
+func (op Lfsrn2r) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Lfsrn2r) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Lfsrn2r) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Lfsrn2r) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Lfsrn2r) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	lfbits := arch.Shared_bits("lfsrn")
+	reg := rom_field(arch, 0, int(arch.R))
+	lf := rom_field(arch, int(arch.R), lfbits)
+	widths := Lfsrn_widths(arch.Shared_constraints)
+	rsize := int(arch.Rsize)
+
+	reg_num := 1 << arch.R
+
+	result := ""
+	result += "					LFSRN2R: begin\n"
+	if len(widths) > 0 {
+		result += "						case (" + reg + ")\n"
+		for i := 0; i < reg_num; i++ {
+			result += "						" + strings.ToUpper(Get_register_name(i)) + " : begin\n"
+			result += "							case (" + lf + ")\n"
+			for j, width := range widths {
+				port := "lfsrn" + strconv.Itoa(j) + "out"
+				if width >= rsize {
+					port += "[" + strconv.Itoa(rsize-1) + ":0]"
+				} else {
+					port = "{" + strconv.Itoa(rsize-width) + "'b0, " + port + "}"
+				}
+				result += "							" + strconv.Itoa(lfbits) + "'d" + strconv.Itoa(j) + " : begin\n"
+				result += "								_" + strings.ToLower(Get_register_name(i)) + " <= #1 " + port + ";\n"
+				result += "								$display(\"LFSRN2R " + strings.ToUpper(Get_register_name(i)) + " LFSRN" + strconv.Itoa(j) + "\");\n"
+				result += "							end\n"
+			}
+			result += "							endcase\n"
+			result += "						end\n"
+		}
+		result += "						endcase\n"
+	} else {
+		result += "						$display(\"NOP\");\n"
+	}
+	result += "						_pc <= #1 _pc + 1'b1;\n"
+	result += "					end\n"
+	return result
+}
+
+func (op Lfsrn2r) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Lfsrn2r) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	lfso := Lfsrn{}
+	lfnum := arch.Shared_num(lfso.Shr_get_name())
+	lfbits := arch.Shared_bits(lfso.Shr_get_name())
+	rom_word := arch.Max_word()
+
+	reg_num := 1 << arch.R
+
+	if len(words) != 2 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	for i := 0; i < reg_num; i++ {
+		if words[0] == strings.ToLower(Get_register_name(i)) {
+			result += zeros_prefix(int(arch.R), get_binary(i))
+			break
+		}
+	}
+
+	if result == "" {
+		return "", Prerror{"Unknown register name " + words[0]}
+	}
+
+	if partial, err := Process_shared(lfso.Shortname(), words[1], lfnum); err == nil {
+		result += zeros_prefix(lfbits, partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	for i := opbits + int(arch.R) + lfbits; i < rom_word; i++ {
+		result += "0"
+	}
+
+	return result, nil
+}
+
+func (op Lfsrn2r) Disassembler(arch *Arch, instr string) (string, error) {
+	lfso := Lfsrn{}
+	lfbits := arch.Shared_bits(lfso.Shr_get_name())
+	reg_id := get_id(instr[:arch.R])
+	result := strings.ToLower(Get_register_name(reg_id)) + " "
+	lf_id := get_id(instr[arch.R : int(arch.R)+lfbits])
+	result += lfso.Shortname() + strconv.Itoa(lf_id)
+	return result, nil
+}
+
+func (op Lfsrn2r) Simulate(vm *VM, instr string) error {
+	lfbits := vm.Mach.Shared_bits("lfsrn")
+	reg := get_id(instr[:vm.Mach.R])
+	lf := get_id(instr[vm.Mach.R : int(vm.Mach.R)+lfbits])
+	value, err := vm.Sync_lfsr(lf)
+	if err != nil {
+		return err
+	}
+	vm.Registers[reg] = vm.word(int(value))
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+// The random genaration does nothing
+func (op Lfsrn2r) Generate(arch *Arch) string {
+	return ""
+}
+
+func (op Lfsrn2r) Required_shared() (bool, []string) {
+	return true, []string{"lfsrn"}
+}
+
+func (op Lfsrn2r) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Lfsrn2r) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Lfsrn2r) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Lfsrn2r) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	result := make([]UsageNotify, 0)
+	return result, nil
+}
+
+func (Op Lfsrn2r) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Lfsrn2r) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 0, true).Chain(Mux_cost(arch.Shared_num("lfsrn"), int(arch.Rsize)))
+}
+
+func (op Lfsrn2r) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -154,8 +154,13 @@ func (op M2r) Disassembler(arch *Arch, instr string) (string, error) {
 func (op M2r) Simulate(vm *VM, instr string) error {
 	reg_bits := vm.Mach.R
 	reg := get_id(instr[:reg_bits])
-	memval := get_id(instr[reg_bits : reg_bits+8])
-	vm.Registers[reg] = uint8(memval)
+	addr := get_id(instr[reg_bits : reg_bits+vm.Mach.L])
+	value, err := vm.Mem_read(addr)
+	if err != nil {
+		return err
+	}
+	vm.Registers[reg] = value
+	vm.Pc = vm.Pc + 1
 	return nil
 }
 
@@ -205,3 +210,12 @@ func (Op M2r) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op M2r) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 0, true).Chain(Resources{Luts: int(arch.L), Levels: 1})
+}
+
+func (op M2r) Op_instruction_latency(arch *Arch) (int, string) {
+	// The RAM output is registered on the second cycle
+	return 2, STALL_RAM
+}
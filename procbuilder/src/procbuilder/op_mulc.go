@@ -44,6 +44,7 @@ func (op Mulc) Op_instruction_verilog_state_machine(arch *Arch, flavor string) s
 	opbits := arch.Opcodes_bits()
 
 	reg_num := 1 << arch.R
+	rsize := strconv.Itoa(int(arch.Rsize))
 
 	result := ""
 	result += "					MULC: begin\n"
@@ -63,7 +64,9 @@ func (op Mulc) Op_instruction_verilog_state_machine(arch *Arch, flavor string) s
 
 		for j := 0; j < reg_num; j++ {
 			result += "							" + strings.ToUpper(Get_register_name(j)) + " : begin\n"
-			result += "								{ carryflag, _" + strings.ToLower(Get_register_name(i)) + "} <= #1 { 1'b0, _" + strings.ToLower(Get_register_name(j)) + "} * {1'b0,  _" + strings.ToLower(Get_register_name(i)) + "};\n"
+			// The carry is set if any bit of the product at or above Rsize is set
+			result += "								_" + strings.ToLower(Get_register_name(i)) + " <= #1 _" + strings.ToLower(Get_register_name(i)) + " * _" + strings.ToLower(Get_register_name(j)) + ";\n"
+			result += "								carryflag <= #1 |(({" + rsize + "'b0, _" + strings.ToLower(Get_register_name(i)) + "} * {" + rsize + "'b0, _" + strings.ToLower(Get_register_name(j)) + "}) >> " + rsize + ");\n"
 			result += "								$display(\"MULC " + strings.ToUpper(Get_register_name(i)) + " " + strings.ToUpper(Get_register_name(j)) + "\");\n"
 			result += "							end\n"
 
@@ -133,19 +136,12 @@ func (op Mulc) Disassembler(arch *Arch, instr string) (string, error) {
 	return result, nil
 }
 
-// The simulation does nothing
+// The carry is set if the product does not fit in a register
 func (op Mulc) Simulate(vm *VM, instr string) error {
 	reg_bits := vm.Mach.R
 	regdest := get_id(instr[:reg_bits])
 	regsrc := get_id(instr[reg_bits : reg_bits*2])
-	switch vm.Mach.Rsize {
-	case 8:
-		vm.Registers[regdest] = vm.Registers[regdest].(uint8) * vm.Registers[regsrc].(uint8)
-	case 16:
-		vm.Registers[regdest] = vm.Registers[regdest].(uint16) * vm.Registers[regsrc].(uint16)
-	default:
-		// TODO Fix
-	}
+	vm.carry_result(regdest, word_value(vm.Registers[regdest])*word_value(vm.Registers[regsrc]))
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -218,3 +214,12 @@ func (Op Mulc) Op_instruction_verilog_extra_block(arch *Arch, flavor string, lev
 	}
 	return result
 }
+
+func (op Mulc) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// The carry is set if the upper half of the product is not zero
+	return Regfile_cost(arch, 2, true).Chain(Multiplier_cost(arch, flavor)).Chain(Resources{Luts: int(arch.Rsize) / 6, Ffs: 1, Levels: 1})
+}
+
+func (op Mulc) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -479,3 +479,11 @@ func (Op Multf) Op_instruction_verilog_extra_block(arch *Arch, flavor string, le
 	}
 	return result
 }
+
+func (op Multf) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 2, true).Chain(Float_cost(arch, flavor, "mult"))
+}
+
+func (op Multf) Op_instruction_latency(arch *Arch) (int, string) {
+	return 15, STALL_FPU
+}
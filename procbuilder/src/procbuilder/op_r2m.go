@@ -176,9 +176,13 @@ func (op R2m) Disassembler(arch *Arch, instr string) (string, error) {
 	return result, nil
 }
 
-// The simulation does nothing
 func (op R2m) Simulate(vm *VM, instr string) error {
-	// TODO
+	reg_bits := vm.Mach.R
+	reg := get_id(instr[:reg_bits])
+	addr := get_id(instr[reg_bits : reg_bits+vm.Mach.L])
+	if err := vm.Mem_write(addr, vm.Registers[reg]); err != nil {
+		return err
+	}
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -232,3 +236,11 @@ func (Op R2m) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op R2m) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, false).Chain(Resources{Luts: int(arch.L) + 1, Levels: 1})
+}
+
+func (op R2m) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -299,3 +299,11 @@ func (Op R2o) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op R2o) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, false).Chain(Resources{Luts: int(arch.M), Levels: 1})
+}
+
+func (op R2o) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -314,3 +314,13 @@ func (Op R2owa) Op_instruction_verilog_extra_block(arch *Arch, flavor string, le
 	}
 	return result
 }
+
+func (op R2owa) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// The output valid signals
+	return Regfile_cost(arch, 1, false).Chain(Resources{Luts: 2 * int(arch.M), Ffs: int(arch.M), Levels: 1})
+}
+
+func (op R2owa) Op_instruction_latency(arch *Arch) (int, string) {
+	// The received handshake with no wait on the other side
+	return 2, STALL_IO
+}
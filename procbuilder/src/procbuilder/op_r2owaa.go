@@ -334,3 +334,12 @@ func (Op R2owaa) Op_instruction_verilog_extra_block(arch *Arch, flavor string, l
 	}
 	return result
 }
+
+func (op R2owaa) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// The output valid and received signals
+	return Regfile_cost(arch, 1, false).Chain(Resources{Luts: 3 * int(arch.M), Ffs: 2 * int(arch.M), Levels: 1})
+}
+
+func (op R2owaa) Op_instruction_latency(arch *Arch) (int, string) {
+	return 2, STALL_IO
+}
@@ -169,3 +169,11 @@ func (Op R2s) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op R2s) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Resources{}
+}
+
+func (op R2s) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -218,3 +218,11 @@ func (Op Rsc) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Rsc) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 2, true).Chain(Adder_cost(arch))
+}
+
+func (op Rsc) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -187,3 +187,11 @@ func (Op Rset) Op_instruction_verilog_extra_block(arch *Arch, flavor string, lev
 	}
 	return result
 }
+
+func (op Rset) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 0, true)
+}
+
+func (op Rset) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -165,3 +165,11 @@ func (Op S2r) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op S2r) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Resources{}
+}
+
+func (op S2r) Op_instruction_latency(arch *Arch) (int, string) {
+	return 2, STALL_SHARED
+}
@@ -126,3 +126,11 @@ func (Op Saj) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Saj) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Resources{}
+}
+
+func (op Saj) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
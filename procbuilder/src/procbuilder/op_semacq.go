@@ -0,0 +1,181 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The Semacq opcode acquires a semaphore: the request is held until the shared object acknowledges it, then it is
+// dropped and the processor waits for the acknowledge to fall before another request.
+type Semacq struct{}
+
+func (op Semacq) Op_get_name() string {
+	return "semacq"
+}
+
+func (op Semacq) Op_get_desc() string {
+	return "Acquire a semaphore SO"
+}
+
+func (op Semacq) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	sebits := arch.Shared_bits("semaphore")
+	result := "semacq [" + strconv.Itoa(sebits) + "(Semaphore)]	// Acquire a semaphore SO, wait while its count is 0 [" + strconv.Itoa(opbits+sebits) + "]\n"
+	return result
+}
+
+func (op Semacq) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	sebits := arch.Shared_bits("semaphore")
+	return opbits + sebits // The bits for the opcode + bits for the semaphore id
+}
+
+func (op Semacq) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Semacq) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	result := ""
+
+	setflag := true
+	for _, currop := range arch.Op {
+		if currop.Op_get_name() == "semrel" {
+			setflag = false
+			break
+		} else if currop.Op_get_name() == "semacq" {
+			break
+		}
+	}
+	if setflag && arch.Shared_num("semaphore") > 0 {
+		result += "\t\t\tse_acq_i <= #1 'b0;\n"
+		result += "\t\t\tse_rel_i <= #1 'b0;\n"
+	}
+
+	return result
+}
+
+func (Op Semacq) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Semacq) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Semacq) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	sebits := arch.Shared_bits("semaphore")
+	se := rom_field(arch, 0, sebits)
+
+	result := ""
+	result += "					SEMACQ: begin\n"
+	if arch.Shared_num("semaphore") > 0 {
+		result += "						if (se_acq_i[" + se + "] && se_ack_i[" + se + "]) begin\n"
+		result += "							se_acq_i[" + se + "] <= #1 1'b0;\n"
+		result += "							$display(\"SEMACQ SE\", " + se + ");\n"
+		result += "							_pc <= #1 _pc + 1'b1;\n"
+		result += "						end\n"
+		result += "						else if (!se_acq_i[" + se + "] && !se_ack_i[" + se + "])\n"
+		result += "							se_acq_i[" + se + "] <= #1 1'b1;\n"
+	} else {
+		result += "						$display(\"NOP\");\n"
+		result += "						_pc <= #1 _pc + 1'b1;\n"
+	}
+	result += "					end\n"
+	return result
+}
+
+func (op Semacq) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Semacq) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	seso := Semaphore{}
+	senum := arch.Shared_num(seso.Shr_get_name())
+	sebits := arch.Shared_bits(seso.Shr_get_name())
+	rom_word := arch.Max_word()
+
+	if len(words) != 1 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	if partial, err := Process_shared(seso.Shortname(), words[0], senum); err == nil {
+		result += zeros_prefix(sebits, partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	for i := opbits + sebits; i < rom_word; i++ {
+		result += "0"
+	}
+
+	return result, nil
+}
+
+func (op Semacq) Disassembler(arch *Arch, instr string) (string, error) {
+	seso := Semaphore{}
+	sebits := arch.Shared_bits(seso.Shr_get_name())
+	se_id := get_id(instr[:sebits])
+	result := seso.Shortname() + strconv.Itoa(se_id)
+	return result, nil
+}
+
+// The acquire is executed again while the count is 0
+func (op Semacq) Simulate(vm *VM, instr string) error {
+	sebits := vm.Mach.Shared_bits("semaphore")
+	se := get_id(instr[:sebits])
+	acquired, err := vm.Sync_semaphore(se, true)
+	if err != nil {
+		return err
+	}
+	if !acquired {
+		vm.waiting = STALL_SHARED
+		return nil
+	}
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+// The random genaration does nothing
+func (op Semacq) Generate(arch *Arch) string {
+	return ""
+}
+
+func (op Semacq) Required_shared() (bool, []string) {
+	return true, []string{"semaphore"}
+}
+
+func (op Semacq) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Semacq) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Semacq) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Semacq) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	result := make([]UsageNotify, 0)
+	return result, nil
+}
+
+func (Op Semacq) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Semacq) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Handshake_cost(arch)
+}
+
+func (op Semacq) Op_instruction_latency(arch *Arch) (int, string) {
+	// The request and the acknowledge
+	return 2, STALL_SHARED
+}
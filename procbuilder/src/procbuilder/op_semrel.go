@@ -0,0 +1,175 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The Semrel opcode releases a semaphore with the same handshake of semacq, the count saturates.
+type Semrel struct{}
+
+func (op Semrel) Op_get_name() string {
+	return "semrel"
+}
+
+func (op Semrel) Op_get_desc() string {
+	return "Release a semaphore SO"
+}
+
+func (op Semrel) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	sebits := arch.Shared_bits("semaphore")
+	result := "semrel [" + strconv.Itoa(sebits) + "(Semaphore)]	// Release a semaphore SO [" + strconv.Itoa(opbits+sebits) + "]\n"
+	return result
+}
+
+func (op Semrel) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	sebits := arch.Shared_bits("semaphore")
+	return opbits + sebits // The bits for the opcode + bits for the semaphore id
+}
+
+func (op Semrel) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Semrel) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	result := ""
+
+	setflag := true
+	for _, currop := range arch.Op {
+		if currop.Op_get_name() == "semacq" {
+			setflag = false
+			break
+		} else if currop.Op_get_name() == "semrel" {
+			break
+		}
+	}
+	if setflag && arch.Shared_num("semaphore") > 0 {
+		result += "\t\t\tse_acq_i <= #1 'b0;\n"
+		result += "\t\t\tse_rel_i <= #1 'b0;\n"
+	}
+
+	return result
+}
+
+func (Op Semrel) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Semrel) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Semrel) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	sebits := arch.Shared_bits("semaphore")
+	se := rom_field(arch, 0, sebits)
+
+	result := ""
+	result += "					SEMREL: begin\n"
+	if arch.Shared_num("semaphore") > 0 {
+		result += "						if (se_rel_i[" + se + "] && se_ack_i[" + se + "]) begin\n"
+		result += "							se_rel_i[" + se + "] <= #1 1'b0;\n"
+		result += "							$display(\"SEMREL SE\", " + se + ");\n"
+		result += "							_pc <= #1 _pc + 1'b1;\n"
+		result += "						end\n"
+		result += "						else if (!se_rel_i[" + se + "] && !se_ack_i[" + se + "])\n"
+		result += "							se_rel_i[" + se + "] <= #1 1'b1;\n"
+	} else {
+		result += "						$display(\"NOP\");\n"
+		result += "						_pc <= #1 _pc + 1'b1;\n"
+	}
+	result += "					end\n"
+	return result
+}
+
+func (op Semrel) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Semrel) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	seso := Semaphore{}
+	senum := arch.Shared_num(seso.Shr_get_name())
+	sebits := arch.Shared_bits(seso.Shr_get_name())
+	rom_word := arch.Max_word()
+
+	if len(words) != 1 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	if partial, err := Process_shared(seso.Shortname(), words[0], senum); err == nil {
+		result += zeros_prefix(sebits, partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	for i := opbits + sebits; i < rom_word; i++ {
+		result += "0"
+	}
+
+	return result, nil
+}
+
+func (op Semrel) Disassembler(arch *Arch, instr string) (string, error) {
+	seso := Semaphore{}
+	sebits := arch.Shared_bits(seso.Shr_get_name())
+	se_id := get_id(instr[:sebits])
+	result := seso.Shortname() + strconv.Itoa(se_id)
+	return result, nil
+}
+
+// A release always completes
+func (op Semrel) Simulate(vm *VM, instr string) error {
+	sebits := vm.Mach.Shared_bits("semaphore")
+	se := get_id(instr[:sebits])
+	if _, err := vm.Sync_semaphore(se, false); err != nil {
+		return err
+	}
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+// The random genaration does nothing
+func (op Semrel) Generate(arch *Arch) string {
+	return ""
+}
+
+func (op Semrel) Required_shared() (bool, []string) {
+	return true, []string{"semaphore"}
+}
+
+func (op Semrel) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Semrel) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Semrel) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Semrel) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	result := make([]UsageNotify, 0)
+	return result, nil
+}
+
+func (Op Semrel) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Semrel) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Handshake_cost(arch)
+}
+
+func (op Semrel) Op_instruction_latency(arch *Arch) (int, string) {
+	// The request and the acknowledge
+	return 2, STALL_SHARED
+}
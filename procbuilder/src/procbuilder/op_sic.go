@@ -267,3 +267,13 @@ func (Op Sic) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Sic) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	// The last input value is kept to detect the change
+	return Regfile_cost(arch, 1, true).Chain(Mux_cost(int(arch.N), int(arch.Rsize))).Chain(Adder_cost(arch)).Chain(Resources{Luts: int(arch.Rsize), Ffs: int(arch.Rsize)})
+}
+
+func (op Sic) Op_instruction_latency(arch *Arch) (int, string) {
+	// Sampling and the first comparison, every input change costs one more cycle
+	return 2, STALL_IO
+}
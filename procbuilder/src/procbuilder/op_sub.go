@@ -125,9 +125,11 @@ func (op Sub) Disassembler(arch *Arch, instr string) (string, error) {
 	return result, nil
 }
 
-// The simulation does nothing
 func (op Sub) Simulate(vm *VM, instr string) error {
-	// TODO
+	reg_bits := vm.Mach.R
+	regdest := get_id(instr[:reg_bits])
+	regsrc := get_id(instr[reg_bits : reg_bits*2])
+	vm.Registers[regdest] = vm.word(word_value(vm.Registers[regdest]) - word_value(vm.Registers[regsrc]))
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -184,3 +186,11 @@ func (Op Sub) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Sub) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 2, true).Chain(Adder_cost(arch))
+}
+
+func (op Sub) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -0,0 +1,123 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The Tmp opcode arms the processor timer periodic, with a period of the register shifted left by the immediate, in cycles
+type Tmp struct{}
+
+func (op Tmp) Op_get_name() string {
+	return "tmp"
+}
+
+func (op Tmp) Op_get_desc() string {
+	return "Arm the timer periodic"
+}
+
+func (op Tmp) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	result := "tmp [" + strconv.Itoa(int(arch.R)) + "(Reg)] [" + strconv.Itoa(Timer_shift_bits) + "(Shift)]	// Arm the timer to expire every register shifted left cycles [" + strconv.Itoa(opbits+int(arch.R)+Timer_shift_bits) + "]\n"
+	return result
+}
+
+func (op Tmp) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	return opbits + int(arch.R) + Timer_shift_bits // The bits for the opcode + bits for a register + bits for the shift
+}
+
+func (op Tmp) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	if timer_generator(arch, op.Op_get_name()) {
+		return timer_verilog_header(arch)
+	}
+	return ""
+}
+
+func (Op Tmp) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	if timer_generator(arch, Op.Op_get_name()) {
+		return timer_verilog_reset(arch)
+	}
+	return ""
+}
+
+func (Op Tmp) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Tmp) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	if timer_generator(arch, Op.Op_get_name()) {
+		return timer_verilog_default_state(arch)
+	}
+	return ""
+}
+
+func (op Tmp) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	result := ""
+	result += "					TMP: begin\n"
+	result += timer_verilog_arm(arch, true)
+	result += "						$display(\"TMP\");\n"
+	result += "					end\n"
+	return result
+}
+
+func (op Tmp) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Tmp) Assembler(arch *Arch, words []string) (string, error) {
+	return timer_assembler(arch, op.Op_get_name(), words)
+}
+
+func (op Tmp) Disassembler(arch *Arch, instr string) (string, error) {
+	return timer_disassembler(arch, instr)
+}
+
+func (op Tmp) Simulate(vm *VM, instr string) error {
+	reg_bits := vm.Mach.R
+	reg := get_id(instr[:reg_bits])
+	shift := uint(get_id(instr[reg_bits : int(reg_bits)+Timer_shift_bits]))
+	vm.Timer_arm(uint64(word_value(vm.Registers[reg]))<<shift, true)
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+func (op Tmp) Generate(arch *Arch) string {
+	return timer_generate(arch)
+}
+
+func (op Tmp) Required_shared() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Tmp) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Tmp) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Tmp) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Tmp) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	return timer_abstract_assembler(Op.Op_get_name(), words)
+}
+
+func (Op Tmp) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Tmp) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return timer_resources(arch, op.Op_get_name(), 1, false)
+}
+
+func (op Tmp) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
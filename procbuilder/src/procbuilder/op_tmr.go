@@ -0,0 +1,123 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The Tmr opcode reads the cycles counter of the processor timer, shifted right by the immediate
+type Tmr struct{}
+
+func (op Tmr) Op_get_name() string {
+	return "tmr"
+}
+
+func (op Tmr) Op_get_desc() string {
+	return "Read the timer cycles counter"
+}
+
+func (op Tmr) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	result := "tmr [" + strconv.Itoa(int(arch.R)) + "(Reg)] [" + strconv.Itoa(Timer_shift_bits) + "(Shift)]	// Set the register to the cycles counter shifted right [" + strconv.Itoa(opbits+int(arch.R)+Timer_shift_bits) + "]\n"
+	return result
+}
+
+func (op Tmr) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	return opbits + int(arch.R) + Timer_shift_bits // The bits for the opcode + bits for a register + bits for the shift
+}
+
+func (op Tmr) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	if timer_generator(arch, op.Op_get_name()) {
+		return timer_verilog_header(arch)
+	}
+	return ""
+}
+
+func (Op Tmr) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	if timer_generator(arch, Op.Op_get_name()) {
+		return timer_verilog_reset(arch)
+	}
+	return ""
+}
+
+func (Op Tmr) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Tmr) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	if timer_generator(arch, Op.Op_get_name()) {
+		return timer_verilog_default_state(arch)
+	}
+	return ""
+}
+
+func (op Tmr) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	result := ""
+	result += "					TMR: begin\n"
+	result += timer_verilog_read(arch)
+	result += "						$display(\"TMR\");\n"
+	result += "					end\n"
+	return result
+}
+
+func (op Tmr) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Tmr) Assembler(arch *Arch, words []string) (string, error) {
+	return timer_assembler(arch, op.Op_get_name(), words)
+}
+
+func (op Tmr) Disassembler(arch *Arch, instr string) (string, error) {
+	return timer_disassembler(arch, instr)
+}
+
+func (op Tmr) Simulate(vm *VM, instr string) error {
+	reg_bits := vm.Mach.R
+	reg := get_id(instr[:reg_bits])
+	shift := uint(get_id(instr[reg_bits : int(reg_bits)+Timer_shift_bits]))
+	vm.Registers[reg] = vm.word(int(vm.Timer.Cycles >> shift))
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+func (op Tmr) Generate(arch *Arch) string {
+	return timer_generate(arch)
+}
+
+func (op Tmr) Required_shared() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Tmr) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Tmr) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Tmr) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Tmr) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	return timer_abstract_assembler(Op.Op_get_name(), words)
+}
+
+func (Op Tmr) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Tmr) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return timer_resources(arch, op.Op_get_name(), 0, true)
+}
+
+func (op Tmr) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
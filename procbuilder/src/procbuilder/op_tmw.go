@@ -0,0 +1,142 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The Tmw opcode waits for the expiration of the processor timer and consumes it
+type Tmw struct{}
+
+func (op Tmw) Op_get_name() string {
+	return "tmw"
+}
+
+func (op Tmw) Op_get_desc() string {
+	return "Wait for the timer"
+}
+
+func (op Tmw) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	result := "tmw [" + strconv.Itoa(opbits) + "]	// Wait for the timer expiration [" + strconv.Itoa(opbits) + "]\n"
+	return result
+}
+
+func (op Tmw) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	return opbits
+}
+
+func (op Tmw) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	if timer_generator(arch, op.Op_get_name()) {
+		return timer_verilog_header(arch)
+	}
+	return ""
+}
+
+func (Op Tmw) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	if timer_generator(arch, Op.Op_get_name()) {
+		return timer_verilog_reset(arch)
+	}
+	return ""
+}
+
+func (Op Tmw) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Tmw) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	if timer_generator(arch, Op.Op_get_name()) {
+		return timer_verilog_default_state(arch)
+	}
+	return ""
+}
+
+func (op Tmw) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	result := ""
+	result += "					TMW: begin\n"
+	result += "						if (tm_expired)\n"
+	result += "						begin\n"
+	result += "							tm_expired <= #1 1'b0;\n"
+	result += "							_pc <= #1 _pc + 1'b1;\n"
+	result += "						end\n"
+	result += "						$display(\"TMW\");\n"
+	result += "					end\n"
+	return result
+}
+
+func (op Tmw) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Tmw) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	rom_word := arch.Max_word()
+
+	if len(words) != 0 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	for i := opbits; i < rom_word; i++ {
+		result += "0"
+	}
+	return result, nil
+}
+
+func (op Tmw) Disassembler(arch *Arch, instr string) (string, error) {
+	return "", nil
+}
+
+func (op Tmw) Simulate(vm *VM, instr string) error {
+	if vm.Timer_wait() {
+		vm.Pc = vm.Pc + 1
+	} else {
+		vm.waiting = STALL_TIMER
+	}
+	return nil
+}
+
+// The random genaration does nothing
+func (op Tmw) Generate(arch *Arch) string {
+	return ""
+}
+
+func (op Tmw) Required_shared() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Tmw) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Tmw) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Tmw) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Tmw) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	result := make([]UsageNotify, 1)
+	newnot := UsageNotify{C_OPCODE, "tmw", I_NIL}
+	result[0] = newnot
+	return result, nil
+}
+
+func (Op Tmw) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Tmw) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return timer_resources(arch, op.Op_get_name(), 0, false)
+}
+
+func (op Tmw) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -0,0 +1,168 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The Unlock opcode drops the request to a mutex, the shared object passes it to the next processor.
+type Unlock struct{}
+
+func (op Unlock) Op_get_name() string {
+	return "unlock"
+}
+
+func (op Unlock) Op_get_desc() string {
+	return "Unlock a mutex SO"
+}
+
+func (op Unlock) Op_show_assembler(arch *Arch) string {
+	opbits := arch.Opcodes_bits()
+	mxbits := arch.Shared_bits("mutex")
+	result := "unlock [" + strconv.Itoa(mxbits) + "(Mutex)]	// Unlock a mutex SO [" + strconv.Itoa(opbits+mxbits) + "]\n"
+	return result
+}
+
+func (op Unlock) Op_get_instruction_len(arch *Arch) int {
+	opbits := arch.Opcodes_bits()
+	mxbits := arch.Shared_bits("mutex")
+	return opbits + mxbits // The bits for the opcode + bits for the mutex id
+}
+
+func (op Unlock) Op_instruction_verilog_header(conf *Config, arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Unlock) Op_instruction_verilog_reset(arch *Arch, flavor string) string {
+	result := ""
+
+	setflag := true
+	for _, currop := range arch.Op {
+		if currop.Op_get_name() == "lock" {
+			setflag = false
+			break
+		} else if currop.Op_get_name() == "unlock" {
+			break
+		}
+	}
+	if setflag && arch.Shared_num("mutex") > 0 {
+		result += "\t\t\tmx_req_i <= #1 'b0;\n"
+	}
+
+	return result
+}
+
+func (Op Unlock) Op_instruction_verilog_internal_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (Op Unlock) Op_instruction_verilog_default_state(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Unlock) Op_instruction_verilog_state_machine(arch *Arch, flavor string) string {
+	mxbits := arch.Shared_bits("mutex")
+	mx := rom_field(arch, 0, mxbits)
+
+	result := ""
+	result += "					UNLOCK: begin\n"
+	if arch.Shared_num("mutex") > 0 {
+		result += "						mx_req_i[" + mx + "] <= #1 1'b0;\n"
+		result += "						$display(\"UNLOCK MX\", " + mx + ");\n"
+	} else {
+		result += "						$display(\"NOP\");\n"
+	}
+	result += "						_pc <= #1 _pc + 1'b1;\n"
+	result += "					end\n"
+	return result
+}
+
+func (op Unlock) Op_instruction_verilog_footer(arch *Arch, flavor string) string {
+	return ""
+}
+
+func (op Unlock) Assembler(arch *Arch, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	mxso := Mutex{}
+	mxnum := arch.Shared_num(mxso.Shr_get_name())
+	mxbits := arch.Shared_bits(mxso.Shr_get_name())
+	rom_word := arch.Max_word()
+
+	if len(words) != 1 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	if partial, err := Process_shared(mxso.Shortname(), words[0], mxnum); err == nil {
+		result += zeros_prefix(mxbits, partial)
+	} else {
+		return "", Prerror{err.Error()}
+	}
+
+	for i := opbits + mxbits; i < rom_word; i++ {
+		result += "0"
+	}
+
+	return result, nil
+}
+
+func (op Unlock) Disassembler(arch *Arch, instr string) (string, error) {
+	mxso := Mutex{}
+	mxbits := arch.Shared_bits(mxso.Shr_get_name())
+	mx_id := get_id(instr[:mxbits])
+	result := mxso.Shortname() + strconv.Itoa(mx_id)
+	return result, nil
+}
+
+// Unlocking a mutex held by another processor does nothing
+func (op Unlock) Simulate(vm *VM, instr string) error {
+	mxbits := vm.Mach.Shared_bits("mutex")
+	mx := get_id(instr[:mxbits])
+	if _, err := vm.Sync_lock(mx, false); err != nil {
+		return err
+	}
+	vm.Pc = vm.Pc + 1
+	return nil
+}
+
+// The random genaration does nothing
+func (op Unlock) Generate(arch *Arch) string {
+	return ""
+}
+
+func (op Unlock) Required_shared() (bool, []string) {
+	return true, []string{"mutex"}
+}
+
+func (op Unlock) Required_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (op Unlock) Forbidden_modes() (bool, []string) {
+	return false, []string{}
+}
+
+func (Op Unlock) Op_instruction_verilog_extra_modules(arch *Arch, flavor string) ([]string, []string) {
+	return []string{}, []string{}
+}
+
+func (Op Unlock) Abstract_Assembler(arch *Arch, words []string) ([]UsageNotify, error) {
+	result := make([]UsageNotify, 0)
+	return result, nil
+}
+
+func (Op Unlock) Op_instruction_verilog_extra_block(arch *Arch, flavor string, level uint8, blockname string, objects []string) string {
+	result := ""
+	switch blockname {
+	default:
+		result = ""
+	}
+	return result
+}
+
+func (op Unlock) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Handshake_cost(arch)
+}
+
+func (op Unlock) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
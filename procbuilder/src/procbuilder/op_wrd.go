@@ -189,12 +189,12 @@ func (op Wrd) Disassembler(arch *Arch, instr string) (string, error) {
 }
 
 func (op Wrd) Simulate(vm *VM, instr string) error {
-	//TODO
-	outbits := vm.Mach.Outputs_bits()
-	reg_bits := vm.Mach.R
+	chso := Channel{}
+	chanbits := vm.Mach.Shared_bits(chso.Shr_get_name())
+	reg_bits := int(vm.Mach.R)
 	reg := get_id(instr[:reg_bits])
-	inp := get_id(instr[reg_bits : int(reg_bits)+outbits])
-	vm.Outputs[inp] = vm.Registers[reg]
+	ch := get_id(instr[reg_bits : reg_bits+chanbits])
+	vm.Channel_ops = append(vm.Channel_ops, Channel_op{Channel: ch, Reg: reg, Write: false})
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -285,3 +285,12 @@ func (Op Wrd) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Wrd) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 0, true).Chain(Handshake_cost(arch))
+}
+
+func (op Wrd) Op_instruction_latency(arch *Arch) (int, string) {
+	// The request and the channel acknowledge
+	return 2, STALL_CHANNEL
+}
@@ -238,12 +238,12 @@ func (op Wwr) Disassembler(arch *Arch, instr string) (string, error) {
 }
 
 func (op Wwr) Simulate(vm *VM, instr string) error {
-	//TODO
-	outbits := vm.Mach.Outputs_bits()
-	reg_bits := vm.Mach.R
+	chso := Channel{}
+	chanbits := vm.Mach.Shared_bits(chso.Shr_get_name())
+	reg_bits := int(vm.Mach.R)
 	reg := get_id(instr[:reg_bits])
-	inp := get_id(instr[reg_bits : int(reg_bits)+outbits])
-	vm.Outputs[inp] = vm.Registers[reg]
+	ch := get_id(instr[reg_bits : reg_bits+chanbits])
+	vm.Channel_ops = append(vm.Channel_ops, Channel_op{Channel: ch, Reg: reg, Write: true})
 	vm.Pc = vm.Pc + 1
 	return nil
 }
@@ -286,3 +286,11 @@ func (Op Wwr) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Wwr) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 1, false).Chain(Handshake_cost(arch))
+}
+
+func (op Wwr) Op_instruction_latency(arch *Arch) (int, string) {
+	return 2, STALL_CHANNEL
+}
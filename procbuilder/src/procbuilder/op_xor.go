@@ -212,3 +212,11 @@ func (Op Xor) Op_instruction_verilog_extra_block(arch *Arch, flavor string, leve
 	}
 	return result
 }
+
+func (op Xor) Op_instruction_resources(arch *Arch, flavor string) Resources {
+	return Regfile_cost(arch, 2, true).Chain(Logic_cost(arch))
+}
+
+func (op Xor) Op_instruction_latency(arch *Arch) (int, string) {
+	return single_cycle()
+}
@@ -0,0 +1,212 @@
+package procbuilder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Policies for the generated files that are already present in the output directory
+const (
+	OUT_FAIL  = uint8(0) + iota // Stop if an existing file would change (unchanged files are fine)
+	OUT_KEEP                    // Keep the existing files, they are reported as stale in the manifest if different
+	OUT_FORCE                   // Overwrite the existing files
+)
+
+const (
+	MANIFEST_FILE = "manifest.json"
+	IVERILOG_FILE = "iverilog.f"
+	VIVADO_FILE   = "vivado.tcl"
+)
+
+// Toolchains with a project file written with the manifest
+const (
+	TOOLCHAIN_VIVADO  = "vivado"
+	TOOLCHAIN_QUARTUS = "quartus"
+)
+
+type Output_file struct {
+	Name   string
+	Sha256 string
+	Status string // written, unchanged, kept or stale
+}
+
+type Output struct {
+	Dir    string
+	Policy uint8
+	Files  []Output_file
+}
+
+// The design the manifest describes, the toolchain and its part are empty for simulation only outputs
+type Target struct {
+	Top       string
+	Toolchain string // vivado, quartus, icestorm or trellis
+	Part      string
+	Family    string // The device family, needed by quartus
+}
+
+type Manifest struct {
+	Top       string
+	Toolchain string
+	Part      string
+	Files     []Output_file
+}
+
+func Output_policy(force bool, keep bool) (uint8, error) {
+	if force && keep {
+		return OUT_FAIL, Prerror{"Force and keep are mutually exclusive"}
+	}
+	if force {
+		return OUT_FORCE, nil
+	}
+	if keep {
+		return OUT_KEEP, nil
+	}
+	return OUT_FAIL, nil
+}
+
+func (out *Output) Init() error {
+	if out.Dir == "" {
+		out.Dir = "."
+	}
+	out.Files = make([]Output_file, 0)
+	return os.MkdirAll(out.Dir, 0755)
+}
+
+func content_hash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// Write creates a file in the output directory following the overwrite policy
+func (out *Output) Write(name string, content string) error {
+	filename := filepath.Join(out.Dir, name)
+	newhash := content_hash([]byte(content))
+	status := "written"
+
+	if old, err := ioutil.ReadFile(filename); err == nil {
+		oldhash := content_hash(old)
+		if oldhash == newhash {
+			status = "unchanged"
+		} else {
+			switch out.Policy {
+			case OUT_FAIL:
+				return Prerror{filename + " already exists with a different content, use force or keep"}
+			case OUT_KEEP:
+				status = "stale"
+				newhash = oldhash
+			}
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if status == "written" {
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	for i, file := range out.Files {
+		if file.Name == name {
+			out.Files[i] = Output_file{name, newhash, status}
+			return nil
+		}
+	}
+	out.Files = append(out.Files, Output_file{name, newhash, status})
+	return nil
+}
+
+// Stale returns the files kept with a content different from the generated one
+func (out *Output) Stale() []string {
+	result := make([]string, 0)
+	for _, file := range out.Files {
+		if file.Status == "stale" {
+			result = append(result, file.Name)
+		}
+	}
+	return result
+}
+
+// Write_manifest writes the list of the generated files with their hashes and the tools project files.
+// The Vivado script and the Quartus project are created for the respective toolchains if the FPGA part is known,
+// the other toolchains have no project file.
+func (out *Output) Write_manifest(target Target) error {
+	sources := make([]string, 0)
+	constraints := make(map[string][]string)
+	for _, file := range out.Files {
+		switch ext := filepath.Ext(file.Name); ext {
+		case ".v":
+			sources = append(sources, file.Name)
+		case ".xdc", ".sdc", ".tcl":
+			if file.Name != VIVADO_FILE {
+				constraints[ext] = append(constraints[ext], file.Name)
+			}
+		}
+	}
+	sort.Strings(sources)
+	for _, files := range constraints {
+		sort.Strings(files)
+	}
+
+	if err := out.Write(IVERILOG_FILE, strings.Join(sources, "\n")+"\n"); err != nil {
+		return err
+	}
+
+	if target.Part != "" {
+		switch target.Toolchain {
+		case TOOLCHAIN_VIVADO:
+			tcl := "create_project -force " + target.Top + " ./vivado -part " + target.Part + "\n"
+			tcl += "add_files {" + strings.Join(sources, " ") + "}\n"
+			if len(constraints[".xdc"]) > 0 {
+				tcl += "add_files -fileset constrs_1 {" + strings.Join(constraints[".xdc"], " ") + "}\n"
+			}
+			tcl += "set_property top " + target.Top + " [current_fileset]\n"
+			tcl += "update_compile_order -fileset sources_1\n"
+			if err := out.Write(VIVADO_FILE, tcl); err != nil {
+				return err
+			}
+		case TOOLCHAIN_QUARTUS:
+			// The project revision is named after the top module, the pins are sourced from the constraints scripts
+			qpf := "PROJECT_REVISION = \"" + target.Top + "\"\n"
+			qsf := ""
+			if target.Family != "" {
+				qsf += "set_global_assignment -name FAMILY \"" + target.Family + "\"\n"
+			}
+			qsf += "set_global_assignment -name DEVICE " + target.Part + "\n"
+			qsf += "set_global_assignment -name TOP_LEVEL_ENTITY " + target.Top + "\n"
+			for _, source := range sources {
+				qsf += "set_global_assignment -name VERILOG_FILE " + source + "\n"
+			}
+			for _, sdc := range constraints[".sdc"] {
+				qsf += "set_global_assignment -name SDC_FILE " + sdc + "\n"
+			}
+			for _, tcl := range constraints[".tcl"] {
+				qsf += "set_global_assignment -name SOURCE_TCL_SCRIPT_FILE " + tcl + "\n"
+			}
+			if err := out.Write(target.Top+".qpf", qpf); err != nil {
+				return err
+			}
+			if err := out.Write(target.Top+".qsf", qsf); err != nil {
+				return err
+			}
+		}
+	}
+
+	files := make([]Output_file, len(out.Files))
+	copy(files, out.Files)
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+	b, err := json.MarshalIndent(Manifest{target.Top, target.Toolchain, target.Part, files}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// The manifest is always rewritten
+	return ioutil.WriteFile(filepath.Join(out.Dir, MANIFEST_FILE), append(b, '\n'), 0644)
+}
@@ -0,0 +1,90 @@
+package procbuilder
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestOutputPolicies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "procbuilder_output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := &Output{Dir: dir, Policy: OUT_FAIL}
+	if err := out.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Write("p0.v", "module p0;\nendmodule\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := out.Write("p0.v", "module p0;\nendmodule\n"); err != nil {
+		t.Error("Unchanged file refused", err)
+	}
+	if err := out.Write("p0.v", "module p1;\nendmodule\n"); err == nil {
+		t.Error("Changed file overwritten with the fail policy")
+	}
+
+	out.Policy = OUT_KEEP
+	if err := out.Write("p0.v", "module p1;\nendmodule\n"); err != nil || len(out.Stale()) != 1 {
+		t.Error("Changed file not kept as stale")
+	}
+
+	out.Policy = OUT_FORCE
+	if err := out.Write("p0.v", "module p1;\nendmodule\n"); err != nil || len(out.Stale()) != 0 {
+		t.Error("Changed file not overwritten with the force policy")
+	}
+
+	if err := out.Write_manifest(Target{Top: "p0"}); err != nil {
+		t.Fatal(err)
+	}
+	manifest, _ := ioutil.ReadFile(dir + "/" + MANIFEST_FILE)
+	fmt.Print(string(manifest))
+}
+
+// Each toolchain gets its own project file, referencing its constraints
+func TestManifestProjects(t *testing.T) {
+	dir, err := ioutil.TempDir("", "procbuilder_manifest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := &Output{Dir: dir, Policy: OUT_FORCE}
+	if err := out.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"top.v", "p0.v", "top.sdc", "top_pins.tcl", "top.xdc"} {
+		if err := out.Write(name, "\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := out.Write_manifest(Target{Top: "top", Toolchain: TOOLCHAIN_QUARTUS, Part: "5CSEBA6U23I7", Family: "Cyclone V"}); err != nil {
+		t.Fatal(err)
+	}
+	qsf, err := ioutil.ReadFile(dir + "/top.qsf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Print(string(qsf))
+	for _, line := range []string{"VERILOG_FILE p0.v\n", "SDC_FILE top.sdc\n", "SOURCE_TCL_SCRIPT_FILE top_pins.tcl\n", "TOP_LEVEL_ENTITY top\n"} {
+		if !strings.Contains(string(qsf), line) {
+			t.Error("Quartus project without " + line)
+		}
+	}
+	if _, err := os.Stat(dir + "/" + VIVADO_FILE); err == nil {
+		t.Error("Vivado script written for a quartus board")
+	}
+
+	if err := out.Write_manifest(Target{Top: "top", Toolchain: TOOLCHAIN_VIVADO, Part: "xc7a35tcpg236-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if tcl, err := ioutil.ReadFile(dir + "/" + VIVADO_FILE); err != nil || !strings.Contains(string(tcl), "constrs_1 {top.xdc}") {
+		t.Error("Wrong vivado script", string(tcl), err)
+	}
+}
@@ -23,6 +23,8 @@ type Opcode interface {
 	Required_shared() (bool, []string)
 	Required_modes() (bool, []string)
 	Forbidden_modes() (bool, []string)
+	Op_instruction_resources(*Arch, string) Resources
+	Op_instruction_latency(*Arch) (int, string)
 }
 
 type Sharedel interface {
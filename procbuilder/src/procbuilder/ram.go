@@ -14,6 +14,12 @@ func (ram *Ram) Write_verilog(mach *Machine, ram_module_name string, flavor stri
 
 	result := ""
 
+	if mach.Execution_model() != "ha" {
+		// The data are in the unified memory generated along with the rom
+		result += "// " + ram_module_name + " is part of the unified memory of the " + mach.Execution_model() + " model\n"
+		return result
+	}
+
 	result += "`timescale 1ns/1ps\n"
 	result += "module " + ram_module_name + "(clk, rst, din, dout, addr, wren, en);\n"
 	result += "\n"
@@ -0,0 +1,66 @@
+package procbuilder
+
+import (
+	"sort"
+)
+
+// The registry of the opcodes and of the processor side of the shared objects. The built in ones are registered by
+// the package init, a package providing its own opcodes or shared objects registers them from its init as well and
+// they become available to the machines, the assemblers and the command line tools.
+
+// Register_opcode adds an opcode to Allopcodes, the list is kept ordered by name and the names have to be unique
+func Register_opcode(op Opcode) error {
+	name := op.Op_get_name()
+	i := sort.Search(len(Allopcodes), func(i int) bool { return Allopcodes[i].Op_get_name() >= name })
+	if i < len(Allopcodes) && Allopcodes[i].Op_get_name() == name {
+		return Prerror{"Opcode " + name + " already registered"}
+	}
+	Allopcodes = append(Allopcodes, nil)
+	copy(Allopcodes[i+1:], Allopcodes[i:])
+	Allopcodes[i] = op
+	return nil
+}
+
+// Register_shared adds a shared object to Allshared, the names have to be unique
+func Register_shared(so Sharedel) error {
+	if _, ok := Get_shared(so.Shr_get_name()); ok {
+		return Prerror{"Shared object " + so.Shr_get_name() + " already registered"}
+	}
+	Allshared = append(Allshared, so)
+	return nil
+}
+
+func Get_opcode(name string) (Opcode, bool) {
+	for _, op := range Allopcodes {
+		if op.Op_get_name() == name {
+			return op, true
+		}
+	}
+	return nil, false
+}
+
+func Get_shared(name string) (Sharedel, bool) {
+	for _, so := range Allshared {
+		if so.Shr_get_name() == name {
+			return so, true
+		}
+	}
+	return nil, false
+}
+
+// The registered names, the opcodes are in alphabetical order and the shared objects in registration order
+func Opcode_names() []string {
+	result := make([]string, len(Allopcodes))
+	for i, op := range Allopcodes {
+		result[i] = op.Op_get_name()
+	}
+	return result
+}
+
+func Shared_names() []string {
+	result := make([]string, len(Allshared))
+	for i, so := range Allshared {
+		result[i] = so.Shr_get_name()
+	}
+	return result
+}
@@ -0,0 +1,44 @@
+package procbuilder
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// An opcode provided by another package, here a nop under a different name
+type custom_nop struct {
+	Nop
+}
+
+func (op custom_nop) Op_get_name() string {
+	return "cnop"
+}
+
+func TestRegistry(t *testing.T) {
+	saved := append([]Opcode{}, Allopcodes...)
+	defer func() { Allopcodes = saved }()
+
+	if err := Register_opcode(custom_nop{}); err != nil {
+		t.Error(err)
+	}
+	if err := Register_opcode(custom_nop{}); err == nil {
+		t.Error("Duplicate opcode accepted")
+	}
+	if err := Register_opcode(Add{}); err == nil {
+		t.Error("Duplicate built in opcode accepted")
+	}
+
+	names := Opcode_names()
+	fmt.Println(names)
+	if !sort.StringsAreSorted(names) {
+		t.Error("Opcodes not ordered by name")
+	}
+	if op, ok := Get_opcode("cnop"); !ok || op.Op_get_name() != "cnop" {
+		t.Error("Registered opcode not found")
+	}
+
+	if err := Register_shared(Channel{}); err == nil {
+		t.Error("Duplicate shared object accepted")
+	}
+}
@@ -0,0 +1,259 @@
+package procbuilder
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Resources estimation. Every opcode supplies the cost of its own datapath (operands selection included),
+// the machine adds the register file, the memories and the control logic. The figures are meant to compare
+// architectures with each other, not to replace the synthesis reports.
+
+// Estimated FPGA resources
+type Resources struct {
+	Luts   int
+	Ffs    int
+	Brams  int // 18Kb blocks, or the equivalent memory block of the device
+	Dsps   int
+	Levels int // Logic levels of the longest combinational path
+}
+
+// The device family the estimation is made for
+type Flavor_model struct {
+	Name        string
+	Level_delay float64 // ns per logic level, routing included
+	Base_delay  float64 // ns of clock to output, setup and the state machine decode
+	Dsp_width   int     // Width of the hardwired multipliers, 0 if they are built with LUTs
+	Bram_bits   int     // Bits of a memory block
+	Lutram_bits int     // Bits of distributed memory per LUT, 0 if not available
+}
+
+var Flavor_models = map[string]Flavor_model{
+	"iverilog": {"iverilog", 0.5, 3.5, 18, 18432, 64},
+	"kintex7":  {"kintex7", 0.35, 2.5, 18, 18432, 64},
+	"basys3":   {"basys3", 0.5, 3.5, 18, 18432, 64},
+	"de10nano": {"de10nano", 0.55, 4.0, 18, 10240, 32},
+}
+
+// A named contribution to the resources of a design
+type Resource_part struct {
+	Name string
+	Resources
+}
+
+type Resource_report struct {
+	Flavor     string
+	Total      Resources
+	Parts      []Resource_part
+	Fmax_mhz   int
+	Fmax_class string
+}
+
+// The model of a flavor, simulation flavors and unknown ones use the iverilog (generic) model
+func Get_flavor_model(flavor string) Flavor_model {
+	if model, ok := Flavor_models[flavor]; ok {
+		return model
+	}
+	for name, model := range Flavor_models {
+		if flavor == name+"_simulation" {
+			return model
+		}
+	}
+	return Flavor_models["iverilog"]
+}
+
+// Serial composition, the logic levels add up
+func (r Resources) Chain(o Resources) Resources {
+	return Resources{r.Luts + o.Luts, r.Ffs + o.Ffs, r.Brams + o.Brams, r.Dsps + o.Dsps, r.Levels + o.Levels}
+}
+
+// Parallel composition, the longest path wins
+func (r Resources) Merge(o Resources) Resources {
+	result := r.Chain(o)
+	result.Levels = r.Levels
+	if o.Levels > r.Levels {
+		result.Levels = o.Levels
+	}
+	return result
+}
+
+func (r Resources) String() string {
+	return fmt.Sprintf("LUTs: %d, FFs: %d, BRAMs: %d, DSPs: %d, logic levels: %d", r.Luts, r.Ffs, r.Brams, r.Dsps, r.Levels)
+}
+
+func ceil_div(a int, b int) int {
+	return (a + b - 1) / b
+}
+
+// The number of 4:1 levels needed to select among n inputs
+func mux_levels(n int) int {
+	levels := 0
+	for served := 1; served < n; served *= 4 {
+		levels++
+	}
+	return levels
+}
+
+// A n:1 multiplexer on width bits, made of 6 inputs LUTs (a 4:1 mux each)
+func Mux_cost(n int, width int) Resources {
+	if n <= 1 {
+		return Resources{}
+	}
+	return Resources{Luts: width * ceil_div(n-1, 3), Levels: mux_levels(n)}
+}
+
+// Reading reads registers and, if write is set, writing the result back to the register file
+func Regfile_cost(arch *Arch, reads int, write bool) Resources {
+	result := Resources{}
+	rsize := int(arch.Rsize)
+	for i := 0; i < reads; i++ {
+		result = result.Merge(Mux_cost(1<<arch.R, rsize))
+	}
+	if write {
+		// Write enables decode and one more input on the registers next value mux
+		result = result.Chain(Resources{Luts: (1 << arch.R) + rsize, Levels: 1})
+	}
+	return result
+}
+
+// Rsize bits adder, subtractor or comparator on the carry chain
+func Adder_cost(arch *Arch) Resources {
+	rsize := int(arch.Rsize)
+	return Resources{Luts: rsize, Levels: 1 + rsize/16}
+}
+
+// Bitwise logic, one LUT per bit
+func Logic_cost(arch *Arch) Resources {
+	return Resources{Luts: int(arch.Rsize), Levels: 1}
+}
+
+// Rsize x Rsize multiplier, on the DSP blocks when available
+func Multiplier_cost(arch *Arch, flavor string) Resources {
+	rsize := int(arch.Rsize)
+	model := Get_flavor_model(flavor)
+	if model.Dsp_width == 0 {
+		return Resources{Luts: rsize * rsize, Levels: 2 * mux_levels(rsize) * (1 + rsize/16)}
+	}
+	blocks := ceil_div(rsize, model.Dsp_width)
+	result := Resources{Dsps: blocks * blocks, Levels: 3 * blocks}
+	if blocks > 1 {
+		// Partial products sum
+		result.Luts = 2 * rsize * blocks
+	}
+	return result
+}
+
+// Rsize bits combinational divider (quotient or remainder), one subtractor per bit
+func Divider_cost(arch *Arch) Resources {
+	rsize := int(arch.Rsize)
+	return Resources{Luts: rsize * (rsize + 2), Levels: rsize * (1 + rsize/16)}
+}
+
+// Floating point units, they are only meaningful with 32 bits registers
+func Float_cost(arch *Arch, flavor string, operation string) Resources {
+	model := Get_flavor_model(flavor)
+	switch operation {
+	case "add":
+		return Resources{Luts: 430, Levels: 14}
+	case "mult":
+		if model.Dsp_width == 0 {
+			return Resources{Luts: 24*24 + 150, Levels: 20}
+		}
+		return Resources{Luts: 150, Dsps: 2, Levels: 10}
+	case "div":
+		return Resources{Luts: 900, Levels: 40}
+	}
+	return Resources{}
+}
+
+// Loading the program counter
+func Jump_cost(arch *Arch) Resources {
+	return Resources{Luts: int(arch.O), Levels: 1}
+}
+
+// The handshake with a shared object, a few control signals and the data port
+func Handshake_cost(arch *Arch) Resources {
+	return Resources{Luts: int(arch.Rsize) + 8, Ffs: 4, Levels: 2}
+}
+
+// A memory of the given size, on memory blocks if big enough, otherwise on distributed memory or flip-flops
+func Memory_cost(words int, width int, flavor string) Resources {
+	model := Get_flavor_model(flavor)
+	bits := words * width
+	if bits == 0 {
+		return Resources{}
+	}
+	if bits >= model.Bram_bits/4 || model.Lutram_bits == 0 && bits > 1024 {
+		return Resources{Brams: ceil_div(bits, model.Bram_bits), Levels: 1}
+	}
+	if model.Lutram_bits == 0 {
+		return Resources{Ffs: bits}.Chain(Mux_cost(words, width))
+	}
+	return Resources{Luts: ceil_div(bits, model.Lutram_bits)}.Chain(Mux_cost(ceil_div(words, model.Lutram_bits), width))
+}
+
+// Estimated frequency for a number of logic levels
+func Fmax(flavor string, levels int) int {
+	model := Get_flavor_model(flavor)
+	return int(math.Floor(1000 / (model.Base_delay + float64(levels)*model.Level_delay)))
+}
+
+func Fmax_class(mhz int) string {
+	switch {
+	case mhz >= 250:
+		return "250+ MHz"
+	case mhz >= 150:
+		return "150-250 MHz"
+	case mhz >= 80:
+		return "80-150 MHz"
+	}
+	return "<80 MHz"
+}
+
+// Add_part adds a contribution to the report
+func (r *Resource_report) Add_part(name string, res Resources) {
+	r.Parts = append(r.Parts, Resource_part{name, res})
+	r.Total = r.Total.Merge(res)
+	r.Fmax_mhz = Fmax(r.Flavor, r.Total.Levels)
+	r.Fmax_class = Fmax_class(r.Fmax_mhz)
+}
+
+func (r *Resource_report) String() string {
+	result := "Estimated resources (" + r.Flavor + ")\n"
+	parts := make([]Resource_part, len(r.Parts))
+	copy(parts, r.Parts)
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].Luts > parts[j].Luts })
+	result += fmt.Sprintf("\t%-20s %8s %8s %6s %6s %7s\n", "", "LUTs", "FFs", "BRAMs", "DSPs", "Levels")
+	for _, part := range parts {
+		result += fmt.Sprintf("\t%-20s %8d %8d %6d %6d %7d\n", part.Name, part.Luts, part.Ffs, part.Brams, part.Dsps, part.Levels)
+	}
+	result += fmt.Sprintf("\t%-20s %8d %8d %6d %6d %7d\n", "Total", r.Total.Luts, r.Total.Ffs, r.Total.Brams, r.Total.Dsps, r.Total.Levels)
+	result += fmt.Sprintf("Expected Fmax: %d MHz (%s)\n", r.Fmax_mhz, r.Fmax_class)
+	return result
+}
+
+// Estimate_resources walks the architecture opcodes and objects and estimates the resources it uses on the given flavor
+func (arch *Arch) Estimate_resources(flavor string) *Resource_report {
+	report := &Resource_report{Flavor: Get_flavor_model(flavor).Name, Parts: make([]Resource_part, 0)}
+	rsize := int(arch.Rsize)
+	// Program counter, instruction decode and the processor state machine
+	control := Resources{Ffs: int(arch.O) + 4}.Merge(Mux_cost(len(arch.Op), 1)).Chain(Resources{Luts: int(arch.O) + len(arch.Op), Levels: 1})
+	report.Add_part("control", control)
+
+	report.Add_part("registers", Resources{Ffs: (1 << arch.R) * rsize})
+	if arch.N > 0 || arch.M > 0 {
+		report.Add_part("io", Resources{Ffs: int(arch.M) * rsize}.Merge(Mux_cost(int(arch.N), rsize)))
+	}
+
+	report.Add_part("rom", Memory_cost(1<<arch.O, arch.Max_word(), flavor))
+	if arch.L > 0 {
+		report.Add_part("ram", Memory_cost(1<<arch.L, rsize, flavor))
+	}
+
+	for _, op := range arch.Op {
+		report.Add_part(op.Op_get_name(), op.Op_instruction_resources(arch, flavor))
+	}
+
+	return report
+}
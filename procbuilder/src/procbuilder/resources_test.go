@@ -0,0 +1,37 @@
+package procbuilder
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEstimateResources(t *testing.T) {
+	mach := model_machine("ha")
+	base := mach.Estimate_resources("basys3")
+	fmt.Print(base)
+
+	if base.Total.Ffs < (1<<mach.R)*int(mach.Rsize) {
+		t.Error("Registers not counted")
+	}
+
+	for _, op := range Allopcodes {
+		if op.Op_get_name() == "div" || op.Op_get_name() == "mult" {
+			mach.Op = append(mach.Op, op)
+		}
+	}
+	ext := mach.Estimate_resources("basys3")
+	fmt.Print(ext)
+
+	if ext.Total.Luts <= base.Total.Luts {
+		t.Error("The divider costs nothing")
+	}
+	if ext.Total.Dsps != 1 {
+		t.Error("The 16 bits multiplier has to use a DSP")
+	}
+	if ext.Fmax_mhz >= base.Fmax_mhz {
+		t.Error("The divider is not on the critical path")
+	}
+	if fast := mach.Estimate_resources("kintex7"); fast.Fmax_mhz <= ext.Fmax_mhz {
+		t.Error("Kintex7 has to be faster than basys3")
+	}
+}
@@ -14,6 +14,10 @@ func (rom *Rom) String() string {
 }
 
 func (rom *Rom) Write_verilog(mach *Machine, rom_module_name string, flavor string) string {
+	if mach.Execution_model() != "ha" {
+		return rom.write_verilog_memory(mach, rom_module_name, flavor)
+	}
+
 	rom_word := mach.Max_word()
 
 	result := ""
@@ -36,3 +40,121 @@ func (rom *Rom) Write_verilog(mach *Machine, rom_module_name string, flavor stri
 
 	return result
 }
+
+// The unified memory of the vn and hy models, it has the program port of the rom and the data port of the ram.
+// The program survives the reset. A data write shifts the word in at the low bits of a program cell, so an instruction
+// wider than a register is patched writing its words the most significant first, data reads get the low bits.
+func (rom *Rom) write_verilog_memory(mach *Machine, rom_module_name string, flavor string) string {
+	rom_word := mach.Max_word()
+	rsize := int(mach.Rsize)
+	rom_depth := 1 << rom.O
+	ram_depth := 1 << mach.L
+	win := mach.Window_size()
+
+	result := ""
+
+	result += "`timescale 1ns/1ps\n"
+	result += "module " + rom_module_name + "(clk, rst, rom_bus, rom_value, din, dout, addr, wren, en);\n"
+	result += "\n"
+	result += "\tinput clk;\n"
+	result += "\tinput rst;\n"
+	result += "\tinput [" + strconv.Itoa(int(rom.O)-1) + ":0] rom_bus;\n"
+	result += "\toutput [" + strconv.Itoa(rom_word-1) + ":0] rom_value;\n"
+	result += "\tinput [" + strconv.Itoa(int(mach.L)-1) + ":0] addr;\n"
+	result += "\tinput [" + strconv.Itoa(rsize-1) + ":0] din;\n"
+	result += "\tinput wren;\n"
+	result += "\tinput en;\n"
+	result += "\toutput [" + strconv.Itoa(rsize-1) + ":0] dout;\n"
+	result += "\n"
+	result += "\treg [" + strconv.Itoa(rsize-1) + ":0] dout_i;\n"
+
+	switch mach.Execution_model() {
+	case "vn":
+		result += "\treg [" + strconv.Itoa(rom_word-1) + ":0] mem [0:" + strconv.Itoa(rom_depth-1) + "];\n"
+		result += "\n"
+		result += "\tinitial\n"
+		result += "\tbegin\n"
+		for i, inst := range mach.Program.Slocs {
+			result += "\tmem[" + strconv.Itoa(i) + "] = " + strconv.Itoa(rom_word) + "'b" + inst + ";\n"
+		}
+		result += "\tend\n"
+		result += "\n"
+		result += "\talways @ (posedge clk)\n"
+		result += "\tbegin\n"
+		result += "\t\tif (!rst && wren)\n"
+		result += "\t\t\tmem[addr] <= #1 " + memory_shift_in("mem[addr]", rom_word, rsize) + ";\n"
+		result += "\t\tif (!wren)\n"
+		result += "\t\t\tdout_i <= #1 " + memory_low_word("mem[addr]", rom_word, rsize) + ";\n"
+		result += "\tend\n"
+		result += "\n"
+		result += "\tassign rom_value = mem[rom_bus];\n"
+	case "hy":
+		romwin := rom_depth - win
+		ramwin := ram_depth - win
+		result += "\treg [" + strconv.Itoa(rom_word-1) + ":0] _rom [0:" + strconv.Itoa(romwin-1) + "];\n"
+		result += "\treg [" + strconv.Itoa(rsize-1) + ":0] mem [0:" + strconv.Itoa(ramwin-1) + "];\n"
+		result += "\treg [" + strconv.Itoa(rom_word-1) + ":0] win [0:" + strconv.Itoa(win-1) + "];		// Shared window\n"
+		result += "\n"
+		result += "\tinitial\n"
+		result += "\tbegin\n"
+		for i, inst := range mach.Program.Slocs {
+			if i < romwin {
+				result += "\t_rom[" + strconv.Itoa(i) + "] = " + strconv.Itoa(rom_word) + "'b" + inst + ";\n"
+			} else {
+				result += "\twin[" + strconv.Itoa(i-romwin) + "] = " + strconv.Itoa(rom_word) + "'b" + inst + ";\n"
+			}
+		}
+		result += "\tend\n"
+		result += "\n"
+		result += "\talways @ (posedge clk)\n"
+		result += "\tbegin : MEM_WRITE\n"
+		result += "\t\tinteger k;\n"
+		result += "\t\tif (rst)\n"
+		result += "\t\tbegin\n"
+		result += "\t\t\tfor(k=0;k<" + strconv.Itoa(ramwin) + ";k=k+1)\n"
+		result += "\t\t\t\tmem[k] <= #1 " + strconv.Itoa(rsize) + "'b0;\n"
+		result += "\t\tend\n"
+		result += "\t\telse if (wren)\n"
+		result += "\t\tbegin\n"
+		result += "\t\t\tif (addr >= " + strconv.Itoa(ramwin) + ")\n"
+		result += "\t\t\t\twin[addr - " + strconv.Itoa(ramwin) + "] <= #1 " + memory_shift_in("win[addr - "+strconv.Itoa(ramwin)+"]", rom_word, rsize) + ";\n"
+		result += "\t\t\telse\n"
+		result += "\t\t\t\tmem[addr] <= #1 din;\n"
+		result += "\t\tend\n"
+		result += "\tend\n"
+		result += "\n"
+		result += "\talways @ (posedge clk)\n"
+		result += "\tbegin : MEM_READ\n"
+		result += "\t\tif (!wren)\n"
+		result += "\t\tbegin\n"
+		result += "\t\t\tif (addr >= " + strconv.Itoa(ramwin) + ")\n"
+		result += "\t\t\t\tdout_i <= #1 " + memory_low_word("win[addr - "+strconv.Itoa(ramwin)+"]", rom_word, rsize) + ";\n"
+		result += "\t\t\telse\n"
+		result += "\t\t\t\tdout_i <= #1 mem[addr];\n"
+		result += "\t\tend\n"
+		result += "\tend\n"
+		result += "\n"
+		result += "\tassign rom_value = (rom_bus >= " + strconv.Itoa(romwin) + ") ? win[rom_bus - " + strconv.Itoa(romwin) + "] : _rom[rom_bus];\n"
+	}
+
+	result += "\tassign dout = dout_i;\n"
+	result += "endmodule\n"
+
+	return result
+}
+
+// memory_shift_in is the new content of a program cell written with din
+func memory_shift_in(cell string, rom_word int, rsize int) string {
+	if rom_word > rsize {
+		return "{" + cell + "[" + strconv.Itoa(rom_word-rsize-1) + ":0], din}"
+	}
+	return "din[" + strconv.Itoa(rom_word-1) + ":0]"
+}
+
+// memory_low_word is the data word read from a program cell
+func memory_low_word(cell string, rom_word int, rsize int) string {
+	if rom_word > rsize {
+		return cell + "[" + strconv.Itoa(rsize-1) + ":0]"
+	}
+	return cell
+}
@@ -0,0 +1,72 @@
+package procbuilder
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// External tools used to check the generated RTL, they are used only if installed
+
+type Rtl_tool struct {
+	Name string
+	Cmd  string
+	Args func([]string, string) []string // The arguments given the file list and the top module
+}
+
+var Rtl_tools = []Rtl_tool{
+	{"iverilog", "iverilog", func(files []string, top string) []string {
+		return append([]string{"-g2005", "-t", "null", "-s", top}, files...)
+	}},
+	{"verilator", "verilator", func(files []string, top string) []string {
+		return append([]string{"--lint-only", "-Wno-fatal", "-Wno-lint", "-Wno-style", "--top-module", top}, files...)
+	}},
+	{"yosys", "yosys", func(files []string, top string) []string {
+		return []string{"-q", "-p", "read_verilog " + strings.Join(files, " ") + "; synth -top " + top}
+	}},
+}
+
+// Rtl_available_tools returns the tools found in the PATH
+func Rtl_available_tools() []Rtl_tool {
+	result := make([]Rtl_tool, 0)
+	for _, tool := range Rtl_tools {
+		if _, err := exec.LookPath(tool.Cmd); err == nil {
+			result = append(result, tool)
+		}
+	}
+	return result
+}
+
+// Rtl_check runs the tool on the files within dir, the output is returned along with an error if the tool failed
+func (tool Rtl_tool) Rtl_check(dir string, files []string, top string) (string, error) {
+	cmd := exec.Command(tool.Cmd, tool.Args(files, top)...)
+	cmd.Dir = dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), Prerror{tool.Name + " failed on " + top + ": " + err.Error()}
+	}
+	return string(output), nil
+}
+
+// Rtl_required_shared returns the shared objects an opcode needs to be instantiated in a processor
+func Rtl_required_shared(opname string) string {
+	switch opname {
+	case "chc", "chclose", "chw", "wrd", "wwr":
+		return "channel:"
+	case "r2s", "s2r":
+		return "sharedmem:8"
+	case "hit":
+		// The barrier number is its timeout, 0 is a barrier without timeout
+		return "barrier:0"
+	case "lfsr82r":
+		return "lfsr8:1"
+	case "lock", "unlock":
+		return "mutex:"
+	case "semacq", "semrel":
+		return "semaphore:1"
+	case "fadd", "fswp":
+		return "counter:"
+	case "lfsrn2r":
+		return "lfsrn:16:1"
+	}
+	return ""
+}
@@ -0,0 +1,110 @@
+package procbuilder
+
+import (
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+// Every opcode is generated within a small matrix of architectures, the result is checked with the external tools if present
+func TestRtlMatrix(t *testing.T) {
+	tools := Rtl_available_tools()
+
+	rsizes := []uint8{8, 16}
+	rbits := []uint8{1, 3}
+	modes := []string{"ha", "vn", "hy"}
+	// The ROM and RAM address bits, the memories sizes change the program counter and the memory addressing
+	memories := [][2]uint8{{4, 4}, {9, 2}, {3, 10}}
+	if testing.Short() {
+		rbits = []uint8{3}
+		modes = []string{"ha"}
+		memories = memories[:1]
+	}
+
+	for _, op := range Allopcodes {
+		for _, rsize := range rsizes {
+			for _, r := range rbits {
+				for _, mode := range modes {
+					for _, mem := range memories {
+						name := op.Op_get_name() + "_" + strconv.Itoa(int(rsize)) + "_" + strconv.Itoa(int(r)) + "_" + mode + "_" + strconv.Itoa(int(mem[0])) + "_" + strconv.Itoa(int(mem[1]))
+						rtl_matrix_case(t, tools, name, op.Op_get_name(), rsize, r, mode, mem[0], mem[1])
+					}
+				}
+			}
+		}
+	}
+
+	if len(tools) == 0 {
+		t.Skip("No RTL tools found, only the generation has been tested")
+	}
+}
+
+func rtl_matrix_case(t *testing.T, tools []Rtl_tool, name string, opname string, rsize uint8, r uint8, mode string, o uint8, l uint8) {
+	defer func() {
+		if err := recover(); err != nil {
+			t.Errorf("%s: generation panicked: %v", name, err)
+		}
+	}()
+
+	mach := new(Machine)
+	arch := &mach.Arch
+	arch.Modes = []string{mode}
+	arch.Rsize = rsize
+	arch.R = r
+	arch.N = 1
+	arch.M = 1
+	arch.L = l
+	arch.O = o
+	arch.Shared_constraints = Rtl_required_shared(opname)
+
+	ops := make([]Opcode, 0)
+	for _, op := range Allopcodes {
+		if op.Op_get_name() == opname || op.Op_get_name() == "nop" {
+			ops = append(ops, op)
+		}
+	}
+	sort.Sort(ByName(ops))
+	arch.Op = ops
+
+	conf := new(Config)
+	conf.Runinfo = new(RuntimeInfo)
+	conf.Runinfo.Init()
+
+	files := map[string]string{
+		"arch.v":      arch.Write_verilog("a0", map[string]string{"processor": "p0", "rom": "p0rom", "ram": "p0ram"}, "iverilog"),
+		"processor.v": arch.Conproc.Write_verilog(conf, arch, "p0", "iverilog"),
+		"ram.v":       arch.Ram.Write_verilog(mach, "p0ram", "iverilog"),
+		"rom.v":       arch.Rom.Write_verilog(mach, "p0rom", "iverilog"),
+	}
+
+	if len(tools) == 0 {
+		return
+	}
+
+	dir, err := ioutil.TempDir("", "rtlcheck")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	out := &Output{Dir: dir, Policy: OUT_FORCE}
+	if err := out.Init(); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	for filename, content := range files {
+		if err := out.Write(filename, content); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, filename)
+	}
+	sort.Strings(names)
+
+	for _, tool := range tools {
+		if output, err := tool.Rtl_check(dir, names, "a0"); err != nil {
+			t.Errorf("%s: %v\n%s", name, err, output)
+		}
+	}
+}
@@ -17,7 +17,7 @@ func (op Channel) Shortname() string {
 
 func (op Channel) Get_header(arch *Arch, shared_constraint string, seq int) string {
 	chname := "ch" + strconv.Itoa(seq)
-	return ", " + chname + "in, " + chname + "wwr, " + chname + "wrd, " + chname + "ack_ch_ready, " + chname + "op_check_ready, " + chname + "finish_channel, " + chname + "out, " + chname + "ack_wwr, " + chname + "ack_wrd, " + chname + "ch_ready, " + chname + "ch_w_r_ready"
+	return ", " + chname + "in, " + chname + "wwr, " + chname + "wrd, " + chname + "ack_ch_ready, " + chname + "op_check_ready, " + chname + "finish_channel, " + chname + "out, " + chname + "ack_wwr, " + chname + "ack_wrd, " + chname + "ch_ready, " + chname + "ch_w_r_ready, " + chname + "close, " + chname + "closed"
 }
 
 func (op Channel) Get_params(arch *Arch, shared_constraint string, seq int) string {
@@ -36,6 +36,8 @@ func (op Channel) Get_params(arch *Arch, shared_constraint string, seq int) stri
 	result += "	input " + chname + "ack_wrd;\n"
 	result += "	input " + chname + "ch_ready;\n"
 	result += "	input [1:0] " + chname + "ch_w_r_ready;\n"
+	result += "	output " + chname + "close;\n"
+	result += "	input " + chname + "closed;\n"
 
 	return result
 }
@@ -67,6 +69,8 @@ func (op Channel) Get_internal_params(arch *Arch, shared_constraint string, seq
 	result += "	input " + chname + "ack_wrd;\n"
 	result += "	input " + chname + "ch_ready;\n"
 	result += "	input [1:0] " + chname + "ch_w_r_ready;\n"
+	result += "	output " + chname + "close;\n"
+	result += "	input " + chname + "closed;\n"
 
 	if seq == 0 {
 		result += "\twire [" + strconv.Itoa(int(arch.Rsize)-1) + ":0] ch2proc_i[" + strconv.Itoa(channel_num-1) + ":0];\n"
@@ -80,6 +84,20 @@ func (op Channel) Get_internal_params(arch *Arch, shared_constraint string, seq
 		result += "\treg [" + strconv.Itoa(channel_num-1) + ":0] ack_ch_ready_i;\n"
 		result += "\treg [" + strconv.Itoa(channel_num-1) + ":0] ch_op_ready_i;\n"
 		result += "\twire [" + strconv.Itoa(channel_num-1) + ":0] finish_channel_i;\n"
+		result += "\twire [" + strconv.Itoa(channel_num-1) + ":0] ch_closed_i;\n"
+		// The close strobes are driven by the chclose opcode, if any
+		closer := false
+		for _, op := range arch.Op {
+			if op.Op_get_name() == "chclose" {
+				closer = true
+				break
+			}
+		}
+		if closer {
+			result += "\treg [" + strconv.Itoa(channel_num-1) + ":0] ch_close_i;\n"
+		} else {
+			result += "\twire [" + strconv.Itoa(channel_num-1) + ":0] ch_close_i = 'b0;\n"
+		}
 	}
 
 	result += "\tassign ch2proc_i[" + strconv.Itoa(seq) + "] = " + chname + "out;\n"
@@ -93,5 +111,7 @@ func (op Channel) Get_internal_params(arch *Arch, shared_constraint string, seq
 	result += "\tassign ch_ready_i[" + strconv.Itoa(seq) + "] = " + chname + "ch_ready;\n"
 	result += "\tassign ch_w_r_ready_i[" + strconv.Itoa(seq) + "] = " + chname + "ch_w_r_ready;\n"
 	result += "\tassign finish_channel_i[" + strconv.Itoa(seq) + "] = " + chname + "finish_channel;\n"
+	result += "\tassign " + chname + "close = ch_close_i[" + strconv.Itoa(seq) + "];\n"
+	result += "\tassign ch_closed_i[" + strconv.Itoa(seq) + "] = " + chname + "closed;\n"
 	return result
 }
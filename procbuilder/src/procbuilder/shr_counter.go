@@ -0,0 +1,74 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The atomic fetch and add counter. The processor holds req with the addend on in until the shared object
+// acknowledges, out is the value of the counter before the addition. With swp set in is stored instead of added.
+
+type Counter struct{}
+
+func (op Counter) Shr_get_name() string {
+	return "counter"
+}
+
+func (op Counter) Shortname() string {
+	return "ct"
+}
+
+func (op Counter) Get_header(arch *Arch, shared_constraint string, seq int) string {
+	ctname := "ct" + strconv.Itoa(seq)
+	return ", " + ctname + "req, " + ctname + "swp, " + ctname + "in, " + ctname + "out, " + ctname + "ack"
+}
+
+func (op Counter) Get_params(arch *Arch, shared_constraint string, seq int) string {
+
+	ctname := "ct" + strconv.Itoa(seq)
+
+	result := ""
+	result += "	output " + ctname + "req;\n"
+	result += "	output " + ctname + "swp;\n"
+	result += "	output [" + strconv.Itoa(int(arch.Rsize)-1) + ":0] " + ctname + "in;\n"
+	result += "	input [" + strconv.Itoa(int(arch.Rsize)-1) + ":0] " + ctname + "out;\n"
+	result += "	input " + ctname + "ack;\n"
+
+	return result
+}
+
+func (op Counter) Get_internal_params(arch *Arch, shared_constraint string, seq int) string {
+	counter_num := arch.Shared_num(op.Shr_get_name())
+
+	ctname := "ct" + strconv.Itoa(seq)
+
+	result := op.Get_params(arch, shared_constraint, seq)
+
+	if seq == 0 {
+		result += "\treg [" + strconv.Itoa(counter_num-1) + ":0] ct_req_i;\n"
+		result += "\treg [" + strconv.Itoa(counter_num-1) + ":0] ct_swp_i;\n"
+		result += "\treg [" + strconv.Itoa(int(arch.Rsize)-1) + ":0] ct_in_i [" + strconv.Itoa(counter_num-1) + ":0];\n"
+		result += "\twire [" + strconv.Itoa(int(arch.Rsize)-1) + ":0] ct_out_i [" + strconv.Itoa(counter_num-1) + ":0];\n"
+		result += "\twire [" + strconv.Itoa(counter_num-1) + ":0] ct_ack_i;\n"
+	}
+
+	result += "\tassign " + ctname + "req = ct_req_i[" + strconv.Itoa(seq) + "];\n"
+	result += "\tassign " + ctname + "swp = ct_swp_i[" + strconv.Itoa(seq) + "];\n"
+	result += "\tassign " + ctname + "in = ct_in_i[" + strconv.Itoa(seq) + "];\n"
+	result += "\tassign ct_out_i[" + strconv.Itoa(seq) + "] = " + ctname + "out;\n"
+	result += "\tassign ct_ack_i[" + strconv.Itoa(seq) + "] = " + ctname + "ack;\n"
+	return result
+}
+
+// Counter_verilog_reset is the reset of the counter requests, written once for fadd and fswp
+func Counter_verilog_reset(arch *Arch) string {
+	result := ""
+	counter_num := arch.Shared_num("counter")
+	if counter_num > 0 {
+		result += "\t\t\tct_req_i <= #1 'b0;\n"
+		result += "\t\t\tct_swp_i <= #1 'b0;\n"
+		for i := 0; i < counter_num; i++ {
+			result += "\t\t\tct_in_i[" + strconv.Itoa(i) + "] <= #1 'b0;\n"
+		}
+	}
+	return result
+}
@@ -0,0 +1,113 @@
+package procbuilder
+
+import (
+	"strconv"
+	"strings"
+)
+
+// The free running LFSR of parametric width, the constraint is lfsrn:width:seed. The feedback taps give the maximal
+// period 2^width-1 (Xilinx XAPP052), the state shifts left and the xor of the taps enters from the right.
+
+type Lfsrn struct{}
+
+var lfsrn_taps = map[int][]int{
+	2: {2, 1}, 3: {3, 2}, 4: {4, 3}, 5: {5, 3}, 6: {6, 5}, 7: {7, 6}, 8: {8, 6, 5, 4}, 9: {9, 5},
+	10: {10, 7}, 11: {11, 9}, 12: {12, 6, 4, 1}, 13: {13, 4, 3, 1}, 14: {14, 5, 3, 1}, 15: {15, 14},
+	16: {16, 15, 13, 4}, 17: {17, 14}, 18: {18, 11}, 19: {19, 6, 2, 1}, 20: {20, 17}, 21: {21, 19},
+	22: {22, 21}, 23: {23, 18}, 24: {24, 23, 22, 17}, 25: {25, 22}, 26: {26, 6, 2, 1}, 27: {27, 5, 2, 1},
+	28: {28, 25}, 29: {29, 27}, 30: {30, 6, 4, 1}, 31: {31, 28}, 32: {32, 22, 2, 1}, 33: {33, 20},
+	34: {34, 27, 2, 1}, 35: {35, 33}, 36: {36, 25}, 37: {37, 5, 4, 3, 2, 1}, 38: {38, 6, 5, 1}, 39: {39, 35},
+	40: {40, 38, 21, 19}, 41: {41, 38}, 42: {42, 41, 20, 19}, 43: {43, 42, 38, 37}, 44: {44, 43, 18, 17},
+	45: {45, 44, 42, 41}, 46: {46, 45, 26, 25}, 47: {47, 42}, 48: {48, 47, 21, 20}, 49: {49, 40},
+	50: {50, 49, 24, 23}, 51: {51, 50, 36, 35}, 52: {52, 49}, 53: {53, 52, 38, 37}, 54: {54, 53, 18, 17},
+	55: {55, 31}, 56: {56, 55, 35, 34}, 57: {57, 50}, 58: {58, 39}, 59: {59, 58, 38, 37}, 60: {60, 59},
+	61: {61, 60, 46, 45}, 62: {62, 61, 6, 5}, 63: {63, 62}, 64: {64, 63, 61, 60},
+}
+
+// Lfsrn_params returns the width and the seed of a lfsrn constraint
+func Lfsrn_params(constraint string) (int, uint64, error) {
+	values := strings.Split(constraint, ":")
+	if len(values) != 3 || values[0] != "lfsrn" {
+		return 0, 0, Prerror{"Wrong lfsrn constraint " + constraint + ", lfsrn:width:seed expected"}
+	}
+	width, err := strconv.Atoi(values[1])
+	if err != nil {
+		return 0, 0, Prerror{"Wrong lfsrn width " + values[1]}
+	}
+	if _, ok := lfsrn_taps[width]; !ok {
+		return 0, 0, Prerror{"Unsupported lfsrn width " + values[1] + ", from 2 to 64 bits"}
+	}
+	seed, err := strconv.ParseUint(values[2], 0, 64)
+	if err != nil {
+		return 0, 0, Prerror{"Wrong lfsrn seed " + values[2]}
+	}
+	if width < 64 {
+		seed &= 1<<uint(width) - 1
+	}
+	if seed == 0 {
+		return 0, 0, Prerror{"The lfsrn seed cannot be 0 within its width"}
+	}
+	return width, seed, nil
+}
+
+// Lfsrn_taps returns the feedback taps of a width, as 1 based bit positions
+func Lfsrn_taps(width int) []int {
+	return lfsrn_taps[width]
+}
+
+// Lfsrn_next is the state following the given one
+func Lfsrn_next(width int, state uint64) uint64 {
+	feedback := uint64(0)
+	for _, tap := range lfsrn_taps[width] {
+		feedback ^= (state >> uint(tap-1)) & 1
+	}
+	state = state<<1 | feedback
+	if width < 64 {
+		state &= 1<<uint(width) - 1
+	}
+	return state
+}
+
+// Lfsrn_widths returns the widths of the lfsrn shared objects of a constraints list, in order
+func Lfsrn_widths(constraints string) []int {
+	result := make([]int, 0)
+	if constraints == "" {
+		return result
+	}
+	for _, constraint := range strings.Split(constraints, ",") {
+		if strings.HasPrefix(constraint, "lfsrn:") {
+			width, _, _ := Lfsrn_params(constraint)
+			result = append(result, width)
+		}
+	}
+	return result
+}
+
+func (op Lfsrn) Shr_get_name() string {
+	return "lfsrn"
+}
+
+func (op Lfsrn) Shortname() string {
+	return "lfsrn"
+}
+
+func (op Lfsrn) Get_header(arch *Arch, shared_constraint string, seq int) string {
+	lfname := "lfsrn" + strconv.Itoa(seq)
+	return ", " + lfname + "out"
+}
+
+func (op Lfsrn) Get_params(arch *Arch, shared_constraint string, seq int) string {
+
+	lfname := "lfsrn" + strconv.Itoa(seq)
+	width, _, _ := Lfsrn_params(shared_constraint)
+
+	result := ""
+	result += "	input [" + strconv.Itoa(width-1) + ":0] " + lfname + "out;\n"
+
+	return result
+}
+
+func (op Lfsrn) Get_internal_params(arch *Arch, shared_constraint string, seq int) string {
+	result := op.Get_params(arch, shared_constraint, seq)
+	return result
+}
@@ -0,0 +1,51 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The hardware mutex. The processor holds req high from the lock to the unlock, the shared object grants it to one
+// processor at the time.
+
+type Mutex struct{}
+
+func (op Mutex) Shr_get_name() string {
+	return "mutex"
+}
+
+func (op Mutex) Shortname() string {
+	return "mx"
+}
+
+func (op Mutex) Get_header(arch *Arch, shared_constraint string, seq int) string {
+	mxname := "mx" + strconv.Itoa(seq)
+	return ", " + mxname + "req, " + mxname + "grant"
+}
+
+func (op Mutex) Get_params(arch *Arch, shared_constraint string, seq int) string {
+
+	mxname := "mx" + strconv.Itoa(seq)
+
+	result := ""
+	result += "	output " + mxname + "req;\n"
+	result += "	input " + mxname + "grant;\n"
+
+	return result
+}
+
+func (op Mutex) Get_internal_params(arch *Arch, shared_constraint string, seq int) string {
+	mutex_num := arch.Shared_num(op.Shr_get_name())
+
+	mxname := "mx" + strconv.Itoa(seq)
+
+	result := op.Get_params(arch, shared_constraint, seq)
+
+	if seq == 0 {
+		result += "\treg [" + strconv.Itoa(mutex_num-1) + ":0] mx_req_i;\n"
+		result += "\twire [" + strconv.Itoa(mutex_num-1) + ":0] mx_grant_i;\n"
+	}
+
+	result += "\tassign " + mxname + "req = mx_req_i[" + strconv.Itoa(seq) + "];\n"
+	result += "\tassign mx_grant_i[" + strconv.Itoa(seq) + "] = " + mxname + "grant;\n"
+	return result
+}
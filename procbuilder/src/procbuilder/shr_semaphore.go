@@ -0,0 +1,54 @@
+package procbuilder
+
+import (
+	"strconv"
+)
+
+// The counting semaphore. An acquire or a release is a request held until the shared object acknowledges it, an
+// acquire is acknowledged only while the count is positive.
+
+type Semaphore struct{}
+
+func (op Semaphore) Shr_get_name() string {
+	return "semaphore"
+}
+
+func (op Semaphore) Shortname() string {
+	return "se"
+}
+
+func (op Semaphore) Get_header(arch *Arch, shared_constraint string, seq int) string {
+	sename := "se" + strconv.Itoa(seq)
+	return ", " + sename + "acq, " + sename + "rel, " + sename + "ack"
+}
+
+func (op Semaphore) Get_params(arch *Arch, shared_constraint string, seq int) string {
+
+	sename := "se" + strconv.Itoa(seq)
+
+	result := ""
+	result += "	output " + sename + "acq;\n"
+	result += "	output " + sename + "rel;\n"
+	result += "	input " + sename + "ack;\n"
+
+	return result
+}
+
+func (op Semaphore) Get_internal_params(arch *Arch, shared_constraint string, seq int) string {
+	semaphore_num := arch.Shared_num(op.Shr_get_name())
+
+	sename := "se" + strconv.Itoa(seq)
+
+	result := op.Get_params(arch, shared_constraint, seq)
+
+	if seq == 0 {
+		result += "\treg [" + strconv.Itoa(semaphore_num-1) + ":0] se_acq_i;\n"
+		result += "\treg [" + strconv.Itoa(semaphore_num-1) + ":0] se_rel_i;\n"
+		result += "\twire [" + strconv.Itoa(semaphore_num-1) + ":0] se_ack_i;\n"
+	}
+
+	result += "\tassign " + sename + "acq = se_acq_i[" + strconv.Itoa(seq) + "];\n"
+	result += "\tassign " + sename + "rel = se_rel_i[" + strconv.Itoa(seq) + "];\n"
+	result += "\tassign se_ack_i[" + strconv.Itoa(seq) + "] = " + sename + "ack;\n"
+	return result
+}
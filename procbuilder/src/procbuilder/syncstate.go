@@ -0,0 +1,178 @@
+package procbuilder
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The simulated synchronization shared objects: the mutexes, the semaphores, the fetch and add counters and the
+// lfsrn. The operations are atomic within the hub, an operation that cannot complete reports it and the opcode is
+// executed again at the next step as the RTL keeps its request high. The lfsrn advances at every read so a program
+// sees its whole sequence.
+
+type Sync_hub struct {
+	Objects []Sync_state
+	lock    sync.Mutex
+}
+
+type Sync_state struct {
+	Kind  string // The shared object name: mutex, semaphore, counter or lfsrn
+	Owner int    // The processor holding a mutex, -1 if free
+	Value uint64 // The count of a semaphore or a counter, the state of a lfsrn
+	Width int    // The bits of the value
+}
+
+// Sync_object returns the initial state of a synchronization shared object constraint, false for the other ones
+func Sync_object(constraint string, rsize uint8) (Sync_state, bool) {
+	values := strings.Split(constraint, ":")
+	state := Sync_state{Kind: values[0], Owner: -1, Width: int(rsize)}
+	switch values[0] {
+	case "mutex":
+	case "semaphore", "counter":
+		if len(values) > 1 && values[1] != "" {
+			init, _ := strconv.ParseUint(values[1], 0, 64)
+			state.Value = init & state.mask()
+		}
+	case "lfsrn":
+		width, seed, err := Lfsrn_params(constraint)
+		if err != nil {
+			return state, false
+		}
+		state.Width = width
+		state.Value = seed
+	default:
+		return state, false
+	}
+	return state, true
+}
+
+// Sync_objects returns the synchronization shared objects of a constraints list, in order
+func Sync_objects(constraints string, rsize uint8) []Sync_state {
+	result := make([]Sync_state, 0)
+	if constraints == "" {
+		return result
+	}
+	for _, constraint := range strings.Split(constraints, ",") {
+		if state, ok := Sync_object(constraint, rsize); ok {
+			result = append(result, state)
+		}
+	}
+	return result
+}
+
+func (state Sync_state) mask() uint64 {
+	if state.Width >= 64 {
+		return ^uint64(0)
+	}
+	return 1<<uint(state.Width) - 1
+}
+
+func (hub *Sync_hub) Init(objects []Sync_state) {
+	hub.Objects = append(make([]Sync_state, 0), objects...)
+}
+
+func (hub *Sync_hub) CopyState(source *Sync_hub) {
+	hub.Objects = append(make([]Sync_state, 0), source.Objects...)
+}
+
+// sync_object returns the hub object of the local shared object seq of kind soname
+func (vm *VM) sync_object(soname string, seq int) (*Sync_state, error) {
+	if vm.Sync == nil || seq >= len(vm.Sync_map[soname]) {
+		return nil, Prerror{"Shared object " + soname + " " + strconv.Itoa(seq) + " not connected"}
+	}
+	return &vm.Sync.Objects[vm.Sync_map[soname][seq]], nil
+}
+
+// Sync_lock locks or unlocks a mutex, false if it is held by another processor
+func (vm *VM) Sync_lock(mx int, lock bool) (bool, error) {
+	state, err := vm.sync_object("mutex", mx)
+	if err != nil {
+		return false, err
+	}
+	vm.Sync.lock.Lock()
+	defer vm.Sync.lock.Unlock()
+	if lock {
+		if state.Owner != -1 && state.Owner != vm.Channel_owner {
+			return false, nil
+		}
+		state.Owner = vm.Channel_owner
+	} else if state.Owner == vm.Channel_owner {
+		state.Owner = -1
+	}
+	return true, nil
+}
+
+// Sync_semaphore acquires or releases a semaphore, an acquire is false while the count is 0. A release saturates.
+func (vm *VM) Sync_semaphore(se int, acquire bool) (bool, error) {
+	state, err := vm.sync_object("semaphore", se)
+	if err != nil {
+		return false, err
+	}
+	vm.Sync.lock.Lock()
+	defer vm.Sync.lock.Unlock()
+	if acquire {
+		if state.Value == 0 {
+			return false, nil
+		}
+		state.Value--
+	} else if state.Value < state.mask() {
+		state.Value++
+	}
+	return true, nil
+}
+
+// Sync_fetch_add adds to a counter and returns its previous value
+func (vm *VM) Sync_fetch_add(ct int, addend uint64) (uint64, error) {
+	state, err := vm.sync_object("counter", ct)
+	if err != nil {
+		return 0, err
+	}
+	vm.Sync.lock.Lock()
+	defer vm.Sync.lock.Unlock()
+	result := state.Value
+	state.Value = (state.Value + addend) & state.mask()
+	return result, nil
+}
+
+// Sync_swap stores in a counter and returns its previous value
+func (vm *VM) Sync_swap(ct int, value uint64) (uint64, error) {
+	state, err := vm.sync_object("counter", ct)
+	if err != nil {
+		return 0, err
+	}
+	vm.Sync.lock.Lock()
+	defer vm.Sync.lock.Unlock()
+	result := state.Value
+	state.Value = value & state.mask()
+	return result, nil
+}
+
+// Sync_lfsr returns the state of a lfsrn and advances it
+func (vm *VM) Sync_lfsr(lf int) (uint64, error) {
+	state, err := vm.sync_object("lfsrn", lf)
+	if err != nil {
+		return 0, err
+	}
+	vm.Sync.lock.Lock()
+	defer vm.Sync.lock.Unlock()
+	result := state.Value
+	state.Value = Lfsrn_next(state.Width, state.Value)
+	return result, nil
+}
+
+// The synchronization objects of a processor simulated alone
+func (vm *VM) init_sync() {
+	if vm.Sync == nil {
+		objects := Sync_objects(vm.Mach.Shared_constraints, vm.Mach.Rsize)
+		if len(objects) == 0 {
+			return
+		}
+		vm.Sync = new(Sync_hub)
+		vm.Sync.Init(objects)
+		vm.Sync_map = make(map[string][]int)
+		for i, state := range objects {
+			vm.Sync_map[state.Kind] = append(vm.Sync_map[state.Kind], i)
+		}
+	}
+}
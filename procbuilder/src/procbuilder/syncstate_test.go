@@ -0,0 +1,122 @@
+package procbuilder
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// Every supported width has the maximal period, checked up to 16 bits
+func TestLfsrnPeriod(t *testing.T) {
+	for width := 2; width <= 16; width++ {
+		state := uint64(1)
+		period := 0
+		for {
+			state = Lfsrn_next(width, state)
+			period++
+			if state == 1 || period > 1<<uint(width) {
+				break
+			}
+		}
+		if period != 1<<uint(width)-1 {
+			t.Error("Wrong period of the", width, "bits lfsrn:", period)
+		}
+	}
+
+	for _, constraint := range []string{"lfsrn:8:0", "lfsrn:4:16", "lfsrn:65:1", "lfsrn:8"} {
+		if _, _, err := Lfsrn_params(constraint); err == nil {
+			t.Error("Accepted " + constraint)
+		}
+	}
+}
+
+// A processor alone locks, acquires the semaphore twice and blocks on the third acquire
+func TestSyncObjects(t *testing.T) {
+	mach := new(Machine)
+	arch := &mach.Arch
+	arch.Modes = []string{"ha"}
+	arch.Rsize = 8
+	arch.R = 2
+	arch.L = 4
+	arch.O = 4
+	arch.Shared_constraints = "mutex:,semaphore:2,counter:5,lfsrn:4:1"
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "fadd", "lfsrn2r", "lock", "rset", "semacq", "unlock":
+			arch.Op = append(arch.Op, op)
+		}
+	}
+	sort.Sort(ByName(arch.Op))
+
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("lock mx0\nlock mx0\nrset r0 3\nfadd r0 ct0\nlfsrn2r r1 lfsrn0\nlfsrn2r r2 lfsrn0\nunlock mx0\nsemacq se0\nsemacq se0\nsemacq se0\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 12; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fmt.Println(vm.Pc, vm.Dump_registers(), vm.Sync.Objects, vm.Counters.Stalls)
+
+	if word_value(vm.Registers[0]) != 5 || vm.Sync.Objects[2].Value != 8 {
+		t.Error("Wrong fetch and add")
+	}
+	if word_value(vm.Registers[1]) != 1 || word_value(vm.Registers[2]) != 2 {
+		t.Error("Wrong lfsrn sequence")
+	}
+	if vm.Sync.Objects[0].Owner != -1 {
+		t.Error("Mutex not unlocked")
+	}
+	if vm.Pc != 9 || vm.Sync.Objects[1].Value != 0 || vm.Counters.Stalls[STALL_SHARED] != 3 {
+		t.Error("The empty semaphore did not block")
+	}
+}
+
+// The swap stores in the counter and returns the previous count, as the fetch and add does
+func TestCounterSwap(t *testing.T) {
+	mach := new(Machine)
+	arch := &mach.Arch
+	arch.Modes = []string{"ha"}
+	arch.Rsize = 8
+	arch.R = 2
+	arch.L = 4
+	arch.O = 4
+	arch.Shared_constraints = "counter:5"
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "fadd", "fswp", "rset":
+			arch.Op = append(arch.Op, op)
+		}
+	}
+	sort.Sort(ByName(arch.Op))
+
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("rset r0 200\nfswp r0 ct0\nrset r1 2\nfadd r1 ct0\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fmt.Println(vm.Pc, vm.Dump_registers(), vm.Sync.Objects)
+
+	if word_value(vm.Registers[0]) != 5 || word_value(vm.Registers[1]) != 200 || vm.Sync.Objects[0].Value != 202 {
+		t.Error("Wrong swap")
+	}
+}
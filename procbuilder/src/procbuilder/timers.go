@@ -0,0 +1,289 @@
+package procbuilder
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// The processor timer. A free running counter counts the clock cycles and a timer, armed one shot by tma or periodic
+// by tmp, expires after the value of a register shifted left by the immediate of the instruction, in cycles. tmw
+// blocks until the timer expires and consumes the expiration; as time.Tick does, the periods ending while nobody waits
+// are not accumulated. tmr reads the cycles counter shifted right by its immediate. Nothing is shared, the opcodes never
+// wait for other processors. The counters are wide enough for the largest shift, the VM counts a cycle at every step.
+
+const (
+	STALL_TIMER = "timer" // Waiting for the timer
+)
+
+const (
+	Timer_shift_bits = 5 // The bits of the shift immediate
+)
+
+// Timer_opcodes are the opcodes using the timer, the first of them in the processor generates it
+var Timer_opcodes = []string{"tma", "tmp", "tmr", "tmw"}
+
+type Timer_state struct {
+	Cycles   uint64
+	Left     uint64 // Cycles to the expiration
+	Period   uint64
+	Armed    bool
+	Periodic bool
+	Expired  bool
+	armed    bool // Armed in this cycle, the counting starts on the next one
+	consumed bool // Expiration consumed in this cycle, a new one is lost as in the RTL
+}
+
+// timer_width is the width of the counters, a register shifted by the largest immediate
+func timer_width(arch *Arch) int {
+	return int(arch.Rsize) + (1 << Timer_shift_bits) - 1
+}
+
+func (proc *Conproc) Has_timer() bool {
+	for _, op := range proc.Op {
+		for _, name := range Timer_opcodes {
+			if op.Op_get_name() == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// timer_generator tells if the opcode is the one generating the timer, the first of them in the processor
+func timer_generator(arch *Arch, opname string) bool {
+	for _, op := range arch.Op {
+		for _, name := range Timer_opcodes {
+			if op.Op_get_name() == name {
+				return name == opname
+			}
+		}
+	}
+	return false
+}
+
+func (vm *VM) init_timer() {
+	vm.Timer = nil
+	if vm.Mach.Has_timer() {
+		vm.Timer = new(Timer_state)
+	}
+}
+
+func (ts *Timer_state) CopyState(tssource *Timer_state) {
+	*ts = *tssource
+}
+
+// timer_tick counts a cycle, after the instruction of the step as the RTL assignments of the opcodes take precedence
+func (vm *VM) timer_tick() {
+	ts := vm.Timer
+	ts.Cycles++
+	if ts.armed {
+		ts.armed = false
+		ts.consumed = false
+		return
+	}
+	if ts.Armed {
+		if ts.Left <= 1 {
+			if !ts.consumed {
+				ts.Expired = true
+			}
+			if ts.Periodic {
+				ts.Left = ts.Period
+			} else {
+				ts.Armed = false
+			}
+		} else {
+			ts.Left--
+		}
+	}
+	ts.consumed = false
+}
+
+// Timer_arm starts the timer, a zero duration expires at once
+func (vm *VM) Timer_arm(cycles uint64, periodic bool) {
+	ts := vm.Timer
+	ts.Left = cycles
+	ts.Period = cycles
+	ts.Armed = cycles != 0
+	ts.Periodic = periodic
+	ts.Expired = cycles == 0
+	ts.armed = true
+}
+
+// Timer_wait consumes the expiration of the timer, false if it has not expired yet
+func (vm *VM) Timer_wait() bool {
+	ts := vm.Timer
+	if !ts.Expired {
+		return false
+	}
+	ts.Expired = false
+	ts.consumed = true
+	return true
+}
+
+// The registers of the timer
+func timer_verilog_header(arch *Arch) string {
+	width := strconv.Itoa(timer_width(arch) - 1)
+	result := "\n"
+	result += "	// Processor timer\n"
+	result += "	reg [" + width + ":0] tm_cycles;\n"
+	result += "	reg [" + width + ":0] tm_left;\n"
+	result += "	reg [" + width + ":0] tm_period;\n"
+	result += "	reg tm_armed;\n"
+	result += "	reg tm_periodic;\n"
+	result += "	reg tm_expired;\n"
+	return result
+}
+
+func timer_verilog_reset(arch *Arch) string {
+	result := ""
+	result += "			tm_cycles <= #1 'b0;\n"
+	result += "			tm_left <= #1 'b0;\n"
+	result += "			tm_period <= #1 'b0;\n"
+	result += "			tm_armed <= #1 1'b0;\n"
+	result += "			tm_periodic <= #1 1'b0;\n"
+	result += "			tm_expired <= #1 1'b0;\n"
+	return result
+}
+
+// The counting, every cycle before the opcodes
+func timer_verilog_default_state(arch *Arch) string {
+	width := strconv.Itoa(timer_width(arch))
+	result := ""
+	result += "				tm_cycles <= #1 tm_cycles + 1'b1;\n"
+	result += "				if (tm_armed)\n"
+	result += "				begin\n"
+	result += "					if (tm_left <= " + width + "'d1)\n"
+	result += "					begin\n"
+	result += "						tm_expired <= #1 1'b1;\n"
+	result += "						if (tm_periodic)\n"
+	result += "							tm_left <= #1 tm_period;\n"
+	result += "						else\n"
+	result += "							tm_armed <= #1 1'b0;\n"
+	result += "					end\n"
+	result += "					else\n"
+	result += "						tm_left <= #1 tm_left - 1'b1;\n"
+	result += "				end\n"
+	return result
+}
+
+// timer_verilog_arm loads the timer with a register shifted by the immediate, the register and the immediate follow the opcode
+func timer_verilog_arm(arch *Arch, periodic bool) string {
+	reg_num := 1 << arch.R
+	shift := rom_field(arch, int(arch.R), Timer_shift_bits)
+	padding := strconv.Itoa((1 << Timer_shift_bits) - 1)
+	flag := "1'b0"
+	if periodic {
+		flag = "1'b1"
+	}
+
+	result := ""
+	result += "						case (" + rom_field(arch, 0, int(arch.R)) + ")\n"
+	for i := 0; i < reg_num; i++ {
+		reg := "_" + strings.ToLower(Get_register_name(i))
+		result += "						" + strings.ToUpper(Get_register_name(i)) + " : begin\n"
+		result += "							tm_left <= #1 {" + padding + "'b0, " + reg + "} << " + shift + ";\n"
+		result += "							tm_period <= #1 {" + padding + "'b0, " + reg + "} << " + shift + ";\n"
+		result += "							tm_armed <= #1 (" + reg + " != 0);\n"
+		result += "							tm_expired <= #1 (" + reg + " == 0);\n"
+		result += "						end\n"
+	}
+	result += "						endcase\n"
+	result += "						tm_periodic <= #1 " + flag + ";\n"
+	result += "						_pc <= #1 _pc + 1'b1;\n"
+	return result
+}
+
+// timer_verilog_read sets a register to the cycles counter shifted by the immediate
+func timer_verilog_read(arch *Arch) string {
+	reg_num := 1 << arch.R
+	shift := rom_field(arch, int(arch.R), Timer_shift_bits)
+
+	result := ""
+	result += "						case (" + rom_field(arch, 0, int(arch.R)) + ")\n"
+	for i := 0; i < reg_num; i++ {
+		result += "						" + strings.ToUpper(Get_register_name(i)) + " : begin\n"
+		result += "							_" + strings.ToLower(Get_register_name(i)) + " <= #1 tm_cycles >> " + shift + ";\n"
+		result += "						end\n"
+	}
+	result += "						endcase\n"
+	result += "						_pc <= #1 _pc + 1'b1;\n"
+	return result
+}
+
+// The register and the shift of the timer opcodes
+func timer_assembler(arch *Arch, opname string, words []string) (string, error) {
+	opbits := arch.Opcodes_bits()
+	rom_word := arch.Max_word()
+
+	reg_num := 1 << arch.R
+
+	if len(words) != 2 {
+		return "", Prerror{"Wrong arguments number"}
+	}
+
+	result := ""
+	for i := 0; i < reg_num; i++ {
+		if words[0] == strings.ToLower(Get_register_name(i)) {
+			result += zeros_prefix(int(arch.R), get_binary(i))
+			break
+		}
+	}
+
+	if result == "" {
+		return "", Prerror{"Unknown register name " + words[0]}
+	}
+
+	if shift, err := strconv.Atoi(words[1]); err != nil || shift < 0 || shift >= 1<<Timer_shift_bits {
+		return "", Prerror{opname + " shift out of range " + words[1]}
+	} else {
+		result += zeros_prefix(Timer_shift_bits, get_binary(shift))
+	}
+
+	for i := opbits + int(arch.R) + Timer_shift_bits; i < rom_word; i++ {
+		result += "0"
+	}
+
+	return result, nil
+}
+
+func timer_disassembler(arch *Arch, instr string) (string, error) {
+	reg_id := get_id(instr[:arch.R])
+	shift := get_id(instr[arch.R : int(arch.R)+Timer_shift_bits])
+	result := strings.ToLower(Get_register_name(reg_id)) + " " + strconv.Itoa(shift)
+	return result, nil
+}
+
+func timer_generate(arch *Arch) string {
+	reg_num := 1 << arch.R
+	reg := rand.Intn(reg_num)
+	shift := rand.Intn(1 << Timer_shift_bits)
+	return zeros_prefix(int(arch.R), get_binary(reg)) + zeros_prefix(Timer_shift_bits, get_binary(shift))
+}
+
+func timer_abstract_assembler(opname string, words []string) ([]UsageNotify, error) {
+	if len(words) != 2 {
+		return []UsageNotify{}, errors.New("Wrong parameters")
+	}
+	seq0, types0 := Sequence_to_0(words[0])
+
+	if len(seq0) > 0 && types0 == O_REGISTER {
+		result := make([]UsageNotify, 2)
+		result[0] = UsageNotify{C_OPCODE, opname, I_NIL}
+		result[1] = UsageNotify{C_REGSIZE, S_NIL, len(seq0)}
+		return result, nil
+	}
+
+	return []UsageNotify{}, errors.New("Wrong parameters")
+}
+
+// The counters and the comparison of the timer are accounted by the opcode generating it
+func timer_resources(arch *Arch, opname string, reads int, write bool) Resources {
+	result := Regfile_cost(arch, reads, write)
+	if timer_generator(arch, opname) {
+		width := timer_width(arch)
+		result = result.Chain(Resources{Luts: 3*width + 4, Ffs: 3*width + 3, Levels: 3})
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package procbuilder
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Timing model. Every opcode reports the clock cycles the generated state machine spends on it and the cause of the
+// cycles beyond the first one. When the VM timing is enabled a step is a clock cycle: an instruction is executed on its
+// last cycle, the previous ones are counted as stalls.
+
+const (
+	STALL_RAM     = "ram"     // RAM read
+	STALL_SHARED  = "shared"  // Shared object access
+	STALL_IO      = "io"      // Inputs and outputs handshakes
+	STALL_CHANNEL = "channel" // Channel operations
+	STALL_FPU     = "fpu"     // Floating point units
+)
+
+// Performance counters of a processor
+type Perf_counters struct {
+	Cycles    uint64
+	Retired   uint64            // Instructions retired
+	Stalls    map[string]uint64 // Stall cycles by cause
+	By_opcode map[string]uint64 // Instructions retired by opcode
+}
+
+func (pc *Perf_counters) Reset() {
+	pc.Cycles = 0
+	pc.Retired = 0
+	pc.Stalls = make(map[string]uint64)
+	pc.By_opcode = make(map[string]uint64)
+}
+
+func (pc *Perf_counters) Stall_cycles() uint64 {
+	result := uint64(0)
+	for _, cycles := range pc.Stalls {
+		result += cycles
+	}
+	return result
+}
+
+func sorted_counters(counters map[string]uint64) []string {
+	result := make([]string, 0, len(counters))
+	for name := range counters {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func (pc *Perf_counters) String() string {
+	result := fmt.Sprintf("cycles: %d, retired: %d, stalls: %d", pc.Cycles, pc.Retired, pc.Stall_cycles())
+	if pc.Retired > 0 {
+		result += fmt.Sprintf(", CPI: %.2f", float64(pc.Cycles)/float64(pc.Retired))
+	}
+	result += "\n"
+	for _, cause := range sorted_counters(pc.Stalls) {
+		result += fmt.Sprintf("\tstall %-10s %d\n", cause, pc.Stalls[cause])
+	}
+	for _, opname := range sorted_counters(pc.By_opcode) {
+		result += fmt.Sprintf("\t%-16s %d\n", opname, pc.By_opcode[opname])
+	}
+	return result
+}
+
+// One cycle opcodes
+func single_cycle() (int, string) {
+	return 1, ""
+}
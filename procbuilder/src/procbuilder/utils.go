@@ -37,6 +37,14 @@ func Get_channel_name(i int) string {
 	return "ch" + strconv.Itoa(i)
 }
 
+func Get_mutex_name(i int) string {
+	return "mx" + strconv.Itoa(i)
+}
+
+func Get_counter_name(i int) string {
+	return "ct" + strconv.Itoa(i)
+}
+
 func Get_input_name(i int) string {
 	result := "i" + strconv.Itoa(i)
 	return result
@@ -63,6 +71,15 @@ func zeros_suffix(num int, value string) string {
 	return result
 }
 
+// rom_field is the Verilog slice of the instruction field of the given bits, offset bits after the opcode
+func rom_field(arch *Arch, offset int, bits int) string {
+	high := arch.Max_word() - arch.Opcodes_bits() - offset - 1
+	if bits == 1 {
+		return "rom_value[" + strconv.Itoa(high) + "]"
+	}
+	return "rom_value[" + strconv.Itoa(high) + ":" + strconv.Itoa(high-bits+1) + "]"
+}
+
 func get_id(intr string) int {
 	result := 0
 	for i := 0; i < len(intr); i++ {
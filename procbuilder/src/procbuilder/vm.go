@@ -7,13 +7,28 @@ import (
 )
 
 type VM struct {
-	Mach         *Machine
-	Registers    []interface{}
-	Memory       []interface{}
-	Inputs       []interface{}
-	Outputs      []interface{}
-	Pc           uint64
-	Extra_states map[string]interface{}
+	Mach          *Machine
+	Registers     []interface{}
+	Memory        []interface{}
+	Inputs        []interface{}
+	Outputs       []interface{}
+	Pc            uint64
+	Code          []string // The program memory of the vn and hy models, it can be written by the data operations
+	Extra_states  map[string]interface{}
+	Timing        bool // Model the opcodes latencies, every step is a clock cycle
+	Counters      Perf_counters
+	busy          int // Cycles left to the current instruction
+	busy_cause    string
+	Source        map[int]*Debug_line // Source of the program by ROM address, from the debug info
+	Channels      *Channel_hub        // The simulated channels, shared by the processors of a bondmachine
+	Channel_map   []int               // Local channel -> channel of the hub
+	Channel_owner int                 // Processor id within the hub
+	Channel_ops   []Channel_op        // The wwr and wrd waiting for a chw or a chc
+	Sync          *Sync_hub           // The simulated mutexes, semaphores, counters and lfsrn
+	Sync_map      map[string][]int    // Local shared objects of a kind -> objects of the hub
+	Events        *Event_state        // The input events, nil if the processor has no event opcodes
+	Timer         *Timer_state        // The processor timer, nil if the processor has no timer opcodes
+	waiting       string              // The stall cause if the instruction did not complete and will be executed again
 }
 
 func (vm *VM) CopyState(vmsource *VM) {
@@ -26,7 +41,38 @@ func (vm *VM) CopyState(vmsource *VM) {
 	for i, outp := range vmsource.Outputs {
 		vm.Outputs[i] = outp
 	}
-	// TODO FINISH
+	copy(vm.Code, vmsource.Code)
+	copy(vm.Memory, vmsource.Memory)
+	vm.Pc = vmsource.Pc
+	vm.Extra_states = make(map[string]interface{})
+	for name, state := range vmsource.Extra_states {
+		vm.Extra_states[name] = state
+	}
+	vm.Timing = vmsource.Timing
+	vm.Counters.Reset()
+	vm.Counters.Cycles = vmsource.Counters.Cycles
+	vm.Counters.Retired = vmsource.Counters.Retired
+	for cause, cycles := range vmsource.Counters.Stalls {
+		vm.Counters.Stalls[cause] = cycles
+	}
+	for opname, retired := range vmsource.Counters.By_opcode {
+		vm.Counters.By_opcode[opname] = retired
+	}
+	vm.busy = vmsource.busy
+	vm.busy_cause = vmsource.busy_cause
+	vm.Channel_ops = append(make([]Channel_op, 0), vmsource.Channel_ops...)
+	if vm.Channels != nil && vmsource.Channels != nil && vm.Channels != vmsource.Channels {
+		vm.Channels.CopyState(vmsource.Channels)
+	}
+	if vm.Sync != nil && vmsource.Sync != nil && vm.Sync != vmsource.Sync {
+		vm.Sync.CopyState(vmsource.Sync)
+	}
+	if vm.Events != nil && vmsource.Events != nil {
+		vm.Events.CopyState(vmsource.Events)
+	}
+	if vm.Timer != nil && vmsource.Timer != nil {
+		vm.Timer.CopyState(vmsource.Timer)
+	}
 }
 
 // Simbox rules are converted in a sim drive when the simulation starts and applied during the simulation
@@ -109,8 +155,137 @@ func (vm *VM) Init() error {
 		// TODO Fix
 	}
 
+	if vm.Mach.Execution_model() != "ha" {
+		vm.Code = make([]string, 1<<vm.Mach.O)
+		copy(vm.Code, vm.Mach.Program.Slocs)
+	}
+
 	vm.Extra_states = make(map[string]interface{})
 
+	vm.Counters.Reset()
+	vm.busy = 0
+	vm.init_channels()
+	vm.init_sync()
+	vm.init_events()
+	vm.init_timer()
+
+	return nil
+}
+
+// Fetch returns the instruction at the program counter, false if the machine is halted
+func (vm *VM) Fetch() (string, bool, error) {
+	if vm.Mach.Execution_model() == "ha" {
+		num_instr := len(vm.Mach.Program.Slocs)
+		if int(vm.Pc) > num_instr {
+			return "", false, Prerror{"Program counter outside limits"}
+		}
+		if int(vm.Pc) == num_instr {
+			return "", false, nil
+		}
+		return vm.Mach.Program.Slocs[vm.Pc], true, nil
+	}
+
+	if int(vm.Pc) >= len(vm.Code) {
+		return "", false, Prerror{"Program counter outside limits"}
+	}
+	// A cell never written halts the machine as the end of the program does in the ha model
+	if vm.Code[vm.Pc] == "" {
+		return "", false, nil
+	}
+	return vm.Code[vm.Pc], true, nil
+}
+
+// Program_len returns the number of locations a jump can reach
+func (vm *VM) Program_len() int {
+	if vm.Code != nil {
+		return len(vm.Code)
+	}
+	return len(vm.Mach.Program.Slocs)
+}
+
+func (vm *VM) word(value int) interface{} {
+	switch vm.Mach.Rsize {
+	case 8:
+		return uint8(value)
+	case 16:
+		return uint16(value)
+	}
+	// TODO Fix
+	return value
+}
+
+func word_value(value interface{}) int {
+	switch v := value.(type) {
+	case uint8:
+		return int(v)
+	case uint16:
+		return int(v)
+	case int:
+		return v
+	}
+	return 0
+}
+
+// The carry flag set by adc, sbc, mulc, incc and cilc, it is kept among the extra states with the name of the RTL register
+func (vm *VM) Carry() bool {
+	if carry, ok := vm.Extra_states["carryflag"]; ok {
+		return carry.(bool)
+	}
+	return false
+}
+
+// carry_result stores the low Rsize bits of a result and sets the carry flag if the result does not fit
+func (vm *VM) carry_result(reg int, result int) {
+	limit := 1 << vm.Mach.Rsize
+	vm.Registers[reg] = vm.word(result)
+	vm.Extra_states["carryflag"] = result < 0 || result >= limit
+}
+
+// code_cell returns the program memory cell a data address refers to, -1 if it is a data only location
+func (vm *VM) code_cell(addr int) int {
+	switch vm.Mach.Execution_model() {
+	case "vn":
+		return addr
+	case "hy":
+		win := vm.Mach.Window_size()
+		if addr >= len(vm.Memory)-win {
+			return len(vm.Code) - len(vm.Memory) + addr
+		}
+	}
+	return -1
+}
+
+// Mem_read returns the data word at the given address, a program cell is read in its low bits
+func (vm *VM) Mem_read(addr int) (interface{}, error) {
+	if addr < 0 || addr >= len(vm.Memory) {
+		return nil, Prerror{"Memory address outside limits"}
+	}
+	if cell := vm.code_cell(addr); cell != -1 {
+		word := vm.Code[cell]
+		if len(word) > int(vm.Mach.Rsize) {
+			word = word[len(word)-int(vm.Mach.Rsize):]
+		}
+		if word == "" {
+			return vm.word(0), nil
+		}
+		return vm.word(get_id(word)), nil
+	}
+	return vm.Memory[addr], nil
+}
+
+// Mem_write stores a data word at the given address. A program cell shifts in the value at its low bits, so an
+// instruction wider than a register is written with a sequence of writes of its words, the most significant first.
+func (vm *VM) Mem_write(addr int, value interface{}) error {
+	if addr < 0 || addr >= len(vm.Memory) {
+		return Prerror{"Memory address outside limits"}
+	}
+	if cell := vm.code_cell(addr); cell != -1 {
+		rom_word := vm.Mach.Max_word()
+		word := zeros_prefix(rom_word, vm.Code[cell]) + zeros_prefix(int(vm.Mach.Rsize), get_binary(word_value(value)))
+		vm.Code[cell] = word[len(word)-rom_word:]
+		return nil
+	}
+	vm.Memory[addr] = value
 	return nil
 }
 
@@ -124,18 +299,34 @@ func (vm *VM) Step(psc *Sim_config) (string, error) {
 	}
 
 	//	reg_num := 1 << vm.Mach.R
-	num_instr := len(vm.Mach.Program.Slocs)
 	opbits := vm.Mach.Opcodes_bits()
 
-	if int(vm.Pc) > num_instr {
-		return "", Prerror{"Program counter outside limits"}
+	// Every step is a cycle of the timer, counted once the instruction is done
+	if vm.Timer != nil {
+		defer vm.timer_tick()
+	}
+
+	// An event is dispatched in place of the instruction, in a cycle of its own
+	if vm.Events != nil {
+		if inp := vm.event_dispatch(vm.busy == 0 && !vm.Events.Started); inp != -1 {
+			vm.Counters.Cycles++
+			vm.Counters.Stalls[STALL_EVENT]++
+			if psc != nil && psc.Show_instruction {
+				result += "\t\tEvent: " + Get_input_name(inp) + "\n"
+			}
+			return result, nil
+		}
+	}
+
+	instr, running, err := vm.Fetch()
+	if err != nil {
+		return "", err
 	}
 
-	if int(vm.Pc) == num_instr {
+	if !running {
 		// Halt computation
 		// vm.Pc = 0
 	} else {
-		instr := vm.Mach.Program.Slocs[vm.Pc]
 
 		if psc != nil {
 			if psc.Show_instruction {
@@ -146,13 +337,28 @@ func (vm *VM) Step(psc *Sim_config) (string, error) {
 		if opcode_id, err := vm.Mach.Conproc.Decode_opcode(instr); err == nil {
 			op := vm.Mach.Arch.Conproc.Op[opcode_id]
 
+			vm.Counters.Cycles++
+			if vm.Timing {
+				if vm.busy == 0 {
+					vm.busy, vm.busy_cause = op.Op_instruction_latency(&vm.Mach.Arch)
+				}
+				vm.busy--
+				if vm.busy > 0 {
+					vm.Counters.Stalls[vm.busy_cause]++
+					if psc != nil && psc.Show_instruction {
+						result += "\t\tStall: " + op.Op_get_name() + " (" + vm.busy_cause + ")\n"
+					}
+					return result, nil
+				}
+			}
+
 			if psc != nil {
 				if psc.Show_disasm {
 					curline := "\t\tDisasm: " + op.Op_get_name() + " "
 					if disas, err := op.Disassembler(&vm.Mach.Arch, instr[opbits:]); err != nil {
 						return "", Prerror{"Disassembling falied"}
 					} else {
-						result += curline + disas + "\n"
+						result += curline + disas + source_comment(vm.Source, int(vm.Pc)) + "\n"
 					}
 				}
 			}
@@ -165,9 +371,20 @@ func (vm *VM) Step(psc *Sim_config) (string, error) {
 				}
 			}
 
+			vm.waiting = ""
+			pc := vm.Pc
 			if err := op.Simulate(vm, instr[opbits:]); err != nil {
 				return "", Prerror{"Simulation failed"}
 			}
+			if vm.Events != nil {
+				vm.Events.Started = vm.Pc == pc && Event_multicycle(&vm.Mach.Arch, op)
+			}
+			if vm.waiting != "" {
+				vm.Counters.Stalls[vm.waiting]++
+			} else {
+				vm.Counters.Retired++
+				vm.Counters.By_opcode[op.Op_get_name()]++
+			}
 
 			if psc != nil {
 				if psc.Show_io_pre {
@@ -0,0 +1,501 @@
+package procbuilder
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func model_machine(mode string) *Machine {
+	mach := new(Machine)
+	arch := &mach.Arch
+	arch.Modes = []string{mode}
+	arch.Rsize = 16
+	arch.R = 2
+	arch.L = 4
+	arch.O = 4
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "j", "m2r", "nop", "r2m", "rset":
+			arch.Op = append(arch.Op, op)
+		}
+	}
+	sort.Sort(ByName(arch.Op))
+	return mach
+}
+
+// A program writes a jump in memory and executes it, only the vn and hy models can do it.
+// The 16 bits data word is shifted in the low part of the empty 21 bits instruction word, its top two bits are the
+// low ones of the jump location, so 0xc000 becomes "j 3".
+func TestExecutionModels(t *testing.T) {
+	for _, mode := range []string{"ha", "vn", "hy"} {
+		mach := model_machine(mode)
+
+		var err error
+		if mach.Program, err = mach.Assembler([]byte("rset r0 0xc000\nr2m r0 9\nm2r r1 9\nj 9\n")); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, ok := mach.Constraint_check(); !ok {
+			t.Fatal(mode + " constraint check failed")
+		}
+
+		vm := new(VM)
+		vm.Mach = mach
+		if err := vm.Init(); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 5; i++ {
+			if _, err := vm.Step(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		fmt.Println(mode, vm.Pc, vm.Dump_registers())
+
+		if word_value(vm.Registers[1]) != 0xc000 {
+			t.Error(mode + ": memory not read back")
+		}
+		switch mode {
+		case "ha":
+			if vm.Pc != 4 {
+				t.Error("ha: the data memory reached the program")
+			}
+		default:
+			if vm.Pc != 3 {
+				t.Error(mode + ": the written instruction was not executed")
+			}
+		}
+	}
+
+	mach := model_machine("vn")
+	mach.L = 3
+	if _, ok := mach.Constraint_check(); ok {
+		t.Error("vn accepted different RAM and ROM widths")
+	}
+}
+
+// A program patches a whole 21 bits instruction, rset r2 0xabcd, with two writes of its words and executes it
+func TestProgramPatch(t *testing.T) {
+	for _, mode := range []string{"vn", "hy"} {
+		mach := model_machine(mode)
+
+		patch, err := mach.Assembler([]byte("rset r2 0xabcd\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		instr := patch.Slocs[0]
+		high := get_id(instr[:len(instr)-16])
+		low := get_id(instr[len(instr)-16:])
+
+		source := fmt.Sprintf("rset r0 %d\nr2m r0 9\nrset r0 %d\nr2m r0 9\nj 9\n", high, low)
+		if mach.Program, err = mach.Assembler([]byte(source)); err != nil {
+			t.Fatal(err)
+		}
+
+		vm := new(VM)
+		vm.Mach = mach
+		if err := vm.Init(); err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < 10; i++ {
+			if _, err := vm.Step(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		fmt.Println(mode, vm.Pc, vm.Dump_registers())
+
+		if word_value(vm.Registers[2]) != 0xabcd || vm.Pc != 10 {
+			t.Error(mode + ": the patched instruction was not executed")
+		}
+	}
+}
+
+// A 16 bits sum and difference on an 8 bits machine, jc jumps only without carry
+func TestCarry(t *testing.T) {
+	mach := model_machine("ha")
+	mach.Rsize = 8
+	mach.Op = nil
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "adc", "clc", "inc", "jc", "rset", "sbc":
+			mach.Op = append(mach.Op, op)
+		}
+	}
+	sort.Sort(ByName(mach.Op))
+
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("rset r0 200\nrset r1 100\nadc r0 r1\njc 5\ninc r2\nsbc r0 r1\njc 8\ninc r2\nclc\njc 11\ninc r2\ninc r3\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fmt.Println(vm.Pc, vm.Dump_registers())
+
+	if word_value(vm.Registers[0]) != 200 || word_value(vm.Registers[2]) != 2 || vm.Pc != 11 {
+		t.Error("Wrong carry handling")
+	}
+}
+
+// The mulc carry tells if any bit of the product does not fit, 200*200 is 0x9c40 and its bit 8 is clear
+func TestMulcCarry(t *testing.T) {
+	mach := model_machine("ha")
+	mach.Rsize = 8
+	mach.Op = nil
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "mulc", "rset":
+			mach.Op = append(mach.Op, op)
+		}
+	}
+	sort.Sort(ByName(mach.Op))
+
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("rset r0 200\nrset r1 200\nmulc r0 r1\nrset r2 15\nrset r3 17\nmulc r2 r3\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	carries := make([]bool, 0)
+	for i := 0; i < 6; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+		carries = append(carries, vm.Carry())
+	}
+
+	fmt.Println(vm.Pc, vm.Dump_registers(), carries)
+
+	if word_value(vm.Registers[0]) != 0x40 || !carries[2] {
+		t.Error("Wrong overflowing product")
+	}
+	if word_value(vm.Registers[2]) != 255 || carries[5] {
+		t.Error("Wrong fitting product")
+	}
+
+	conf := new(Config)
+	conf.Runinfo = new(RuntimeInfo)
+	conf.Runinfo.Init()
+	if rtl := mach.Conproc.Write_verilog(conf, &mach.Arch, "p0", "iverilog"); !strings.Contains(rtl, "carryflag <= #1 |(({8'b0, _r0} * {8'b0, _r1}) >> 8);") {
+		t.Error("The RTL carry does not cover the whole high part of the product")
+	}
+}
+
+// The m2r needs two cycles, the register is written on the second one
+func TestTiming(t *testing.T) {
+	mach := model_machine("ha")
+
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("rset r0 5\nr2m r0 2\nm2r r1 2\nnop\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	vm.Timing = true
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vm.Pc != 2 || word_value(vm.Registers[1]) != 0 {
+		t.Error("m2r executed on its first cycle")
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fmt.Print(vm.Counters.String())
+
+	if word_value(vm.Registers[1]) != 5 {
+		t.Error("Memory not read back")
+	}
+	if vm.Counters.Cycles != 5 || vm.Counters.Retired != 4 || vm.Counters.Stalls[STALL_RAM] != 1 {
+		t.Error("Wrong counters")
+	}
+}
+
+// A processor alone on a buffered channel of depth 2: the third write does not fit and the last read waits for data
+func TestBufferedChannel(t *testing.T) {
+	mach := model_machine("ha")
+	mach.Rsize = 8
+	mach.Op = make([]Opcode, 0)
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "chc", "chw", "rset", "wrd", "wwr":
+			mach.Op = append(mach.Op, op)
+		}
+	}
+	sort.Sort(ByName(mach.Op))
+	mach.Shared_constraints = "channel:2"
+
+	program := "rset r0 5\nwwr r0 ch0\nchw r1\nrset r0 6\nwwr r0 ch0\nchw r1\nwwr r0 ch0\nchc r2 r1\n"
+	program += "wrd r3 ch0\nchw r1\nwrd r3 ch0\nchw r1\nwrd r3 ch0\nchw r1\n"
+	var err error
+	if mach.Program, err = mach.Assembler([]byte(program)); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vm.Pc != 10 || word_value(vm.Registers[2]) != 0 || word_value(vm.Registers[3]) != 5 {
+		t.Error("Wrong buffered writes", vm.Pc, vm.Dump_registers())
+	}
+	for i := 0; i < 10; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fmt.Print(vm.Counters.String())
+
+	if vm.Pc != 13 || word_value(vm.Registers[3]) != 6 || vm.Counters.Stalls[STALL_CHANNEL] != 7 {
+		t.Error("The read of the empty channel did not wait", vm.Pc, vm.Dump_registers())
+	}
+}
+
+// Two buffered channels holding data are read by consecutive selects, both serve the first queued read
+func TestSelectOrder(t *testing.T) {
+	mach := model_machine("ha")
+	mach.Rsize = 8
+	mach.R = 3
+	mach.O = 5
+	mach.Op = make([]Opcode, 0)
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "chw", "rset", "wrd", "wwr":
+			mach.Op = append(mach.Op, op)
+		}
+	}
+	sort.Sort(ByName(mach.Op))
+	mach.Shared_constraints = "channel:2,channel:2"
+
+	program := "rset r0 1\nwwr r0 ch0\nchw r3\nwwr r0 ch0\nchw r3\nwwr r0 ch1\nchw r3\nwwr r0 ch1\nchw r3\n"
+	program += "wrd r1 ch0\nwrd r2 ch1\nchw r4\nwrd r1 ch0\nwrd r2 ch1\nchw r5\n"
+	var err error
+	if mach.Program, err = mach.Assembler([]byte(program)); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 15; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vm.Pc != 15 || word_value(vm.Registers[4]) != 0 || word_value(vm.Registers[5]) != 0 {
+		t.Error("The selects did not serve the first queued read", vm.Pc, vm.Dump_registers())
+	}
+}
+
+// A closed buffered channel refuses the writes, delivers what it holds and then completes the reads as closed
+func TestChannelClose(t *testing.T) {
+	mach := model_machine("ha")
+	mach.Rsize = 8
+	mach.Op = make([]Opcode, 0)
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "chc", "chclose", "chw", "rset", "wrd", "wwr":
+			mach.Op = append(mach.Op, op)
+		}
+	}
+	sort.Sort(ByName(mach.Op))
+	mach.Shared_constraints = "channel:2"
+
+	program := "rset r0 5\nwwr r0 ch0\nchw r1\nchclose ch0\nwwr r0 ch0\nchc r2 r1\n"
+	program += "wrd r3 ch0\nchw r1\nrset r3 7\nwrd r3 ch0\nwwr r0 ch0\nchw r1\n"
+	var err error
+	if mach.Program, err = mach.Assembler([]byte(program)); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 8; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vm.Pc != 8 || word_value(vm.Registers[2]) != 0 || word_value(vm.Registers[1]) != 0 || word_value(vm.Registers[3]) != 5 {
+		t.Error("Wrong reads before the drain", vm.Pc, vm.Dump_registers())
+	}
+	for i := 0; i < 4; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if vm.Pc != 12 || word_value(vm.Registers[1]) != 2 || word_value(vm.Registers[3]) != 0 {
+		t.Error("The read of the drained channel did not report the close", vm.Pc, vm.Dump_registers())
+	}
+}
+
+// A processor counting in a loop serves the events of two inputs, changing at the same time: the lower input goes first
+// and its handler disables it, so that its next change is ignored
+func TestEvents(t *testing.T) {
+	mach := model_machine("ha")
+	mach.N = 2
+	mach.Op = make([]Opcode, 0)
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "evd", "eve", "evr", "i2r", "inc", "j":
+			mach.Op = append(mach.Op, op)
+		}
+	}
+	sort.Sort(ByName(mach.Op))
+
+	program := "eve i1 4\neve i0 6\ninc r0\nj 2\ni2r r1 i1\nevr\ninc r2\nevd i0\nevr\n"
+	var err error
+	if mach.Program, err = mach.Assembler([]byte(program)); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	step := func(n int) {
+		for i := 0; i < n; i++ {
+			if _, err := vm.Step(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	step(4)
+	vm.Inputs[0] = vm.word(3)
+	vm.Inputs[1] = vm.word(7)
+	step(8)
+	if vm.Pc != 3 || word_value(vm.Registers[1]) != 7 || word_value(vm.Registers[2]) != 1 || vm.Counters.Stalls[STALL_EVENT] != 2 {
+		t.Error("Wrong events handling", vm.Pc, vm.Dump_registers())
+	}
+
+	vm.Inputs[0] = vm.word(4)
+	vm.Inputs[1] = vm.word(8)
+	step(2)
+	fmt.Print(vm.Counters.String())
+
+	if vm.Pc != 4 || word_value(vm.Registers[2]) != 1 || vm.Events.Pending[0] {
+		t.Error("The disabled event has been served", vm.Pc, vm.Dump_registers())
+	}
+}
+
+// An event arriving while sic waits for its input is served only once sic is done, as the RTL does not interrupt the
+// multi-cycle instructions
+func TestEventsBoundary(t *testing.T) {
+	mach := model_machine("ha")
+	mach.N = 2
+	mach.Op = make([]Opcode, 0)
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "eve", "evr", "i2r", "j", "sic":
+			mach.Op = append(mach.Op, op)
+		}
+	}
+	sort.Sort(ByName(mach.Op))
+
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("eve i0 3\nsic r0 i1\nj 1\ni2r r2 i0\nevr\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	step := func(n int) {
+		for i := 0; i < n; i++ {
+			if _, err := vm.Step(nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	step(2)
+	vm.Inputs[0] = vm.word(7)
+	step(3)
+	if vm.Pc != 1 || !vm.Events.Pending[0] {
+		t.Error("The event interrupted sic", vm.Pc, vm.Dump_registers())
+	}
+
+	vm.Inputs[1] = vm.word(1)
+	step(2)
+	if vm.Pc != 3 {
+		t.Error("The event has not been served after sic", vm.Pc, vm.Dump_registers())
+	}
+}
+
+// A periodic timer of 3<<1 cycles paces a loop, the cycles counter read within it advances by the period
+func TestTimer(t *testing.T) {
+	mach := model_machine("ha")
+	mach.Op = make([]Opcode, 0)
+	for _, op := range Allopcodes {
+		switch op.Op_get_name() {
+		case "inc", "j", "rset", "tmp", "tmr", "tmw":
+			mach.Op = append(mach.Op, op)
+		}
+	}
+	sort.Sort(ByName(mach.Op))
+
+	var err error
+	if mach.Program, err = mach.Assembler([]byte("rset r0 3\ntmp r0 1\ntmw\ninc r1\ntmr r2 0\nj 2\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	vm := new(VM)
+	vm.Mach = mach
+	if err := vm.Init(); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 11; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if word_value(vm.Registers[1]) != 1 || word_value(vm.Registers[2]) != 10 {
+		t.Error("Wrong first period", vm.Pc, vm.Dump_registers())
+	}
+	for i := 0; i < 6; i++ {
+		if _, err := vm.Step(nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fmt.Print(vm.Counters.String())
+
+	if word_value(vm.Registers[1]) != 2 || word_value(vm.Registers[2]) != 16 || vm.Counters.Stalls[STALL_TIMER] != 8 {
+		t.Error("Wrong period", vm.Pc, vm.Dump_registers())
+	}
+}
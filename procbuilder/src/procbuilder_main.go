@@ -23,7 +23,7 @@ var execution_model = flag.String("execution-model", "ha", "Execution model: vn
 
 var register_size = flag.Int("register-size", 8, "Number of bits per register (n-bit)")
 
-var enabled_opcodes = flag.String("opcodes", "adc,add,addf,addi,and,cil,cilc,cir,cirn,chc,chw,clc,clr,cpy,cset,dec,div,divf,dpc,hit,hlt,i2r,i2rw,incc,inc,j,jc,je,jz,lfsr82r,m2r,mod,mulc,mult,multf,nand,nop,nor,not,or,r2m,r2o,r2owa,r2owaa,r2s,rsc,rset,sic,s2r,saj,sbc,sub,wrd,wwr,xnor,xor", "Enabled opcodes")
+var enabled_opcodes = flag.String("opcodes", "adc,add,addf,addi,and,cil,cilc,cir,cirn,chc,chw,clc,clr,cpy,cset,dec,div,divf,dpc,hit,hlt,i2r,i2rw,incc,inc,j,jc,je,jz,lfsr82r,m2r,mod,mulc,mult,multf,nand,nop,nor,not,or,r2m,r2o,r2owa,r2owaa,r2s,rsc,rset,sic,s2r,saj,sbc,sub,wrd,wwr,xnor,xor", "Enabled opcodes, all enables every registered opcode")
 
 var rbit = flag.Int("registers", 3, "Number of n-bit registers 2^")
 var lbit = flag.Int("ram", 8, "Number of n-bit RAM memory cells 2^")
@@ -45,6 +45,9 @@ var create_verilog_arch = flag.String("create-verilog-arch", "", "Filename of ve
 var create_verilog_testbench = flag.String("create-verilog-testbench", "", "Filename of verilog testbench")
 var create_verilog_main = flag.String("create-verilog-main", "", "Filename of verilog main file for FPGA")
 var verilog_flavor = flag.String("verilog-flavor", "iverilog", "Choose the type of verilog device. currently supported: iverilog,kintex7.")
+var output_dir = flag.String("output-dir", ".", "Directory where the generated files are written")
+var force = flag.Bool("force", false, "Overwrite the generated files already present in the output directory")
+var keep = flag.Bool("keep", false, "Keep the generated files already present in the output directory")
 
 var show_instructions_alias = flag.Bool("show-instructions-alias", false, "Show instructions alias for the processor")
 var show_program_alias = flag.Bool("show-program-alias", false, "Show program alias for the processor")
@@ -58,6 +61,10 @@ var show_program_binary = flag.Bool("show-program-binary", false, "Show program
 var show_program_disassembled = flag.Bool("show-program-disassembled", false, "Show disassebled program")
 
 var show_opcodes = flag.Bool("show-opcodes", false, "Show loaded opcodes")
+var list_opcodes = flag.Bool("list-opcodes", false, "List the registered opcodes")
+var list_shared_objects = flag.Bool("list-shared-objects", false, "List the registered shared objects")
+
+var save_resources = flag.String("save-resources", "", "Save the estimated resources for the verilog flavor in JSON format")
 
 var hex = flag.Bool("hex", false, "Use HEX")
 var numlines = flag.Bool("numlines", false, "Use line numbers")
@@ -66,6 +73,14 @@ var simbox_file = flag.String("simbox-file", "", "Filename of the simulation dat
 
 var sim = flag.Bool("sim", false, "Simulate machine")
 var sim_interactions = flag.Int("sim-interactions", 10, "Simulation interaction")
+var debug_info = flag.String("debug-info", "", "Debug info JSON file from bondgo, the disassembly shows the source of the instructions")
+var sim_timing = flag.Bool("sim-timing", false, "Model the opcodes latencies in the simulation (a tick is a clock cycle) and show the performance counters")
+
+var cosim = flag.Bool("cosim", false, "Co-simulate the machine on the VM and on iverilog, report the first divergence")
+var cosim_dir = flag.String("cosim-dir", "cosim", "Directory where the co-simulation files are written")
+
+var debugger = flag.Bool("debugger", false, "Debug the machine interactively, the simbox drives the inputs")
+var debugger_gdb_port = flag.Int("debugger-gdb-port", 0, "Serve the debugger with the GDB remote protocol on this localhost port instead of the console")
 
 var run = flag.Bool("run", false, "Run machine")
 var run_interactions = flag.Int("run-interactions", 1000, "Run interaction")
@@ -76,6 +91,33 @@ func check(e error) {
 	}
 }
 
+func load_simbox() *simbox.Simbox {
+	var sbox *simbox.Simbox
+	if *simbox_file != "" {
+		sbox = new(simbox.Simbox)
+		if _, err := os.Stat(*simbox_file); err == nil {
+			// Open the simbox file is exists
+			if simbox_json, err := ioutil.ReadFile(*simbox_file); err == nil {
+				if err := json.Unmarshal([]byte(simbox_json), sbox); err != nil {
+					panic(err)
+				}
+			} else {
+				panic(err)
+			}
+		}
+	}
+	return sbox
+}
+
+func load_debug_info() *procbuilder.Debug_info {
+	if *debug_info == "" {
+		return nil
+	}
+	di, err := procbuilder.Load_debug_info(*debug_info)
+	check(err)
+	return di
+}
+
 func init() {
 	rand.Seed(int64(time.Now().Unix()))
 	flag.Parse()
@@ -85,6 +127,20 @@ func main() {
 	//ep := new(mel.Evolution_parameters)
 	//ep.Pars = make(map[string]string)
 
+	// The registered components, used by the shell completion too
+	if *list_opcodes {
+		for _, name := range procbuilder.Opcode_names() {
+			fmt.Println(name)
+		}
+		return
+	}
+	if *list_shared_objects {
+		for _, name := range procbuilder.Shared_names() {
+			fmt.Println(name)
+		}
+		return
+	}
+
 	ri := new(procbuilder.RuntimeInfo)
 	ri.Init()
 
@@ -199,7 +255,9 @@ func main() {
 			// TODO include opcodes checks
 			// TODO keep the opcodes unique and sorted
 			var eops []string
-			if *enabled_opcodes != "" {
+			if *enabled_opcodes == "all" {
+				eops = procbuilder.Opcode_names()
+			} else if *enabled_opcodes != "" {
 				eops = strings.Split(*enabled_opcodes, ",")
 			} else {
 				panic("Missing opcodes")
@@ -250,6 +308,13 @@ func main() {
 
 		if *verbose {
 			fmt.Print(checks)
+			fmt.Print(mymachine.Estimate_resources(*verilog_flavor))
+		}
+
+		if *save_resources != "" {
+			b, err := json.MarshalIndent(mymachine.Estimate_resources(*verilog_flavor), "", "  ")
+			check(err)
+			check(ioutil.WriteFile(*save_resources, b, 0644))
 		}
 
 		// Eventually show alias instrictions data
@@ -310,125 +375,61 @@ func main() {
 		}
 
 		// Eventually create verilog files
-		if *create_verilog {
-			if _, err := os.Stat("arch.v"); os.IsNotExist(err) {
-				f, err := os.Create("arch.v")
-				check(err)
-				defer f.Close()
-				_, err = f.WriteString(myarch.Write_verilog("a0", map[string]string{"processor": "p0", "rom": "p0rom", "ram": "p0ram"}, *verilog_flavor))
-				check(err)
-			}
-
-			if _, err := os.Stat("processor.v"); os.IsNotExist(err) {
-				f, err := os.Create("processor.v")
-				check(err)
-				defer f.Close()
-				_, err = f.WriteString(myarch.Conproc.Write_verilog(conf, myarch, "p0", *verilog_flavor))
-				check(err)
-			}
+		if *create_verilog || *create_verilog_processor != "" || *create_verilog_ram != "" || *create_verilog_rom != "" || *create_verilog_testbench != "" || *create_verilog_main != "" {
+			policy, err := procbuilder.Output_policy(*force, *keep)
+			check(err)
+			out := &procbuilder.Output{Dir: *output_dir, Policy: policy}
+			check(out.Init())
 
-			if _, err := os.Stat("ram.v"); os.IsNotExist(err) {
-				f, err := os.Create("ram.v")
-				check(err)
-				defer f.Close()
-				_, err = f.WriteString(myarch.Ram.Write_verilog(mymachine, "p0ram", *verilog_flavor))
-				check(err)
+			var files map[string]string
+			if *create_verilog {
+				files = map[string]string{"arch": "arch.v", "processor": "processor.v", "ram": "ram.v", "rom": "rom.v", "testbench": "testbench.v", "main": "main.v"}
+			} else {
+				files = map[string]string{"processor": *create_verilog_processor, "ram": *create_verilog_ram, "rom": *create_verilog_rom, "testbench": *create_verilog_testbench, "main": *create_verilog_main}
 			}
 
-			if _, err := os.Stat("rom.v"); os.IsNotExist(err) {
-				f, err := os.Create("rom.v")
-				check(err)
-				defer f.Close()
-				_, err = f.WriteString(myarch.Rom.Write_verilog(mymachine, "p0rom", *verilog_flavor))
-				check(err)
+			if files["arch"] != "" {
+				check(out.Write(files["arch"], myarch.Write_verilog("a0", map[string]string{"processor": "p0", "rom": "p0rom", "ram": "p0ram"}, *verilog_flavor)))
 			}
-
-			if _, err := os.Stat("testbench.v"); os.IsNotExist(err) {
-				f, err := os.Create("testbench.v")
-				check(err)
-				defer f.Close()
-				_, err = f.WriteString(myarch.Write_verilog_testbench("a0", "processor", "memory", *verilog_flavor))
-				check(err)
+			if files["processor"] != "" {
+				check(out.Write(files["processor"], myarch.Conproc.Write_verilog(conf, myarch, "p0", *verilog_flavor)))
 			}
-
-			if _, err := os.Stat("main.v"); os.IsNotExist(err) {
-				f, err := os.Create("main.v")
-				check(err)
-				defer f.Close()
-				_, err = f.WriteString(myarch.Write_verilog_main("p0", "p0rom", "processor", "memory", *verilog_flavor))
-				check(err)
+			if files["ram"] != "" {
+				check(out.Write(files["ram"], myarch.Ram.Write_verilog(mymachine, "p0ram", *verilog_flavor)))
 			}
-		} else {
-			if *create_verilog_processor != "" {
-				if _, err := os.Stat(*create_verilog_processor); os.IsNotExist(err) {
-					f, err := os.Create(*create_verilog_processor)
-					check(err)
-					defer f.Close()
-					_, err = f.WriteString(myarch.Conproc.Write_verilog(conf, myarch, "p0", *verilog_flavor))
-					check(err)
-				}
+			if files["rom"] != "" {
+				check(out.Write(files["rom"], myarch.Rom.Write_verilog(mymachine, "p0rom", *verilog_flavor)))
 			}
-
-			if *create_verilog_ram != "" {
-				if _, err := os.Stat(*create_verilog_ram); os.IsNotExist(err) {
-					f, err := os.Create(*create_verilog_ram)
-					check(err)
-					defer f.Close()
-					_, err = f.WriteString(myarch.Ram.Write_verilog(mymachine, "p0ram", *verilog_flavor))
-					check(err)
-				}
+			if files["testbench"] != "" {
+				check(out.Write(files["testbench"], myarch.Write_verilog_testbench("a0", "processor", "memory", *verilog_flavor)))
 			}
-
-			if *create_verilog_rom != "" {
-				if _, err := os.Stat(*create_verilog_rom); os.IsNotExist(err) {
-					f, err := os.Create(*create_verilog_rom)
-					check(err)
-					defer f.Close()
-					_, err = f.WriteString(myarch.Rom.Write_verilog(mymachine, "p0rom", *verilog_flavor))
-					check(err)
-				}
+			if files["main"] != "" {
+				check(out.Write(files["main"], myarch.Write_verilog_main("p0", "p0rom", "processor", "memory", *verilog_flavor)))
 			}
 
-			if *create_verilog_testbench != "" {
-				if _, err := os.Stat(*create_verilog_testbench); os.IsNotExist(err) {
-					f, err := os.Create(*create_verilog_testbench)
-					check(err)
-					defer f.Close()
-					_, err = f.WriteString(myarch.Write_verilog_testbench("a0", "processor", "memory", *verilog_flavor))
-					check(err)
+			// The top is the outermost module written
+			top := ""
+			for _, kind := range [][2]string{{"testbench", "main_tb"}, {"main", "main"}, {"arch", "a0"}, {"processor", "p0"}} {
+				if files[kind[0]] != "" {
+					top = kind[1]
+					break
 				}
 			}
+			check(out.Write_manifest(procbuilder.Target{Top: top}))
 
-			if *create_verilog_main != "" {
-				if _, err := os.Stat(*create_verilog_main); os.IsNotExist(err) {
-					f, err := os.Create(*create_verilog_main)
-					check(err)
-					defer f.Close()
-					_, err = f.WriteString(myarch.Write_verilog_main("p0", "p0rom", "processor", "memory", *verilog_flavor))
-					check(err)
-				}
+			for _, stale := range out.Stale() {
+				fmt.Println("Warning: kept stale file " + stale)
 			}
 		}
-		if *sim {
-			var sbox *simbox.Simbox
-			if *simbox_file != "" {
-				sbox = new(simbox.Simbox)
-				if _, err := os.Stat(*simbox_file); err == nil {
-					// Open the simbox file is exists
-					if simbox_json, err := ioutil.ReadFile(*simbox_file); err == nil {
-						if err := json.Unmarshal([]byte(simbox_json), sbox); err != nil {
-							panic(err)
-						}
-					} else {
-						panic(err)
-					}
-				}
 
-			}
+		if *sim {
+			sbox := load_simbox()
 
 			// Build the VM
 			vm := new(procbuilder.VM)
 			vm.Mach = mymachine
+			vm.Timing = *sim_timing
+			vm.Source = load_debug_info().Processor(0)
 			err := vm.Init()
 			check(err)
 
@@ -450,7 +451,9 @@ func main() {
 				if sconfig.Show_pc {
 					fmt.Println("Program Counter:", vm.Pc)
 				}
-				fmt.Println("Instruction: ", vm.Mach.Slocs[vm.Pc])
+				instr, _, err := vm.Fetch()
+				check(err)
+				fmt.Println("Instruction: ", instr)
 				fmt.Println("Registers before: ", vm.Dump_registers())
 				fmt.Println("IO before: ", vm.Dump_io())
 
@@ -461,7 +464,7 @@ func main() {
 					}
 				}
 
-				_, err := vm.Step(sconfig)
+				_, err = vm.Step(sconfig)
 				check(err)
 
 				// This will get value to report on this tick
@@ -474,16 +477,55 @@ func main() {
 				fmt.Println("Registers after: ", vm.Dump_registers())
 				fmt.Println("IO after: ", vm.Dump_io(), "\n")
 			}
+			if *sim_timing {
+				fmt.Print("Performance counters: ", vm.Counters.String())
+			}
+		} else if *debugger {
+			sbox := load_simbox()
+
+			vm := new(procbuilder.VM)
+			vm.Mach = mymachine
+			vm.Timing = *sim_timing
+			vm.Source = load_debug_info().Processor(0)
+			err := vm.Init()
+			check(err)
+
+			target := new(procbuilder.Machine_target)
+			target.Vm = vm
+			if sbox != nil {
+				target.Drive = new(procbuilder.Sim_drive)
+				check(target.Drive.Init(sbox, vm))
+			}
+
+			dbg := new(procbuilder.Debugger)
+			dbg.Target = target
+			if *debugger_gdb_port != 0 {
+				check(dbg.Serve_gdb(*debugger_gdb_port))
+			} else {
+				dbg.Repl(os.Stdin, os.Stdout)
+			}
+		} else if *cosim {
+			report, equal, err := mymachine.Cosim(*cosim_dir, load_simbox(), *sim_interactions)
+			check(err)
+			fmt.Print(report)
+			if !equal {
+				os.Exit(1)
+			}
 		} else if *run {
 			// TODO The sdrive and report goes also here
 			vm := new(procbuilder.VM)
 			vm.Mach = mymachine
+			vm.Timing = *sim_timing
+			vm.Source = load_debug_info().Processor(0)
 			err := vm.Init()
 			check(err)
 			for i := 0; i < *run_interactions; i++ {
 				_, err := vm.Step(nil)
 				check(err)
 			}
+			if *sim_timing {
+				fmt.Print("Performance counters: ", vm.Counters.String())
+			}
 		}
 	} else {
 		fmt.Println("Constraint check failed: " + checks)